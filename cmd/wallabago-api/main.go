@@ -5,16 +5,93 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/andriihomiak/wallabago/internal/app"
+	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/andriihomiak/wallabago/internal/http"
 )
 
 func main() {
-	addr := "0.0.0.0:8080"
-	if port, ok := os.LookupEnv("WALLABAGO_PORT"); ok {
+	port := "8080"
+	if envPort, ok := os.LookupEnv("WALLABAGO_PORT"); ok {
 		// todo: check if port is int?
-		addr = fmt.Sprintf("0.0.0.0:%s", port)
+		port = envPort
+	}
+	addr := fmt.Sprintf("0.0.0.0:%s", port)
+	publicURL := fmt.Sprintf("http://localhost:%s", port)
+	if envPublicURL, ok := os.LookupEnv("WALLABAGO_PUBLIC_URL"); ok {
+		publicURL = envPublicURL
+	}
+	// the verification keys are separated by commas, e.g. the previous
+	// signing key can be listed here when rotating to a new one
+	signingKeyFile := os.Getenv("WALLABAGO_SIGNING_KEY_FILE")
+	var verificationKeyFiles []string
+	if envVerificationKeyFiles := os.Getenv("WALLABAGO_VERIFICATION_KEY_FILES"); envVerificationKeyFiles != "" {
+		verificationKeyFiles = strings.Split(envVerificationKeyFiles, ",")
+	}
+	// clients register themselves either with the initial access token
+	// or without any when the registration is open
+	clientRegistrationOpen, _ := strconv.ParseBool(os.Getenv("WALLABAGO_CLIENT_REGISTRATION_OPEN"))
+	clientRegistrationToken := os.Getenv("WALLABAGO_CLIENT_REGISTRATION_TOKEN")
+	// the users log in at the upstream OpenID Connect provider when its issuer is set,
+	// the administrators are the users with the admin claim
+	upstream := core.UpstreamConfig{
+		Issuer:       os.Getenv("WALLABAGO_UPSTREAM_OIDC_ISSUER"),
+		ClientID:     os.Getenv("WALLABAGO_UPSTREAM_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("WALLABAGO_UPSTREAM_OIDC_CLIENT_SECRET"),
+		Scope:        os.Getenv("WALLABAGO_UPSTREAM_OIDC_SCOPE"),
+		Name:         os.Getenv("WALLABAGO_UPSTREAM_OIDC_NAME"),
+		Admin: core.AdminClaimMapping{
+			Claim: os.Getenv("WALLABAGO_UPSTREAM_OIDC_ADMIN_CLAIM"),
+			Value: os.Getenv("WALLABAGO_UPSTREAM_OIDC_ADMIN_VALUE"),
+		},
+	}
+	// the users without a local password are checked against the directory when its url is set
+	ldapStartTLS, _ := strconv.ParseBool(os.Getenv("WALLABAGO_LDAP_START_TLS"))
+	ldap := core.LDAPConfig{
+		URL:          os.Getenv("WALLABAGO_LDAP_URL"),
+		StartTLS:     ldapStartTLS,
+		BindDN:       os.Getenv("WALLABAGO_LDAP_BIND_DN"),
+		BindPassword: os.Getenv("WALLABAGO_LDAP_BIND_PASSWORD"),
+		BaseDN:       os.Getenv("WALLABAGO_LDAP_BASE_DN"),
+		UserFilter:   os.Getenv("WALLABAGO_LDAP_USER_FILTER"),
+		Attributes: core.LDAPAttributes{
+			Username: os.Getenv("WALLABAGO_LDAP_USERNAME_ATTRIBUTE"),
+			Email:    os.Getenv("WALLABAGO_LDAP_EMAIL_ATTRIBUTE"),
+			Groups:   os.Getenv("WALLABAGO_LDAP_GROUPS_ATTRIBUTE"),
+		},
+		AdminGroup: os.Getenv("WALLABAGO_LDAP_ADMIN_GROUP"),
+	}
+	// the password reset emails go to the smtp server, the file or the log, whichever is set first
+	smtpStartTLS, _ := strconv.ParseBool(os.Getenv("WALLABAGO_SMTP_START_TLS"))
+	mailLog, _ := strconv.ParseBool(os.Getenv("WALLABAGO_MAIL_LOG"))
+	mail := core.MailConfig{
+		From: os.Getenv("WALLABAGO_MAIL_FROM"),
+		SMTP: core.SMTPConfig{
+			Addr:     os.Getenv("WALLABAGO_SMTP_ADDR"),
+			StartTLS: smtpStartTLS,
+			Username: os.Getenv("WALLABAGO_SMTP_USERNAME"),
+			Password: os.Getenv("WALLABAGO_SMTP_PASSWORD"),
+		},
+		File: os.Getenv("WALLABAGO_MAIL_FILE"),
+		Log:  mailLog,
+	}
+	// the unset or invalid numbers fall back to the defaults
+	passwordMinLength, _ := strconv.Atoi(os.Getenv("WALLABAGO_PASSWORD_MIN_LENGTH"))
+	argon2Memory, _ := strconv.ParseUint(os.Getenv("WALLABAGO_ARGON2_MEMORY_KIB"), 10, 32)
+	argon2Iterations, _ := strconv.ParseUint(os.Getenv("WALLABAGO_ARGON2_ITERATIONS"), 10, 32)
+	argon2Parallelism, _ := strconv.ParseUint(os.Getenv("WALLABAGO_ARGON2_PARALLELISM"), 10, 8)
+	//nolint:gosec //the numbers were parsed with the bit sizes of the fields
+	passwords := core.PasswordConfig{
+		MinLength:    passwordMinLength,
+		BreachedFile: os.Getenv("WALLABAGO_PASSWORD_BREACHED_FILE"),
+		Argon2: core.Argon2Params{
+			Memory:      uint32(argon2Memory),
+			Iterations:  uint32(argon2Iterations),
+			Parallelism: uint8(argon2Parallelism),
+		},
 	}
 	_, instrument := os.LookupEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
 	dbConnString := os.Getenv("DB")
@@ -24,6 +101,17 @@ func main() {
 			Addr:                   addr,
 			InstrumentationEnabled: instrument,
 			DBConnectionString:     dbConnString,
+			PublicURL:              publicURL,
+			SigningKeyFile:         signingKeyFile,
+			VerificationKeyFiles:   verificationKeyFiles,
+
+			ClientRegistrationOpen:               clientRegistrationOpen,
+			ClientRegistrationInitialAccessToken: clientRegistrationToken,
+
+			Upstream:  upstream,
+			LDAP:      ldap,
+			Mail:      mail,
+			Passwords: passwords,
 		},
 	)
 	if err != nil {
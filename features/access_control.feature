@@ -15,3 +15,18 @@ Feature: Access Control
             |invalid|valid  |rejected       |
             |valid  |invalid|rejected       |
             |invalid|invalid|rejected       |
+
+    Scenario: Refresh Token Rotation
+        Given I am authenticated as admin
+        When I refresh the access token
+        Then the client should be authenticated
+        When I refresh the access token using the previous refresh token
+        Then the client should be rejected
+        When I refresh the access token
+        Then the client should be rejected
+
+    Scenario: Refresh Token Revocation
+        Given I am authenticated as admin
+        When I revoke the refresh token
+        And I refresh the access token
+        Then the client should be rejected
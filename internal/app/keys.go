@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+func loadSigningKey(path string) (*core.SigningKey, error) {
+	//nolint:gosec //the path comes from the operator
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	key, err := core.NewSigningKeyFromPEM("", pemBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load key %s", path)
+	}
+	return key, nil
+}
+
+// loadKeySet reads the keys used to sign and verify tokens.
+// Without a configured signing key a new one is generated on every start,
+// which invalidates all issued tokens on restart.
+func loadKeySet(ctx context.Context, config *Config) (*core.KeySet, error) {
+	var signing *core.SigningKey
+	var err error
+	if config.SigningKeyFile == "" {
+		slog.WarnContext(ctx, "No signing key configured, using an ephemeral key. "+
+			"Issued tokens will not survive a restart")
+		signing, err = core.NewEphemeralSigningKey()
+	} else {
+		signing, err = loadSigningKey(config.SigningKeyFile)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	verification := make([]*core.SigningKey, 0, len(config.VerificationKeyFiles))
+	for _, path := range config.VerificationKeyFiles {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		verification = append(verification, key)
+	}
+
+	keys, err := core.NewKeySet(signing, verification...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	slog.InfoContext(ctx, "Loaded signing keys", "activeKeyID", signing.ID, "verificationKeys", len(verification))
+	return keys, nil
+}
@@ -5,16 +5,19 @@ import (
 	"database/sql"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	stderrors "errors"
 
 	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/andriihomiak/wallabago/internal/database"
 	"github.com/andriihomiak/wallabago/internal/engines"
+	"github.com/andriihomiak/wallabago/internal/federation"
 	"github.com/andriihomiak/wallabago/internal/http/handlers"
-	"github.com/andriihomiak/wallabago/internal/http/handlers/docs"
 	"github.com/andriihomiak/wallabago/internal/http/middleware"
 	"github.com/andriihomiak/wallabago/internal/instrumentation"
+	"github.com/andriihomiak/wallabago/internal/mailer"
 	"github.com/andriihomiak/wallabago/internal/managers"
 	"github.com/andriihomiak/wallabago/internal/storage"
 	"github.com/pkg/errors"
@@ -24,11 +27,40 @@ type Config struct {
 	Addr                   string
 	InstrumentationEnabled bool
 	DBConnectionString     string
+	// PublicURL is the base url the server is reachable at by the users,
+	// used to build links handed out to other devices
+	PublicURL string
+	// SigningKeyFile is the PEM encoded private key used to sign tokens
+	SigningKeyFile string
+	// VerificationKeyFiles are PEM encoded keys still accepted for the verification,
+	// e.g. the previous signing key during a rotation
+	VerificationKeyFiles []string
 
 	BootstrapAdminEmail, BootstrapAdminUsername, BootstrapAdminPassword string
 	BootstrapClientID, BootstrapClientSecret                            string
+
+	// ClientRegistrationOpen lets anyone register a client dynamically
+	ClientRegistrationOpen bool
+	// ClientRegistrationInitialAccessToken has to be presented to register a client
+	// when the registration is not open, the registration is disabled when neither is set
+	ClientRegistrationInitialAccessToken string
+
+	// Upstream is the OpenID Connect provider the users can log in with,
+	// the upstream login is disabled when its issuer is empty
+	Upstream core.UpstreamConfig
+	// LDAP checks the passwords of the users without a local one,
+	// the directory is not used when its url is empty
+	LDAP core.LDAPConfig
+	// Mail sends the password reset links,
+	// the password reset is disabled when no sender is configured
+	Mail core.MailConfig
+	// Passwords is the policy of the passwords chosen by the local users
+	Passwords core.PasswordConfig
 }
 
+// upstreamTimeout limits the requests to the upstream identity provider and the directory.
+const upstreamTimeout = 10 * time.Second
+
 type Wallabago struct {
 	identityManager  *managers.IdentityManager
 	bootstrapManager *managers.BootstrapManager
@@ -46,14 +78,23 @@ func (w *Wallabago) Config() *Config {
 }
 
 func NewWallabago(ctx context.Context, config *Config) (*Wallabago, error) {
+	// keys
+	keys, err := loadKeySet(ctx, config)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to load signing keys")
+	}
 	// database
 	dbPool, err := database.NewDBPool(ctx, config.DBConnectionString)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	postgresStorage := storage.NewPostreSQLStorage(dbPool)
+	passwords, err := core.NewPasswordPolicy(config.Passwords)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to configure the password policy")
+	}
 	// engines
-	bootstrapEngine := engines.NewBoostrapEngine(postgresStorage)
+	bootstrapEngine := engines.NewBoostrapEngine(postgresStorage, passwords)
 	// managers
 	boostrapManager := managers.NewBootstrapManager(postgresStorage, bootstrapEngine, core.BootstrapAdminCredentials{
 		Username: config.BootstrapAdminUsername,
@@ -61,9 +102,55 @@ func NewWallabago(ctx context.Context, config *Config) (*Wallabago, error) {
 		Email:    config.BootstrapAdminEmail,
 	}, core.Client{
 		ID:     config.BootstrapClientID,
+		Name:   "Web UI",
 		Secret: config.BootstrapClientSecret,
+		ClientPolicy: core.ClientPolicy{
+			GrantTypes: core.SupportedGrantTypes(),
+			Scope:      *core.FullScope(),
+		},
 	})
-	identityManager := managers.NewIdentityManager(postgresStorage)
+	var upstream managers.UpstreamProvider
+	if config.Upstream.Issuer != "" {
+		upstream = federation.NewOIDCProvider(
+			config.Upstream,
+			strings.TrimSuffix(config.PublicURL, "/")+handlers.UpstreamCallbackPath,
+			&http.Client{Timeout: upstreamTimeout},
+		)
+	}
+	var credentials managers.CredentialVerifier
+	if config.LDAP.URL != "" {
+		credentials, err = federation.NewLDAPVerifier(config.LDAP, upstreamTimeout, nil)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to configure ldap")
+		}
+	}
+	// the smtp server wins over the file and the log
+	var sender managers.Mailer
+	switch {
+	case config.Mail.SMTP.Addr != "":
+		sender, err = mailer.NewSMTPSender(config.Mail.From, config.Mail.SMTP, upstreamTimeout, nil)
+	case config.Mail.File != "":
+		sender, err = mailer.NewFileSender(config.Mail.From, config.Mail.File)
+	case config.Mail.Log:
+		slog.WarnContext(ctx, "Emails are written to the log instead of being sent. "+
+			"The password reset links are readable by anyone with access to the log")
+		sender = mailer.LogSender{}
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to configure mail")
+	}
+	identityManager := managers.NewIdentityManager(
+		postgresStorage,
+		keys,
+		strings.TrimSuffix(config.PublicURL, "/"),
+		managers.IdentityOptions{
+			Registration: core.NewRegistrationPolicy(config.ClientRegistrationOpen, config.ClientRegistrationInitialAccessToken),
+			Passwords:    &passwords,
+			Upstream:     upstream,
+			Credentials:  credentials,
+			Mailer:       sender,
+		},
+	)
 
 	return &Wallabago{
 		bootstrapManager: boostrapManager,
@@ -116,26 +203,17 @@ func (w *Wallabago) Prepare(ctx context.Context) error {
 }
 
 func (w *Wallabago) Handler() http.Handler {
-	mux := http.NewServeMux()
-
-	auth := middleware.NewOAuth2Middleware(w.identityManager)
-
-	oauth2 := handlers.NewOAuth2Handler(w.identityManager)
-	mux.HandleFunc("POST /oauth/v2/token", oauth2.TokenEndpoint)
-
-	ui := handlers.NewWebUI()
-	api := handlers.NewAPI()
-
-	mux.HandleFunc("/", ui.Index)
-	mux.Handle("/docs/", http.StripPrefix("/docs/", docs.OpenAPI))
-	mux.Handle("/protected", auth.Wrap(http.HandlerFunc(api.AuthInfo)))
+	router := handlers.NewRouter(w.identityManager, w.config.PublicURL, core.Client{
+		ID:     w.config.BootstrapClientID,
+		Secret: w.config.BootstrapClientSecret,
+	})
 
 	globalMiddleware := middleware.NewChain(
 		middleware.LoggingMiddleware,
 		middleware.NewOtelHTTPMiddleware(),
 	)
 
-	return globalMiddleware.Wrap(mux)
+	return globalMiddleware.Wrap(router)
 }
 
 func (w *Wallabago) bootstrap(ctx context.Context) error {
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ResponseTypeCode = "code"
+
+	CodeChallengeMethodS256 = "S256"
+)
+
+// see https://datatracker.ietf.org/doc/html/rfc7636#section-4.1.
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// NewCodeChallenge derives the S256 PKCE code challenge from the verifier.
+func NewCodeChallenge(codeVerifier string) string {
+	digest := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// ValidCodeChallenge reports whether the challenge looks like
+// a base64url encoded SHA-256 digest.
+func ValidCodeChallenge(codeChallenge string) bool {
+	return len(codeChallenge) == base64.RawURLEncoding.EncodedLen(sha256.Size) &&
+		codeVerifierPattern.MatchString(codeChallenge)
+}
+
+// VerifyCodeVerifier checks the verifier presented at the token endpoint
+// against the challenge that was presented at the authorization endpoint.
+func VerifyCodeVerifier(codeChallenge, codeChallengeMethod, codeVerifier string) bool {
+	if codeChallengeMethod != CodeChallengeMethodS256 {
+		return false
+	}
+	if !codeVerifierPattern.MatchString(codeVerifier) {
+		return false
+	}
+	expected := NewCodeChallenge(codeVerifier)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
+
+// NewOpaqueToken returns a random url-safe string with 256 bits of entropy.
+func NewOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ValidateRedirectURI checks that the uri is suitable to be registered
+// as a redirection endpoint of a client.
+func ValidateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !parsed.IsAbs() {
+		return errors.Errorf("redirect uri must be absolute: %s", redirectURI)
+	}
+	if parsed.Fragment != "" {
+		return errors.Errorf("redirect uri must not contain a fragment: %s", redirectURI)
+	}
+	return nil
+}
+
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// Nonce binds the id token to the session of the client, only used with the openid scope.
+	Nonce string
+}
+
+type AuthorizationCode struct {
+	// Code is only known right after the code was issued,
+	// afterwards just its hash is available
+	Code                string
+	CodeHash            []byte
+	ClientID            string
+	UserID              string
+	RedirectURI         string
+	Scope               Scope
+	CodeChallenge       string
+	CodeChallengeMethod string
+	IssuedAt            time.Time
+	ExpiresAt           time.Time
+	Used                bool
+	// TokenFamilyID is the family of the refresh token issued for the code.
+	TokenFamilyID string
+	Nonce         string
+}
+
+func NewAuthorizationCode(userID string, req AuthorizationRequest, scope Scope, expiration time.Duration) (*AuthorizationCode, error) {
+	code, err := NewOpaqueToken()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	issuedAt := time.Now()
+	return &AuthorizationCode{
+		Code:                code,
+		CodeHash:            HashToken(code),
+		ClientID:            req.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		IssuedAt:            issuedAt,
+		ExpiresAt:           issuedAt.Add(expiration),
+		Used:                false,
+		Nonce:               req.Nonce,
+	}, nil
+}
+
+func (c *AuthorizationCode) Expired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+type AuthorizationCodeFlowRequest struct {
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	// ClientIP and UserAgent describe the device of the session, empty if unknown
+	ClientIP  string
+	UserAgent string
+}
+
+// RedirectableAuthError is an error of the authorization endpoint that
+// is safe to report back to the client via its redirection endpoint.
+type RedirectableAuthError struct {
+	AuthError
+	RedirectURI string
+	State       string
+}
+
+func (e *RedirectableAuthError) Error() string {
+	return e.AuthError.Error()
+}
+
+// Location returns the redirection endpoint with the error attached.
+func (e *RedirectableAuthError) Location() (string, error) {
+	params := url.Values{}
+	params.Set("error", string(e.ErrorName))
+	if e.ErrorDescription != "" {
+		params.Set("error_description", e.ErrorDescription)
+	}
+	return AuthorizationRedirectLocation(e.RedirectURI, params, e.State)
+}
+
+// AuthorizationRedirectLocation appends the response parameters
+// to the query of the redirection endpoint.
+func AuthorizationRedirectLocation(redirectURI string, params url.Values, state string) (string, error) {
+	location, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	query := location.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	location.RawQuery = query.Encode()
+	return location.String(), nil
+}
+
+//nolint:errcheck //only to make sure it implements error
+var _ error = (*RedirectableAuthError)(nil)
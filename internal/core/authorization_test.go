@@ -0,0 +1,61 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+// see https://datatracker.ietf.org/doc/html/rfc7636#appendix-B.
+const (
+	rfcCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	cases := []struct {
+		challenge string
+		method    string
+		verifier  string
+		valid     bool
+	}{
+		{challenge: rfcCodeChallenge, method: core.CodeChallengeMethodS256, verifier: rfcCodeVerifier, valid: true},
+		{challenge: rfcCodeChallenge, method: "plain", verifier: rfcCodeVerifier, valid: false},
+		{challenge: rfcCodeChallenge, method: core.CodeChallengeMethodS256, verifier: rfcCodeVerifier + "a", valid: false},
+		{challenge: rfcCodeChallenge, method: core.CodeChallengeMethodS256, verifier: "short", valid: false},
+		{challenge: rfcCodeVerifier, method: "plain", verifier: rfcCodeVerifier, valid: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestVerifyCodeVerifier_%d", i), func(t *testing.T) {
+			result := core.VerifyCodeVerifier(testCase.challenge, testCase.method, testCase.verifier)
+			if result != testCase.valid {
+				t.Fatalf("Expected %#v but got %#v", testCase.valid, result)
+			}
+		})
+	}
+	if !core.ValidCodeChallenge(rfcCodeChallenge) {
+		t.Fatalf("Challenge from the RFC should be valid")
+	}
+	if core.ValidCodeChallenge(rfcCodeVerifier + "a") {
+		t.Fatalf("Challenge of a wrong length should be invalid")
+	}
+}
+
+func TestAuthorizationRedirectLocation(t *testing.T) {
+	err := &core.RedirectableAuthError{
+		AuthError: core.AuthError{
+			ErrorName: core.AuthErrorAccessDenied,
+		},
+		RedirectURI: "https://client.example/cb?keep=1",
+		State:       "xyz",
+	}
+	location, locationErr := err.Location()
+	if locationErr != nil {
+		t.Fatalf("Should succeed without error: %v", locationErr)
+	}
+	expected := "https://client.example/cb?error=access_denied&keep=1&state=xyz"
+	if location != expected {
+		t.Fatalf("Expected %#v but got %#v", expected, location)
+	}
+}
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxClientNameLength keeps the names readable in the client listings.
+	maxClientNameLength = 100
+	// MaxAccessTokenLifetime bounds the lifetime a client may ask for its access tokens,
+	// they can not be revoked once handed to a resource server that does not introspect them.
+	MaxAccessTokenLifetime = 24 * time.Hour
+	// MaxRefreshTokenLifetime bounds the lifetime a client may ask for its refresh tokens.
+	MaxRefreshTokenLifetime = 365 * 24 * time.Hour
+)
+
+var validGrantTypes = []GrantType{
+	GrantTypePassword,
+	GrantTypeRefreshToken,
+	GrantTypeAuthorizationCode,
+	GrantTypeClientCredentials,
+	GrantTypeDeviceCode,
+}
+
+// SupportedGrantTypes lists every grant the server implements.
+func SupportedGrantTypes() []GrantType {
+	return slices.Clone(validGrantTypes)
+}
+
+// ClientPolicy limits what the client is allowed to do.
+type ClientPolicy struct {
+	RedirectURIs []string
+	GrantTypes   []GrantType
+	// Scope is the widest scope the client may request.
+	Scope Scope
+	// Public clients can not keep a secret, like the apps running
+	// on the devices of the users, so they authenticate with the id only.
+	Public bool
+	// AccessTokenLifetime overrides the server default when not zero.
+	AccessTokenLifetime time.Duration
+	// RefreshTokenLifetime limits how long a refresh token can be exchanged,
+	// zero means it can be used until it is rotated or revoked.
+	RefreshTokenLifetime time.Duration
+}
+
+// DefaultClientPolicy is applied to the clients registered by the users
+// unless they ask for something else.
+func DefaultClientPolicy() ClientPolicy {
+	return ClientPolicy{
+		GrantTypes: []GrantType{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		Scope:      *DefaultScope(),
+	}
+}
+
+// Validate checks that the policy is consistent.
+func (p ClientPolicy) Validate() error {
+	for _, grantType := range p.GrantTypes {
+		if !slices.Contains(validGrantTypes, grantType) {
+			return errors.Errorf("unsupported grant type: %s", grantType)
+		}
+	}
+	if p.Public && slices.Contains(p.GrantTypes, GrantTypeClientCredentials) {
+		return errors.New("public clients can not use the client_credentials grant")
+	}
+	if slices.Contains(p.GrantTypes, GrantTypeAuthorizationCode) && len(p.RedirectURIs) == 0 {
+		return errors.New("authorization_code grant requires at least one redirect uri")
+	}
+	for _, redirectURI := range p.RedirectURIs {
+		err := ValidateRedirectURI(redirectURI)
+		if err != nil {
+			return err
+		}
+	}
+	if len(p.Scope.Names()) == 0 {
+		return errors.New("client scope must not be empty")
+	}
+	if _, err := NewScope(p.Scope.Names()...); err != nil {
+		return err
+	}
+	if p.AccessTokenLifetime < 0 || p.RefreshTokenLifetime < 0 {
+		return errors.New("token lifetimes must not be negative")
+	}
+	if p.AccessTokenLifetime > MaxAccessTokenLifetime {
+		return errors.Errorf("access token lifetime must not exceed %s", MaxAccessTokenLifetime)
+	}
+	if p.RefreshTokenLifetime > MaxRefreshTokenLifetime {
+		return errors.Errorf("refresh token lifetime must not exceed %s", MaxRefreshTokenLifetime)
+	}
+	return nil
+}
+
+type Client struct {
+	ID string
+	// Secret is only known when the client is created or its secret rotated,
+	// afterwards just its hash is available
+	Secret     string
+	SecretHash []byte
+	// RegistrationAccessToken lets a dynamically registered client manage its registration,
+	// like the secret it is only known when issued and just its hash is stored
+	RegistrationAccessToken     string
+	RegistrationAccessTokenHash []byte
+	ClientPolicy
+	// ServiceAccountUserID is the user the client acts as
+	// in the client_credentials grant, empty if not bound.
+	ServiceAccountUserID string
+	// ServiceAccountScope limits the scope available to the service account.
+	ServiceAccountScope Scope
+	// OwnerUserID is the user who registered the client,
+	// empty for the clients created by the server itself.
+	OwnerUserID string
+	Name        string
+	CreatedAt   time.Time
+}
+
+// NewClient registers a client on behalf of the owner.
+// Confidential clients get a freshly generated secret.
+func NewClient(ownerUserID, name string, policy ClientPolicy) (*Client, error) {
+	name, err := validateClientName(name)
+	if err != nil {
+		return nil, err
+	}
+	err = policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	client := Client{
+		ID:           uuid.New().String(),
+		ClientPolicy: policy,
+		OwnerUserID:  ownerUserID,
+		Name:         name,
+		CreatedAt:    time.Now(),
+	}
+	if !client.Public {
+		err = client.RotateSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &client, nil
+}
+
+func validateClientName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("client name must not be empty")
+	}
+	if len(name) > maxClientNameLength {
+		return "", errors.Errorf("client name must not be longer than %d characters", maxClientNameLength)
+	}
+	return name, nil
+}
+
+// RotateSecret replaces the secret of the client with a freshly generated one.
+func (c *Client) RotateSecret() error {
+	if c.Public {
+		return errors.New("public clients have no secret")
+	}
+	secret, err := NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	secretHash, err := HashClientSecret(secret)
+	if err != nil {
+		return err
+	}
+	c.Secret = secret
+	c.SecretHash = secretHash
+	return nil
+}
+
+// Authenticate checks the secret presented by the client,
+// public clients must not present any.
+func (c *Client) Authenticate(secret string) bool {
+	if c.Public {
+		return secret == ""
+	}
+	return VerifyClientSecret(c.SecretHash, secret)
+}
+
+// AllowsGrantType reports whether the client may use the grant.
+func (c *Client) AllowsGrantType(grantType GrantType) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// GrantableScope resolves the scope requested by the client. Empty request
+// falls back to the default scope, or to the whole scope of the client
+// when the default is not allowed for it.
+func (c *Client) GrantableScope(requested string) (*Scope, error) {
+	if requested == "" {
+		if c.Scope.Includes(*DefaultScope()) {
+			return DefaultScope(), nil
+		}
+		scope := c.Scope
+		return &scope, nil
+	}
+	scope, err := NewScopeFromString(requested)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Scope.Includes(*scope) {
+		return nil, errors.Errorf("scope exceeds the one allowed for the client: %s", requested)
+	}
+	return scope, nil
+}
+
+// ParseGrantTypes reads the grant types stored separated by spaces.
+func ParseGrantTypes(grantTypes string) []GrantType {
+	parts := strings.Fields(grantTypes)
+	result := make([]GrantType, 0, len(parts))
+	for _, part := range parts {
+		result = append(result, GrantType(part))
+	}
+	return result
+}
+
+// FormatGrantTypes joins the grant types with spaces for storage.
+func FormatGrantTypes(grantTypes []GrantType) string {
+	parts := make([]string, 0, len(grantTypes))
+	for _, grantType := range grantTypes {
+		parts = append(parts, string(grantType))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,120 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestNewClient(t *testing.T) {
+	withPolicy := func(modify func(policy *core.ClientPolicy)) core.ClientPolicy {
+		policy := core.DefaultClientPolicy()
+		policy.RedirectURIs = []string{"https://example.com/callback"}
+		modify(&policy)
+		return policy
+	}
+	cases := []struct {
+		name          string
+		policy        core.ClientPolicy
+		shouldSucceed bool
+	}{
+		{name: "My reader", policy: withPolicy(func(*core.ClientPolicy) {}), shouldSucceed: true},
+		{name: "  ", policy: withPolicy(func(*core.ClientPolicy) {}), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.RedirectURIs = nil }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.RedirectURIs = []string{"/callback"} }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.RedirectURIs = []string{"https://example.com/#fragment"} }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.GrantTypes = []core.GrantType{"implicit"} }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.Scope = "" }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.Scope = "bad" }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.Public = true }), shouldSucceed: true},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) {
+			p.Public = true
+			p.GrantTypes = []core.GrantType{core.GrantTypeClientCredentials}
+		}), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.AccessTokenLifetime = -1 }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.AccessTokenLifetime = core.MaxAccessTokenLifetime }), shouldSucceed: true},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.AccessTokenLifetime = core.MaxAccessTokenLifetime + 1 }), shouldSucceed: false},
+		{name: "My reader", policy: withPolicy(func(p *core.ClientPolicy) { p.RefreshTokenLifetime = core.MaxRefreshTokenLifetime + 1 }), shouldSucceed: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestNewClient_%d_%#v", i, testCase.name), func(t *testing.T) {
+			client, err := core.NewClient("owner", testCase.name, testCase.policy)
+			if (err == nil) != testCase.shouldSucceed {
+				t.Fatalf("Expected success %v but got error %v", testCase.shouldSucceed, err)
+			}
+			if err != nil {
+				return
+			}
+			if client.OwnerUserID != "owner" {
+				t.Fatalf("Expected owner %#v but got %#v", "owner", client.OwnerUserID)
+			}
+			if client.Public != (client.Secret == "") {
+				t.Fatalf("Only confidential clients should get a secret")
+			}
+			if !client.Authenticate(client.Secret) {
+				t.Fatalf("Client should authenticate with the generated secret")
+			}
+		})
+	}
+}
+
+func TestClientRotateSecret(t *testing.T) {
+	client, err := core.NewClient("owner", "My reader", core.ClientPolicy{
+		GrantTypes: []core.GrantType{core.GrantTypePassword},
+		Scope:      *core.DefaultScope(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	previousSecret := client.Secret
+	err = client.RotateSecret()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if client.Secret == previousSecret {
+		t.Fatalf("Rotated secret should differ from the previous one")
+	}
+	if client.Authenticate(previousSecret) {
+		t.Fatalf("Previous secret should no longer match")
+	}
+
+	client.Public = true
+	if err := client.RotateSecret(); err == nil {
+		t.Fatalf("Public client should not get a secret")
+	}
+}
+
+func TestClientGrantableScope(t *testing.T) {
+	cases := []struct {
+		allowed   core.Scope
+		requested string
+		expected  core.Scope
+		shouldErr bool
+	}{
+		{allowed: "entries tags", requested: "", expected: "entries"},
+		{allowed: "tags export", requested: "", expected: "tags export"},
+		{allowed: "entries tags", requested: "tags", expected: "tags"},
+		{allowed: "entries", requested: "entries:read", expected: "entries:read"},
+		{allowed: "entries", requested: "admin", shouldErr: true},
+		{allowed: "entries", requested: "bad", shouldErr: true},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestClientGrantableScope_%d_%#v", i, testCase.requested), func(t *testing.T) {
+			client := core.Client{ClientPolicy: core.ClientPolicy{Scope: testCase.allowed}}
+			scope, err := client.GrantableScope(testCase.requested)
+			if testCase.shouldErr {
+				if err == nil {
+					t.Fatalf("Expected error but got scope %#v", *scope)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if *scope != testCase.expected {
+				t.Fatalf("Expected %#v but got %#v", testCase.expected, *scope)
+			}
+		})
+	}
+}
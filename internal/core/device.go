@@ -0,0 +1,146 @@
+package core
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+// errors of the device authorization grant,
+// see https://datatracker.ietf.org/doc/html/rfc8628#section-3.5.
+const (
+	AuthErrorAuthorizationPending = "authorization_pending"
+	AuthErrorSlowDown             = "slow_down"
+	AuthErrorExpiredToken         = "expired_token"
+)
+
+type DeviceCodeStatus string
+
+const (
+	DeviceCodeStatusPending  DeviceCodeStatus = "pending"
+	DeviceCodeStatusApproved DeviceCodeStatus = "approved"
+	DeviceCodeStatusDenied   DeviceCodeStatus = "denied"
+	DeviceCodeStatusConsumed DeviceCodeStatus = "consumed"
+)
+
+// userCodeAlphabet omits vowels to avoid forming words
+// and is case insensitive to be easy to type on e-readers.
+const (
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// DeviceCodeSlowDownStep is added to the polling interval
+// each time the client polls too often.
+const DeviceCodeSlowDownStep = 5 * time.Second
+
+type DeviceCode struct {
+	// DeviceCode is only known right after the code was issued,
+	// afterwards just its hash is available
+	DeviceCode     string
+	DeviceCodeHash []byte
+	UserCode       string
+	ClientID       string
+	Scope          Scope
+	IssuedAt       time.Time
+	ExpiresAt      time.Time
+	Interval       time.Duration
+	LastPolledAt   time.Time
+	Status         DeviceCodeStatus
+	// UserID is the user that approved or denied the request.
+	UserID string
+}
+
+func NewDeviceCode(clientID string, scope Scope, expiration, interval time.Duration) (*DeviceCode, error) {
+	deviceCode, err := NewOpaqueToken()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	userCode, err := newUserCode()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	issuedAt := time.Now()
+	return &DeviceCode{
+		DeviceCode:     deviceCode,
+		DeviceCodeHash: HashToken(deviceCode),
+		UserCode:       userCode,
+		ClientID:       clientID,
+		Scope:          scope,
+		IssuedAt:       issuedAt,
+		ExpiresAt:      issuedAt.Add(expiration),
+		Interval:       interval,
+		Status:         DeviceCodeStatusPending,
+	}, nil
+}
+
+func newUserCode() (string, error) {
+	sb := strings.Builder{}
+	alphabetSize := big.NewInt(int64(len(userCodeAlphabet)))
+	for range userCodeLength {
+		index, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		sb.WriteByte(userCodeAlphabet[index.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// NormalizeUserCode turns the code typed in by the user into the stored form,
+// ignoring the case and any separators.
+func NormalizeUserCode(userCode string) string {
+	sb := strings.Builder{}
+	for _, char := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(userCodeAlphabet, char) {
+			sb.WriteRune(char)
+		}
+	}
+	return sb.String()
+}
+
+// FormatUserCode splits the code into halves for readability.
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+func (c *DeviceCode) Expired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// PolledTooEarly reports whether the client ignored the polling interval.
+func (c *DeviceCode) PolledTooEarly(now time.Time) bool {
+	return !c.LastPolledAt.IsZero() && now.Sub(c.LastPolledAt) < c.Interval
+}
+
+type DeviceAuthorizationRequest struct {
+	ClientID     string
+	ClientSecret string
+	Scope        string
+}
+
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+}
+
+type DeviceCodeFlowRequest struct {
+	ClientID     string
+	ClientSecret string
+	DeviceCode   string
+	// ClientIP and UserAgent describe the device of the session, empty if unknown
+	ClientIP  string
+	UserAgent string
+}
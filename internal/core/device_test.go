@@ -0,0 +1,61 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestNormalizeUserCode(t *testing.T) {
+	cases := []struct {
+		userCode string
+		expected string
+	}{
+		{userCode: "WDJB-MJHT", expected: "WDJBMJHT"},
+		{userCode: "wdjb mjht", expected: "WDJBMJHT"},
+		{userCode: " wdjbMJHT\n", expected: "WDJBMJHT"},
+		{userCode: "AEIO-U123", expected: ""},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestNormalizeUserCode_%d_%#v", i, testCase.userCode), func(t *testing.T) {
+			if result := core.NormalizeUserCode(testCase.userCode); result != testCase.expected {
+				t.Fatalf("Expected %#v but got %#v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewDeviceCode(t *testing.T) {
+	code, err := core.NewDeviceCode("client", *core.DefaultScope(), time.Minute, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if code.DeviceCode == code.UserCode {
+		t.Fatalf("Device code must not be guessable from the user code")
+	}
+	formatted := core.FormatUserCode(code.UserCode)
+	if len(formatted) != 9 || formatted[4] != '-' {
+		t.Fatalf("Unexpected user code format: %s", formatted)
+	}
+	if core.NormalizeUserCode(formatted) != code.UserCode {
+		t.Fatalf("Formatted user code %s does not normalize back to %s", formatted, code.UserCode)
+	}
+	if code.Status != core.DeviceCodeStatusPending {
+		t.Fatalf("Expected new code to be pending but got %s", code.Status)
+	}
+
+	now := time.Now()
+	if code.PolledTooEarly(now) {
+		t.Fatalf("First poll must never be too early")
+	}
+	code.LastPolledAt = now.Add(-time.Second)
+	if !code.PolledTooEarly(now) {
+		t.Fatalf("Poll within the interval must be too early")
+	}
+	code.LastPolledAt = now.Add(-code.Interval)
+	if code.PolledTooEarly(now) {
+		t.Fatalf("Poll after the interval must be accepted")
+	}
+}
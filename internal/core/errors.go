@@ -0,0 +1,16 @@
+package core
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotFound signals that the requested entity does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidInput signals that the request can not be fulfilled as it is.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrInvalidCredentials signals that the username or the password does not match.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrForbidden signals that the server does not allow the operation, whoever asks for it.
+	ErrForbidden = errors.New("forbidden")
+)
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// GrantTypeUpstream is recorded for the tokens issued after a login at the upstream
+// identity provider, it can not be requested at the token endpoint.
+const GrantTypeUpstream = "upstream"
+
+// upstreamLoginPurpose tells the sealed logins apart from the other tokens signed by the server.
+const upstreamLoginPurpose = "upstream_login"
+
+// UpstreamConfig describes the OpenID Connect provider the users log in with.
+type UpstreamConfig struct {
+	// Issuer is where the discovery document of the provider is found.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scope is requested from the provider, it has to contain openid.
+	Scope string
+	// Name is shown on the login page.
+	Name  string
+	Admin AdminClaimMapping
+}
+
+// AdminClaimMapping grants the administrator role to the users with the claim,
+// the role follows the claim on every login once the mapping is configured.
+type AdminClaimMapping struct {
+	Claim string
+	// Value has to be equal to the claim or be one of its values when the claim is a list,
+	// empty value accepts a boolean claim set to true.
+	Value string
+}
+
+// Enabled reports whether the administrator role is managed by the provider.
+func (m AdminClaimMapping) Enabled() bool {
+	return m.Claim != ""
+}
+
+// IsAdmin checks the claims of the user against the mapping.
+func (m AdminClaimMapping) IsAdmin(claims map[string]any) bool {
+	switch value := claims[m.Claim].(type) {
+	case bool:
+		return m.Value == "" && value
+	case string:
+		return m.Value != "" && value == m.Value
+	case []any:
+		for _, item := range value {
+			if item, ok := item.(string); ok && m.Value != "" && item == m.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpstreamClaims are the verified claims of the id token issued by the provider.
+type UpstreamClaims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Nonce             string
+	// Raw holds every claim, e.g. for the administrator mapping.
+	Raw map[string]any
+}
+
+// NewUpstreamClaims picks the known claims out of the id token.
+func NewUpstreamClaims(raw map[string]any) (*UpstreamClaims, error) {
+	claims := UpstreamClaims{Raw: raw}
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Email, _ = raw["email"].(string)
+	claims.EmailVerified, _ = raw["email_verified"].(bool)
+	claims.PreferredUsername, _ = raw["preferred_username"].(string)
+	claims.Nonce, _ = raw["nonce"].(string)
+	if claims.Issuer == "" || claims.Subject == "" {
+		return nil, errors.New("id token is missing the iss or sub claim")
+	}
+	return &claims, nil
+}
+
+// Username suggests the name of the user provisioned for the claims.
+func (c UpstreamClaims) Username() string {
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+	if local, _, ok := strings.Cut(c.Email, "@"); ok && local != "" {
+		return local
+	}
+	return c.Subject
+}
+
+// FederatedIdentity links the account at the provider to the local user.
+type FederatedIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    string
+	CreatedAt time.Time
+}
+
+// UpstreamLogin is the state of a login in progress at the provider, it is kept
+// by the browser in a sealed form until the provider redirects the user back.
+type UpstreamLogin struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	// Next is the local page the user is sent to after the login.
+	Next string
+	// LinkUserID is the user that links the account at the provider, empty for plain logins.
+	LinkUserID string
+}
+
+// NewUpstreamLogin starts a login with fresh random values.
+func NewUpstreamLogin(next, linkUserID string) (*UpstreamLogin, error) {
+	values := make([]string, 3)
+	for i := range values {
+		value, err := NewOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return &UpstreamLogin{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+		Next:         next,
+		LinkUserID:   linkUserID,
+	}, nil
+}
+
+// Seal signs the login so that it can be handed to the browser without being tampered with.
+func (l UpstreamLogin) Seal(issuer string, lifetime time.Duration, keys *KeySet) (*JWT, error) {
+	now := time.Now()
+	return keys.Sign(map[string]any{
+		"iss":           issuer,
+		"aud":           issuer,
+		"iat":           now.Unix(),
+		"exp":           now.Add(lifetime).Unix(),
+		"purpose":       upstreamLoginPurpose,
+		"state":         l.State,
+		"nonce":         l.Nonce,
+		"code_verifier": l.CodeVerifier,
+		"next":          l.Next,
+		"link_user_id":  l.LinkUserID,
+	})
+}
+
+// OpenUpstreamLogin verifies the sealed login and returns its state.
+func OpenUpstreamLogin(sealed JWT, issuer string, keys *KeySet) (*UpstreamLogin, error) {
+	claims, err := keys.Parse(sealed,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != upstreamLoginPurpose {
+		return nil, errors.New("token is not an upstream login")
+	}
+	login := UpstreamLogin{}
+	login.State, _ = claims["state"].(string)
+	login.Nonce, _ = claims["nonce"].(string)
+	login.CodeVerifier, _ = claims["code_verifier"].(string)
+	login.Next, _ = claims["next"].(string)
+	login.LinkUserID, _ = claims["link_user_id"].(string)
+	if login.State == "" || login.Nonce == "" || login.CodeVerifier == "" {
+		return nil, errors.New("upstream login is incomplete")
+	}
+	return &login, nil
+}
+
+// UpstreamCallbackRequest is what the provider sends the user back with,
+// the client is the web ui the session is issued for.
+type UpstreamCallbackRequest struct {
+	ClientID     string
+	ClientSecret string
+	SealedLogin  JWT
+	State        string
+	Code         string
+	// Error is set by the provider when the login failed there.
+	Error string
+	// ClientIP and UserAgent describe the device of the session, empty if unknown
+	ClientIP  string
+	UserAgent string
+}
@@ -0,0 +1,98 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestAdminClaimMappingIsAdmin(t *testing.T) {
+	cases := []struct {
+		mapping  core.AdminClaimMapping
+		claims   map[string]any
+		expected bool
+	}{
+		{mapping: core.AdminClaimMapping{Claim: "admin"}, claims: map[string]any{"admin": true}, expected: true},
+		{mapping: core.AdminClaimMapping{Claim: "admin"}, claims: map[string]any{"admin": false}, expected: false},
+		{mapping: core.AdminClaimMapping{Claim: "admin", Value: "yes"}, claims: map[string]any{"admin": true}, expected: false},
+		{mapping: core.AdminClaimMapping{Claim: "role", Value: "admin"}, claims: map[string]any{"role": "admin"}, expected: true},
+		{mapping: core.AdminClaimMapping{Claim: "role", Value: "admin"}, claims: map[string]any{"role": "user"}, expected: false},
+		{mapping: core.AdminClaimMapping{Claim: "role"}, claims: map[string]any{"role": ""}, expected: false},
+		{mapping: core.AdminClaimMapping{Claim: "groups", Value: "admins"}, claims: map[string]any{"groups": []any{"users", "admins"}}, expected: true},
+		{mapping: core.AdminClaimMapping{Claim: "groups", Value: "admins"}, claims: map[string]any{"groups": []any{"users"}}, expected: false},
+		{mapping: core.AdminClaimMapping{Claim: "groups", Value: "admins"}, claims: map[string]any{}, expected: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestAdminClaimMappingIsAdmin_%d", i), func(t *testing.T) {
+			if result := testCase.mapping.IsAdmin(testCase.claims); result != testCase.expected {
+				t.Fatalf("Expected %t but got %t for %#v", testCase.expected, result, testCase.claims)
+			}
+		})
+	}
+}
+
+func TestUpstreamClaimsUsername(t *testing.T) {
+	cases := []struct {
+		claims   core.UpstreamClaims
+		expected string
+	}{
+		{claims: core.UpstreamClaims{Subject: "sub", Email: "user@example.com", PreferredUsername: "preferred"}, expected: "preferred"},
+		{claims: core.UpstreamClaims{Subject: "sub", Email: "user@example.com"}, expected: "user"},
+		{claims: core.UpstreamClaims{Subject: "sub", Email: "@example.com"}, expected: "sub"},
+		{claims: core.UpstreamClaims{Subject: "sub"}, expected: "sub"},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestUpstreamClaimsUsername_%d_%s", i, testCase.expected), func(t *testing.T) {
+			if result := testCase.claims.Username(); result != testCase.expected {
+				t.Fatalf("Expected %#v but got %#v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestUpstreamLoginSeal(t *testing.T) {
+	keys := newTestKeySet(t)
+	login, err := core.NewUpstreamLogin("/next", "user")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealed, err := login.Seal("issuer", time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	opened, err := core.OpenUpstreamLogin(*sealed, "issuer", keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if *opened != *login {
+		t.Fatalf("Expected %#v but got %#v", login, opened)
+	}
+
+	_, err = core.OpenUpstreamLogin(*sealed, "other", keys)
+	if err == nil {
+		t.Fatalf("Expected error for another issuer")
+	}
+	_, err = core.OpenUpstreamLogin(*sealed, "issuer", newTestKeySet(t))
+	if err == nil {
+		t.Fatalf("Expected error for another key")
+	}
+	expired, err := login.Seal("issuer", -time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = core.OpenUpstreamLogin(*expired, "issuer", keys)
+	if err == nil {
+		t.Fatalf("Expected error for expired login")
+	}
+	// the other tokens signed by the server are not logins
+	accessToken, err := core.NewAccessToken("issuer", "user", "client", *core.DefaultScope(), time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = core.OpenUpstreamLogin(accessToken.Token, "issuer", keys)
+	if err == nil {
+		t.Fatalf("Expected error for access token")
+	}
+}
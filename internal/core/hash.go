@@ -0,0 +1,36 @@
+package core
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashToken returns the digest under which a bearer credential is stored.
+// The tokens are random enough to not need a salt or a slow hash.
+func HashToken(token string) []byte {
+	digest := sha256.Sum256([]byte(token))
+	return digest[:]
+}
+
+// VerifyTokenHash checks the presented token against the stored digest in constant time.
+func VerifyTokenHash(tokenHash []byte, token string) bool {
+	return subtle.ConstantTimeCompare(tokenHash, HashToken(token)) == 1
+}
+
+// HashClientSecret hashes the secret for storage, the secrets
+// might be chosen by people so they are treated like passwords.
+func HashClientSecret(secret string) ([]byte, error) {
+	secretHash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return secretHash, nil
+}
+
+// VerifyClientSecret checks the presented secret against the stored hash.
+func VerifyClientSecret(secretHash []byte, secret string) bool {
+	return bcrypt.CompareHashAndPassword(secretHash, []byte(secret)) == nil
+}
@@ -0,0 +1,50 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestVerifyTokenHash(t *testing.T) {
+	tokenHash := core.HashToken("token")
+	cases := []struct {
+		token    string
+		expected bool
+	}{
+		{token: "token", expected: true},
+		{token: "Token", expected: false},
+		{token: "token ", expected: false},
+		{token: "", expected: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestVerifyTokenHash_%d_%#v", i, testCase.token), func(t *testing.T) {
+			if result := core.VerifyTokenHash(tokenHash, testCase.token); result != testCase.expected {
+				t.Fatalf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestVerifyClientSecret(t *testing.T) {
+	secretHash, err := core.HashClientSecret("secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	cases := []struct {
+		secret   string
+		expected bool
+	}{
+		{secret: "secret", expected: true},
+		{secret: "Secret", expected: false},
+		{secret: "", expected: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestVerifyClientSecret_%d_%#v", i, testCase.secret), func(t *testing.T) {
+			if result := core.VerifyClientSecret(secretHash, testCase.secret); result != testCase.expected {
+				t.Fatalf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package core
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// signing algorithms supported for the issued tokens.
+const (
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmES256 = "ES256"
+	SigningAlgorithmES384 = "ES384"
+	SigningAlgorithmES512 = "ES512"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is a key pair used to sign tokens, or just the public part
+// of a retired key that is still accepted during the rotation.
+type SigningKey struct {
+	// ID is put into the kid header to find the key needed for the verification.
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+}
+
+// NewSigningKeyFromPEM parses a PKCS #8, PKCS #1 or SEC 1 private key,
+// or a PKIX public key in which case the key can only be used for verification.
+// Empty id derives it from the JWK thumbprint of the key.
+func NewSigningKeyFromPEM(id string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	key := SigningKey{ID: id}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+	key.Algorithm, err = signingAlgorithm(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	if key.ID == "" {
+		key.ID, err = key.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &key, nil
+}
+
+// NewEphemeralSigningKey generates a key that only lives as long as the process.
+func NewEphemeralSigningKey() (*SigningKey, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	key := SigningKey{
+		Algorithm: SigningAlgorithmES256,
+		Private:   private,
+		Public:    private.Public(),
+	}
+	key.ID, err = key.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func signingAlgorithm(public crypto.PublicKey) (string, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return "", errors.Errorf("RSA key too short: %d bits", public.N.BitLen())
+		}
+		return SigningAlgorithmRS256, nil
+	case *ecdsa.PublicKey:
+		switch public.Curve {
+		case elliptic.P256():
+			return SigningAlgorithmES256, nil
+		case elliptic.P384():
+			return SigningAlgorithmES384, nil
+		case elliptic.P521():
+			return SigningAlgorithmES512, nil
+		}
+		return "", errors.Errorf("unsupported elliptic curve: %s", public.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return SigningAlgorithmEdDSA, nil
+	}
+	return "", errors.Errorf("unsupported key type: %T", public)
+}
+
+// JWK is the public part of a signing key as a JSON Web Key,
+// see https://datatracker.ietf.org/doc/html/rfc7517#section-4.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func encodeBigInt(value *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(value.FillBytes(make([]byte, size)))
+}
+
+// JWK returns the public part of the key.
+func (k *SigningKey) JWK() (*JWK, error) {
+	jwk := JWK{
+		KeyID:     k.ID,
+		Use:       "sig",
+		Algorithm: k.Algorithm,
+	}
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = encodeBigInt(public.X, size)
+		jwk.Y = encodeBigInt(public.Y, size)
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return nil, errors.Errorf("unsupported key type: %T", public)
+	}
+	return &jwk, nil
+}
+
+// Thumbprint computes the JWK thumbprint of the public key,
+// see https://datatracker.ietf.org/doc/html/rfc7638#section-3.
+func (k *SigningKey) Thumbprint() (string, error) {
+	jwk, err := k.JWK()
+	if err != nil {
+		return "", err
+	}
+	// only the required members in lexicographic order and without whitespace
+	var canonical string
+	switch jwk.KeyType {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.KeyType, jwk.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk.Curve, jwk.KeyType, jwk.X, jwk.Y)
+	default:
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, jwk.Curve, jwk.KeyType, jwk.X)
+	}
+	digest := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(digest[:]), nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// SigningKey parses the public key published as the JWK, the key
+// can only be used to verify the tokens signed by its owner.
+func (k JWK) SigningKey() (*SigningKey, error) {
+	var public crypto.PublicKey
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > math.MaxInt32 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		public = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported elliptic curve: %s", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		public = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if k.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("unsupported OKP key: %s", k.Curve)
+		}
+		public = ed25519.PublicKey(x)
+	default:
+		return nil, errors.Errorf("unsupported key type: %s", k.KeyType)
+	}
+
+	key := SigningKey{ID: k.KeyID, Public: public}
+	var err error
+	key.Algorithm, err = signingAlgorithm(public)
+	if err != nil {
+		return nil, err
+	}
+	// RSA keys might be used with the other SHA-2 variants
+	if k.Algorithm != "" && jwt.GetSigningMethod(k.Algorithm) != nil {
+		key.Algorithm = k.Algorithm
+	}
+	return &key, nil
+}
+
+// KeySet signs tokens with a single active key and verifies them
+// with any of the known keys, which allows rotating the active key
+// without invalidating the tokens issued before.
+type KeySet struct {
+	signing *SigningKey
+	// verification starts with the signing key
+	verification []*SigningKey
+}
+
+// NewVerificationKeySet creates a key set that can not sign,
+// e.g. for the tokens issued by another server.
+func NewVerificationKeySet(verification ...*SigningKey) *KeySet {
+	return &KeySet{verification: verification}
+}
+
+// NewKeySet creates a key set signing with the first key.
+func NewKeySet(signing *SigningKey, verification ...*SigningKey) (*KeySet, error) {
+	if signing == nil || signing.Private == nil {
+		return nil, errors.New("signing key must contain a private key")
+	}
+	keys := KeySet{
+		signing:      signing,
+		verification: []*SigningKey{signing},
+	}
+	for _, key := range verification {
+		if keys.key(key.ID) != nil {
+			return nil, errors.Errorf("duplicate key id: %s", key.ID)
+		}
+		keys.verification = append(keys.verification, key)
+	}
+	return &keys, nil
+}
+
+func (s *KeySet) key(id string) *SigningKey {
+	for _, key := range s.verification {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// Algorithm is the one the active key signs with.
+func (s *KeySet) Algorithm() string {
+	if s.signing == nil {
+		return ""
+	}
+	return s.signing.Algorithm
+}
+
+// Sign creates a token with the claims signed by the active key.
+func (s *KeySet) Sign(claims map[string]any) (*JWT, error) {
+	if s.signing == nil {
+		return nil, errors.New("key set can only verify tokens")
+	}
+	token := jwt.NewWithClaims(
+		jwt.GetSigningMethod(s.signing.Algorithm),
+		jwt.MapClaims(claims),
+	)
+	token.Header["kid"] = s.signing.ID
+	signed, err := token.SignedString(s.signing.Private)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	result := JWT(signed)
+	return &result, nil
+}
+
+// Parse verifies the signature of the token and returns its claims.
+func (s *KeySet) Parse(token JWT, options ...jwt.ParserOption) (map[string]any, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		string(token),
+		claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			key := s.key(kid)
+			if key == nil {
+				return nil, errors.Errorf("unknown key id: %s", kid)
+			}
+			// never let the token choose the algorithm for our key
+			if token.Method.Alg() != key.Algorithm {
+				return nil, errors.Errorf("unexpected signing algorithm: %s", token.Method.Alg())
+			}
+			return key.Public, nil
+		},
+		options...,
+	)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys to be published for offline verification.
+func (s *KeySet) JWKS() (*JWKSet, error) {
+	set := JWKSet{Keys: make([]JWK, 0, len(s.verification))}
+	for _, key := range s.verification {
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	return &set, nil
+}
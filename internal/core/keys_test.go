@@ -0,0 +1,169 @@
+package core_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func newTestKeySet(t *testing.T) *core.KeySet {
+	t.Helper()
+	key, err := core.NewEphemeralSigningKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	keys, err := core.NewKeySet(key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return keys
+}
+
+func encodePEM(t *testing.T, private any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestNewSigningKeyFromPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		pem       []byte
+		algorithm string
+	}{
+		{pem: encodePEM(t, rsaKey), algorithm: core.SigningAlgorithmRS256},
+		{pem: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), algorithm: core.SigningAlgorithmRS256},
+		{pem: encodePEM(t, ecKey), algorithm: core.SigningAlgorithmES256},
+		{pem: encodePEM(t, edKey), algorithm: core.SigningAlgorithmEdDSA},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestNewSigningKeyFromPEM_%d_%s", i, testCase.algorithm), func(t *testing.T) {
+			key, err := core.NewSigningKeyFromPEM("", testCase.pem)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if key.Algorithm != testCase.algorithm {
+				t.Fatalf("Expected %s but got %s", testCase.algorithm, key.Algorithm)
+			}
+			if key.ID == "" {
+				t.Fatalf("Key id should be derived from the thumbprint")
+			}
+			keys, err := core.NewKeySet(key)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			token, err := keys.Sign(map[string]any{"sub": "user"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			claims, err := keys.Parse(*token)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if claims["sub"] != "user" {
+				t.Fatalf("Expected sub claim to survive the round trip, got %#v", claims)
+			}
+		})
+	}
+
+	if _, err := core.NewSigningKeyFromPEM("", []byte("not a key")); err == nil {
+		t.Fatalf("Expected error for invalid PEM")
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	previous, err := core.NewEphemeralSigningKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	current, err := core.NewEphemeralSigningKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	before, err := core.NewKeySet(previous)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	oldToken, err := before.Sign(map[string]any{"sub": "user"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// only the public part of the previous key is kept
+	retired := &core.SigningKey{ID: previous.ID, Algorithm: previous.Algorithm, Public: previous.Public}
+	after, err := core.NewKeySet(current, retired)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := after.Parse(*oldToken); err != nil {
+		t.Fatalf("Token signed with the previous key should still verify: %s", err)
+	}
+	newToken, err := after.Sign(map[string]any{"sub": "user"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := before.Parse(*newToken); err == nil {
+		t.Fatalf("Token signed with an unknown key should be rejected")
+	}
+
+	jwks, err := after.JWKS()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != current.ID || jwks.Keys[1].KeyID != previous.ID {
+		t.Fatalf("Expected both keys with the active one first, got %#v", jwks.Keys)
+	}
+
+	if _, err := core.NewKeySet(retired); err == nil {
+		t.Fatalf("Key set must not sign with a public key only")
+	}
+}
+
+// see https://datatracker.ietf.org/doc/html/rfc7638#section-3.1.
+func TestThumbprint(t *testing.T) {
+	const (
+		rfcModulus    = "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+		rfcThumbprint = "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+	)
+	modulus, err := base64.RawURLEncoding.DecodeString(rfcModulus)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	key := core.SigningKey{
+		Algorithm: core.SigningAlgorithmRS256,
+		Public:    &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: 65537},
+	}
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if thumbprint != rfcThumbprint {
+		t.Fatalf("Expected %s but got %s", rfcThumbprint, thumbprint)
+	}
+}
@@ -0,0 +1,39 @@
+package core
+
+// LDAPConfig describes the directory the password grant checks the credentials against.
+type LDAPConfig struct {
+	// URL of the server, e.g. ldaps://ldap.example.com:636.
+	URL string
+	// StartTLS upgrades the plain ldap:// connection before any credentials are sent.
+	StartTLS bool
+	// BindDN and BindPassword are the service account searching for the users,
+	// the search is anonymous when they are empty.
+	BindDN       string
+	BindPassword string
+	// BaseDN is where the users are searched.
+	BaseDN string
+	// UserFilter finds the user by the username substituted for %s, e.g. (uid=%s),
+	// the username attribute is matched when it is empty.
+	UserFilter string
+	Attributes LDAPAttributes
+	// AdminGroup is the distinguished name of the group whose members are administrators,
+	// the administrator role is left alone when it is empty.
+	AdminGroup string
+}
+
+// LDAPAttributes name the attributes of the user entries.
+type LDAPAttributes struct {
+	Username string
+	Email    string
+	// Groups lists the distinguished names of the groups of the user, e.g. memberOf.
+	Groups string
+}
+
+// DefaultLDAPAttributes follow the inetOrgPerson schema with the memberOf overlay.
+func DefaultLDAPAttributes() LDAPAttributes {
+	return LDAPAttributes{
+		Username: "uid",
+		Email:    "mail",
+		Groups:   "memberOf",
+	}
+}
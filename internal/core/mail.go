@@ -0,0 +1,33 @@
+package core
+
+// MailMessage is a plain text email to a single recipient.
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MailConfig decides how the emails are sent, the features relying
+// on the emails are disabled when no sender is configured.
+type MailConfig struct {
+	// From is the sender address of the emails, e.g. Wallabago <no-reply@example.com>.
+	From string
+	// SMTP relays the emails through the server when its address is set.
+	SMTP SMTPConfig
+	// File collects the emails instead of sending them, e.g. for the development.
+	File string
+	// Log writes the emails to the log instead of sending them.
+	Log bool
+}
+
+// SMTPConfig describes the server the emails are relayed through.
+type SMTPConfig struct {
+	// Addr is the host and the port of the server, e.g. smtp.example.com:587.
+	Addr string
+	// StartTLS upgrades the plain connection before any credentials are sent.
+	StartTLS bool
+	// Username and Password authenticate with the PLAIN mechanism,
+	// the emails are sent without authentication when they are empty.
+	Username string
+	Password string
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
@@ -23,11 +22,50 @@ type (
 )
 
 const (
-	ScopeEntries ScopeName = "entries"
+	// ScopeEntries grants full access to the entries,
+	// it is kept for the clients that predate the finer grained scopes.
+	ScopeEntries      ScopeName = "entries"
+	ScopeEntriesRead  ScopeName = "entries:read"
+	ScopeEntriesWrite ScopeName = "entries:write"
+	ScopeTags         ScopeName = "tags"
+	ScopeAnnotations  ScopeName = "annotations"
+	ScopeExport       ScopeName = "export"
+	// ScopeClients allows managing the OAuth clients registered by the user.
+	ScopeClients ScopeName = "clients"
+	// ScopeTokens allows managing the personal access tokens of the user.
+	ScopeTokens ScopeName = "tokens"
+	// ScopeSessions allows listing and ending the sessions of the user.
+	ScopeSessions ScopeName = "sessions"
+	// ScopeAdmin is only usable by administrators, the scope alone
+	// does not grant any privileges.
+	ScopeAdmin ScopeName = "admin"
+	// ScopeOpenID turns the request into an OpenID Connect authentication,
+	// the profile and email scopes release the matching claims of the user,
+	// see https://openid.net/specs/openid-connect-core-1_0.html#ScopeClaims.
+	ScopeOpenID  ScopeName = "openid"
+	ScopeProfile ScopeName = "profile"
+	ScopeEmail   ScopeName = "email"
 )
 
 var validScopeNames = []ScopeName{
 	ScopeEntries,
+	ScopeEntriesRead,
+	ScopeEntriesWrite,
+	ScopeTags,
+	ScopeAnnotations,
+	ScopeExport,
+	ScopeClients,
+	ScopeTokens,
+	ScopeSessions,
+	ScopeAdmin,
+	ScopeOpenID,
+	ScopeProfile,
+	ScopeEmail,
+}
+
+// impliedScopeNames lists the narrower scopes granted along with a broader one.
+var impliedScopeNames = map[ScopeName][]ScopeName{
+	ScopeEntries: {ScopeEntriesRead, ScopeEntriesWrite},
 }
 
 func DefaultScope() *Scope {
@@ -35,6 +73,12 @@ func DefaultScope() *Scope {
 	return &scope
 }
 
+// FullScope contains every known scope name.
+func FullScope() *Scope {
+	scope, _ := NewScope(validScopeNames...)
+	return scope
+}
+
 func NewScope(scopeNames ...ScopeName) (*Scope, error) {
 	sb := strings.Builder{}
 	for i, scopeName := range scopeNames {
@@ -59,43 +103,108 @@ func NewScopeFromString(scope string) (*Scope, error) {
 	return NewScope(names...)
 }
 
-type JWT string
+// Names returns the individual scope names contained in the scope.
+func (s Scope) Names() []ScopeName {
+	parts := strings.Fields(string(s))
+	names := make([]ScopeName, 0, len(parts))
+	for _, part := range parts {
+		names = append(names, ScopeName(part))
+	}
+	return names
+}
 
-func NewJWT(claims map[string]any, key []byte) (*JWT, error) {
-	refreshToken, err := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.MapClaims(claims),
-	).SignedString(key)
-	if err != nil {
-		return nil, errors.WithStack(err)
+// Grants reports whether the scope contains the scope name
+// either directly or through a broader scope implying it.
+func (s Scope) Grants(name ScopeName) bool {
+	for _, granted := range s.Names() {
+		if granted == name || slices.Contains(impliedScopeNames[granted], name) {
+			return true
+		}
 	}
+	return false
+}
 
-	result := JWT(refreshToken)
-	return &result, nil
+// Missing returns the required scope names not granted by the scope.
+func (s Scope) Missing(required ...ScopeName) []ScopeName {
+	var missing []ScopeName
+	for _, name := range required {
+		if !s.Grants(name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Includes reports whether every scope name of other is also granted by s.
+func (s Scope) Includes(other Scope) bool {
+	return len(s.Missing(other.Names()...)) == 0
 }
 
-func NewRefreshToken(userID, clientID string, key []byte) (*RefreshToken, error) {
+type JWT string
+
+// NewRefreshToken creates a refresh token belonging to the given family.
+// Empty familyID starts a new family with the token as its first member,
+// zero lifetime issues a token that does not expire.
+func NewRefreshToken(issuer, userID, clientID, familyID string, scope Scope, lifetime time.Duration, keys *KeySet) (*RefreshToken, error) {
 	tokenID := uuid.New().String()
+	if familyID == "" {
+		familyID = tokenID
+	}
+	issuedAt := time.Now()
 	claims := map[string]any{
-		"iat": time.Now().Unix(),
-		// "exp": time.Now().Add(accessTokenExpiry).Unix(),
+		"iss": issuer,
+		"iat": issuedAt.Unix(),
 		"sub": userID,
 		"aud": clientID,
 		"jti": tokenID,
 	}
+	var expiresAt time.Time
+	if lifetime > 0 {
+		expiresAt = issuedAt.Add(lifetime)
+		claims["exp"] = expiresAt.Unix()
+	}
 
-	token, err := NewJWT(claims, key)
+	token, err := keys.Sign(claims)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return &RefreshToken{Token: *token, ID: tokenID, ClientID: clientID, Revoked: false}, nil
+	return &RefreshToken{
+		Token:     *token,
+		TokenHash: HashToken(string(*token)),
+		ID:        tokenID,
+		FamilyID:  familyID,
+		ClientID:  clientID,
+		UserID:    userID,
+		Scope:     scope,
+		IssuedAt:  issuedAt,
+		ExpiresAt: expiresAt,
+		Revoked:   false,
+		Rotated:   false,
+	}, nil
 }
 
 type RefreshToken struct {
-	Token    JWT
-	ID       string
-	ClientID string
-	Revoked  bool
+	// Token is only known right after the token was issued,
+	// afterwards just its hash is available
+	Token     JWT
+	TokenHash []byte
+	ID        string
+	FamilyID  string
+	ClientID  string
+	UserID    string
+	Scope     Scope
+	IssuedAt  time.Time
+	// ExpiresAt is zero for the tokens that do not expire.
+	ExpiresAt time.Time
+	Revoked   bool
+	// Rotated is set once the token was exchanged for a new one,
+	// presenting it again indicates that it leaked.
+	Rotated bool
+}
+
+// Expired reports whether the token can no longer be exchanged.
+func (t *RefreshToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt)
 }
 
 type AccessToken struct {
@@ -103,32 +212,50 @@ type AccessToken struct {
 	ExpiresInSeconds int64     `json:"expires_in"`
 	Scope            Scope     `json:"scope"`
 	TokenType        TokenType `json:"token_type"`
+	TokenHash        []byte    `json:"-"`
 	Revoked          bool      `json:"-"`
 	ID               string    `json:"-"`
 	ClientID         string    `json:"-"`
 	UserID           string    `json:"-"`
 	IssuedAt         time.Time `json:"-"`
+	GrantType        GrantType `json:"-"`
 }
 
-func NewAccessToken(userID, clientID string, scope Scope, expiration time.Duration, key []byte) (*AccessToken, error) {
-	tokenID := uuid.New()
+// ExpiresAt returns the time after which the token is no longer accepted.
+func (t *AccessToken) ExpiresAt() time.Time {
+	return t.IssuedAt.Add(time.Duration(t.ExpiresInSeconds) * time.Second)
+}
+
+// Active reports whether the token can still be used at the given time.
+func (t *AccessToken) Active(now time.Time) bool {
+	return !t.Revoked && now.Before(t.ExpiresAt())
+}
+
+// NewAccessToken creates a token for the api served by the issuer,
+// the claims follow https://datatracker.ietf.org/doc/html/rfc9068#section-2.2.
+func NewAccessToken(issuer, userID, clientID string, scope Scope, expiration time.Duration, keys *KeySet) (*AccessToken, error) {
+	tokenID := uuid.New().String()
 	issuedAt := time.Now()
 	claims := map[string]any{
-		"iat": issuedAt.Unix(),
-		"exp": issuedAt.Add(expiration).Unix(),
-		"sub": userID,
-		"aud": clientID,
-		"jti": tokenID,
+		"iss":       issuer,
+		"iat":       issuedAt.Unix(),
+		"exp":       issuedAt.Add(expiration).Unix(),
+		"sub":       userID,
+		"aud":       issuer,
+		"jti":       tokenID,
+		"client_id": clientID,
+		"scope":     string(scope),
 	}
-	token, err := NewJWT(claims, key)
+	token, err := keys.Sign(claims)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
 	return &AccessToken{
 		Token:            *token,
+		TokenHash:        HashToken(string(*token)),
 		ExpiresInSeconds: int64(expiration.Seconds()),
-		ID:               string(*token),
+		ID:               tokenID,
 		Scope:            scope,
 		Revoked:          false,
 		TokenType:        TokenTypeBearer,
@@ -141,13 +268,17 @@ func NewAccessToken(userID, clientID string, scope Scope, expiration time.Durati
 type AccessTokenResponse struct {
 	AccessToken
 	RefreshToken JWT `json:"refresh_token,omitempty"`
+	// IDToken is only issued for the openid scope.
+	IDToken JWT `json:"id_token,omitempty"`
 }
 
 type GrantType string
 
 const (
-	GrantTypePassword     = "password"
-	GrantTypeRefreshToken = "refresh_token"
+	GrantTypePassword          = "password"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
 )
 
 type AuthErrorName string
@@ -159,12 +290,30 @@ const (
 	AuthErrorUnauthorizedClient   = "unauthorized_client"
 	AuthErrorUnsupportedGrantType = "unsupported_grant_type"
 	AuthErrorInvalidScope         = "invalid_scope"
+	// errors of the protected resources,
+	// see https://datatracker.ietf.org/doc/html/rfc6750#section-3.1.
+	AuthErrorInvalidToken      = "invalid_token"
+	AuthErrorInsufficientScope = "insufficient_scope"
+	// errors of the authorization endpoint
+	AuthErrorAccessDenied            = "access_denied"
+	AuthErrorUnsupportedResponseType = "unsupported_response_type"
+	AuthErrorServerError             = "server_error"
+	// errors of the dynamic client registration,
+	// see https://datatracker.ietf.org/doc/html/rfc7591#section-3.2.2.
+	AuthErrorInvalidRedirectURI    = "invalid_redirect_uri"
+	AuthErrorInvalidClientMetadata = "invalid_client_metadata"
+	// AuthErrorMFARequired tells the client of the password grant to send
+	// the one-time password of the user in the otp parameter along with the credentials
+	AuthErrorMFARequired = "mfa_required"
+	// AuthErrorEmailUnverified tells the self-registered users to open
+	// the verification link emailed to them before they log in
+	AuthErrorEmailUnverified = "email_unverified"
 	// todo: check if proper semantics are used
 	AuthErrorUnauthorized = "unauthorized"
 )
 
 type AuthError struct {
-	ErrorName        AuthErrorName `json:"error_name,omitempty"`
+	ErrorName        AuthErrorName `json:"error"`
 	ErrorDescription string        `json:"error_description,omitempty"`
 	ErrorURI         string        `json:"error_uri,omitempty"`
 }
@@ -180,6 +329,51 @@ type RefreshTokenFlowRequest struct {
 	ClientID     string
 	ClientSecret string
 	RefreshToken string
+	Scope        string
+	// ClientIP and UserAgent update the device of the session, empty if unknown
+	ClientIP  string
+	UserAgent string
+}
+
+type ClientCredentialsFlowRequest struct {
+	ClientID     string
+	ClientSecret string
+	Scope        string
+}
+
+type TokenTypeHint string
+
+// hints about the type of the token passed to the revocation endpoint,
+// see https://datatracker.ietf.org/doc/html/rfc7009#section-2.1.
+const (
+	TokenTypeHintAccessToken  TokenTypeHint = "access_token"
+	TokenTypeHintRefreshToken TokenTypeHint = "refresh_token"
+)
+
+type RevocationRequest struct {
+	ClientID      string
+	ClientSecret  string
+	Token         string
+	TokenTypeHint TokenTypeHint
+}
+
+type IntrospectionRequest struct {
+	ClientID      string
+	ClientSecret  string
+	Token         string
+	TokenTypeHint TokenTypeHint
+}
+
+// IntrospectionResponse describes the state of a token,
+// see https://datatracker.ietf.org/doc/html/rfc7662#section-2.2.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     Scope  `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	JWTID     string `json:"jti,omitempty"`
 }
 
 type PasswordFlowRequest struct {
@@ -188,6 +382,11 @@ type PasswordFlowRequest struct {
 	Username     string
 	Password     string
 	Scope        string
+	// ClientIP is the address the login came from, empty if unknown
+	ClientIP  string
+	UserAgent string
+	// OTP is the one-time password or a recovery code of the users with a second factor
+	OTP string
 }
 
 type UserInfo struct {
@@ -195,9 +394,7 @@ type UserInfo struct {
 	Username     string
 	Email        string
 	PasswordHash []byte
-}
-
-type Client struct {
-	ID     string
-	Secret string
+	// EmailUnverified marks the self-registered users until they open
+	// the verification link, they can not log in before.
+	EmailUnverified bool
 }
@@ -2,7 +2,9 @@ package core_test
 
 import (
 	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/andriihomiak/wallabago/internal/core"
 )
@@ -50,3 +52,102 @@ func TestNewScope(t *testing.T) {
 		})
 	}
 }
+
+func TestScopeIncludes(t *testing.T) {
+	cases := []struct {
+		scope    core.Scope
+		other    core.Scope
+		includes bool
+	}{
+		{scope: "entries", other: "entries", includes: true},
+		{scope: "entries", other: "", includes: true},
+		{scope: "", other: "entries", includes: false},
+		{scope: "entries other", other: "other", includes: true},
+		{scope: "other", other: "entries other", includes: false},
+		{scope: "entries", other: "entries:read", includes: true},
+		{scope: "entries:read entries:write", other: "entries", includes: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestScopeIncludes_%d_%#v_%#v", i, testCase.scope, testCase.other), func(t *testing.T) {
+			if result := testCase.scope.Includes(testCase.other); result != testCase.includes {
+				t.Fatalf("Expected %#v but got %#v", testCase.includes, result)
+			}
+		})
+	}
+}
+
+func TestScopeMissing(t *testing.T) {
+	cases := []struct {
+		scope    core.Scope
+		required []core.ScopeName
+		missing  []core.ScopeName
+	}{
+		{scope: "entries", required: []core.ScopeName{core.ScopeEntriesRead, core.ScopeEntriesWrite}, missing: nil},
+		{scope: "entries:read", required: []core.ScopeName{core.ScopeEntriesRead}, missing: nil},
+		{scope: "entries:read", required: []core.ScopeName{core.ScopeEntriesWrite}, missing: []core.ScopeName{core.ScopeEntriesWrite}},
+		{scope: "tags", required: []core.ScopeName{core.ScopeAdmin, core.ScopeTags}, missing: []core.ScopeName{core.ScopeAdmin}},
+		{scope: "", required: nil, missing: nil},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestScopeMissing_%d_%#v_%#v", i, testCase.scope, testCase.required), func(t *testing.T) {
+			if result := testCase.scope.Missing(testCase.required...); !slices.Equal(result, testCase.missing) {
+				t.Fatalf("Expected %#v but got %#v", testCase.missing, result)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenFamily(t *testing.T) {
+	keys := newTestKeySet(t)
+	first, err := core.NewRefreshToken("issuer", "user", "client", "", core.Scope("entries"), 0, keys)
+	if err != nil {
+		t.Fatalf("Should succeed without error: %v", err)
+	}
+	if first.FamilyID != first.ID {
+		t.Fatalf("First token of a family should start it, got family %#v for token %#v", first.FamilyID, first.ID)
+	}
+
+	second, err := core.NewRefreshToken("issuer", "user", "client", first.FamilyID, first.Scope, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("Should succeed without error: %v", err)
+	}
+	if second.FamilyID != first.FamilyID {
+		t.Fatalf("Rotated token should stay in family %#v, got %#v", first.FamilyID, second.FamilyID)
+	}
+
+	claims, err := keys.Parse(second.Token)
+	if err != nil {
+		t.Fatalf("Should succeed without error: %v", err)
+	}
+	if claims["jti"] != second.ID {
+		t.Fatalf("Expected jti %#v but got %#v", second.ID, claims["jti"])
+	}
+
+	if _, err := newTestKeySet(t).Parse(second.Token); err == nil {
+		t.Fatalf("Token signed with another key should be rejected")
+	}
+
+	if first.Expired(first.IssuedAt.Add(24 * 365 * time.Hour)) {
+		t.Fatalf("Token without lifetime should never expire")
+	}
+	if second.Expired(second.IssuedAt) || !second.Expired(second.IssuedAt.Add(time.Hour+time.Second)) {
+		t.Fatalf("Token should expire after its lifetime, expires at %v", second.ExpiresAt)
+	}
+}
+
+func TestAccessTokenActive(t *testing.T) {
+	token, err := core.NewAccessToken("issuer", "user", "client", *core.DefaultScope(), time.Hour, newTestKeySet(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !token.Active(time.Now()) {
+		t.Fatalf("Fresh token should be active")
+	}
+	if token.Active(token.IssuedAt.Add(time.Hour + time.Second)) {
+		t.Fatalf("Token should not be active after it expired")
+	}
+	token.Revoked = true
+	if token.Active(time.Now()) {
+		t.Fatalf("Revoked token should not be active")
+	}
+}
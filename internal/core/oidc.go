@@ -0,0 +1,147 @@
+package core
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// newAlgorithmHash returns the hash function of the signing algorithm,
+// it is used for the hashes of the tokens put into the id tokens.
+func newAlgorithmHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case SigningAlgorithmRS256, SigningAlgorithmES256:
+		return sha256.New(), nil
+	case SigningAlgorithmES384:
+		return sha512.New384(), nil
+	// Ed25519 is built on SHA-512
+	case SigningAlgorithmES512, SigningAlgorithmEdDSA:
+		return sha512.New(), nil
+	}
+	return nil, errors.Errorf("unsupported signing algorithm: %s", algorithm)
+}
+
+// AccessTokenHash computes the at_hash claim of the id token issued along with the access token,
+// see https://openid.net/specs/openid-connect-core-1_0.html#CodeIDToken.
+func AccessTokenHash(accessToken JWT, algorithm string) (string, error) {
+	h, err := newAlgorithmHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(accessToken))
+	digest := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(digest[:len(digest)/2]), nil
+}
+
+// NewIDToken tells the client who the user is, the nonce is only known
+// when the token is issued for an authentication request,
+// see https://openid.net/specs/openid-connect-core-1_0.html#IDToken.
+func NewIDToken(issuer, userID, clientID, nonce string, accessToken JWT, expiration time.Duration, keys *KeySet) (*JWT, error) {
+	atHash, err := AccessTokenHash(accessToken, keys.Algorithm())
+	if err != nil {
+		return nil, err
+	}
+	issuedAt := time.Now()
+	claims := map[string]any{
+		"iss":     issuer,
+		"sub":     userID,
+		"aud":     clientID,
+		"iat":     issuedAt.Unix(),
+		"exp":     issuedAt.Add(expiration).Unix(),
+		"at_hash": atHash,
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	token, err := keys.Sign(claims)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return token, nil
+}
+
+// UserInfoClaims are released by the userinfo endpoint depending on the scope of the access token,
+// see https://openid.net/specs/openid-connect-core-1_0.html#StandardClaims.
+type UserInfoClaims struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+}
+
+func NewUserInfoClaims(user UserInfo, scope Scope) UserInfoClaims {
+	claims := UserInfoClaims{Subject: user.ID}
+	if scope.Grants(ScopeProfile) {
+		claims.PreferredUsername = user.Username
+	}
+	if scope.Grants(ScopeEmail) {
+		claims.Email = user.Email
+	}
+	return claims
+}
+
+// ServerEndpoints are the absolute urls the server handles the protocol at.
+type ServerEndpoints struct {
+	Authorization       string
+	Token               string
+	Revocation          string
+	Introspection       string
+	DeviceAuthorization string
+	Registration        string
+	UserInfo            string
+	JWKS                string
+}
+
+// AuthorizationServerMetadata describes the server to the clients, it is served both as
+// https://datatracker.ietf.org/doc/html/rfc8414#section-2 and as
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata.
+type AuthorizationServerMetadata struct {
+	Issuer                            string      `json:"issuer"`
+	AuthorizationEndpoint             string      `json:"authorization_endpoint"`
+	TokenEndpoint                     string      `json:"token_endpoint"`
+	JWKSURI                           string      `json:"jwks_uri"`
+	UserInfoEndpoint                  string      `json:"userinfo_endpoint"`
+	RegistrationEndpoint              string      `json:"registration_endpoint,omitempty"`
+	RevocationEndpoint                string      `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string      `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint       string      `json:"device_authorization_endpoint"`
+	ScopesSupported                   []ScopeName `json:"scopes_supported"`
+	ResponseTypesSupported            []string    `json:"response_types_supported"`
+	GrantTypesSupported               []GrantType `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string    `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string    `json:"code_challenge_methods_supported"`
+	SubjectTypesSupported             []string    `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string    `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported                   []string    `json:"claims_supported"`
+}
+
+// NewAuthorizationServerMetadata advertises the endpoints, the empty registration
+// endpoint is left out for the servers that do not allow the dynamic registration.
+func NewAuthorizationServerMetadata(issuer string, endpoints ServerEndpoints, signingAlgorithm string) *AuthorizationServerMetadata {
+	return &AuthorizationServerMetadata{
+		Issuer:                      issuer,
+		AuthorizationEndpoint:       endpoints.Authorization,
+		TokenEndpoint:               endpoints.Token,
+		JWKSURI:                     endpoints.JWKS,
+		UserInfoEndpoint:            endpoints.UserInfo,
+		RegistrationEndpoint:        endpoints.Registration,
+		RevocationEndpoint:          endpoints.Revocation,
+		IntrospectionEndpoint:       endpoints.Introspection,
+		DeviceAuthorizationEndpoint: endpoints.DeviceAuthorization,
+		ScopesSupported:             FullScope().Names(),
+		ResponseTypesSupported:      []string{ResponseTypeCode},
+		GrantTypesSupported:         SupportedGrantTypes(),
+		TokenEndpointAuthMethodsSupported: []string{
+			TokenEndpointAuthMethodClientSecretBasic,
+			TokenEndpointAuthMethodClientSecretPost,
+			TokenEndpointAuthMethodNone,
+		},
+		CodeChallengeMethodsSupported:    []string{CodeChallengeMethodS256},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{signingAlgorithm},
+		ClaimsSupported:                  []string{"iss", "sub", "aud", "iat", "exp", "nonce", "at_hash", "preferred_username", "email"},
+	}
+}
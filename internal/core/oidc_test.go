@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestAccessTokenHash(t *testing.T) {
+	// example from https://openid.net/specs/openid-connect-core-1_0.html#code-id_tokenExample
+	result, err := core.AccessTokenHash("jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y", core.SigningAlgorithmRS256)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := "77QmUPtjPfzWtF2AnpK9RQ"; result != expected {
+		t.Fatalf("Expected %#v but got %#v", expected, result)
+	}
+	_, err = core.AccessTokenHash("token", "none")
+	if err == nil {
+		t.Fatalf("Expected error for unsupported algorithm")
+	}
+}
+
+func TestNewIDToken(t *testing.T) {
+	keys := newTestKeySet(t)
+	accessToken, err := core.NewAccessToken("issuer", "user", "client", core.Scope(core.ScopeOpenID), time.Hour, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	cases := []struct {
+		nonce string
+	}{
+		{nonce: ""},
+		{nonce: "n-0S6_WzA2Mj"},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestNewIDToken_%d_%#v", i, testCase.nonce), func(t *testing.T) {
+			idToken, err := core.NewIDToken("issuer", "user", "client", testCase.nonce, accessToken.Token, time.Hour, keys)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			claims, err := keys.Parse(*idToken)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if claims["iss"] != "issuer" || claims["sub"] != "user" || claims["aud"] != "client" {
+				t.Fatalf("Unexpected claims: %#v", claims)
+			}
+			atHash, _ := core.AccessTokenHash(accessToken.Token, keys.Algorithm())
+			if claims["at_hash"] != atHash {
+				t.Fatalf("Expected at_hash %#v but got %#v", atHash, claims["at_hash"])
+			}
+			nonce, ok := claims["nonce"]
+			if testCase.nonce == "" && ok {
+				t.Fatalf("Expected no nonce but got %#v", nonce)
+			}
+			if testCase.nonce != "" && nonce != testCase.nonce {
+				t.Fatalf("Expected nonce %#v but got %#v", testCase.nonce, nonce)
+			}
+		})
+	}
+}
+
+func TestNewUserInfoClaims(t *testing.T) {
+	user := core.UserInfo{ID: "id", Username: "user", Email: "user@example.com"}
+	cases := []struct {
+		scope    core.Scope
+		expected core.UserInfoClaims
+	}{
+		{scope: "openid", expected: core.UserInfoClaims{Subject: "id"}},
+		{scope: "openid profile", expected: core.UserInfoClaims{Subject: "id", PreferredUsername: "user"}},
+		{scope: "openid email", expected: core.UserInfoClaims{Subject: "id", Email: "user@example.com"}},
+		{scope: "openid profile email entries", expected: core.UserInfoClaims{Subject: "id", PreferredUsername: "user", Email: "user@example.com"}},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestNewUserInfoClaims_%d_%s", i, testCase.scope), func(t *testing.T) {
+			if result := core.NewUserInfoClaims(user, testCase.scope); result != testCase.expected {
+				t.Fatalf("Expected %#v but got %#v", testCase.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// GrantTypePasskey is recorded for the tokens issued after a passkey login,
+// it can not be requested at the token endpoint.
+const GrantTypePasskey = "passkey"
+
+// Passkey is the WebAuthn credential the user logs in with,
+// see https://www.w3.org/TR/webauthn-3/#credential-record.
+type Passkey struct {
+	CredentialID []byte
+	UserID       string
+	// PublicKey is COSE encoded as the authenticator returned it.
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	// SignCount is the signature counter of the authenticator, always zero for the ones that do not count.
+	SignCount      uint32
+	Transports     []string
+	BackupEligible bool
+	BackupState    bool
+	CreatedAt      time.Time
+	// LastUsedAt is empty until the first login.
+	LastUsedAt time.Time
+}
+
+// ID identifies the passkey in the urls.
+func (p Passkey) ID() string {
+	return base64.RawURLEncoding.EncodeToString(p.CredentialID)
+}
+
+// ParsePasskeyID is the inverse of Passkey.ID.
+func ParsePasskeyID(id string) ([]byte, error) {
+	credentialID, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidInput, "passkey id")
+	}
+	return credentialID, nil
+}
+
+// WebAuthnCeremony tells the registrations and the logins apart.
+type WebAuthnCeremony string
+
+const (
+	WebAuthnCeremonyRegistration WebAuthnCeremony = "registration"
+	WebAuthnCeremonyLogin        WebAuthnCeremony = "login"
+)
+
+// WebAuthnChallenge is the ceremony waiting for the answer of the authenticator.
+type WebAuthnChallenge struct {
+	// Challenge is base64url encoded as it comes back in the client data.
+	Challenge string
+	Ceremony  WebAuthnCeremony
+	// UserID is the user registering a passkey, empty for the logins since the passkey tells the user.
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// PasskeyLoginRequest finishes the passkey login of the web ui client.
+type PasskeyLoginRequest struct {
+	ClientID     string
+	ClientSecret string
+	// Credential is the JSON of the PublicKeyCredential returned by navigator.credentials.get.
+	Credential []byte
+	// ClientIP and UserAgent describe the device of the session, empty if unknown
+	ClientIP  string
+	UserAgent string
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	//nolint:gosec //the breached password lists are published as sha-1 digests
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// defaultPasswordMinLength follows the NIST guidelines for the passwords chosen by the users.
+	defaultPasswordMinLength = 8
+	// maxPasswordLength keeps the hashing from being fed arbitrary amounts of data.
+	maxPasswordLength = 1024
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	// argon2Prefix starts the hashes in the PHC string format.
+	argon2Prefix = "$argon2id$"
+)
+
+// Argon2Params tune the cost of the argon2id password hashes.
+type Argon2Params struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultArgon2Params are the minimum recommended by OWASP.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+	}
+}
+
+// PasswordConfig is the password policy chosen by the administrator,
+// the zero fields fall back to the defaults.
+type PasswordConfig struct {
+	MinLength int
+	// BreachedFile lists the passwords nobody may choose as the hex SHA-1 digests, one per line
+	// and sorted in the ascending order, each optionally followed by a colon and the count of
+	// the breaches. That is the "ordered by hash" download of Have I Been Pwned, e.g.
+	//
+	//	000000005AD76BD555C1D6D771DE417A4B87E4B4:10
+	//	00000000A8DAE4228F821FB418F59826079BF368:4
+	//
+	// The file is searched where it lies rather than loaded, so it has to stay in place.
+	BreachedFile string
+	Argon2       Argon2Params
+}
+
+// PasswordPolicy checks the passwords chosen by the users and hashes them for storage.
+type PasswordPolicy struct {
+	MinLength int
+	Argon2    Argon2Params
+	// breached looks up the SHA-1 digests of the passwords nobody may choose, nil when there are none
+	breached *breachedPasswords
+}
+
+// DefaultPasswordPolicy hashes with the default parameters and knows no breached passwords.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength: defaultPasswordMinLength,
+		Argon2:    DefaultArgon2Params(),
+	}
+}
+
+// NewPasswordPolicy applies the configuration and loads the breached passwords.
+func NewPasswordPolicy(config PasswordConfig) (PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+	if config.MinLength > 0 {
+		policy.MinLength = config.MinLength
+	}
+	if config.Argon2.Memory > 0 {
+		policy.Argon2.Memory = config.Argon2.Memory
+	}
+	if config.Argon2.Iterations > 0 {
+		policy.Argon2.Iterations = config.Argon2.Iterations
+	}
+	if config.Argon2.Parallelism > 0 {
+		policy.Argon2.Parallelism = config.Argon2.Parallelism
+	}
+	if config.BreachedFile != "" {
+		breached, err := openBreachedPasswords(config.BreachedFile)
+		if err != nil {
+			return PasswordPolicy{}, errors.WithMessage(err, "failed to load the breached passwords")
+		}
+		policy.breached = breached
+	}
+	return policy, nil
+}
+
+// breachedPasswords binary searches the sorted file of the breached password digests,
+// the lists of Have I Been Pwned are far too big to be kept in memory.
+type breachedPasswords struct {
+	file *os.File
+	size int64
+}
+
+func openBreachedPasswords(path string) (*breachedPasswords, error) {
+	//nolint:gosec //the path comes from the operator
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		//nolint:errcheck //ok here since we only read
+		file.Close()
+		return nil, errors.WithStack(err)
+	}
+	breached := &breachedPasswords{file: file, size: info.Size()}
+	// a file in another format is rather reported right away than on the first password
+	_, _, err = breached.lineAt(0)
+	if err != nil {
+		//nolint:errcheck //ok here since we only read
+		file.Close()
+		return nil, err
+	}
+	return breached, nil
+}
+
+// contains halves the range of the offsets the line of the digest could start in until it is found.
+func (b *breachedPasswords) contains(digest [sha1.Size]byte) (bool, error) {
+	target := strings.ToUpper(hex.EncodeToString(digest[:]))
+	low, high := int64(0), b.size
+	for low < high {
+		middle := low + (high-low)/2
+		hexDigest, next, err := b.lineAt(middle)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case hexDigest == "" || hexDigest > target:
+			// no line starting at or after the middle can hold the digest
+			high = middle
+		case hexDigest < target:
+			low = next
+		default:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lineAt reads the digest of the first line starting at the offset or after it, unless the offset
+// is the start of the file, and returns the offset of the following line. The digest is empty past the last line.
+func (b *breachedPasswords) lineAt(offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		// the previous byte tells whether the offset is the start of a line
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(b.file, start, b.size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return "", b.size, nil
+		}
+		if err != nil {
+			return "", 0, errors.WithStack(err)
+		}
+		start += int64(len(skipped))
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, errors.WithStack(err)
+	}
+	next := start + int64(len(line))
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" && next == b.size {
+		return "", b.size, nil
+	}
+	hexDigest, _, _ := strings.Cut(line, ":")
+	if _, err := hex.DecodeString(hexDigest); err != nil || len(hexDigest) != hex.EncodedLen(sha1.Size) {
+		return "", 0, errors.Errorf("malformed breached password digest at offset %d", start)
+	}
+	return strings.ToUpper(hexDigest), next, nil
+}
+
+// Check returns ErrInvalidInput when the password is too short, too long or known to be breached,
+// any other error comes from reading the breached passwords.
+func (p PasswordPolicy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return errors.Wrapf(ErrInvalidInput, "password must be at least %d characters long", p.MinLength)
+	}
+	if len(password) > maxPasswordLength {
+		return errors.Wrapf(ErrInvalidInput, "password must not be longer than %d bytes", maxPasswordLength)
+	}
+	if p.breached == nil {
+		return nil
+	}
+	//nolint:gosec //only used to look the password up
+	breached, err := p.breached.contains(sha1.Sum([]byte(password)))
+	if err != nil {
+		return err
+	}
+	if breached {
+		return errors.Wrap(ErrInvalidInput, "password appeared in a data breach, choose another one")
+	}
+	return nil
+}
+
+// Hash checks the password and hashes it with argon2id for storage.
+func (p PasswordPolicy) Hash(password string) ([]byte, error) {
+	err := p.Check(password)
+	if err != nil {
+		return nil, err
+	}
+	return p.Rehash(password)
+}
+
+// Rehash hashes the password accepted earlier, e.g. the one the user just logged in with,
+// without checking it against the policy that might have changed since.
+func (p PasswordPolicy) Rehash(password string) ([]byte, error) {
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Argon2.Iterations, p.Argon2.Memory, p.Argon2.Parallelism, argon2KeyLength)
+	return fmt.Appendf(nil, "%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, p.Argon2.Memory, p.Argon2.Iterations, p.Argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks the password against the argon2id or the bcrypt hash, rehash
+// tells whether the hash should be replaced by the one with the current parameters.
+// The empty hash never matches.
+func (p PasswordPolicy) Verify(passwordHash []byte, password string) (match, rehash bool) {
+	if !bytes.HasPrefix(passwordHash, []byte(argon2Prefix)) {
+		if len(passwordHash) == 0 || bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) != nil {
+			return false, false
+		}
+		return true, true
+	}
+	params, salt, key, err := parseArgon2Hash(string(passwordHash))
+	if err != nil {
+		return false, false
+	}
+	//nolint:gosec //the key length comes from our own hashes
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return false, false
+	}
+	return true, params != p.Argon2
+}
+
+func parseArgon2Hash(passwordHash string) (Argon2Params, []byte, []byte, error) {
+	params := Argon2Params{}
+	// $argon2id$v=19$m=19456,t=2,p=1$salt$key
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	version := 0
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, errors.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, errors.Errorf("malformed argon2id parameters %q", parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errors.WithStack(err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("malformed argon2id key")
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// passwordResetPurpose tells the password resets apart from the other tokens signed by the server.
+const passwordResetPurpose = "password_reset"
+
+// PasswordResetLinkParameter carries the sealed reset in the link emailed to the user.
+const PasswordResetLinkParameter = "token"
+
+// PasswordReset lets the user who forgot the password choose a new one, it can be used once.
+type PasswordReset struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// NewPasswordReset starts a reset of the password of the user.
+func NewPasswordReset(userID string, lifetime time.Duration) *PasswordReset {
+	createdAt := time.Now()
+	return &PasswordReset{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(lifetime),
+	}
+}
+
+// Seal signs the reset so that it can be emailed to the user without being tampered with.
+func (r PasswordReset) Seal(issuer string, keys *KeySet) (*JWT, error) {
+	return keys.Sign(map[string]any{
+		"iss":     issuer,
+		"aud":     issuer,
+		"iat":     r.CreatedAt.Unix(),
+		"exp":     r.ExpiresAt.Unix(),
+		"sub":     r.UserID,
+		"jti":     r.ID,
+		"purpose": passwordResetPurpose,
+	})
+}
+
+// OpenPasswordReset verifies the sealed reset, whether it was already used is up to the caller.
+func OpenPasswordReset(sealed JWT, issuer string, keys *KeySet) (*PasswordReset, error) {
+	claims, err := keys.Parse(sealed,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != passwordResetPurpose {
+		return nil, errors.New("token is not a password reset")
+	}
+	reset := PasswordReset{}
+	reset.ID, _ = claims["jti"].(string)
+	reset.UserID, _ = claims["sub"].(string)
+	if reset.ID == "" || reset.UserID == "" {
+		return nil, errors.New("password reset is incomplete")
+	}
+	return &reset, nil
+}
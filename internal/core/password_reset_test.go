@@ -0,0 +1,54 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestPasswordResetSealing(t *testing.T) {
+	keys := newTestKeySet(t)
+	reset := core.NewPasswordReset("user", time.Minute)
+	sealed, err := reset.Seal("issuer", keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	opened, err := core.OpenPasswordReset(*sealed, "issuer", keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if opened.ID != reset.ID || opened.UserID != reset.UserID {
+		t.Fatalf("Expected %#v but got %#v", reset, opened)
+	}
+
+	_, err = core.OpenPasswordReset(*sealed, "other", keys)
+	if err == nil {
+		t.Fatalf("Expected error for another issuer")
+	}
+	_, err = core.OpenPasswordReset(*sealed, "issuer", newTestKeySet(t))
+	if err == nil {
+		t.Fatalf("Expected error for another key")
+	}
+	expired, err := core.NewPasswordReset("user", -time.Minute).Seal("issuer", keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = core.OpenPasswordReset(*expired, "issuer", keys)
+	if err == nil {
+		t.Fatalf("Expected error for expired reset")
+	}
+	// the upstream logins are signed by the same keys
+	login, err := core.NewUpstreamLogin("/", "user")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealedLogin, err := login.Seal("issuer", time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = core.OpenPasswordReset(*sealedLogin, "issuer", keys)
+	if err == nil {
+		t.Fatalf("Expected error for upstream login")
+	}
+}
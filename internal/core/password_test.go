@@ -0,0 +1,154 @@
+package core_test
+
+import (
+	"bytes"
+	//nolint:gosec //the breached password lists are published as sha-1 digests
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestPasswordPolicy keeps the hashing cheap, the lines of the breached passwords are written to a file.
+func newTestPasswordPolicy(t *testing.T, breachedLines ...string) core.PasswordPolicy {
+	t.Helper()
+	config := core.PasswordConfig{
+		Argon2: core.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1},
+	}
+	if len(breachedLines) > 0 {
+		config.BreachedFile = filepath.Join(t.TempDir(), "breached.txt")
+		err := os.WriteFile(config.BreachedFile, []byte(strings.Join(breachedLines, "\r\n")+"\r\n"), 0o600)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	policy, err := core.NewPasswordPolicy(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return policy
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := newTestPasswordPolicy(t, breachedLine("correct horse"))
+	cases := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{name: "long enough", password: "long enough", valid: true},
+		{name: "multibyte characters", password: "pässwörd", valid: true},
+		{name: "empty", password: ""},
+		{name: "too short", password: "short"},
+		{name: "too long", password: strings.Repeat("a", 1025)},
+		{name: "breached", password: "correct horse"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password)
+			if tc.valid && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !tc.valid && !errors.Is(err, core.ErrInvalidInput) {
+				t.Fatalf("Expected invalid input but got %v", err)
+			}
+		})
+	}
+}
+
+// breachedLine lists the password like Have I Been Pwned does.
+func breachedLine(password string) string {
+	//nolint:gosec //the lists hold the sha-1 digests
+	digest := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(digest[:])) + ":1"
+}
+
+func TestPasswordPolicyBreachedSearch(t *testing.T) {
+	lines := []string{}
+	for i := range 1000 {
+		lines = append(lines, breachedLine(fmt.Sprintf("breached-%d", i)))
+	}
+	slices.Sort(lines)
+	// the digests without the counts and in lower case are found too
+	lines[0], _, _ = strings.Cut(strings.ToLower(lines[0]), ":")
+	policy := newTestPasswordPolicy(t, lines...)
+	for i := range 1000 {
+		if err := policy.Check(fmt.Sprintf("breached-%d", i)); !errors.Is(err, core.ErrInvalidInput) {
+			t.Fatalf("Expected breached-%d to be rejected but got %v", i, err)
+		}
+		if err := policy.Check(fmt.Sprintf("accepted-%d", i)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	for _, content := range []string{"correct horse\n", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD\n"} {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if _, err := core.NewPasswordPolicy(core.PasswordConfig{BreachedFile: path}); err == nil {
+			t.Fatalf("Expected error for the malformed file %q", content)
+		}
+	}
+}
+
+func TestPasswordPolicyBreachedDigest(t *testing.T) {
+	// the lists of Have I Been Pwned hold the sha-1 digests of the passwords with the count of the breaches
+	policy := newTestPasswordPolicy(t, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004")
+	if err := policy.Check("password"); !errors.Is(err, core.ErrInvalidInput) {
+		t.Fatalf("Expected the breached password to be rejected but got %v", err)
+	}
+	if _, err := core.NewPasswordPolicy(core.PasswordConfig{BreachedFile: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Fatalf("Expected error for missing file")
+	}
+}
+
+func TestPasswordPolicyVerify(t *testing.T) {
+	policy := newTestPasswordPolicy(t)
+	argon2Hash, err := policy.Hash("password")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !bytes.HasPrefix(argon2Hash, []byte("$argon2id$v=19$m=64,t=1,p=1$")) {
+		t.Fatalf("Unexpected hash %s", argon2Hash)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	stronger := policy
+	stronger.Argon2.Iterations = 2
+	cases := []struct {
+		name           string
+		policy         core.PasswordPolicy
+		passwordHash   []byte
+		password       string
+		expectedMatch  bool
+		expectedRehash bool
+	}{
+		{name: "argon2id", policy: policy, passwordHash: argon2Hash, password: "password", expectedMatch: true},
+		{name: "argon2id wrong password", policy: policy, passwordHash: argon2Hash, password: "Password"},
+		{name: "argon2id outdated parameters", policy: stronger, passwordHash: argon2Hash, password: "password", expectedMatch: true, expectedRehash: true},
+		{name: "bcrypt", policy: policy, passwordHash: bcryptHash, password: "password", expectedMatch: true, expectedRehash: true},
+		{name: "bcrypt wrong password", policy: policy, passwordHash: bcryptHash, password: "Password"},
+		{name: "empty hash", policy: policy, passwordHash: []byte{}, password: ""},
+		{name: "malformed argon2id", policy: policy, passwordHash: []byte("$argon2id$v=19$m=64"), password: "password"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			match, rehash := tc.policy.Verify(tc.passwordHash, tc.password)
+			if match != tc.expectedMatch || rehash != tc.expectedRehash {
+				t.Fatalf("Expected match %v and rehash %v but got %v and %v", tc.expectedMatch, tc.expectedRehash, match, rehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// GrantTypePersonalAccessToken is recorded for the requests authenticated with
+// a personal access token, it can not be requested at the token endpoint.
+const GrantTypePersonalAccessToken = "personal_access_token"
+
+// PersonalAccessTokenPrefix tells the personal access tokens apart from the signed ones,
+// it also makes them easy to find by secret scanners.
+const PersonalAccessTokenPrefix = "wbg_pat_"
+
+// MaxPersonalAccessTokenLifetime bounds the lifetime a user may ask for the expiring tokens.
+const MaxPersonalAccessTokenLifetime = 10 * 365 * 24 * time.Hour
+
+// IsPersonalAccessToken reports whether the bearer token looks like a personal access token.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// PersonalAccessToken is the long-lived credential the users create for their scripts.
+type PersonalAccessToken struct {
+	// Token is only known right after the token was created,
+	// afterwards just its hash is available
+	Token     string
+	TokenHash []byte
+	ID        string
+	UserID    string
+	Name      string
+	Scope     Scope
+	CreatedAt time.Time
+	// ExpiresAt is zero for the tokens that do not expire.
+	ExpiresAt time.Time
+	// LastUsedAt is zero until the token authenticates the first request.
+	LastUsedAt time.Time
+}
+
+// NewPersonalAccessToken creates a token of the user, zero lifetime creates a token that does not expire.
+func NewPersonalAccessToken(userID, name string, scope Scope, lifetime time.Duration) (*PersonalAccessToken, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.Wrap(ErrInvalidInput, "token name is required")
+	}
+	if lifetime < 0 {
+		return nil, errors.Wrap(ErrInvalidInput, "token lifetime must not be negative")
+	}
+	if lifetime > MaxPersonalAccessTokenLifetime {
+		return nil, errors.Wrapf(ErrInvalidInput, "token lifetime must not exceed %s", MaxPersonalAccessTokenLifetime)
+	}
+	secret, err := NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	token := PersonalAccessTokenPrefix + secret
+	createdAt := time.Now()
+	var expiresAt time.Time
+	if lifetime > 0 {
+		expiresAt = createdAt.Add(lifetime)
+	}
+	return &PersonalAccessToken{
+		Token:     token,
+		TokenHash: HashToken(token),
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Expired reports whether the token can no longer be used.
+func (t *PersonalAccessToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt)
+}
+
+// AccessToken presents the token to the api handlers like any other access token,
+// the lifetime stays zero for the tokens that do not expire.
+func (t *PersonalAccessToken) AccessToken() *AccessToken {
+	accessToken := &AccessToken{
+		Token:     JWT(t.Token),
+		Scope:     t.Scope,
+		TokenType: TokenTypeBearer,
+		TokenHash: t.TokenHash,
+		ID:        t.ID,
+		UserID:    t.UserID,
+		IssuedAt:  t.CreatedAt,
+		GrantType: GrantTypePersonalAccessToken,
+	}
+	if !t.ExpiresAt.IsZero() {
+		accessToken.ExpiresInSeconds = int64(t.ExpiresAt.Sub(t.CreatedAt).Seconds())
+	}
+	return accessToken
+}
@@ -0,0 +1,49 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestNewPersonalAccessToken(t *testing.T) {
+	cases := []struct {
+		name          string
+		tokenName     string
+		lifetime      time.Duration
+		expectedError error
+		expires       bool
+	}{
+		{name: "without expiry", tokenName: "backup"},
+		{name: "with expiry", tokenName: "backup", lifetime: time.Hour, expires: true},
+		{name: "blank name", tokenName: " ", expectedError: core.ErrInvalidInput},
+		{name: "negative lifetime", tokenName: "backup", lifetime: -time.Hour, expectedError: core.ErrInvalidInput},
+		{name: "too long lifetime", tokenName: "backup", lifetime: core.MaxPersonalAccessTokenLifetime + time.Second, expectedError: core.ErrInvalidInput},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := core.NewPersonalAccessToken("user", tc.tokenName, *core.DefaultScope(), tc.lifetime)
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Fatalf("Expected error %s but got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !core.IsPersonalAccessToken(token.Token) || !core.VerifyTokenHash(token.TokenHash, token.Token) {
+				t.Fatalf("Unexpected token %#v", token)
+			}
+			if token.Expired(time.Now().Add(2*time.Hour)) != tc.expires {
+				t.Fatalf("Expected expiry %t for %#v", tc.expires, token)
+			}
+			accessToken := token.AccessToken()
+			if accessToken.GrantType != core.GrantTypePersonalAccessToken || accessToken.UserID != "user" {
+				t.Fatalf("Unexpected access token %#v", accessToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"slices"
+)
+
+// Methods the clients authenticate with at the token endpoint,
+// see https://datatracker.ietf.org/doc/html/rfc7591#section-2.
+const (
+	TokenEndpointAuthMethodNone              = "none"
+	TokenEndpointAuthMethodClientSecretBasic = "client_secret_basic"
+	TokenEndpointAuthMethodClientSecretPost  = "client_secret_post"
+)
+
+// defaultRegisteredClientName is shown for the clients that registered without a name.
+const defaultRegisteredClientName = "Unnamed client"
+
+// RegistrationPolicy decides who may register clients dynamically.
+type RegistrationPolicy struct {
+	// Open lets anyone register a client.
+	Open bool
+	// InitialAccessTokenHash is the hash of the token to present for the registration
+	// when it is not open, the registration is disabled when neither is set.
+	InitialAccessTokenHash []byte
+}
+
+// NewRegistrationPolicy hashes the initial access token chosen by the administrator.
+func NewRegistrationPolicy(open bool, initialAccessToken string) RegistrationPolicy {
+	policy := RegistrationPolicy{Open: open}
+	if initialAccessToken != "" {
+		policy.InitialAccessTokenHash = HashToken(initialAccessToken)
+	}
+	return policy
+}
+
+// Enabled reports whether the clients can be registered at all.
+func (p RegistrationPolicy) Enabled() bool {
+	return p.Open || len(p.InitialAccessTokenHash) > 0
+}
+
+// Allows reports whether the registration may proceed with the presented initial access token.
+func (p RegistrationPolicy) Allows(initialAccessToken string) bool {
+	if p.Open {
+		return true
+	}
+	return len(p.InitialAccessTokenHash) > 0 && VerifyTokenHash(p.InitialAccessTokenHash, initialAccessToken)
+}
+
+// ClientMetadata describes the client in the terms of the dynamic registration,
+// see https://datatracker.ietf.org/doc/html/rfc7591#section-2.
+type ClientMetadata struct {
+	RedirectURIs            []string    `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string      `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []GrantType `json:"grant_types,omitempty"`
+	ResponseTypes           []string    `json:"response_types,omitempty"`
+	ClientName              string      `json:"client_name,omitempty"`
+	Scope                   Scope       `json:"scope,omitempty"`
+}
+
+// NewClientMetadata describes the registered client.
+func NewClientMetadata(client Client) ClientMetadata {
+	metadata := ClientMetadata{
+		RedirectURIs:            client.RedirectURIs,
+		TokenEndpointAuthMethod: TokenEndpointAuthMethodClientSecretBasic,
+		GrantTypes:              client.GrantTypes,
+		ResponseTypes:           []string{},
+		ClientName:              client.Name,
+		Scope:                   client.Scope,
+	}
+	if client.Public {
+		metadata.TokenEndpointAuthMethod = TokenEndpointAuthMethodNone
+	}
+	if client.AllowsGrantType(GrantTypeAuthorizationCode) {
+		metadata.ResponseTypes = []string{ResponseTypeCode}
+	}
+	return metadata
+}
+
+// ClientPolicy fills in the defaults of the omitted metadata and checks the result.
+func (m ClientMetadata) ClientPolicy() (*ClientPolicy, error) {
+	policy := ClientPolicy{
+		RedirectURIs: m.RedirectURIs,
+		GrantTypes:   m.GrantTypes,
+		Scope:        m.Scope,
+	}
+	if len(policy.GrantTypes) == 0 {
+		policy.GrantTypes = []GrantType{GrantTypeAuthorizationCode}
+	}
+	if policy.Scope == "" {
+		policy.Scope = *DefaultScope()
+	}
+
+	switch m.TokenEndpointAuthMethod {
+	case "", TokenEndpointAuthMethodClientSecretBasic, TokenEndpointAuthMethodClientSecretPost:
+	case TokenEndpointAuthMethodNone:
+		policy.Public = true
+	default:
+		return nil, &AuthError{
+			ErrorName:        AuthErrorInvalidClientMetadata,
+			ErrorDescription: "Unsupported token_endpoint_auth_method: " + m.TokenEndpointAuthMethod,
+		}
+	}
+	for _, responseType := range m.ResponseTypes {
+		if responseType != ResponseTypeCode || !slices.Contains(policy.GrantTypes, GrantTypeAuthorizationCode) {
+			return nil, &AuthError{
+				ErrorName:        AuthErrorInvalidClientMetadata,
+				ErrorDescription: "Unsupported response type: " + responseType,
+			}
+		}
+	}
+
+	// the redirect uris get their own error so that the client knows what to fix
+	for _, redirectURI := range policy.RedirectURIs {
+		err := ValidateRedirectURI(redirectURI)
+		if err != nil {
+			return nil, &AuthError{
+				ErrorName:        AuthErrorInvalidRedirectURI,
+				ErrorDescription: err.Error(),
+			}
+		}
+	}
+	if slices.Contains(policy.GrantTypes, GrantTypeAuthorizationCode) && len(policy.RedirectURIs) == 0 {
+		return nil, &AuthError{
+			ErrorName:        AuthErrorInvalidRedirectURI,
+			ErrorDescription: "The authorization_code grant requires at least one redirect uri",
+		}
+	}
+	err := policy.Validate()
+	if err != nil {
+		return nil, &AuthError{
+			ErrorName:        AuthErrorInvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		}
+	}
+	return &policy, nil
+}
+
+func (m ClientMetadata) name() string {
+	if m.ClientName == "" {
+		return defaultRegisteredClientName
+	}
+	return m.ClientName
+}
+
+// NewRegisteredClient registers a client on its own behalf, so it has no owner
+// and manages the registration with the registration access token instead.
+func NewRegisteredClient(metadata ClientMetadata) (*Client, error) {
+	policy, err := metadata.ClientPolicy()
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClient("", metadata.name(), *policy)
+	if err != nil {
+		return nil, &AuthError{
+			ErrorName:        AuthErrorInvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		}
+	}
+	err = client.IssueRegistrationAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// UpdateMetadata replaces the metadata of the dynamically registered client.
+// The client gets a new secret when it stops being public
+// and loses the previous one when it becomes public.
+func (c *Client) UpdateMetadata(metadata ClientMetadata) error {
+	policy, err := metadata.ClientPolicy()
+	if err != nil {
+		return err
+	}
+	name, err := validateClientName(metadata.name())
+	if err != nil {
+		return &AuthError{
+			ErrorName:        AuthErrorInvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		}
+	}
+	// the lifetimes are not part of the metadata, so they are kept
+	policy.AccessTokenLifetime = c.AccessTokenLifetime
+	policy.RefreshTokenLifetime = c.RefreshTokenLifetime
+
+	wasPublic := c.Public
+	c.Name = name
+	c.ClientPolicy = *policy
+	switch {
+	case c.Public:
+		c.Secret = ""
+		c.SecretHash = nil
+	case wasPublic:
+		return c.RotateSecret()
+	}
+	return nil
+}
+
+// DynamicallyRegistered reports whether the client registered on its own behalf.
+func (c *Client) DynamicallyRegistered() bool {
+	return len(c.RegistrationAccessTokenHash) > 0
+}
+
+// IssueRegistrationAccessToken replaces the registration access token with a freshly generated one.
+func (c *Client) IssueRegistrationAccessToken() error {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	c.RegistrationAccessToken = token
+	c.RegistrationAccessTokenHash = HashToken(token)
+	return nil
+}
+
+// AuthenticateRegistration checks the registration access token presented by the client.
+func (c *Client) AuthenticateRegistration(registrationAccessToken string) bool {
+	return c.DynamicallyRegistered() && VerifyTokenHash(c.RegistrationAccessTokenHash, registrationAccessToken)
+}
+
+// ClientUpdateRequest replaces the metadata of a dynamically registered client,
+// see https://datatracker.ietf.org/doc/html/rfc7592#section-2.2.
+type ClientUpdateRequest struct {
+	ClientMetadata
+	ClientID string `json:"client_id"`
+	// ClientSecret has to match the current one when present
+	ClientSecret string `json:"client_secret,omitempty"`
+}
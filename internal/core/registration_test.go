@@ -0,0 +1,105 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestClientMetadataClientPolicy(t *testing.T) {
+	redirectURIs := []string{"https://example.com/callback"}
+	cases := []struct {
+		metadata       core.ClientMetadata
+		expectedError  core.AuthErrorName
+		expectedPublic bool
+	}{
+		{metadata: core.ClientMetadata{RedirectURIs: redirectURIs}},
+		{metadata: core.ClientMetadata{RedirectURIs: redirectURIs, TokenEndpointAuthMethod: "none"}, expectedPublic: true},
+		{metadata: core.ClientMetadata{RedirectURIs: redirectURIs, TokenEndpointAuthMethod: "private_key_jwt"}, expectedError: core.AuthErrorInvalidClientMetadata},
+		{metadata: core.ClientMetadata{RedirectURIs: redirectURIs, ResponseTypes: []string{"token"}}, expectedError: core.AuthErrorInvalidClientMetadata},
+		{metadata: core.ClientMetadata{}, expectedError: core.AuthErrorInvalidRedirectURI},
+		{metadata: core.ClientMetadata{RedirectURIs: []string{"/callback"}}, expectedError: core.AuthErrorInvalidRedirectURI},
+		{metadata: core.ClientMetadata{GrantTypes: []core.GrantType{core.GrantTypeClientCredentials}}},
+		{metadata: core.ClientMetadata{GrantTypes: []core.GrantType{core.GrantTypeClientCredentials}, ResponseTypes: []string{"code"}}, expectedError: core.AuthErrorInvalidClientMetadata},
+		{metadata: core.ClientMetadata{RedirectURIs: redirectURIs, Scope: "bad"}, expectedError: core.AuthErrorInvalidClientMetadata},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestClientMetadataClientPolicy_%d", i), func(t *testing.T) {
+			policy, err := testCase.metadata.ClientPolicy()
+			if testCase.expectedError != "" {
+				authError := &core.AuthError{}
+				if !errors.As(err, &authError) || authError.ErrorName != testCase.expectedError {
+					t.Fatalf("Expected %#v but got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if policy.Public != testCase.expectedPublic {
+				t.Fatalf("Expected public %v but got %v", testCase.expectedPublic, policy.Public)
+			}
+			if policy.Scope == "" || len(policy.GrantTypes) == 0 {
+				t.Fatalf("Expected defaults to be filled in but got %#v", *policy)
+			}
+		})
+	}
+}
+
+func TestRegistrationPolicyAllows(t *testing.T) {
+	cases := []struct {
+		policy             core.RegistrationPolicy
+		initialAccessToken string
+		expected           bool
+	}{
+		{policy: core.NewRegistrationPolicy(false, ""), initialAccessToken: "", expected: false},
+		{policy: core.NewRegistrationPolicy(true, ""), initialAccessToken: "", expected: true},
+		{policy: core.NewRegistrationPolicy(false, "token"), initialAccessToken: "token", expected: true},
+		{policy: core.NewRegistrationPolicy(false, "token"), initialAccessToken: "other", expected: false},
+		{policy: core.NewRegistrationPolicy(false, "token"), initialAccessToken: "", expected: false},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestRegistrationPolicyAllows_%d_%#v", i, testCase.initialAccessToken), func(t *testing.T) {
+			if result := testCase.policy.Allows(testCase.initialAccessToken); result != testCase.expected {
+				t.Fatalf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestClientUpdateMetadata(t *testing.T) {
+	client, err := core.NewRegisteredClient(core.ClientMetadata{
+		ClientName:              "Extension",
+		RedirectURIs:            []string{"https://example.com/callback"},
+		TokenEndpointAuthMethod: core.TokenEndpointAuthMethodNone,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !client.Public || client.Secret != "" || client.OwnerUserID != "" {
+		t.Fatalf("Expected public client without owner but got %#v", *client)
+	}
+	if !client.AuthenticateRegistration(client.RegistrationAccessToken) || client.AuthenticateRegistration("other") {
+		t.Fatalf("Client should authenticate with the registration access token only")
+	}
+
+	err = client.UpdateMetadata(core.ClientMetadata{
+		RedirectURIs: []string{"https://example.com/other"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if client.Public || client.Secret == "" || !client.Authenticate(client.Secret) {
+		t.Fatalf("Client should get a secret when it stops being public")
+	}
+	if client.Name != "Unnamed client" {
+		t.Fatalf("Expected default name but got %#v", client.Name)
+	}
+
+	err = client.UpdateMetadata(core.ClientMetadata{TokenEndpointAuthMethod: core.TokenEndpointAuthMethodNone})
+	if err == nil {
+		t.Fatalf("Expected error for authorization_code grant without redirect uris")
+	}
+}
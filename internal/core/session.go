@@ -0,0 +1,44 @@
+package core
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// maxUserAgentLength keeps the clients from storing arbitrary amounts of text with every login.
+const maxUserAgentLength = 512
+
+// SessionDevice describes where the tokens were requested from, the fields are empty when unknown.
+type SessionDevice struct {
+	UserAgent string
+	ClientIP  string
+}
+
+// NewSessionDevice shortens the overly long user agents.
+func NewSessionDevice(userAgent, clientIP string) SessionDevice {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+		// do not leave a broken character behind
+		for !utf8.ValidString(userAgent) {
+			userAgent = userAgent[:len(userAgent)-1]
+		}
+	}
+	return SessionDevice{
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	}
+}
+
+// Session is a login of the user, it lasts as long as the refresh token family the login started.
+type Session struct {
+	// ID is the id of the refresh token family.
+	ID       string
+	UserID   string
+	ClientID string
+	// ClientName is only known when the sessions are listed.
+	ClientName string
+	// Device is where the session was used from most recently.
+	Device     SessionDevice
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
@@ -0,0 +1,32 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestNewSessionDevice(t *testing.T) {
+	cases := []struct {
+		name           string
+		userAgent      string
+		expectedLength int
+	}{
+		{name: "short", userAgent: "curl/8.0", expectedLength: 8},
+		{name: "long", userAgent: strings.Repeat("a", 1000), expectedLength: 512},
+		{name: "multibyte character at the limit", userAgent: strings.Repeat("a", 511) + "é", expectedLength: 511},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := core.NewSessionDevice(tc.userAgent, "192.0.2.1")
+			if len(device.UserAgent) != tc.expectedLength || !utf8.ValidString(device.UserAgent) {
+				t.Fatalf("Expected a valid user agent of %d bytes but got %q", tc.expectedLength, device.UserAgent)
+			}
+			if device.ClientIP != "192.0.2.1" {
+				t.Fatalf("Unexpected client ip %q", device.ClientIP)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"net/mail"
+	"regexp"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// SignupMode decides who may register an account.
+type SignupMode string
+
+const (
+	// SignupModeClosed leaves the accounts to the administrators and the identity providers.
+	SignupModeClosed SignupMode = "closed"
+	// SignupModeInvite lets anyone with an invite of an administrator register.
+	SignupModeInvite SignupMode = "invite"
+	// SignupModeOpen lets anyone register.
+	SignupModeOpen SignupMode = "open"
+)
+
+// NewSignupMode validates the mode chosen by the administrator.
+func NewSignupMode(mode string) (SignupMode, error) {
+	switch SignupMode(mode) {
+	case SignupModeClosed, SignupModeInvite, SignupModeOpen:
+		return SignupMode(mode), nil
+	default:
+		return "", errors.Wrapf(ErrInvalidInput, "unknown signup mode %q", mode)
+	}
+}
+
+// usernamePattern keeps the usernames of the registered users safe to put into the paths and the emails.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,63}$`)
+
+// SignupRequest is the account a visitor wants to register.
+type SignupRequest struct {
+	Username string
+	Email    string
+	Password string
+	// Invite is the code of the invite, required while the signup is invite-only
+	Invite string
+	// ClientName creates a client of the user along with the account when set
+	ClientName string
+}
+
+// Validate checks the username and the email, the password is up to the password policy.
+func (r SignupRequest) Validate() error {
+	if !usernamePattern.MatchString(r.Username) {
+		return errors.Wrap(ErrInvalidInput, "username must be 3 to 64 letters, digits, dots, dashes or underscores")
+	}
+	address, err := mail.ParseAddress(r.Email)
+	if err != nil || address.Address != r.Email {
+		return errors.Wrap(ErrInvalidInput, "email is not a valid address")
+	}
+	return nil
+}
+
+// Invite lets a single visitor register while the signup is invite-only.
+type Invite struct {
+	// Code is only known right after the invite was created,
+	// afterwards just its hash is available
+	Code      string
+	CodeHash  []byte
+	ID        string
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// UsedAt is zero until somebody registers with the invite.
+	UsedAt time.Time
+}
+
+// MaxInviteLifetime bounds how long an invite may wait for somebody to register with it.
+const MaxInviteLifetime = 90 * 24 * time.Hour
+
+// NewInvite creates an invite of the administrator.
+func NewInvite(createdBy string, lifetime time.Duration) (*Invite, error) {
+	if lifetime <= 0 {
+		return nil, errors.Wrap(ErrInvalidInput, "invite lifetime must be positive")
+	}
+	if lifetime > MaxInviteLifetime {
+		return nil, errors.Wrapf(ErrInvalidInput, "invite lifetime must not exceed %s", MaxInviteLifetime)
+	}
+	code, err := NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	createdAt := time.Now()
+	return &Invite{
+		Code:      code,
+		CodeHash:  HashToken(code),
+		ID:        uuid.New().String(),
+		CreatedBy: createdBy,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(lifetime),
+	}, nil
+}
+
+// emailVerificationPurpose tells the email verifications apart from the other tokens signed by the server.
+const emailVerificationPurpose = "email_verification"
+
+// EmailVerificationLinkParameter carries the sealed verification in the link emailed to the user.
+const EmailVerificationLinkParameter = "token"
+
+// EmailVerification proves that the user received the email sent to the address.
+type EmailVerification struct {
+	UserID string
+	Email  string
+}
+
+// Seal signs the verification so that it can be emailed to the user without being tampered with.
+func (v EmailVerification) Seal(issuer string, lifetime time.Duration, keys *KeySet) (*JWT, error) {
+	now := time.Now()
+	return keys.Sign(map[string]any{
+		"iss":     issuer,
+		"aud":     issuer,
+		"iat":     now.Unix(),
+		"exp":     now.Add(lifetime).Unix(),
+		"sub":     v.UserID,
+		"email":   v.Email,
+		"purpose": emailVerificationPurpose,
+	})
+}
+
+// OpenEmailVerification verifies the sealed verification, whether the email
+// still belongs to the user is up to the caller.
+func OpenEmailVerification(sealed JWT, issuer string, keys *KeySet) (*EmailVerification, error) {
+	claims, err := keys.Parse(sealed,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != emailVerificationPurpose {
+		return nil, errors.New("token is not an email verification")
+	}
+	verification := EmailVerification{}
+	verification.UserID, _ = claims["sub"].(string)
+	verification.Email, _ = claims["email"].(string)
+	if verification.UserID == "" || verification.Email == "" {
+		return nil, errors.New("email verification is incomplete")
+	}
+	return &verification, nil
+}
@@ -0,0 +1,75 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestSignupRequestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		username string
+		email    string
+		valid    bool
+	}{
+		{name: "valid", username: "reader.one", email: "reader@example.com", valid: true},
+		{name: "short username", username: "ab", email: "reader@example.com"},
+		{name: "username with space", username: "a reader", email: "reader@example.com"},
+		{name: "username starting with dot", username: ".reader", email: "reader@example.com"},
+		{name: "missing email", username: "reader"},
+		{name: "email with name", username: "reader", email: "Reader <reader@example.com>"},
+		{name: "email without domain", username: "reader", email: "reader"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := core.SignupRequest{Username: tc.username, Email: tc.email}.Validate()
+			if tc.valid && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !tc.valid && !errors.Is(err, core.ErrInvalidInput) {
+				t.Fatalf("Expected invalid input but got %v", err)
+			}
+		})
+	}
+}
+
+func TestEmailVerificationSealing(t *testing.T) {
+	keys := newTestKeySet(t)
+	verification := core.EmailVerification{UserID: "user", Email: "reader@example.com"}
+	sealed, err := verification.Seal("issuer", time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	opened, err := core.OpenEmailVerification(*sealed, "issuer", keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if *opened != verification {
+		t.Fatalf("Expected %#v but got %#v", verification, opened)
+	}
+
+	_, err = core.OpenEmailVerification(*sealed, "other", keys)
+	if err == nil {
+		t.Fatalf("Expected error for another issuer")
+	}
+	expired, err := verification.Seal("issuer", -time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = core.OpenEmailVerification(*expired, "issuer", keys)
+	if err == nil {
+		t.Fatalf("Expected error for expired verification")
+	}
+	// a password reset must not verify the email by accident
+	reset, err := core.NewPasswordReset("user", time.Minute).Seal("issuer", keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = core.OpenEmailVerification(*reset, "issuer", keys)
+	if err == nil {
+		t.Fatalf("Expected error for password reset")
+	}
+}
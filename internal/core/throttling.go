@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+type LoginAttemptsKind string
+
+const (
+	LoginAttemptsKindUsername LoginAttemptsKind = "username"
+	LoginAttemptsKindIP       LoginAttemptsKind = "ip"
+)
+
+// LoginAttempts counts the failed logins in a row of a username or of a client ip.
+type LoginAttempts struct {
+	Kind           LoginAttemptsKind
+	Subject        string
+	FailedAttempts int
+	LastFailedAt   time.Time
+	// LockedUntil is zero unless the subject is locked out.
+	LockedUntil time.Time
+}
+
+// LoginThrottle decides how long the logins have to wait after failing.
+type LoginThrottle struct {
+	// FreeAttempts may fail without any delay.
+	FreeAttempts int
+	// BaseDelay is doubled with each further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAttempts failures in a row lock the subject out for LockoutDuration.
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	// ResetAfter without any failure forgets the previous ones.
+	ResetAfter time.Duration
+}
+
+// DefaultUsernameLoginThrottle protects a single account against guessing of its password.
+func DefaultUsernameLoginThrottle() LoginThrottle {
+	return LoginThrottle{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: 10,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      24 * time.Hour,
+	}
+}
+
+// DefaultIPLoginThrottle slows down guessing across many accounts, it is more lenient
+// than the one for the usernames since many users might share the same address.
+func DefaultIPLoginThrottle() LoginThrottle {
+	return LoginThrottle{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: 50,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+}
+
+// current forgets the failures that no longer matter at the time.
+func (t LoginThrottle) current(attempts LoginAttempts, now time.Time) LoginAttempts {
+	lockoutOver := !attempts.LockedUntil.IsZero() && !now.Before(attempts.LockedUntil)
+	if lockoutOver || now.Sub(attempts.LastFailedAt) >= t.ResetAfter {
+		return LoginAttempts{Kind: attempts.Kind, Subject: attempts.Subject}
+	}
+	return attempts
+}
+
+func (t LoginThrottle) delay(failedAttempts int) time.Duration {
+	if failedAttempts <= t.FreeAttempts {
+		return 0
+	}
+	delay := t.BaseDelay
+	for i := t.FreeAttempts + 1; i < failedAttempts && delay < t.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.MaxDelay)
+}
+
+// RetryAfter tells how long the next login has to wait, zero when it may proceed right away.
+func (t LoginThrottle) RetryAfter(attempts LoginAttempts, now time.Time) time.Duration {
+	attempts = t.current(attempts, now)
+	if attempts.LockedUntil.After(now) {
+		return attempts.LockedUntil.Sub(now)
+	}
+	retryAt := attempts.LastFailedAt.Add(t.delay(attempts.FailedAttempts))
+	if retryAt.After(now) {
+		return retryAt.Sub(now)
+	}
+	return 0
+}
+
+// Fail records another failed login and locks the subject out once it failed too often.
+func (t LoginThrottle) Fail(attempts LoginAttempts, now time.Time) LoginAttempts {
+	attempts = t.current(attempts, now)
+	attempts.FailedAttempts++
+	attempts.LastFailedAt = now
+	if attempts.FailedAttempts >= t.LockoutAttempts {
+		attempts.LockedUntil = now.Add(t.LockoutDuration)
+	}
+	return attempts
+}
+
+// LoginThrottledError rejects the login without checking the password.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry after %s", e.RetryAfter)
+}
+
+//nolint:errcheck //only to make sure it implements error
+var _ error = (*LoginThrottledError)(nil)
@@ -0,0 +1,67 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestLoginThrottleRetryAfter(t *testing.T) {
+	throttle := core.LoginThrottle{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutAttempts: 8,
+		LockoutDuration: time.Hour,
+		ResetAfter:      24 * time.Hour,
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		failures int
+		// elapsed since the last failure
+		elapsed  time.Duration
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 2, expected: 0},
+		{failures: 3, expected: time.Second},
+		{failures: 4, expected: 2 * time.Second},
+		{failures: 5, expected: 4 * time.Second},
+		{failures: 5, elapsed: time.Second, expected: 3 * time.Second},
+		{failures: 7, expected: 10 * time.Second},
+		{failures: 8, expected: time.Hour},
+		{failures: 8, elapsed: time.Hour, expected: 0},
+		{failures: 7, elapsed: 24 * time.Hour, expected: 0},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestLoginThrottleRetryAfter_%d_%d", i, testCase.failures), func(t *testing.T) {
+			attempts := core.LoginAttempts{Kind: core.LoginAttemptsKindUsername, Subject: "user"}
+			failedAt := now.Add(-testCase.elapsed)
+			for range testCase.failures {
+				attempts = throttle.Fail(attempts, failedAt)
+			}
+			if result := throttle.RetryAfter(attempts, now); result != testCase.expected {
+				t.Fatalf("Expected %s but got %s", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleFailAfterLockout(t *testing.T) {
+	throttle := core.DefaultUsernameLoginThrottle()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	attempts := core.LoginAttempts{Kind: core.LoginAttemptsKindUsername, Subject: "user"}
+	for range throttle.LockoutAttempts {
+		attempts = throttle.Fail(attempts, now)
+	}
+	if attempts.LockedUntil.IsZero() {
+		t.Fatalf("Expected lockout after %d failures", throttle.LockoutAttempts)
+	}
+
+	attempts = throttle.Fail(attempts, attempts.LockedUntil)
+	if attempts.FailedAttempts != 1 || !attempts.LockedUntil.IsZero() {
+		t.Fatalf("Expected the failures to start over once the lockout is over but got %#v", attempts)
+	}
+}
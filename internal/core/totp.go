@@ -0,0 +1,168 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec //the authenticator apps only support sha1 reliably
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TOTPIssuer names the service in the authenticator apps.
+const TOTPIssuer = "wallabago"
+
+const (
+	// the parameters of the codes, the defaults every authenticator app supports,
+	// see https://datatracker.ietf.org/doc/html/rfc6238#section-4
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+	// totpModulo cuts the code to its digits
+	totpModulo = 1_000_000
+	// totpSkew accepts the codes of the neighbouring periods for the clocks that drift
+	totpSkew = 1
+	// totpSecretSize is the size of the secret in bytes, as recommended for sha1,
+	// see https://datatracker.ietf.org/doc/html/rfc4226#section-4
+	totpSecretSize = 20
+
+	recoveryCodeCount = 10
+	// recoveryCodeSize is the number of base32 characters of a recovery code
+	recoveryCodeSize = 12
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP is the time-based one-time password generator of the user,
+// see https://datatracker.ietf.org/doc/html/rfc6238.
+type TOTP struct {
+	UserID string
+	// Secret is base32 encoded as the authenticator apps expect it.
+	Secret string
+	// ConfirmedAt is empty while the enrollment is pending.
+	ConfirmedAt time.Time
+	// LastUsedStep is the period of the last accepted code, which can not be used again.
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// NewTOTP starts the enrollment of the user with a fresh secret.
+func NewTOTP(userID string) (*TOTP, error) {
+	secret := make([]byte, totpSecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &TOTP{
+		UserID:    userID,
+		Secret:    totpEncoding.EncodeToString(secret),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// Confirmed reports whether the user proved to have the secret and the second factor is enforced.
+func (t TOTP) Confirmed() bool {
+	return !t.ConfirmedAt.IsZero()
+}
+
+// ProvisioningURI is shown as a QR code to add the secret to an authenticator app,
+// see https://github.com/google/google-authenticator/wiki/Key-Uri-Format.
+func (t TOTP) ProvisioningURI(accountName string) string {
+	query := url.Values{
+		"secret":    {t.Secret},
+		"issuer":    {TOTPIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(int(totpPeriod.Seconds()))},
+	}
+	location := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + TOTPIssuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+	return location.String()
+}
+
+func totpStep(now time.Time) int64 {
+	return now.Unix() / int64(totpPeriod.Seconds())
+}
+
+// TOTPCode computes the code of the period, see https://datatracker.ietf.org/doc/html/rfc4226#section-5.3.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	message := make([]byte, 8)
+	//nolint:gosec //the step of the current time is never negative
+	binary.BigEndian.PutUint64(message, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo), nil
+}
+
+// Verify checks the code against the periods around now and returns the period it belongs to,
+// the codes of the periods up to the last used one are rejected so that they can not be replayed.
+func (t TOTP) Verify(code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= t.LastUsedStep {
+			continue
+		}
+		expected, err := TOTPCode(t.Secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPEnrollment is what the user needs to add the secret to an authenticator app.
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// TOTPStatus describes the second factor of the user.
+type TOTPStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int64
+}
+
+// NewRecoveryCodes generates the codes shown to the user once, only their hashes are stored.
+func NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		random := make([]byte, recoveryCodeSize)
+		_, err := rand.Read(random)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(random))[:recoveryCodeSize]
+		codes[i] = encoded[:4] + "-" + encoded[4:8] + "-" + encoded[8:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the digest the recovery code is stored under,
+// the code is compared regardless of the case and the dashes.
+func HashRecoveryCode(code string) []byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashToken(normalized)
+}
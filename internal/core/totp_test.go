@@ -0,0 +1,119 @@
+package core_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+// rfc6238Secret is the sha1 key of the test vectors, see https://datatracker.ietf.org/doc/html/rfc6238#appendix-B.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	cases := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+	}
+	for _, testCase := range cases {
+		t.Run(fmt.Sprint(testCase.unix), func(t *testing.T) {
+			code, err := core.TOTPCode(rfc6238Secret, testCase.unix/30)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if code != testCase.expected {
+				t.Fatalf("Expected %s but got %s", testCase.expected, code)
+			}
+		})
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / 30
+	cases := []struct {
+		name         string
+		code         string
+		lastUsedStep int64
+		valid        bool
+	}{
+		{name: "current", code: "005924", valid: true},
+		{name: "spaced", code: "005 924", valid: true},
+		{name: "previous period", code: mustTOTPCode(t, step-1), valid: true},
+		{name: "next period", code: mustTOTPCode(t, step+1), valid: true},
+		{name: "out of the window", code: mustTOTPCode(t, step+2), valid: false},
+		{name: "already used", code: "005924", lastUsedStep: step, valid: false},
+		{name: "wrong", code: "123456", valid: false},
+		{name: "too short", code: "5924", valid: false},
+		{name: "empty", code: "", valid: false},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			totp := core.TOTP{Secret: rfc6238Secret, LastUsedStep: testCase.lastUsedStep}
+			_, valid := totp.Verify(testCase.code, now)
+			if valid != testCase.valid {
+				t.Fatalf("Expected valid %t but got %t", testCase.valid, valid)
+			}
+		})
+	}
+}
+
+func mustTOTPCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := core.TOTPCode(rfc6238Secret, step)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return code
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	totp, err := core.NewTOTP("user-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if totp.Confirmed() {
+		t.Fatalf("Expected new enrollment to be pending")
+	}
+	location, err := url.Parse(totp.ProvisioningURI("alice"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	query := location.Query()
+	if location.Scheme != "otpauth" || location.Host != "totp" || location.Path != "/wallabago:alice" ||
+		query.Get("secret") != totp.Secret || query.Get("issuer") != core.TOTPIssuer {
+		t.Fatalf("Unexpected provisioning uri %s", location)
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	codes, err := core.NewRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 14 || seen[code] {
+			t.Fatalf("Unexpected recovery code %s", code)
+		}
+		seen[code] = true
+	}
+	expected := core.HashRecoveryCode("abcd-efgh-ijkl")
+	for _, variant := range []string{"ABCD-EFGH-IJKL", "abcdefghijkl", " abcd efgh ijkl "} {
+		if !bytes.Equal(core.HashRecoveryCode(variant), expected) {
+			t.Fatalf("Expected %q to match the recovery code", variant)
+		}
+	}
+	if bytes.Equal(core.HashRecoveryCode("abcd-efgh-ijkm"), expected) {
+		t.Fatalf("Expected another code not to match")
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// tokenLeeway tolerates small clock differences between the servers.
+const tokenLeeway = 30 * time.Second
+
+// VerifyAccessToken checks the token without consulting the storage,
+// so it has to be complemented by a revocation check using the returned token id.
+//
+// The returned error is an [AuthError] explaining why the token was rejected.
+func VerifyAccessToken(token JWT, keys *KeySet, issuer string, now time.Time) (string, error) {
+	claims, err := keys.Parse(
+		token,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(tokenLeeway),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		return "", &AuthError{
+			ErrorName:        AuthErrorInvalidToken,
+			ErrorDescription: invalidTokenReason(err),
+		}
+	}
+
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return "", &AuthError{
+			ErrorName:        AuthErrorInvalidToken,
+			ErrorDescription: "The access token has no identifier",
+		}
+	}
+	return tokenID, nil
+}
+
+func invalidTokenReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "The access token expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "The access token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "The access token was issued by another server"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "The access token is not intended for this server"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "The access token is missing required claims"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "The access token is malformed"
+	case errors.Is(err, jwt.ErrTokenUnverifiable), errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "The access token signature is invalid"
+	}
+	return "The access token is invalid"
+}
@@ -0,0 +1,64 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+)
+
+func TestVerifyAccessToken(t *testing.T) {
+	const issuer = "https://wallabago.example"
+	keys := newTestKeySet(t)
+	accessToken, err := core.NewAccessToken(issuer, "user", "client", *core.DefaultScope(), time.Hour, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	refreshToken, err := core.NewRefreshToken(issuer, "user", "client", "", *core.DefaultScope(), 0, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	foreignToken, err := core.NewAccessToken("https://other.example", "user", "client", *core.DefaultScope(), time.Hour, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		name        string
+		token       core.JWT
+		keys        *core.KeySet
+		now         time.Time
+		description string
+	}{
+		{name: "valid", token: accessToken.Token, keys: keys, now: time.Now()},
+		{name: "expired", token: accessToken.Token, keys: keys, now: time.Now().Add(2 * time.Hour), description: "The access token expired"},
+		{name: "not yet issued", token: accessToken.Token, keys: keys, now: time.Now().Add(-time.Hour), description: "The access token is not valid yet"},
+		{name: "refresh token", token: refreshToken.Token, keys: keys, now: time.Now(), description: "The access token is not intended for this server"},
+		{name: "other issuer", token: foreignToken.Token, keys: keys, now: time.Now(), description: "The access token was issued by another server"},
+		{name: "unknown key", token: accessToken.Token, keys: newTestKeySet(t), now: time.Now(), description: "The access token signature is invalid"},
+		{name: "malformed", token: core.JWT("not-a-token"), keys: keys, now: time.Now(), description: "The access token is malformed"},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestVerifyAccessToken_%d_%s", i, testCase.name), func(t *testing.T) {
+			tokenID, err := core.VerifyAccessToken(testCase.token, testCase.keys, issuer, testCase.now)
+			if testCase.description == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				if tokenID != accessToken.ID {
+					t.Fatalf("Expected token id %s but got %s", accessToken.ID, tokenID)
+				}
+				return
+			}
+			authError := &core.AuthError{}
+			if !errors.As(err, &authError) {
+				t.Fatalf("Expected auth error but got %v", err)
+			}
+			if authError.ErrorName != core.AuthErrorInvalidToken || authError.ErrorDescription != testCase.description {
+				t.Fatalf("Expected %#v but got %#v", testCase.description, authError)
+			}
+		})
+	}
+}
@@ -30,29 +30,110 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.addAppUserStmt, err = db.PrepareContext(ctx, addAppUser); err != nil {
 		return nil, fmt.Errorf("error preparing query AddAppUser: %w", err)
 	}
+	if q.addAuthorizationCodeStmt, err = db.PrepareContext(ctx, addAuthorizationCode); err != nil {
+		return nil, fmt.Errorf("error preparing query AddAuthorizationCode: %w", err)
+	}
 	if q.addClientStmt, err = db.PrepareContext(ctx, addClient); err != nil {
 		return nil, fmt.Errorf("error preparing query AddClient: %w", err)
 	}
+	if q.addClientRedirectURIStmt, err = db.PrepareContext(ctx, addClientRedirectURI); err != nil {
+		return nil, fmt.Errorf("error preparing query AddClientRedirectURI: %w", err)
+	}
+	if q.addDeviceCodeStmt, err = db.PrepareContext(ctx, addDeviceCode); err != nil {
+		return nil, fmt.Errorf("error preparing query AddDeviceCode: %w", err)
+	}
+	if q.addFederatedIdentityStmt, err = db.PrepareContext(ctx, addFederatedIdentity); err != nil {
+		return nil, fmt.Errorf("error preparing query AddFederatedIdentity: %w", err)
+	}
 	if q.addIdentityUserStmt, err = db.PrepareContext(ctx, addIdentityUser); err != nil {
 		return nil, fmt.Errorf("error preparing query AddIdentityUser: %w", err)
 	}
+	if q.addInviteStmt, err = db.PrepareContext(ctx, addInvite); err != nil {
+		return nil, fmt.Errorf("error preparing query AddInvite: %w", err)
+	}
+	if q.addPasswordResetStmt, err = db.PrepareContext(ctx, addPasswordReset); err != nil {
+		return nil, fmt.Errorf("error preparing query AddPasswordReset: %w", err)
+	}
+	if q.addPersonalAccessTokenStmt, err = db.PrepareContext(ctx, addPersonalAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query AddPersonalAccessToken: %w", err)
+	}
+	if q.addRecoveryCodeStmt, err = db.PrepareContext(ctx, addRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query AddRecoveryCode: %w", err)
+	}
 	if q.addRefreshTokenStmt, err = db.PrepareContext(ctx, addRefreshToken); err != nil {
 		return nil, fmt.Errorf("error preparing query AddRefreshToken: %w", err)
 	}
+	if q.addWebAuthnChallengeStmt, err = db.PrepareContext(ctx, addWebAuthnChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query AddWebAuthnChallenge: %w", err)
+	}
+	if q.addWebAuthnCredentialStmt, err = db.PrepareContext(ctx, addWebAuthnCredential); err != nil {
+		return nil, fmt.Errorf("error preparing query AddWebAuthnCredential: %w", err)
+	}
+	if q.confirmUserTOTPStmt, err = db.PrepareContext(ctx, confirmUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query ConfirmUserTOTP: %w", err)
+	}
+	if q.countPasswordResetsStmt, err = db.PrepareContext(ctx, countPasswordResets); err != nil {
+		return nil, fmt.Errorf("error preparing query CountPasswordResets: %w", err)
+	}
+	if q.countRecoveryCodesStmt, err = db.PrepareContext(ctx, countRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query CountRecoveryCodes: %w", err)
+	}
 	if q.deleteAccessTokenByIDStmt, err = db.PrepareContext(ctx, deleteAccessTokenByID); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteAccessTokenByID: %w", err)
 	}
 	if q.deleteClientByIDStmt, err = db.PrepareContext(ctx, deleteClientByID); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteClientByID: %w", err)
 	}
+	if q.deleteClientRedirectURIsStmt, err = db.PrepareContext(ctx, deleteClientRedirectURIs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteClientRedirectURIs: %w", err)
+	}
+	if q.deleteExpiredWebAuthnChallengesStmt, err = db.PrepareContext(ctx, deleteExpiredWebAuthnChallenges); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredWebAuthnChallenges: %w", err)
+	}
 	if q.deleteIdentityUserByIDStmt, err = db.PrepareContext(ctx, deleteIdentityUserByID); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteIdentityUserByID: %w", err)
 	}
+	if q.deleteInviteStmt, err = db.PrepareContext(ctx, deleteInvite); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInvite: %w", err)
+	}
+	if q.deleteLoginAttemptsStmt, err = db.PrepareContext(ctx, deleteLoginAttempts); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteLoginAttempts: %w", err)
+	}
+	if q.deletePasswordResetsStmt, err = db.PrepareContext(ctx, deletePasswordResets); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePasswordResets: %w", err)
+	}
+	if q.deletePersonalAccessTokenStmt, err = db.PrepareContext(ctx, deletePersonalAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePersonalAccessToken: %w", err)
+	}
+	if q.deletePersonalAccessTokensStmt, err = db.PrepareContext(ctx, deletePersonalAccessTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePersonalAccessTokens: %w", err)
+	}
+	if q.deleteRecoveryCodesStmt, err = db.PrepareContext(ctx, deleteRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRecoveryCodes: %w", err)
+	}
 	if q.deleteRefreshTokenByIDStmt, err = db.PrepareContext(ctx, deleteRefreshTokenByID); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteRefreshTokenByID: %w", err)
 	}
-	if q.getAccessTokenByJWTStmt, err = db.PrepareContext(ctx, getAccessTokenByJWT); err != nil {
-		return nil, fmt.Errorf("error preparing query GetAccessTokenByJWT: %w", err)
+	if q.deleteUserTOTPStmt, err = db.PrepareContext(ctx, deleteUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserTOTP: %w", err)
+	}
+	if q.deleteWebAuthnCredentialStmt, err = db.PrepareContext(ctx, deleteWebAuthnCredential); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebAuthnCredential: %w", err)
+	}
+	if q.getAccessTokenByIDStmt, err = db.PrepareContext(ctx, getAccessTokenByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccessTokenByID: %w", err)
+	}
+	if q.getAccessTokenFamilyIDStmt, err = db.PrepareContext(ctx, getAccessTokenFamilyID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccessTokenFamilyID: %w", err)
+	}
+	if q.getActiveSessionsStmt, err = db.PrepareContext(ctx, getActiveSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetActiveSessions: %w", err)
+	}
+	if q.getAppUserByIDStmt, err = db.PrepareContext(ctx, getAppUserByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAppUserByID: %w", err)
+	}
+	if q.getAuthorizationCodeStmt, err = db.PrepareContext(ctx, getAuthorizationCode); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAuthorizationCode: %w", err)
 	}
 	if q.getBoostrapConditionsStmt, err = db.PrepareContext(ctx, getBoostrapConditions); err != nil {
 		return nil, fmt.Errorf("error preparing query GetBoostrapConditions: %w", err)
@@ -60,11 +141,65 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getClientByIDStmt, err = db.PrepareContext(ctx, getClientByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetClientByID: %w", err)
 	}
+	if q.getClientRedirectURIsStmt, err = db.PrepareContext(ctx, getClientRedirectURIs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetClientRedirectURIs: %w", err)
+	}
+	if q.getClientsStmt, err = db.PrepareContext(ctx, getClients); err != nil {
+		return nil, fmt.Errorf("error preparing query GetClients: %w", err)
+	}
+	if q.getClientsByOwnerUserIDStmt, err = db.PrepareContext(ctx, getClientsByOwnerUserID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetClientsByOwnerUserID: %w", err)
+	}
+	if q.getDeviceCodeByDeviceCodeHashStmt, err = db.PrepareContext(ctx, getDeviceCodeByDeviceCodeHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeviceCodeByDeviceCodeHash: %w", err)
+	}
+	if q.getDeviceCodeByUserCodeStmt, err = db.PrepareContext(ctx, getDeviceCodeByUserCode); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeviceCodeByUserCode: %w", err)
+	}
+	if q.getFederatedIdentityStmt, err = db.PrepareContext(ctx, getFederatedIdentity); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFederatedIdentity: %w", err)
+	}
+	if q.getIdentityUserByEmailStmt, err = db.PrepareContext(ctx, getIdentityUserByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIdentityUserByEmail: %w", err)
+	}
+	if q.getIdentityUserByIDStmt, err = db.PrepareContext(ctx, getIdentityUserByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIdentityUserByID: %w", err)
+	}
 	if q.getIdentityUserByUsernameStmt, err = db.PrepareContext(ctx, getIdentityUserByUsername); err != nil {
 		return nil, fmt.Errorf("error preparing query GetIdentityUserByUsername: %w", err)
 	}
-	if q.getRefreshTokenByJWTStmt, err = db.PrepareContext(ctx, getRefreshTokenByJWT); err != nil {
-		return nil, fmt.Errorf("error preparing query GetRefreshTokenByJWT: %w", err)
+	if q.getInvitesStmt, err = db.PrepareContext(ctx, getInvites); err != nil {
+		return nil, fmt.Errorf("error preparing query GetInvites: %w", err)
+	}
+	if q.getLoginAttemptsStmt, err = db.PrepareContext(ctx, getLoginAttempts); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLoginAttempts: %w", err)
+	}
+	if q.getPersonalAccessTokenByHashStmt, err = db.PrepareContext(ctx, getPersonalAccessTokenByHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPersonalAccessTokenByHash: %w", err)
+	}
+	if q.getPersonalAccessTokensStmt, err = db.PrepareContext(ctx, getPersonalAccessTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPersonalAccessTokens: %w", err)
+	}
+	if q.getRefreshTokenByIDStmt, err = db.PrepareContext(ctx, getRefreshTokenByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRefreshTokenByID: %w", err)
+	}
+	if q.getSessionStmt, err = db.PrepareContext(ctx, getSession); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSession: %w", err)
+	}
+	if q.getSettingStmt, err = db.PrepareContext(ctx, getSetting); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSetting: %w", err)
+	}
+	if q.getUserTOTPStmt, err = db.PrepareContext(ctx, getUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserTOTP: %w", err)
+	}
+	if q.getWebAuthnCredentialStmt, err = db.PrepareContext(ctx, getWebAuthnCredential); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebAuthnCredential: %w", err)
+	}
+	if q.getWebAuthnCredentialsStmt, err = db.PrepareContext(ctx, getWebAuthnCredentials); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebAuthnCredentials: %w", err)
+	}
+	if q.markAuthorizationCodeUsedStmt, err = db.PrepareContext(ctx, markAuthorizationCodeUsed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkAuthorizationCodeUsed: %w", err)
 	}
 	if q.markBootstrapConditionSatisfiedStmt, err = db.PrepareContext(ctx, markBootstrapConditionSatisfied); err != nil {
 		return nil, fmt.Errorf("error preparing query MarkBootstrapConditionSatisfied: %w", err)
@@ -72,9 +207,78 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.revokeAccessTokenByIDStmt, err = db.PrepareContext(ctx, revokeAccessTokenByID); err != nil {
 		return nil, fmt.Errorf("error preparing query RevokeAccessTokenByID: %w", err)
 	}
+	if q.revokeAccessTokensByRefreshTokenFamilyIDStmt, err = db.PrepareContext(ctx, revokeAccessTokensByRefreshTokenFamilyID); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeAccessTokensByRefreshTokenFamilyID: %w", err)
+	}
+	if q.revokeAccessTokensByRefreshTokenIDStmt, err = db.PrepareContext(ctx, revokeAccessTokensByRefreshTokenID); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeAccessTokensByRefreshTokenID: %w", err)
+	}
 	if q.revokeRefreshTokenByIDStmt, err = db.PrepareContext(ctx, revokeRefreshTokenByID); err != nil {
 		return nil, fmt.Errorf("error preparing query RevokeRefreshTokenByID: %w", err)
 	}
+	if q.revokeRefreshTokensByFamilyIDStmt, err = db.PrepareContext(ctx, revokeRefreshTokensByFamilyID); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeRefreshTokensByFamilyID: %w", err)
+	}
+	if q.rotateRefreshTokenByIDStmt, err = db.PrepareContext(ctx, rotateRefreshTokenByID); err != nil {
+		return nil, fmt.Errorf("error preparing query RotateRefreshTokenByID: %w", err)
+	}
+	if q.setAppUserIsAdminStmt, err = db.PrepareContext(ctx, setAppUserIsAdmin); err != nil {
+		return nil, fmt.Errorf("error preparing query SetAppUserIsAdmin: %w", err)
+	}
+	if q.setClientSecretHashStmt, err = db.PrepareContext(ctx, setClientSecretHash); err != nil {
+		return nil, fmt.Errorf("error preparing query SetClientSecretHash: %w", err)
+	}
+	if q.setClientServiceAccountStmt, err = db.PrepareContext(ctx, setClientServiceAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query SetClientServiceAccount: %w", err)
+	}
+	if q.setDeviceCodeStatusStmt, err = db.PrepareContext(ctx, setDeviceCodeStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDeviceCodeStatus: %w", err)
+	}
+	if q.setIdentityUserEmailVerifiedStmt, err = db.PrepareContext(ctx, setIdentityUserEmailVerified); err != nil {
+		return nil, fmt.Errorf("error preparing query SetIdentityUserEmailVerified: %w", err)
+	}
+	if q.setIdentityUserPasswordHashStmt, err = db.PrepareContext(ctx, setIdentityUserPasswordHash); err != nil {
+		return nil, fmt.Errorf("error preparing query SetIdentityUserPasswordHash: %w", err)
+	}
+	if q.setLoginAttemptsStmt, err = db.PrepareContext(ctx, setLoginAttempts); err != nil {
+		return nil, fmt.Errorf("error preparing query SetLoginAttempts: %w", err)
+	}
+	if q.setSessionStmt, err = db.PrepareContext(ctx, setSession); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSession: %w", err)
+	}
+	if q.setSettingStmt, err = db.PrepareContext(ctx, setSetting); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSetting: %w", err)
+	}
+	if q.setUserTOTPStmt, err = db.PrepareContext(ctx, setUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserTOTP: %w", err)
+	}
+	if q.takeWebAuthnChallengeStmt, err = db.PrepareContext(ctx, takeWebAuthnChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query TakeWebAuthnChallenge: %w", err)
+	}
+	if q.updateClientStmt, err = db.PrepareContext(ctx, updateClient); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateClient: %w", err)
+	}
+	if q.updateDeviceCodePollingStmt, err = db.PrepareContext(ctx, updateDeviceCodePolling); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateDeviceCodePolling: %w", err)
+	}
+	if q.useInviteStmt, err = db.PrepareContext(ctx, useInvite); err != nil {
+		return nil, fmt.Errorf("error preparing query UseInvite: %w", err)
+	}
+	if q.usePasswordResetStmt, err = db.PrepareContext(ctx, usePasswordReset); err != nil {
+		return nil, fmt.Errorf("error preparing query UsePasswordReset: %w", err)
+	}
+	if q.usePersonalAccessTokenStmt, err = db.PrepareContext(ctx, usePersonalAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query UsePersonalAccessToken: %w", err)
+	}
+	if q.useRecoveryCodeStmt, err = db.PrepareContext(ctx, useRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query UseRecoveryCode: %w", err)
+	}
+	if q.useUserTOTPStepStmt, err = db.PrepareContext(ctx, useUserTOTPStep); err != nil {
+		return nil, fmt.Errorf("error preparing query UseUserTOTPStep: %w", err)
+	}
+	if q.useWebAuthnCredentialStmt, err = db.PrepareContext(ctx, useWebAuthnCredential); err != nil {
+		return nil, fmt.Errorf("error preparing query UseWebAuthnCredential: %w", err)
+	}
 	return &q, nil
 }
 
@@ -90,21 +294,86 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing addAppUserStmt: %w", cerr)
 		}
 	}
+	if q.addAuthorizationCodeStmt != nil {
+		if cerr := q.addAuthorizationCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addAuthorizationCodeStmt: %w", cerr)
+		}
+	}
 	if q.addClientStmt != nil {
 		if cerr := q.addClientStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing addClientStmt: %w", cerr)
 		}
 	}
+	if q.addClientRedirectURIStmt != nil {
+		if cerr := q.addClientRedirectURIStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addClientRedirectURIStmt: %w", cerr)
+		}
+	}
+	if q.addDeviceCodeStmt != nil {
+		if cerr := q.addDeviceCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addDeviceCodeStmt: %w", cerr)
+		}
+	}
+	if q.addFederatedIdentityStmt != nil {
+		if cerr := q.addFederatedIdentityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addFederatedIdentityStmt: %w", cerr)
+		}
+	}
 	if q.addIdentityUserStmt != nil {
 		if cerr := q.addIdentityUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing addIdentityUserStmt: %w", cerr)
 		}
 	}
+	if q.addInviteStmt != nil {
+		if cerr := q.addInviteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addInviteStmt: %w", cerr)
+		}
+	}
+	if q.addPasswordResetStmt != nil {
+		if cerr := q.addPasswordResetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addPasswordResetStmt: %w", cerr)
+		}
+	}
+	if q.addPersonalAccessTokenStmt != nil {
+		if cerr := q.addPersonalAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addPersonalAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.addRecoveryCodeStmt != nil {
+		if cerr := q.addRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addRecoveryCodeStmt: %w", cerr)
+		}
+	}
 	if q.addRefreshTokenStmt != nil {
 		if cerr := q.addRefreshTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing addRefreshTokenStmt: %w", cerr)
 		}
 	}
+	if q.addWebAuthnChallengeStmt != nil {
+		if cerr := q.addWebAuthnChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addWebAuthnChallengeStmt: %w", cerr)
+		}
+	}
+	if q.addWebAuthnCredentialStmt != nil {
+		if cerr := q.addWebAuthnCredentialStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addWebAuthnCredentialStmt: %w", cerr)
+		}
+	}
+	if q.confirmUserTOTPStmt != nil {
+		if cerr := q.confirmUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing confirmUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.countPasswordResetsStmt != nil {
+		if cerr := q.countPasswordResetsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countPasswordResetsStmt: %w", cerr)
+		}
+	}
+	if q.countRecoveryCodesStmt != nil {
+		if cerr := q.countRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countRecoveryCodesStmt: %w", cerr)
+		}
+	}
 	if q.deleteAccessTokenByIDStmt != nil {
 		if cerr := q.deleteAccessTokenByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteAccessTokenByIDStmt: %w", cerr)
@@ -115,19 +384,89 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteClientByIDStmt: %w", cerr)
 		}
 	}
+	if q.deleteClientRedirectURIsStmt != nil {
+		if cerr := q.deleteClientRedirectURIsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteClientRedirectURIsStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredWebAuthnChallengesStmt != nil {
+		if cerr := q.deleteExpiredWebAuthnChallengesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredWebAuthnChallengesStmt: %w", cerr)
+		}
+	}
 	if q.deleteIdentityUserByIDStmt != nil {
 		if cerr := q.deleteIdentityUserByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteIdentityUserByIDStmt: %w", cerr)
 		}
 	}
+	if q.deleteInviteStmt != nil {
+		if cerr := q.deleteInviteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInviteStmt: %w", cerr)
+		}
+	}
+	if q.deleteLoginAttemptsStmt != nil {
+		if cerr := q.deleteLoginAttemptsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteLoginAttemptsStmt: %w", cerr)
+		}
+	}
+	if q.deletePasswordResetsStmt != nil {
+		if cerr := q.deletePasswordResetsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePasswordResetsStmt: %w", cerr)
+		}
+	}
+	if q.deletePersonalAccessTokenStmt != nil {
+		if cerr := q.deletePersonalAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePersonalAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.deletePersonalAccessTokensStmt != nil {
+		if cerr := q.deletePersonalAccessTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePersonalAccessTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteRecoveryCodesStmt != nil {
+		if cerr := q.deleteRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRecoveryCodesStmt: %w", cerr)
+		}
+	}
 	if q.deleteRefreshTokenByIDStmt != nil {
 		if cerr := q.deleteRefreshTokenByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteRefreshTokenByIDStmt: %w", cerr)
 		}
 	}
-	if q.getAccessTokenByJWTStmt != nil {
-		if cerr := q.getAccessTokenByJWTStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing getAccessTokenByJWTStmt: %w", cerr)
+	if q.deleteUserTOTPStmt != nil {
+		if cerr := q.deleteUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebAuthnCredentialStmt != nil {
+		if cerr := q.deleteWebAuthnCredentialStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebAuthnCredentialStmt: %w", cerr)
+		}
+	}
+	if q.getAccessTokenByIDStmt != nil {
+		if cerr := q.getAccessTokenByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccessTokenByIDStmt: %w", cerr)
+		}
+	}
+	if q.getAccessTokenFamilyIDStmt != nil {
+		if cerr := q.getAccessTokenFamilyIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccessTokenFamilyIDStmt: %w", cerr)
+		}
+	}
+	if q.getActiveSessionsStmt != nil {
+		if cerr := q.getActiveSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getActiveSessionsStmt: %w", cerr)
+		}
+	}
+	if q.getAppUserByIDStmt != nil {
+		if cerr := q.getAppUserByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAppUserByIDStmt: %w", cerr)
+		}
+	}
+	if q.getAuthorizationCodeStmt != nil {
+		if cerr := q.getAuthorizationCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAuthorizationCodeStmt: %w", cerr)
 		}
 	}
 	if q.getBoostrapConditionsStmt != nil {
@@ -140,14 +479,104 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getClientByIDStmt: %w", cerr)
 		}
 	}
+	if q.getClientRedirectURIsStmt != nil {
+		if cerr := q.getClientRedirectURIsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getClientRedirectURIsStmt: %w", cerr)
+		}
+	}
+	if q.getClientsStmt != nil {
+		if cerr := q.getClientsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getClientsStmt: %w", cerr)
+		}
+	}
+	if q.getClientsByOwnerUserIDStmt != nil {
+		if cerr := q.getClientsByOwnerUserIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getClientsByOwnerUserIDStmt: %w", cerr)
+		}
+	}
+	if q.getDeviceCodeByDeviceCodeHashStmt != nil {
+		if cerr := q.getDeviceCodeByDeviceCodeHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeviceCodeByDeviceCodeHashStmt: %w", cerr)
+		}
+	}
+	if q.getDeviceCodeByUserCodeStmt != nil {
+		if cerr := q.getDeviceCodeByUserCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeviceCodeByUserCodeStmt: %w", cerr)
+		}
+	}
+	if q.getFederatedIdentityStmt != nil {
+		if cerr := q.getFederatedIdentityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFederatedIdentityStmt: %w", cerr)
+		}
+	}
+	if q.getIdentityUserByEmailStmt != nil {
+		if cerr := q.getIdentityUserByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIdentityUserByEmailStmt: %w", cerr)
+		}
+	}
+	if q.getIdentityUserByIDStmt != nil {
+		if cerr := q.getIdentityUserByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIdentityUserByIDStmt: %w", cerr)
+		}
+	}
 	if q.getIdentityUserByUsernameStmt != nil {
 		if cerr := q.getIdentityUserByUsernameStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getIdentityUserByUsernameStmt: %w", cerr)
 		}
 	}
-	if q.getRefreshTokenByJWTStmt != nil {
-		if cerr := q.getRefreshTokenByJWTStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing getRefreshTokenByJWTStmt: %w", cerr)
+	if q.getInvitesStmt != nil {
+		if cerr := q.getInvitesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getInvitesStmt: %w", cerr)
+		}
+	}
+	if q.getLoginAttemptsStmt != nil {
+		if cerr := q.getLoginAttemptsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLoginAttemptsStmt: %w", cerr)
+		}
+	}
+	if q.getPersonalAccessTokenByHashStmt != nil {
+		if cerr := q.getPersonalAccessTokenByHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPersonalAccessTokenByHashStmt: %w", cerr)
+		}
+	}
+	if q.getPersonalAccessTokensStmt != nil {
+		if cerr := q.getPersonalAccessTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPersonalAccessTokensStmt: %w", cerr)
+		}
+	}
+	if q.getRefreshTokenByIDStmt != nil {
+		if cerr := q.getRefreshTokenByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRefreshTokenByIDStmt: %w", cerr)
+		}
+	}
+	if q.getSessionStmt != nil {
+		if cerr := q.getSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionStmt: %w", cerr)
+		}
+	}
+	if q.getSettingStmt != nil {
+		if cerr := q.getSettingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSettingStmt: %w", cerr)
+		}
+	}
+	if q.getUserTOTPStmt != nil {
+		if cerr := q.getUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.getWebAuthnCredentialStmt != nil {
+		if cerr := q.getWebAuthnCredentialStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebAuthnCredentialStmt: %w", cerr)
+		}
+	}
+	if q.getWebAuthnCredentialsStmt != nil {
+		if cerr := q.getWebAuthnCredentialsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebAuthnCredentialsStmt: %w", cerr)
+		}
+	}
+	if q.markAuthorizationCodeUsedStmt != nil {
+		if cerr := q.markAuthorizationCodeUsedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markAuthorizationCodeUsedStmt: %w", cerr)
 		}
 	}
 	if q.markBootstrapConditionSatisfiedStmt != nil {
@@ -160,11 +589,126 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing revokeAccessTokenByIDStmt: %w", cerr)
 		}
 	}
+	if q.revokeAccessTokensByRefreshTokenFamilyIDStmt != nil {
+		if cerr := q.revokeAccessTokensByRefreshTokenFamilyIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeAccessTokensByRefreshTokenFamilyIDStmt: %w", cerr)
+		}
+	}
+	if q.revokeAccessTokensByRefreshTokenIDStmt != nil {
+		if cerr := q.revokeAccessTokensByRefreshTokenIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeAccessTokensByRefreshTokenIDStmt: %w", cerr)
+		}
+	}
 	if q.revokeRefreshTokenByIDStmt != nil {
 		if cerr := q.revokeRefreshTokenByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing revokeRefreshTokenByIDStmt: %w", cerr)
 		}
 	}
+	if q.revokeRefreshTokensByFamilyIDStmt != nil {
+		if cerr := q.revokeRefreshTokensByFamilyIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeRefreshTokensByFamilyIDStmt: %w", cerr)
+		}
+	}
+	if q.rotateRefreshTokenByIDStmt != nil {
+		if cerr := q.rotateRefreshTokenByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rotateRefreshTokenByIDStmt: %w", cerr)
+		}
+	}
+	if q.setAppUserIsAdminStmt != nil {
+		if cerr := q.setAppUserIsAdminStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setAppUserIsAdminStmt: %w", cerr)
+		}
+	}
+	if q.setClientSecretHashStmt != nil {
+		if cerr := q.setClientSecretHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setClientSecretHashStmt: %w", cerr)
+		}
+	}
+	if q.setClientServiceAccountStmt != nil {
+		if cerr := q.setClientServiceAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setClientServiceAccountStmt: %w", cerr)
+		}
+	}
+	if q.setDeviceCodeStatusStmt != nil {
+		if cerr := q.setDeviceCodeStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDeviceCodeStatusStmt: %w", cerr)
+		}
+	}
+	if q.setIdentityUserEmailVerifiedStmt != nil {
+		if cerr := q.setIdentityUserEmailVerifiedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setIdentityUserEmailVerifiedStmt: %w", cerr)
+		}
+	}
+	if q.setIdentityUserPasswordHashStmt != nil {
+		if cerr := q.setIdentityUserPasswordHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setIdentityUserPasswordHashStmt: %w", cerr)
+		}
+	}
+	if q.setLoginAttemptsStmt != nil {
+		if cerr := q.setLoginAttemptsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setLoginAttemptsStmt: %w", cerr)
+		}
+	}
+	if q.setSessionStmt != nil {
+		if cerr := q.setSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSessionStmt: %w", cerr)
+		}
+	}
+	if q.setSettingStmt != nil {
+		if cerr := q.setSettingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSettingStmt: %w", cerr)
+		}
+	}
+	if q.setUserTOTPStmt != nil {
+		if cerr := q.setUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.takeWebAuthnChallengeStmt != nil {
+		if cerr := q.takeWebAuthnChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing takeWebAuthnChallengeStmt: %w", cerr)
+		}
+	}
+	if q.updateClientStmt != nil {
+		if cerr := q.updateClientStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateClientStmt: %w", cerr)
+		}
+	}
+	if q.updateDeviceCodePollingStmt != nil {
+		if cerr := q.updateDeviceCodePollingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateDeviceCodePollingStmt: %w", cerr)
+		}
+	}
+	if q.useInviteStmt != nil {
+		if cerr := q.useInviteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useInviteStmt: %w", cerr)
+		}
+	}
+	if q.usePasswordResetStmt != nil {
+		if cerr := q.usePasswordResetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing usePasswordResetStmt: %w", cerr)
+		}
+	}
+	if q.usePersonalAccessTokenStmt != nil {
+		if cerr := q.usePersonalAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing usePersonalAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.useRecoveryCodeStmt != nil {
+		if cerr := q.useRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useRecoveryCodeStmt: %w", cerr)
+		}
+	}
+	if q.useUserTOTPStepStmt != nil {
+		if cerr := q.useUserTOTPStepStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useUserTOTPStepStmt: %w", cerr)
+		}
+	}
+	if q.useWebAuthnCredentialStmt != nil {
+		if cerr := q.useWebAuthnCredentialStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useWebAuthnCredentialStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -202,47 +746,183 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                                  DBTX
-	tx                                  *sql.Tx
-	addAccessTokenStmt                  *sql.Stmt
-	addAppUserStmt                      *sql.Stmt
-	addClientStmt                       *sql.Stmt
-	addIdentityUserStmt                 *sql.Stmt
-	addRefreshTokenStmt                 *sql.Stmt
-	deleteAccessTokenByIDStmt           *sql.Stmt
-	deleteClientByIDStmt                *sql.Stmt
-	deleteIdentityUserByIDStmt          *sql.Stmt
-	deleteRefreshTokenByIDStmt          *sql.Stmt
-	getAccessTokenByJWTStmt             *sql.Stmt
-	getBoostrapConditionsStmt           *sql.Stmt
-	getClientByIDStmt                   *sql.Stmt
-	getIdentityUserByUsernameStmt       *sql.Stmt
-	getRefreshTokenByJWTStmt            *sql.Stmt
-	markBootstrapConditionSatisfiedStmt *sql.Stmt
-	revokeAccessTokenByIDStmt           *sql.Stmt
-	revokeRefreshTokenByIDStmt          *sql.Stmt
+	db                                           DBTX
+	tx                                           *sql.Tx
+	addAccessTokenStmt                           *sql.Stmt
+	addAppUserStmt                               *sql.Stmt
+	addAuthorizationCodeStmt                     *sql.Stmt
+	addClientStmt                                *sql.Stmt
+	addClientRedirectURIStmt                     *sql.Stmt
+	addDeviceCodeStmt                            *sql.Stmt
+	addFederatedIdentityStmt                     *sql.Stmt
+	addIdentityUserStmt                          *sql.Stmt
+	addInviteStmt                                *sql.Stmt
+	addPasswordResetStmt                         *sql.Stmt
+	addPersonalAccessTokenStmt                   *sql.Stmt
+	addRecoveryCodeStmt                          *sql.Stmt
+	addRefreshTokenStmt                          *sql.Stmt
+	addWebAuthnChallengeStmt                     *sql.Stmt
+	addWebAuthnCredentialStmt                    *sql.Stmt
+	confirmUserTOTPStmt                          *sql.Stmt
+	countPasswordResetsStmt                      *sql.Stmt
+	countRecoveryCodesStmt                       *sql.Stmt
+	deleteAccessTokenByIDStmt                    *sql.Stmt
+	deleteClientByIDStmt                         *sql.Stmt
+	deleteClientRedirectURIsStmt                 *sql.Stmt
+	deleteExpiredWebAuthnChallengesStmt          *sql.Stmt
+	deleteIdentityUserByIDStmt                   *sql.Stmt
+	deleteInviteStmt                             *sql.Stmt
+	deleteLoginAttemptsStmt                      *sql.Stmt
+	deletePasswordResetsStmt                     *sql.Stmt
+	deletePersonalAccessTokenStmt                *sql.Stmt
+	deletePersonalAccessTokensStmt               *sql.Stmt
+	deleteRecoveryCodesStmt                      *sql.Stmt
+	deleteRefreshTokenByIDStmt                   *sql.Stmt
+	deleteUserTOTPStmt                           *sql.Stmt
+	deleteWebAuthnCredentialStmt                 *sql.Stmt
+	getAccessTokenByIDStmt                       *sql.Stmt
+	getAccessTokenFamilyIDStmt                   *sql.Stmt
+	getActiveSessionsStmt                        *sql.Stmt
+	getAppUserByIDStmt                           *sql.Stmt
+	getAuthorizationCodeStmt                     *sql.Stmt
+	getBoostrapConditionsStmt                    *sql.Stmt
+	getClientByIDStmt                            *sql.Stmt
+	getClientRedirectURIsStmt                    *sql.Stmt
+	getClientsStmt                               *sql.Stmt
+	getClientsByOwnerUserIDStmt                  *sql.Stmt
+	getDeviceCodeByDeviceCodeHashStmt            *sql.Stmt
+	getDeviceCodeByUserCodeStmt                  *sql.Stmt
+	getFederatedIdentityStmt                     *sql.Stmt
+	getIdentityUserByEmailStmt                   *sql.Stmt
+	getIdentityUserByIDStmt                      *sql.Stmt
+	getIdentityUserByUsernameStmt                *sql.Stmt
+	getInvitesStmt                               *sql.Stmt
+	getLoginAttemptsStmt                         *sql.Stmt
+	getPersonalAccessTokenByHashStmt             *sql.Stmt
+	getPersonalAccessTokensStmt                  *sql.Stmt
+	getRefreshTokenByIDStmt                      *sql.Stmt
+	getSessionStmt                               *sql.Stmt
+	getSettingStmt                               *sql.Stmt
+	getUserTOTPStmt                              *sql.Stmt
+	getWebAuthnCredentialStmt                    *sql.Stmt
+	getWebAuthnCredentialsStmt                   *sql.Stmt
+	markAuthorizationCodeUsedStmt                *sql.Stmt
+	markBootstrapConditionSatisfiedStmt          *sql.Stmt
+	revokeAccessTokenByIDStmt                    *sql.Stmt
+	revokeAccessTokensByRefreshTokenFamilyIDStmt *sql.Stmt
+	revokeAccessTokensByRefreshTokenIDStmt       *sql.Stmt
+	revokeRefreshTokenByIDStmt                   *sql.Stmt
+	revokeRefreshTokensByFamilyIDStmt            *sql.Stmt
+	rotateRefreshTokenByIDStmt                   *sql.Stmt
+	setAppUserIsAdminStmt                        *sql.Stmt
+	setClientSecretHashStmt                      *sql.Stmt
+	setClientServiceAccountStmt                  *sql.Stmt
+	setDeviceCodeStatusStmt                      *sql.Stmt
+	setIdentityUserEmailVerifiedStmt             *sql.Stmt
+	setIdentityUserPasswordHashStmt              *sql.Stmt
+	setLoginAttemptsStmt                         *sql.Stmt
+	setSessionStmt                               *sql.Stmt
+	setSettingStmt                               *sql.Stmt
+	setUserTOTPStmt                              *sql.Stmt
+	takeWebAuthnChallengeStmt                    *sql.Stmt
+	updateClientStmt                             *sql.Stmt
+	updateDeviceCodePollingStmt                  *sql.Stmt
+	useInviteStmt                                *sql.Stmt
+	usePasswordResetStmt                         *sql.Stmt
+	usePersonalAccessTokenStmt                   *sql.Stmt
+	useRecoveryCodeStmt                          *sql.Stmt
+	useUserTOTPStepStmt                          *sql.Stmt
+	useWebAuthnCredentialStmt                    *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                                  tx,
-		tx:                                  tx,
-		addAccessTokenStmt:                  q.addAccessTokenStmt,
-		addAppUserStmt:                      q.addAppUserStmt,
-		addClientStmt:                       q.addClientStmt,
-		addIdentityUserStmt:                 q.addIdentityUserStmt,
-		addRefreshTokenStmt:                 q.addRefreshTokenStmt,
-		deleteAccessTokenByIDStmt:           q.deleteAccessTokenByIDStmt,
-		deleteClientByIDStmt:                q.deleteClientByIDStmt,
-		deleteIdentityUserByIDStmt:          q.deleteIdentityUserByIDStmt,
-		deleteRefreshTokenByIDStmt:          q.deleteRefreshTokenByIDStmt,
-		getAccessTokenByJWTStmt:             q.getAccessTokenByJWTStmt,
-		getBoostrapConditionsStmt:           q.getBoostrapConditionsStmt,
-		getClientByIDStmt:                   q.getClientByIDStmt,
-		getIdentityUserByUsernameStmt:       q.getIdentityUserByUsernameStmt,
-		getRefreshTokenByJWTStmt:            q.getRefreshTokenByJWTStmt,
-		markBootstrapConditionSatisfiedStmt: q.markBootstrapConditionSatisfiedStmt,
-		revokeAccessTokenByIDStmt:           q.revokeAccessTokenByIDStmt,
-		revokeRefreshTokenByIDStmt:          q.revokeRefreshTokenByIDStmt,
+		db:                                           tx,
+		tx:                                           tx,
+		addAccessTokenStmt:                           q.addAccessTokenStmt,
+		addAppUserStmt:                               q.addAppUserStmt,
+		addAuthorizationCodeStmt:                     q.addAuthorizationCodeStmt,
+		addClientStmt:                                q.addClientStmt,
+		addClientRedirectURIStmt:                     q.addClientRedirectURIStmt,
+		addDeviceCodeStmt:                            q.addDeviceCodeStmt,
+		addFederatedIdentityStmt:                     q.addFederatedIdentityStmt,
+		addIdentityUserStmt:                          q.addIdentityUserStmt,
+		addInviteStmt:                                q.addInviteStmt,
+		addPasswordResetStmt:                         q.addPasswordResetStmt,
+		addPersonalAccessTokenStmt:                   q.addPersonalAccessTokenStmt,
+		addRecoveryCodeStmt:                          q.addRecoveryCodeStmt,
+		addRefreshTokenStmt:                          q.addRefreshTokenStmt,
+		addWebAuthnChallengeStmt:                     q.addWebAuthnChallengeStmt,
+		addWebAuthnCredentialStmt:                    q.addWebAuthnCredentialStmt,
+		confirmUserTOTPStmt:                          q.confirmUserTOTPStmt,
+		countPasswordResetsStmt:                      q.countPasswordResetsStmt,
+		countRecoveryCodesStmt:                       q.countRecoveryCodesStmt,
+		deleteAccessTokenByIDStmt:                    q.deleteAccessTokenByIDStmt,
+		deleteClientByIDStmt:                         q.deleteClientByIDStmt,
+		deleteClientRedirectURIsStmt:                 q.deleteClientRedirectURIsStmt,
+		deleteExpiredWebAuthnChallengesStmt:          q.deleteExpiredWebAuthnChallengesStmt,
+		deleteIdentityUserByIDStmt:                   q.deleteIdentityUserByIDStmt,
+		deleteInviteStmt:                             q.deleteInviteStmt,
+		deleteLoginAttemptsStmt:                      q.deleteLoginAttemptsStmt,
+		deletePasswordResetsStmt:                     q.deletePasswordResetsStmt,
+		deletePersonalAccessTokenStmt:                q.deletePersonalAccessTokenStmt,
+		deletePersonalAccessTokensStmt:               q.deletePersonalAccessTokensStmt,
+		deleteRecoveryCodesStmt:                      q.deleteRecoveryCodesStmt,
+		deleteRefreshTokenByIDStmt:                   q.deleteRefreshTokenByIDStmt,
+		deleteUserTOTPStmt:                           q.deleteUserTOTPStmt,
+		deleteWebAuthnCredentialStmt:                 q.deleteWebAuthnCredentialStmt,
+		getAccessTokenByIDStmt:                       q.getAccessTokenByIDStmt,
+		getAccessTokenFamilyIDStmt:                   q.getAccessTokenFamilyIDStmt,
+		getActiveSessionsStmt:                        q.getActiveSessionsStmt,
+		getAppUserByIDStmt:                           q.getAppUserByIDStmt,
+		getAuthorizationCodeStmt:                     q.getAuthorizationCodeStmt,
+		getBoostrapConditionsStmt:                    q.getBoostrapConditionsStmt,
+		getClientByIDStmt:                            q.getClientByIDStmt,
+		getClientRedirectURIsStmt:                    q.getClientRedirectURIsStmt,
+		getClientsStmt:                               q.getClientsStmt,
+		getClientsByOwnerUserIDStmt:                  q.getClientsByOwnerUserIDStmt,
+		getDeviceCodeByDeviceCodeHashStmt:            q.getDeviceCodeByDeviceCodeHashStmt,
+		getDeviceCodeByUserCodeStmt:                  q.getDeviceCodeByUserCodeStmt,
+		getFederatedIdentityStmt:                     q.getFederatedIdentityStmt,
+		getIdentityUserByEmailStmt:                   q.getIdentityUserByEmailStmt,
+		getIdentityUserByIDStmt:                      q.getIdentityUserByIDStmt,
+		getIdentityUserByUsernameStmt:                q.getIdentityUserByUsernameStmt,
+		getInvitesStmt:                               q.getInvitesStmt,
+		getLoginAttemptsStmt:                         q.getLoginAttemptsStmt,
+		getPersonalAccessTokenByHashStmt:             q.getPersonalAccessTokenByHashStmt,
+		getPersonalAccessTokensStmt:                  q.getPersonalAccessTokensStmt,
+		getRefreshTokenByIDStmt:                      q.getRefreshTokenByIDStmt,
+		getSessionStmt:                               q.getSessionStmt,
+		getSettingStmt:                               q.getSettingStmt,
+		getUserTOTPStmt:                              q.getUserTOTPStmt,
+		getWebAuthnCredentialStmt:                    q.getWebAuthnCredentialStmt,
+		getWebAuthnCredentialsStmt:                   q.getWebAuthnCredentialsStmt,
+		markAuthorizationCodeUsedStmt:                q.markAuthorizationCodeUsedStmt,
+		markBootstrapConditionSatisfiedStmt:          q.markBootstrapConditionSatisfiedStmt,
+		revokeAccessTokenByIDStmt:                    q.revokeAccessTokenByIDStmt,
+		revokeAccessTokensByRefreshTokenFamilyIDStmt: q.revokeAccessTokensByRefreshTokenFamilyIDStmt,
+		revokeAccessTokensByRefreshTokenIDStmt:       q.revokeAccessTokensByRefreshTokenIDStmt,
+		revokeRefreshTokenByIDStmt:                   q.revokeRefreshTokenByIDStmt,
+		revokeRefreshTokensByFamilyIDStmt:            q.revokeRefreshTokensByFamilyIDStmt,
+		rotateRefreshTokenByIDStmt:                   q.rotateRefreshTokenByIDStmt,
+		setAppUserIsAdminStmt:                        q.setAppUserIsAdminStmt,
+		setClientSecretHashStmt:                      q.setClientSecretHashStmt,
+		setClientServiceAccountStmt:                  q.setClientServiceAccountStmt,
+		setDeviceCodeStatusStmt:                      q.setDeviceCodeStatusStmt,
+		setIdentityUserEmailVerifiedStmt:             q.setIdentityUserEmailVerifiedStmt,
+		setIdentityUserPasswordHashStmt:              q.setIdentityUserPasswordHashStmt,
+		setLoginAttemptsStmt:                         q.setLoginAttemptsStmt,
+		setSessionStmt:                               q.setSessionStmt,
+		setSettingStmt:                               q.setSettingStmt,
+		setUserTOTPStmt:                              q.setUserTOTPStmt,
+		takeWebAuthnChallengeStmt:                    q.takeWebAuthnChallengeStmt,
+		updateClientStmt:                             q.updateClientStmt,
+		updateDeviceCodePollingStmt:                  q.updateDeviceCodePollingStmt,
+		useInviteStmt:                                q.useInviteStmt,
+		usePasswordResetStmt:                         q.usePasswordResetStmt,
+		usePersonalAccessTokenStmt:                   q.usePersonalAccessTokenStmt,
+		useRecoveryCodeStmt:                          q.useRecoveryCodeStmt,
+		useUserTOTPStepStmt:                          q.useUserTOTPStepStmt,
+		useWebAuthnCredentialStmt:                    q.useWebAuthnCredentialStmt,
 	}
 }
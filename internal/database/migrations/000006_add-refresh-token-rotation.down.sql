@@ -0,0 +1,25 @@
+DROP INDEX IF EXISTS identity.access_tokens_refresh_token_id_idx
+;
+
+DROP INDEX IF EXISTS identity.refresh_tokens_family_id_idx
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS rotated
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS family_id
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS issued_at
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS scope
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS user_id
+;
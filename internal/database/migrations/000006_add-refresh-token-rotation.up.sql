@@ -0,0 +1,68 @@
+-- Add owner of the token
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS user_id TEXT REFERENCES identity.users (user_id)
+;
+
+-- Add scope granted to the token
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS scope TEXT
+;
+
+-- Add issued_at
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+;
+
+-- Add family_id linking all tokens obtained via rotation
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS family_id TEXT
+;
+
+-- Add rotated flag
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS rotated BOOL NOT NULL DEFAULT FALSE
+;
+
+-- Populate owner and scope from the access tokens issued alongside
+UPDATE identity.refresh_tokens AS r
+SET
+	user_id = a.user_id,
+	scope = a.scope
+FROM
+	identity.access_tokens AS a
+WHERE
+	a.refresh_token_id = r.token_id
+;
+
+-- Every existing token starts its own family
+UPDATE identity.refresh_tokens
+SET
+	family_id = token_id
+;
+
+-- Tokens without any access token can not be attributed to a user
+DELETE FROM identity.refresh_tokens
+WHERE
+	user_id IS NULL
+;
+
+ALTER TABLE identity.refresh_tokens
+ALTER COLUMN user_id
+SET NOT NULL
+;
+
+ALTER TABLE identity.refresh_tokens
+ALTER COLUMN scope
+SET NOT NULL
+;
+
+ALTER TABLE identity.refresh_tokens
+ALTER COLUMN family_id
+SET NOT NULL
+;
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON identity.refresh_tokens (family_id)
+;
+
+CREATE INDEX IF NOT EXISTS access_tokens_refresh_token_id_idx ON identity.access_tokens (refresh_token_id)
+;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS identity.authorization_codes
+;
+
+DROP TABLE IF EXISTS identity.client_redirect_uris
+;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS identity.client_redirect_uris (
+	client_id TEXT NOT NULL REFERENCES identity.clients (client_id) ON DELETE CASCADE,
+	redirect_uri TEXT NOT NULL,
+	PRIMARY KEY (client_id, redirect_uri)
+)
+;
+
+CREATE TABLE IF NOT EXISTS identity.authorization_codes (
+	code TEXT PRIMARY KEY,
+	client_id TEXT NOT NULL REFERENCES identity.clients (client_id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	redirect_uri TEXT NOT NULL,
+	scope TEXT NOT NULL,
+	code_challenge TEXT NOT NULL,
+	code_challenge_method TEXT NOT NULL,
+	issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	used BOOL NOT NULL DEFAULT FALSE,
+	-- family of the refresh token issued in exchange for the code
+	token_family_id TEXT
+)
+;
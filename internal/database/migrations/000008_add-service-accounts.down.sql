@@ -0,0 +1,11 @@
+ALTER TABLE identity.access_tokens
+DROP COLUMN IF EXISTS grant_type
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS service_account_scope
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS service_account_user_id
+;
@@ -0,0 +1,26 @@
+-- Add service account the client acts as in the client_credentials grant
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS service_account_user_id TEXT REFERENCES identity.users (user_id) ON DELETE SET NULL
+;
+
+-- Add scope the client is restricted to when acting as the service account
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS service_account_scope TEXT NOT NULL DEFAULT ''
+;
+
+-- Add grant the access token was issued with
+ALTER TABLE identity.access_tokens
+ADD COLUMN IF NOT EXISTS grant_type TEXT
+;
+
+UPDATE identity.access_tokens
+SET
+	grant_type = 'password'
+WHERE
+	grant_type IS NULL
+;
+
+ALTER TABLE identity.access_tokens
+ALTER COLUMN grant_type
+SET NOT NULL
+;
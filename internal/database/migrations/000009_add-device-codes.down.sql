@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS identity.device_codes
+;
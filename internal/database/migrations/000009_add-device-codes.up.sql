@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS identity.device_codes (
+	device_code TEXT PRIMARY KEY,
+	user_code TEXT NOT NULL UNIQUE,
+	client_id TEXT NOT NULL REFERENCES identity.clients (client_id) ON DELETE CASCADE,
+	scope TEXT NOT NULL,
+	issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	interval_seconds BIGINT CHECK (interval_seconds > 0) NOT NULL,
+	last_polled_at TIMESTAMP WITH TIME ZONE,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied', 'consumed')),
+	-- user that approved or denied the request
+	user_id TEXT REFERENCES identity.users (user_id) ON DELETE CASCADE
+)
+;
@@ -0,0 +1,80 @@
+-- The original credentials can not be recovered from the digests,
+-- so all issued tokens and codes are revoked and the client secrets
+-- have to be reset by the operator
+DELETE FROM identity.device_codes
+;
+
+ALTER TABLE identity.device_codes
+DROP COLUMN IF EXISTS device_code_hash
+;
+
+ALTER TABLE identity.device_codes
+ADD COLUMN IF NOT EXISTS device_code TEXT PRIMARY KEY
+;
+
+DELETE FROM identity.authorization_codes
+;
+
+ALTER TABLE identity.authorization_codes
+DROP COLUMN IF EXISTS code_hash
+;
+
+ALTER TABLE identity.authorization_codes
+ADD COLUMN IF NOT EXISTS code TEXT PRIMARY KEY
+;
+
+ALTER TABLE identity.access_tokens
+ADD COLUMN IF NOT EXISTS jwt TEXT
+;
+
+UPDATE identity.access_tokens
+SET
+	jwt = encode(token_hash, 'hex'),
+	revoked = TRUE
+;
+
+ALTER TABLE identity.access_tokens
+ALTER COLUMN jwt
+SET NOT NULL
+;
+
+ALTER TABLE identity.access_tokens
+DROP COLUMN IF EXISTS token_hash
+;
+
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS jwt TEXT
+;
+
+UPDATE identity.refresh_tokens
+SET
+	jwt = encode(token_hash, 'hex'),
+	revoked = TRUE
+;
+
+ALTER TABLE identity.refresh_tokens
+ALTER COLUMN jwt
+SET NOT NULL
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS token_hash
+;
+
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS client_secret TEXT
+;
+
+UPDATE identity.clients
+SET
+	client_secret = encode(gen_random_bytes(32), 'hex')
+;
+
+ALTER TABLE identity.clients
+ALTER COLUMN client_secret
+SET NOT NULL
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS client_secret_hash
+;
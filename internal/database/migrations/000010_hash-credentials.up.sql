@@ -0,0 +1,83 @@
+-- Bearer credentials are only stored as digests so that
+-- a leaked database can not be used to impersonate anyone
+CREATE EXTENSION IF NOT EXISTS pgcrypto
+;
+
+-- Client secrets are hashed with bcrypt like the user passwords
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS client_secret_hash bytea
+;
+
+UPDATE identity.clients
+SET
+	client_secret_hash = convert_to(crypt(client_secret, gen_salt('bf', 10)), 'UTF8')
+;
+
+ALTER TABLE identity.clients
+ALTER COLUMN client_secret_hash
+SET NOT NULL
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS client_secret
+;
+
+-- Tokens have enough entropy for a plain SHA-256 digest
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS token_hash bytea
+;
+
+UPDATE identity.refresh_tokens
+SET
+	token_hash = sha256(convert_to(jwt, 'UTF8'))
+;
+
+ALTER TABLE identity.refresh_tokens
+ALTER COLUMN token_hash
+SET NOT NULL
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS jwt
+;
+
+ALTER TABLE identity.access_tokens
+ADD COLUMN IF NOT EXISTS token_hash bytea
+;
+
+UPDATE identity.access_tokens
+SET
+	token_hash = sha256(convert_to(jwt, 'UTF8'))
+;
+
+ALTER TABLE identity.access_tokens
+ALTER COLUMN token_hash
+SET NOT NULL
+;
+
+ALTER TABLE identity.access_tokens
+DROP COLUMN IF EXISTS jwt
+;
+
+-- Codes are short-lived, the pending ones are dropped instead of converted
+DELETE FROM identity.authorization_codes
+;
+
+ALTER TABLE identity.authorization_codes
+DROP COLUMN IF EXISTS code
+;
+
+ALTER TABLE identity.authorization_codes
+ADD COLUMN IF NOT EXISTS code_hash bytea PRIMARY KEY
+;
+
+DELETE FROM identity.device_codes
+;
+
+ALTER TABLE identity.device_codes
+DROP COLUMN IF EXISTS device_code
+;
+
+ALTER TABLE identity.device_codes
+ADD COLUMN IF NOT EXISTS device_code_hash bytea PRIMARY KEY
+;
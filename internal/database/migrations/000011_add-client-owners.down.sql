@@ -0,0 +1,27 @@
+ALTER TABLE identity.refresh_tokens
+DROP CONSTRAINT IF EXISTS refresh_tokens_client_id_fkey
+;
+
+ALTER TABLE identity.refresh_tokens
+ADD CONSTRAINT refresh_tokens_client_id_fkey FOREIGN KEY (client_id) REFERENCES identity.clients (client_id)
+;
+
+ALTER TABLE identity.access_tokens
+DROP CONSTRAINT IF EXISTS access_tokens_client_id_fkey
+;
+
+ALTER TABLE identity.access_tokens
+ADD CONSTRAINT access_tokens_client_id_fkey FOREIGN KEY (client_id) REFERENCES identity.clients (client_id)
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS created_at
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS name
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS owner_user_id
+;
@@ -0,0 +1,30 @@
+-- Add user who registered the client, clients created by the server itself have none
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS owner_user_id TEXT REFERENCES identity.users (user_id) ON DELETE CASCADE
+;
+
+-- Add name shown to the user when managing the clients
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT ''
+;
+
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+;
+
+-- Tokens are deleted together with the client they were issued to
+ALTER TABLE identity.access_tokens
+DROP CONSTRAINT IF EXISTS access_tokens_client_id_fkey
+;
+
+ALTER TABLE identity.access_tokens
+ADD CONSTRAINT access_tokens_client_id_fkey FOREIGN KEY (client_id) REFERENCES identity.clients (client_id) ON DELETE CASCADE
+;
+
+ALTER TABLE identity.refresh_tokens
+DROP CONSTRAINT IF EXISTS refresh_tokens_client_id_fkey
+;
+
+ALTER TABLE identity.refresh_tokens
+ADD CONSTRAINT refresh_tokens_client_id_fkey FOREIGN KEY (client_id) REFERENCES identity.clients (client_id) ON DELETE CASCADE
+;
@@ -0,0 +1,34 @@
+ALTER TABLE identity.refresh_tokens
+DROP COLUMN IF EXISTS expires_at
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS refresh_token_lifetime_seconds
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS access_token_lifetime_seconds
+;
+
+-- Public clients have no secret to fall back to
+DELETE FROM identity.clients
+WHERE
+	public
+;
+
+ALTER TABLE identity.clients
+ALTER COLUMN client_secret_hash
+SET NOT NULL
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS public
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS allowed_scope
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS grant_types
+;
@@ -0,0 +1,40 @@
+-- Add grants the client is allowed to use, separated by spaces like the scopes
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS grant_types TEXT NOT NULL DEFAULT ''
+;
+
+-- Add widest scope the client is allowed to request
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS allowed_scope TEXT NOT NULL DEFAULT ''
+;
+
+-- The existing clients keep the access they had so far
+UPDATE identity.clients
+SET
+	grant_types = 'password refresh_token authorization_code client_credentials urn:ietf:params:oauth:grant-type:device_code',
+	allowed_scope = 'entries entries:read entries:write tags annotations export clients admin'
+;
+
+-- Add public clients that can not keep a secret and authenticate with the id only
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS public BOOL NOT NULL DEFAULT FALSE
+;
+
+ALTER TABLE identity.clients
+ALTER COLUMN client_secret_hash
+DROP NOT NULL
+;
+
+-- Add token lifetimes, zero keeps the server defaults
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS access_token_lifetime_seconds BIGINT NOT NULL DEFAULT 0
+;
+
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS refresh_token_lifetime_seconds BIGINT NOT NULL DEFAULT 0
+;
+
+-- Add expiration of the refresh tokens, empty if they do not expire
+ALTER TABLE identity.refresh_tokens
+ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE
+;
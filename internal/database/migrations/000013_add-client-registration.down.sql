@@ -0,0 +1,9 @@
+-- Dynamically registered clients could not be managed anymore
+DELETE FROM identity.clients
+WHERE
+	registration_access_token_hash IS NOT NULL
+;
+
+ALTER TABLE identity.clients
+DROP COLUMN IF EXISTS registration_access_token_hash
+;
@@ -0,0 +1,5 @@
+-- Add token the dynamically registered clients manage their registration with,
+-- empty for the clients registered in other ways
+ALTER TABLE identity.clients
+ADD COLUMN IF NOT EXISTS registration_access_token_hash BYTEA
+;
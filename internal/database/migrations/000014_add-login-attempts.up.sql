@@ -0,0 +1,11 @@
+-- Failed logins slow down the guessing of passwords,
+-- they are tracked both per username and per client ip
+CREATE TABLE IF NOT EXISTS identity.login_attempts (
+	kind TEXT NOT NULL CHECK (kind IN ('username', 'ip')),
+	subject TEXT NOT NULL,
+	failed_attempts INT CHECK (failed_attempts > 0) NOT NULL,
+	last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	locked_until TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (kind, subject)
+)
+;
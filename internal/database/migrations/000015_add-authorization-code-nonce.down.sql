@@ -0,0 +1,3 @@
+ALTER TABLE identity.authorization_codes
+DROP COLUMN IF EXISTS nonce
+;
@@ -0,0 +1,5 @@
+-- Nonce of the OpenID Connect authentication request,
+-- it is copied into the id token issued for the code
+ALTER TABLE identity.authorization_codes
+ADD COLUMN IF NOT EXISTS nonce TEXT NOT NULL DEFAULT ''
+;
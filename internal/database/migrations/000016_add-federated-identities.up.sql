@@ -0,0 +1,9 @@
+-- Accounts at the upstream identity provider linked to the local users
+CREATE TABLE IF NOT EXISTS identity.federated_identities (
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (issuer, subject)
+)
+;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS identity.user_recovery_codes
+;
+
+DROP TABLE IF EXISTS identity.user_totp
+;
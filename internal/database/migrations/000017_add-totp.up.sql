@@ -0,0 +1,18 @@
+-- Time-based one-time passwords of the users, the enrollment is pending until confirmed_at is set
+CREATE TABLE IF NOT EXISTS identity.user_totp (
+	user_id TEXT PRIMARY KEY REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	secret TEXT NOT NULL,
+	confirmed_at TIMESTAMP WITH TIME ZONE,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+)
+;
+
+-- Single use codes replacing the one-time password when the device is lost
+CREATE TABLE IF NOT EXISTS identity.user_recovery_codes (
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	code_hash BYTEA NOT NULL,
+	used_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (user_id, code_hash)
+)
+;
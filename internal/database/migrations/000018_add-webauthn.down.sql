@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS identity.webauthn_challenges
+;
+
+DROP TABLE IF EXISTS identity.webauthn_credentials
+;
@@ -0,0 +1,27 @@
+-- Passkeys of the users, see https://www.w3.org/TR/webauthn-3/#credential-record
+CREATE TABLE IF NOT EXISTS identity.webauthn_credentials (
+	credential_id BYTEA PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	public_key BYTEA NOT NULL,
+	attestation_type TEXT NOT NULL,
+	aaguid BYTEA NOT NULL,
+	sign_count BIGINT NOT NULL,
+	transports TEXT NOT NULL,
+	backup_eligible BOOLEAN NOT NULL,
+	backup_state BOOLEAN NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	last_used_at TIMESTAMP WITH TIME ZONE
+)
+;
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON identity.webauthn_credentials (user_id)
+;
+
+-- Pending registration and login ceremonies, each challenge is answered at most once
+CREATE TABLE IF NOT EXISTS identity.webauthn_challenges (
+	challenge TEXT PRIMARY KEY,
+	ceremony TEXT NOT NULL,
+	user_id TEXT REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+)
+;
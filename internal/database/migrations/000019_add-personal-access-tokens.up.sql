@@ -0,0 +1,15 @@
+-- Long-lived tokens the users create for their scripts, only the hash of the token is kept
+CREATE TABLE IF NOT EXISTS identity.personal_access_tokens (
+	token_id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token_hash BYTEA NOT NULL UNIQUE,
+	scope TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE,
+	last_used_at TIMESTAMP WITH TIME ZONE
+)
+;
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_id_idx ON identity.personal_access_tokens (user_id)
+;
@@ -0,0 +1,14 @@
+-- Device of every refresh token family, the session lasts as long as one of its tokens is usable
+CREATE TABLE IF NOT EXISTS identity.sessions (
+	family_id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	client_id TEXT NOT NULL REFERENCES identity.clients (client_id) ON DELETE CASCADE,
+	user_agent TEXT NOT NULL,
+	client_ip TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	last_used_at TIMESTAMP WITH TIME ZONE NOT NULL
+)
+;
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON identity.sessions (user_id)
+;
@@ -0,0 +1,12 @@
+-- Password resets emailed to the users, the sealed link only carries the id so that it can be used once
+CREATE TABLE IF NOT EXISTS identity.password_resets (
+	reset_id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	used_at TIMESTAMP WITH TIME ZONE NULL
+)
+;
+
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON identity.password_resets (user_id)
+;
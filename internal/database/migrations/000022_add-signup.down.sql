@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS identity.invites
+;
+
+DROP TABLE IF EXISTS identity.settings
+;
+
+ALTER TABLE identity.users
+DROP COLUMN IF EXISTS email_verified
+;
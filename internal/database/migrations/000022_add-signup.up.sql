@@ -0,0 +1,22 @@
+-- Self-registration: the registered users verify the email before the first login, the existing users count as verified
+ALTER TABLE identity.users
+ADD COLUMN IF NOT EXISTS email_verified BOOL NOT NULL DEFAULT TRUE
+;
+
+-- Settings the administrators change at runtime, e.g. whether the users can register
+CREATE TABLE IF NOT EXISTS identity.settings (
+	name TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+)
+;
+
+-- Single-use invites to register while the registration is invite-only
+CREATE TABLE IF NOT EXISTS identity.invites (
+	invite_id TEXT PRIMARY KEY,
+	code_hash BYTEA NOT NULL UNIQUE,
+	created_by TEXT NOT NULL REFERENCES identity.users (user_id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	used_at TIMESTAMP WITH TIME ZONE NULL
+)
+;
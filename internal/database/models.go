@@ -4,23 +4,148 @@
 
 package database
 
+import (
+	"database/sql"
+	"time"
+)
+
+type IdentityAuthorizationCode struct {
+	ClientID            string
+	UserID              string
+	RedirectUri         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	IssuedAt            time.Time
+	ExpiresAt           time.Time
+	Used                bool
+	TokenFamilyID       sql.NullString
+	CodeHash            []byte
+	Nonce               string
+}
+
 type IdentityClient struct {
-	ClientID     string
-	ClientSecret string
+	ClientID                    string
+	ServiceAccountUserID        sql.NullString
+	ServiceAccountScope         string
+	ClientSecretHash            []byte
+	OwnerUserID                 sql.NullString
+	Name                        string
+	CreatedAt                   time.Time
+	GrantTypes                  string
+	AllowedScope                string
+	Public                      bool
+	AccessTokenLifetimeSeconds  int64
+	RefreshTokenLifetimeSeconds int64
+	RegistrationAccessTokenHash []byte
+}
+
+type IdentityDeviceCode struct {
+	UserCode        string
+	ClientID        string
+	Scope           string
+	IssuedAt        time.Time
+	ExpiresAt       time.Time
+	IntervalSeconds int64
+	LastPolledAt    sql.NullTime
+	Status          string
+	UserID          sql.NullString
+	DeviceCodeHash  []byte
+}
+
+type IdentityFederatedIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    string
+	CreatedAt time.Time
+}
+
+type IdentityInvite struct {
+	InviteID  string
+	CodeHash  []byte
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type IdentityLoginAttempt struct {
+	Kind           string
+	Subject        string
+	FailedAttempts int32
+	LastFailedAt   time.Time
+	LockedUntil    sql.NullTime
+}
+
+type IdentityPersonalAccessToken struct {
+	TokenID    string
+	UserID     string
+	Name       string
+	TokenHash  []byte
+	Scope      string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
 }
 
 type IdentityRefreshToken struct {
-	TokenID  string
-	ClientID string
-	Jwt      string
-	Revoked  bool
+	TokenID   string
+	ClientID  string
+	Revoked   bool
+	UserID    string
+	Scope     string
+	IssuedAt  time.Time
+	FamilyID  string
+	Rotated   bool
+	TokenHash []byte
+	ExpiresAt sql.NullTime
+}
+
+type IdentitySession struct {
+	FamilyID   string
+	UserID     string
+	ClientID   string
+	UserAgent  string
+	ClientIp   string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
 }
 
 type IdentityUser struct {
+	UserID        string
+	Username      string
+	Email         string
+	PasswordHash  []byte
+	EmailVerified bool
+}
+
+type IdentityUserTotp struct {
 	UserID       string
-	Username     string
-	Email        string
-	PasswordHash []byte
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+type IdentityWebauthnChallenge struct {
+	Challenge string
+	Ceremony  string
+	UserID    sql.NullString
+	ExpiresAt time.Time
+}
+
+type IdentityWebauthnCredential struct {
+	CredentialID    []byte
+	UserID          string
+	PublicKey       []byte
+	AttestationType string
+	Aaguid          []byte
+	SignCount       int64
+	Transports      string
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+	LastUsedAt      sql.NullTime
 }
 
 type WallabagoBootstrap struct {
@@ -6,26 +6,96 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
 	AddAccessToken(ctx context.Context, arg AddAccessTokenParams) (*AddAccessTokenRow, error)
 	AddAppUser(ctx context.Context, arg AddAppUserParams) (*WallabagoUser, error)
+	AddAuthorizationCode(ctx context.Context, arg AddAuthorizationCodeParams) (*IdentityAuthorizationCode, error)
 	AddClient(ctx context.Context, arg AddClientParams) (*IdentityClient, error)
+	AddClientRedirectURI(ctx context.Context, arg AddClientRedirectURIParams) error
+	AddDeviceCode(ctx context.Context, arg AddDeviceCodeParams) (*IdentityDeviceCode, error)
+	AddFederatedIdentity(ctx context.Context, arg AddFederatedIdentityParams) error
 	AddIdentityUser(ctx context.Context, arg AddIdentityUserParams) (*IdentityUser, error)
+	AddInvite(ctx context.Context, arg AddInviteParams) error
+	AddPasswordReset(ctx context.Context, arg AddPasswordResetParams) error
+	AddPersonalAccessToken(ctx context.Context, arg AddPersonalAccessTokenParams) error
+	AddRecoveryCode(ctx context.Context, arg AddRecoveryCodeParams) error
 	AddRefreshToken(ctx context.Context, arg AddRefreshTokenParams) (*IdentityRefreshToken, error)
+	AddWebAuthnChallenge(ctx context.Context, arg AddWebAuthnChallengeParams) error
+	AddWebAuthnCredential(ctx context.Context, arg AddWebAuthnCredentialParams) error
+	ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (int64, error)
+	CountPasswordResets(ctx context.Context, arg CountPasswordResetsParams) (int64, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int64, error)
 	DeleteAccessTokenByID(ctx context.Context, tokenID string) error
 	DeleteClientByID(ctx context.Context, clientID string) error
+	DeleteClientRedirectURIs(ctx context.Context, clientID string) error
+	DeleteExpiredWebAuthnChallenges(ctx context.Context, expiresAt time.Time) error
 	DeleteIdentityUserByID(ctx context.Context, userID string) error
+	DeleteInvite(ctx context.Context, inviteID string) (int64, error)
+	DeleteLoginAttempts(ctx context.Context, arg DeleteLoginAttemptsParams) error
+	DeletePasswordResets(ctx context.Context, userID string) error
+	DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error)
+	DeletePersonalAccessTokens(ctx context.Context, userID string) error
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
 	DeleteRefreshTokenByID(ctx context.Context, tokenID string) error
-	GetAccessTokenByJWT(ctx context.Context, jwt string) (*GetAccessTokenByJWTRow, error)
+	DeleteUserTOTP(ctx context.Context, userID string) error
+	DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (int64, error)
+	GetAccessTokenByID(ctx context.Context, tokenID string) (*GetAccessTokenByIDRow, error)
+	GetAccessTokenFamilyID(ctx context.Context, tokenID string) (string, error)
+	GetActiveSessions(ctx context.Context, arg GetActiveSessionsParams) ([]*GetActiveSessionsRow, error)
+	GetAppUserByID(ctx context.Context, userID string) (*WallabagoUser, error)
+	GetAuthorizationCode(ctx context.Context, codeHash []byte) (*IdentityAuthorizationCode, error)
 	GetBoostrapConditions(ctx context.Context) ([]*WallabagoBootstrap, error)
 	GetClientByID(ctx context.Context, clientID string) (*IdentityClient, error)
+	GetClientRedirectURIs(ctx context.Context, clientID string) ([]string, error)
+	GetClients(ctx context.Context) ([]*IdentityClient, error)
+	GetClientsByOwnerUserID(ctx context.Context, ownerUserID sql.NullString) ([]*IdentityClient, error)
+	GetDeviceCodeByDeviceCodeHash(ctx context.Context, deviceCodeHash []byte) (*IdentityDeviceCode, error)
+	GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*IdentityDeviceCode, error)
+	GetFederatedIdentity(ctx context.Context, arg GetFederatedIdentityParams) (*IdentityFederatedIdentity, error)
+	GetIdentityUserByEmail(ctx context.Context, email string) (*IdentityUser, error)
+	GetIdentityUserByID(ctx context.Context, userID string) (*IdentityUser, error)
 	GetIdentityUserByUsername(ctx context.Context, username string) (*IdentityUser, error)
-	GetRefreshTokenByJWT(ctx context.Context, jwt string) (*IdentityRefreshToken, error)
+	GetInvites(ctx context.Context) ([]*IdentityInvite, error)
+	GetLoginAttempts(ctx context.Context, arg GetLoginAttemptsParams) (*IdentityLoginAttempt, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash []byte) (*IdentityPersonalAccessToken, error)
+	GetPersonalAccessTokens(ctx context.Context, userID string) ([]*IdentityPersonalAccessToken, error)
+	GetRefreshTokenByID(ctx context.Context, tokenID string) (*IdentityRefreshToken, error)
+	GetSession(ctx context.Context, familyID string) (*IdentitySession, error)
+	GetSetting(ctx context.Context, name string) (string, error)
+	GetUserTOTP(ctx context.Context, userID string) (*IdentityUserTotp, error)
+	GetWebAuthnCredential(ctx context.Context, credentialID []byte) (*IdentityWebauthnCredential, error)
+	GetWebAuthnCredentials(ctx context.Context, userID string) ([]*IdentityWebauthnCredential, error)
+	MarkAuthorizationCodeUsed(ctx context.Context, arg MarkAuthorizationCodeUsedParams) error
 	MarkBootstrapConditionSatisfied(ctx context.Context, conditionName string) (*WallabagoBootstrap, error)
 	RevokeAccessTokenByID(ctx context.Context, tokenID string) (*RevokeAccessTokenByIDRow, error)
+	RevokeAccessTokensByRefreshTokenFamilyID(ctx context.Context, familyID string) error
+	RevokeAccessTokensByRefreshTokenID(ctx context.Context, refreshTokenID sql.NullString) error
 	RevokeRefreshTokenByID(ctx context.Context, tokenID string) (*IdentityRefreshToken, error)
+	RevokeRefreshTokensByFamilyID(ctx context.Context, familyID string) error
+	RotateRefreshTokenByID(ctx context.Context, tokenID string) (int64, error)
+	SetAppUserIsAdmin(ctx context.Context, arg SetAppUserIsAdminParams) error
+	SetClientSecretHash(ctx context.Context, arg SetClientSecretHashParams) error
+	SetClientServiceAccount(ctx context.Context, arg SetClientServiceAccountParams) error
+	SetDeviceCodeStatus(ctx context.Context, arg SetDeviceCodeStatusParams) error
+	SetIdentityUserEmailVerified(ctx context.Context, userID string) error
+	SetIdentityUserPasswordHash(ctx context.Context, arg SetIdentityUserPasswordHashParams) error
+	SetLoginAttempts(ctx context.Context, arg SetLoginAttemptsParams) error
+	SetSession(ctx context.Context, arg SetSessionParams) error
+	SetSetting(ctx context.Context, arg SetSettingParams) error
+	SetUserTOTP(ctx context.Context, arg SetUserTOTPParams) error
+	TakeWebAuthnChallenge(ctx context.Context, challenge string) (*IdentityWebauthnChallenge, error)
+	UpdateClient(ctx context.Context, arg UpdateClientParams) error
+	UpdateDeviceCodePolling(ctx context.Context, arg UpdateDeviceCodePollingParams) error
+	UseInvite(ctx context.Context, arg UseInviteParams) (int64, error)
+	UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (int64, error)
+	UsePersonalAccessToken(ctx context.Context, arg UsePersonalAccessTokenParams) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error)
+	UseWebAuthnCredential(ctx context.Context, arg UseWebAuthnCredentialParams) error
 }
 
 var _ Querier = (*Queries)(nil)
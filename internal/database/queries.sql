@@ -21,18 +21,52 @@ RETURNING
 
 -- name: AddClient :one
 INSERT INTO
-	identity.clients (client_id, client_secret)
+	identity.clients (
+		client_id,
+		client_secret_hash,
+		owner_user_id,
+		name,
+		created_at,
+		grant_types,
+		allowed_scope,
+		public,
+		access_token_lifetime_seconds,
+		refresh_token_lifetime_seconds,
+		registration_access_token_hash
+	)
 VALUES
-	($1, $2)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING
 	client_id,
-	client_secret
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
 ;
 
 -- name: GetClientByID :one
 SELECT
 	client_id,
-	client_secret
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
 FROM
 	identity.clients
 WHERE
@@ -41,6 +75,81 @@ LIMIT
 	1
 ;
 
+-- name: GetClients :many
+SELECT
+	client_id,
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
+FROM
+	identity.clients
+ORDER BY
+	created_at,
+	client_id
+;
+
+-- name: GetClientsByOwnerUserID :many
+SELECT
+	client_id,
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
+FROM
+	identity.clients
+WHERE
+	owner_user_id = $1
+ORDER BY
+	created_at,
+	client_id
+;
+
+-- name: SetClientSecretHash :exec
+UPDATE identity.clients
+SET
+	client_secret_hash = $2
+WHERE
+	client_id = $1
+;
+
+-- name: UpdateClient :exec
+UPDATE identity.clients
+SET
+	client_secret_hash = $2,
+	name = $3,
+	grant_types = $4,
+	allowed_scope = $5,
+	public = $6
+WHERE
+	client_id = $1
+;
+
+-- name: SetClientServiceAccount :exec
+UPDATE identity.clients
+SET
+	service_account_user_id = $2,
+	service_account_scope = $3
+WHERE
+	client_id = $1
+;
+
 -- name: DeleteClientByID :exec
 DELETE FROM identity.clients
 WHERE
@@ -49,14 +158,21 @@ WHERE
 
 -- name: AddIdentityUser :one
 INSERT INTO
-	identity.users (user_id, username, email, password_hash)
+	identity.users (
+		user_id,
+		username,
+		email,
+		password_hash,
+		email_verified
+	)
 VALUES
-	($1, $2, $3, $4)
+	($1, $2, $3, $4, $5)
 RETURNING
 	user_id,
 	username,
 	email,
-	password_hash
+	password_hash,
+	email_verified
 ;
 
 -- name: GetIdentityUserByUsername :one
@@ -64,7 +180,8 @@ SELECT
 	user_id,
 	username,
 	email,
-	password_hash
+	password_hash,
+	email_verified
 FROM
 	identity.users
 WHERE
@@ -73,6 +190,36 @@ LIMIT
 	1
 ;
 
+-- name: GetIdentityUserByID :one
+SELECT
+	user_id,
+	username,
+	email,
+	password_hash,
+	email_verified
+FROM
+	identity.users
+WHERE
+	user_id = $1
+LIMIT
+	1
+;
+
+-- name: GetIdentityUserByEmail :one
+SELECT
+	user_id,
+	username,
+	email,
+	password_hash,
+	email_verified
+FROM
+	identity.users
+WHERE
+	email = $1
+LIMIT
+	1
+;
+
 -- name: DeleteIdentityUserByID :exec
 DELETE FROM identity.users
 WHERE
@@ -81,28 +228,49 @@ WHERE
 
 -- name: AddRefreshToken :one
 INSERT INTO
-	identity.refresh_tokens (token_id, client_id, jwt, revoked)
+	identity.refresh_tokens (
+		token_id,
+		client_id,
+		revoked,
+		user_id,
+		scope,
+		issued_at,
+		family_id,
+		rotated,
+		token_hash,
+		expires_at
+	)
 VALUES
-	($1, $2, $3, $4)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING
 	token_id,
 	client_id,
-	jwt,
-	revoked
+	revoked,
+	user_id,
+	scope,
+	issued_at,
+	family_id,
+	rotated,
+	token_hash,
+	expires_at
 ;
 
--- name: GetRefreshTokenByJWT :one
+-- name: GetRefreshTokenByID :one
 SELECT
 	token_id,
 	client_id,
-	jwt,
-	revoked
+	revoked,
+	user_id,
+	scope,
+	issued_at,
+	family_id,
+	rotated,
+	token_hash,
+	expires_at
 FROM
 	identity.refresh_tokens
 WHERE
-	jwt = $1
-LIMIT
-	1
+	token_id = $1
 ;
 
 -- name: RevokeRefreshTokenByID :one
@@ -114,8 +282,33 @@ WHERE
 RETURNING
 	token_id,
 	client_id,
-	jwt,
-	revoked
+	revoked,
+	user_id,
+	scope,
+	issued_at,
+	family_id,
+	rotated,
+	token_hash,
+	expires_at
+;
+
+-- name: RotateRefreshTokenByID :execrows
+UPDATE identity.refresh_tokens
+SET
+	revoked = TRUE,
+	rotated = TRUE
+WHERE
+	token_id = $1
+	AND NOT rotated
+	AND NOT revoked
+;
+
+-- name: RevokeRefreshTokensByFamilyID :exec
+UPDATE identity.refresh_tokens
+SET
+	revoked = TRUE
+WHERE
+	family_id = $1
 ;
 
 -- name: DeleteRefreshTokenByID :exec
@@ -131,46 +324,47 @@ INSERT INTO
 		refresh_token_id,
 		client_id,
 		user_id,
-		jwt,
 		revoked,
 		expires_in_seconds,
 		issued_at,
 		scope,
-		type
+		type,
+		grant_type,
+		token_hash
 	)
 VALUES
-	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING
 	token_id,
 	refresh_token_id,
 	client_id,
 	user_id,
-	jwt,
 	revoked,
 	expires_in_seconds,
 	issued_at,
 	scope,
-	type
+	type,
+	grant_type,
+	token_hash
 ;
 
--- name: GetAccessTokenByJWT :one
+-- name: GetAccessTokenByID :one
 SELECT
 	token_id,
 	refresh_token_id,
 	client_id,
 	user_id,
-	jwt,
 	revoked,
 	expires_in_seconds,
 	issued_at,
 	scope,
-	type
+	type,
+	grant_type,
+	token_hash
 FROM
 	identity.access_tokens
 WHERE
-	jwt = $1
-LIMIT
-	1
+	token_id = $1
 ;
 
 -- name: RevokeAccessTokenByID :one
@@ -184,12 +378,36 @@ RETURNING
 	refresh_token_id,
 	client_id,
 	user_id,
-	jwt,
 	revoked,
 	expires_in_seconds,
 	issued_at,
 	scope,
-	type
+	type,
+	grant_type,
+	token_hash
+;
+
+-- name: RevokeAccessTokensByRefreshTokenID :exec
+UPDATE identity.access_tokens
+SET
+	revoked = TRUE
+WHERE
+	refresh_token_id = $1
+;
+
+-- name: RevokeAccessTokensByRefreshTokenFamilyID :exec
+UPDATE identity.access_tokens
+SET
+	revoked = TRUE
+WHERE
+	refresh_token_id IN (
+		SELECT
+			token_id
+		FROM
+			identity.refresh_tokens
+		WHERE
+			family_id = $1
+	)
 ;
 
 -- name: DeleteAccessTokenByID :exec
@@ -198,6 +416,16 @@ WHERE
 	token_id = $1
 ;
 
+-- name: GetAccessTokenFamilyID :one
+SELECT
+	r.family_id
+FROM
+	identity.access_tokens AS a
+	JOIN identity.refresh_tokens AS r ON r.token_id = a.refresh_token_id
+WHERE
+	a.token_id = $1
+;
+
 -- name: AddAppUser :one
 INSERT INTO
 	wallabago.users (user_id, is_admin, username)
@@ -207,4 +435,645 @@ RETURNING
 	user_id,
 	is_admin,
 	username
-;
\ No newline at end of file
+;
+
+-- name: SetAppUserIsAdmin :exec
+UPDATE wallabago.users
+SET
+	is_admin = $2
+WHERE
+	user_id = $1
+;
+
+-- name: AddClientRedirectURI :exec
+INSERT INTO
+	identity.client_redirect_uris (client_id, redirect_uri)
+VALUES
+	($1, $2)
+ON CONFLICT DO NOTHING
+;
+
+-- name: GetClientRedirectURIs :many
+SELECT
+	redirect_uri
+FROM
+	identity.client_redirect_uris
+WHERE
+	client_id = $1
+ORDER BY
+	redirect_uri
+;
+
+-- name: DeleteClientRedirectURIs :exec
+DELETE FROM identity.client_redirect_uris
+WHERE
+	client_id = $1
+;
+
+-- name: AddAuthorizationCode :one
+INSERT INTO
+	identity.authorization_codes (
+		client_id,
+		user_id,
+		redirect_uri,
+		scope,
+		code_challenge,
+		code_challenge_method,
+		issued_at,
+		expires_at,
+		used,
+		code_hash,
+		nonce
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING
+	client_id,
+	user_id,
+	redirect_uri,
+	scope,
+	code_challenge,
+	code_challenge_method,
+	issued_at,
+	expires_at,
+	used,
+	token_family_id,
+	code_hash,
+	nonce
+;
+
+-- name: GetAuthorizationCode :one
+SELECT
+	client_id,
+	user_id,
+	redirect_uri,
+	scope,
+	code_challenge,
+	code_challenge_method,
+	issued_at,
+	expires_at,
+	used,
+	token_family_id,
+	code_hash,
+	nonce
+FROM
+	identity.authorization_codes
+WHERE
+	code_hash = $1
+LIMIT
+	1
+FOR UPDATE
+;
+
+-- name: MarkAuthorizationCodeUsed :exec
+UPDATE identity.authorization_codes
+SET
+	used = TRUE,
+	token_family_id = $2
+WHERE
+	code_hash = $1
+;
+
+
+-- name: GetAppUserByID :one
+SELECT
+	user_id,
+	is_admin,
+	username
+FROM
+	wallabago.users
+WHERE
+	user_id = $1
+LIMIT
+	1
+;
+
+-- name: AddDeviceCode :one
+INSERT INTO
+	identity.device_codes (
+		user_code,
+		client_id,
+		scope,
+		issued_at,
+		expires_at,
+		interval_seconds,
+		status,
+		device_code_hash
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+	user_code,
+	client_id,
+	scope,
+	issued_at,
+	expires_at,
+	interval_seconds,
+	last_polled_at,
+	status,
+	user_id,
+	device_code_hash
+;
+
+-- name: GetDeviceCodeByDeviceCodeHash :one
+SELECT
+	user_code,
+	client_id,
+	scope,
+	issued_at,
+	expires_at,
+	interval_seconds,
+	last_polled_at,
+	status,
+	user_id,
+	device_code_hash
+FROM
+	identity.device_codes
+WHERE
+	device_code_hash = $1
+LIMIT
+	1
+FOR UPDATE
+;
+
+-- name: GetDeviceCodeByUserCode :one
+SELECT
+	user_code,
+	client_id,
+	scope,
+	issued_at,
+	expires_at,
+	interval_seconds,
+	last_polled_at,
+	status,
+	user_id,
+	device_code_hash
+FROM
+	identity.device_codes
+WHERE
+	user_code = $1
+LIMIT
+	1
+FOR UPDATE
+;
+
+-- name: UpdateDeviceCodePolling :exec
+UPDATE identity.device_codes
+SET
+	last_polled_at = $2,
+	interval_seconds = $3
+WHERE
+	device_code_hash = $1
+;
+
+-- name: SetDeviceCodeStatus :exec
+UPDATE identity.device_codes
+SET
+	status = $2,
+	user_id = $3
+WHERE
+	device_code_hash = $1
+;
+
+-- name: GetLoginAttempts :one
+SELECT
+	kind,
+	subject,
+	failed_attempts,
+	last_failed_at,
+	locked_until
+FROM
+	identity.login_attempts
+WHERE
+	kind = $1
+	AND subject = $2
+LIMIT
+	1
+FOR UPDATE
+;
+
+-- name: SetLoginAttempts :exec
+INSERT INTO
+	identity.login_attempts (kind, subject, failed_attempts, last_failed_at, locked_until)
+VALUES
+	($1, $2, $3, $4, $5)
+ON CONFLICT (kind, subject) DO UPDATE
+SET
+	failed_attempts = EXCLUDED.failed_attempts,
+	last_failed_at = EXCLUDED.last_failed_at,
+	locked_until = EXCLUDED.locked_until
+;
+
+-- name: DeleteLoginAttempts :exec
+DELETE FROM identity.login_attempts
+WHERE
+	kind = $1
+	AND subject = $2
+;
+
+-- name: GetFederatedIdentity :one
+SELECT
+	issuer,
+	subject,
+	user_id,
+	created_at
+FROM
+	identity.federated_identities
+WHERE
+	issuer = $1
+	AND subject = $2
+LIMIT
+	1
+;
+
+-- name: AddFederatedIdentity :exec
+INSERT INTO
+	identity.federated_identities (issuer, subject, user_id, created_at)
+VALUES
+	($1, $2, $3, $4)
+;
+
+-- name: GetUserTOTP :one
+SELECT
+	user_id,
+	secret,
+	confirmed_at,
+	last_used_step,
+	created_at
+FROM
+	identity.user_totp
+WHERE
+	user_id = $1
+LIMIT
+	1
+;
+
+-- name: SetUserTOTP :exec
+INSERT INTO
+	identity.user_totp (user_id, secret, confirmed_at, last_used_step, created_at)
+VALUES
+	($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET
+	secret = EXCLUDED.secret,
+	confirmed_at = EXCLUDED.confirmed_at,
+	last_used_step = EXCLUDED.last_used_step,
+	created_at = EXCLUDED.created_at
+;
+
+-- name: ConfirmUserTOTP :execrows
+UPDATE identity.user_totp
+SET
+	confirmed_at = $2,
+	last_used_step = $3
+WHERE
+	user_id = $1
+	AND confirmed_at IS NULL
+;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE identity.user_totp
+SET
+	last_used_step = $2
+WHERE
+	user_id = $1
+	AND last_used_step < $2
+;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM identity.user_totp
+WHERE
+	user_id = $1
+;
+
+-- name: AddRecoveryCode :exec
+INSERT INTO
+	identity.user_recovery_codes (user_id, code_hash)
+VALUES
+	($1, $2)
+;
+
+-- name: UseRecoveryCode :execrows
+UPDATE identity.user_recovery_codes
+SET
+	used_at = $3
+WHERE
+	user_id = $1
+	AND code_hash = $2
+	AND used_at IS NULL
+;
+
+-- name: CountRecoveryCodes :one
+SELECT
+	COUNT(*)
+FROM
+	identity.user_recovery_codes
+WHERE
+	user_id = $1
+	AND used_at IS NULL
+;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM identity.user_recovery_codes
+WHERE
+	user_id = $1
+;
+
+-- name: GetWebAuthnCredentials :many
+SELECT
+	*
+FROM
+	identity.webauthn_credentials
+WHERE
+	user_id = $1
+ORDER BY
+	created_at
+;
+
+-- name: GetWebAuthnCredential :one
+SELECT
+	*
+FROM
+	identity.webauthn_credentials
+WHERE
+	credential_id = $1
+;
+
+-- name: AddWebAuthnCredential :exec
+INSERT INTO
+	identity.webauthn_credentials (
+		credential_id,
+		user_id,
+		public_key,
+		attestation_type,
+		aaguid,
+		sign_count,
+		transports,
+		backup_eligible,
+		backup_state,
+		created_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+;
+
+-- name: UseWebAuthnCredential :exec
+UPDATE identity.webauthn_credentials
+SET
+	sign_count = $2,
+	backup_state = $3,
+	last_used_at = $4
+WHERE
+	credential_id = $1
+;
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM identity.webauthn_credentials
+WHERE
+	user_id = $1
+	AND credential_id = $2
+;
+
+-- name: AddWebAuthnChallenge :exec
+INSERT INTO
+	identity.webauthn_challenges (challenge, ceremony, user_id, expires_at)
+VALUES
+	($1, $2, $3, $4)
+;
+
+-- name: TakeWebAuthnChallenge :one
+DELETE FROM identity.webauthn_challenges
+WHERE
+	challenge = $1
+RETURNING
+	*
+;
+
+-- name: DeleteExpiredWebAuthnChallenges :exec
+DELETE FROM identity.webauthn_challenges
+WHERE
+	expires_at < $1
+;
+
+-- name: GetPersonalAccessTokens :many
+SELECT
+	*
+FROM
+	identity.personal_access_tokens
+WHERE
+	user_id = $1
+ORDER BY
+	created_at
+;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT
+	*
+FROM
+	identity.personal_access_tokens
+WHERE
+	token_hash = $1
+;
+
+-- name: AddPersonalAccessToken :exec
+INSERT INTO
+	identity.personal_access_tokens (
+		token_id,
+		user_id,
+		name,
+		token_hash,
+		scope,
+		created_at,
+		expires_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7)
+;
+
+-- name: UsePersonalAccessToken :exec
+UPDATE identity.personal_access_tokens
+SET
+	last_used_at = $2
+WHERE
+	token_id = $1
+;
+
+-- name: DeletePersonalAccessToken :execrows
+DELETE FROM identity.personal_access_tokens
+WHERE
+	user_id = $1
+	AND token_id = $2
+;
+
+-- name: DeletePersonalAccessTokens :exec
+DELETE FROM identity.personal_access_tokens
+WHERE
+	user_id = $1
+;
+
+-- name: SetSession :exec
+INSERT INTO
+	identity.sessions (
+		family_id,
+		user_id,
+		client_id,
+		user_agent,
+		client_ip,
+		created_at,
+		last_used_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (family_id) DO UPDATE
+SET
+	user_agent = excluded.user_agent,
+	client_ip = excluded.client_ip,
+	last_used_at = excluded.last_used_at
+;
+
+-- name: GetSession :one
+SELECT
+	*
+FROM
+	identity.sessions
+WHERE
+	family_id = $1
+;
+
+-- name: GetActiveSessions :many
+SELECT
+	s.family_id,
+	s.user_id,
+	s.client_id,
+	s.user_agent,
+	s.client_ip,
+	s.created_at,
+	s.last_used_at,
+	c.name AS client_name
+FROM
+	identity.sessions AS s
+	JOIN identity.clients AS c ON c.client_id = s.client_id
+WHERE
+	s.user_id = $1
+	AND EXISTS (
+		SELECT
+			1
+		FROM
+			identity.refresh_tokens AS r
+		WHERE
+			r.family_id = s.family_id
+			AND NOT r.revoked
+			AND (
+				r.expires_at IS NULL
+				OR r.expires_at > $2
+			)
+	)
+ORDER BY
+	s.last_used_at DESC
+;
+
+-- name: SetIdentityUserPasswordHash :exec
+UPDATE identity.users
+SET
+	password_hash = $2
+WHERE
+	user_id = $1
+;
+
+-- name: SetIdentityUserEmailVerified :exec
+UPDATE identity.users
+SET
+	email_verified = TRUE
+WHERE
+	user_id = $1
+;
+
+-- name: AddPasswordReset :exec
+INSERT INTO
+	identity.password_resets (reset_id, user_id, created_at, expires_at)
+VALUES
+	($1, $2, $3, $4)
+;
+
+-- name: UsePasswordReset :execrows
+UPDATE identity.password_resets
+SET
+	used_at = $2
+WHERE
+	reset_id = $1
+	AND used_at IS NULL
+	AND expires_at > $2
+;
+
+-- name: CountPasswordResets :one
+SELECT
+	COUNT(*)
+FROM
+	identity.password_resets
+WHERE
+	user_id = $1
+	AND created_at > $2
+;
+
+-- name: DeletePasswordResets :exec
+DELETE FROM identity.password_resets
+WHERE
+	user_id = $1
+;
+
+-- name: GetSetting :one
+SELECT
+	value
+FROM
+	identity.settings
+WHERE
+	name = $1
+;
+
+-- name: SetSetting :exec
+INSERT INTO
+	identity.settings (name, value)
+VALUES
+	($1, $2)
+ON CONFLICT (name) DO UPDATE
+SET
+	value = excluded.value
+;
+
+-- name: AddInvite :exec
+INSERT INTO
+	identity.invites (
+		invite_id,
+		code_hash,
+		created_by,
+		created_at,
+		expires_at
+	)
+VALUES
+	($1, $2, $3, $4, $5)
+;
+
+-- name: GetInvites :many
+SELECT
+	*
+FROM
+	identity.invites
+ORDER BY
+	created_at DESC
+;
+
+-- name: UseInvite :execrows
+UPDATE identity.invites
+SET
+	used_at = $2
+WHERE
+	code_hash = $1
+	AND used_at IS NULL
+	AND expires_at > $2
+;
+
+-- name: DeleteInvite :execrows
+DELETE FROM identity.invites
+WHERE
+	invite_id = $1
+;
@@ -18,26 +18,28 @@ INSERT INTO
 		refresh_token_id,
 		client_id,
 		user_id,
-		jwt,
 		revoked,
 		expires_in_seconds,
 		issued_at,
 		scope,
-		type
+		type,
+		grant_type,
+		token_hash
 	)
 VALUES
-	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING
 	token_id,
 	refresh_token_id,
 	client_id,
 	user_id,
-	jwt,
 	revoked,
 	expires_in_seconds,
 	issued_at,
 	scope,
-	type
+	type,
+	grant_type,
+	token_hash
 `
 
 type AddAccessTokenParams struct {
@@ -45,12 +47,13 @@ type AddAccessTokenParams struct {
 	RefreshTokenID   sql.NullString
 	ClientID         string
 	UserID           string
-	Jwt              string
 	Revoked          bool
 	ExpiresInSeconds int64
 	IssuedAt         time.Time
 	Scope            string
 	Type             string
+	GrantType        string
+	TokenHash        []byte
 }
 
 type AddAccessTokenRow struct {
@@ -58,12 +61,13 @@ type AddAccessTokenRow struct {
 	RefreshTokenID   sql.NullString
 	ClientID         string
 	UserID           string
-	Jwt              string
 	Revoked          bool
 	ExpiresInSeconds int64
 	IssuedAt         time.Time
 	Scope            string
 	Type             string
+	GrantType        string
+	TokenHash        []byte
 }
 
 func (q *Queries) AddAccessToken(ctx context.Context, arg AddAccessTokenParams) (*AddAccessTokenRow, error) {
@@ -72,12 +76,13 @@ func (q *Queries) AddAccessToken(ctx context.Context, arg AddAccessTokenParams)
 		arg.RefreshTokenID,
 		arg.ClientID,
 		arg.UserID,
-		arg.Jwt,
 		arg.Revoked,
 		arg.ExpiresInSeconds,
 		arg.IssuedAt,
 		arg.Scope,
 		arg.Type,
+		arg.GrantType,
+		arg.TokenHash,
 	)
 	var i AddAccessTokenRow
 	err := row.Scan(
@@ -85,12 +90,13 @@ func (q *Queries) AddAccessToken(ctx context.Context, arg AddAccessTokenParams)
 		&i.RefreshTokenID,
 		&i.ClientID,
 		&i.UserID,
-		&i.Jwt,
 		&i.Revoked,
 		&i.ExpiresInSeconds,
 		&i.IssuedAt,
 		&i.Scope,
 		&i.Type,
+		&i.GrantType,
+		&i.TokenHash,
 	)
 	return &i, err
 }
@@ -119,45 +125,296 @@ func (q *Queries) AddAppUser(ctx context.Context, arg AddAppUserParams) (*Wallab
 	return &i, err
 }
 
+const addAuthorizationCode = `-- name: AddAuthorizationCode :one
+INSERT INTO
+	identity.authorization_codes (
+		client_id,
+		user_id,
+		redirect_uri,
+		scope,
+		code_challenge,
+		code_challenge_method,
+		issued_at,
+		expires_at,
+		used,
+		code_hash,
+		nonce
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING
+	client_id,
+	user_id,
+	redirect_uri,
+	scope,
+	code_challenge,
+	code_challenge_method,
+	issued_at,
+	expires_at,
+	used,
+	token_family_id,
+	code_hash,
+	nonce
+`
+
+type AddAuthorizationCodeParams struct {
+	ClientID            string
+	UserID              string
+	RedirectUri         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	IssuedAt            time.Time
+	ExpiresAt           time.Time
+	Used                bool
+	CodeHash            []byte
+	Nonce               string
+}
+
+func (q *Queries) AddAuthorizationCode(ctx context.Context, arg AddAuthorizationCodeParams) (*IdentityAuthorizationCode, error) {
+	row := q.queryRow(ctx, q.addAuthorizationCodeStmt, addAuthorizationCode,
+		arg.ClientID,
+		arg.UserID,
+		arg.RedirectUri,
+		arg.Scope,
+		arg.CodeChallenge,
+		arg.CodeChallengeMethod,
+		arg.IssuedAt,
+		arg.ExpiresAt,
+		arg.Used,
+		arg.CodeHash,
+		arg.Nonce,
+	)
+	var i IdentityAuthorizationCode
+	err := row.Scan(
+		&i.ClientID,
+		&i.UserID,
+		&i.RedirectUri,
+		&i.Scope,
+		&i.CodeChallenge,
+		&i.CodeChallengeMethod,
+		&i.IssuedAt,
+		&i.ExpiresAt,
+		&i.Used,
+		&i.TokenFamilyID,
+		&i.CodeHash,
+		&i.Nonce,
+	)
+	return &i, err
+}
+
 const addClient = `-- name: AddClient :one
 INSERT INTO
-	identity.clients (client_id, client_secret)
+	identity.clients (
+		client_id,
+		client_secret_hash,
+		owner_user_id,
+		name,
+		created_at,
+		grant_types,
+		allowed_scope,
+		public,
+		access_token_lifetime_seconds,
+		refresh_token_lifetime_seconds,
+		registration_access_token_hash
+	)
 VALUES
-	($1, $2)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING
 	client_id,
-	client_secret
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
 `
 
 type AddClientParams struct {
-	ClientID     string
-	ClientSecret string
+	ClientID                    string
+	ClientSecretHash            []byte
+	OwnerUserID                 sql.NullString
+	Name                        string
+	CreatedAt                   time.Time
+	GrantTypes                  string
+	AllowedScope                string
+	Public                      bool
+	AccessTokenLifetimeSeconds  int64
+	RefreshTokenLifetimeSeconds int64
+	RegistrationAccessTokenHash []byte
 }
 
 func (q *Queries) AddClient(ctx context.Context, arg AddClientParams) (*IdentityClient, error) {
-	row := q.queryRow(ctx, q.addClientStmt, addClient, arg.ClientID, arg.ClientSecret)
+	row := q.queryRow(ctx, q.addClientStmt, addClient,
+		arg.ClientID,
+		arg.ClientSecretHash,
+		arg.OwnerUserID,
+		arg.Name,
+		arg.CreatedAt,
+		arg.GrantTypes,
+		arg.AllowedScope,
+		arg.Public,
+		arg.AccessTokenLifetimeSeconds,
+		arg.RefreshTokenLifetimeSeconds,
+		arg.RegistrationAccessTokenHash,
+	)
 	var i IdentityClient
-	err := row.Scan(&i.ClientID, &i.ClientSecret)
+	err := row.Scan(
+		&i.ClientID,
+		&i.ServiceAccountUserID,
+		&i.ServiceAccountScope,
+		&i.ClientSecretHash,
+		&i.OwnerUserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.GrantTypes,
+		&i.AllowedScope,
+		&i.Public,
+		&i.AccessTokenLifetimeSeconds,
+		&i.RefreshTokenLifetimeSeconds,
+		&i.RegistrationAccessTokenHash,
+	)
 	return &i, err
 }
 
-const addIdentityUser = `-- name: AddIdentityUser :one
+const addClientRedirectURI = `-- name: AddClientRedirectURI :exec
+INSERT INTO
+	identity.client_redirect_uris (client_id, redirect_uri)
+VALUES
+	($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddClientRedirectURIParams struct {
+	ClientID    string
+	RedirectUri string
+}
+
+func (q *Queries) AddClientRedirectURI(ctx context.Context, arg AddClientRedirectURIParams) error {
+	_, err := q.exec(ctx, q.addClientRedirectURIStmt, addClientRedirectURI, arg.ClientID, arg.RedirectUri)
+	return err
+}
+
+const addDeviceCode = `-- name: AddDeviceCode :one
+INSERT INTO
+	identity.device_codes (
+		user_code,
+		client_id,
+		scope,
+		issued_at,
+		expires_at,
+		interval_seconds,
+		status,
+		device_code_hash
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+	user_code,
+	client_id,
+	scope,
+	issued_at,
+	expires_at,
+	interval_seconds,
+	last_polled_at,
+	status,
+	user_id,
+	device_code_hash
+`
+
+type AddDeviceCodeParams struct {
+	UserCode        string
+	ClientID        string
+	Scope           string
+	IssuedAt        time.Time
+	ExpiresAt       time.Time
+	IntervalSeconds int64
+	Status          string
+	DeviceCodeHash  []byte
+}
+
+func (q *Queries) AddDeviceCode(ctx context.Context, arg AddDeviceCodeParams) (*IdentityDeviceCode, error) {
+	row := q.queryRow(ctx, q.addDeviceCodeStmt, addDeviceCode,
+		arg.UserCode,
+		arg.ClientID,
+		arg.Scope,
+		arg.IssuedAt,
+		arg.ExpiresAt,
+		arg.IntervalSeconds,
+		arg.Status,
+		arg.DeviceCodeHash,
+	)
+	var i IdentityDeviceCode
+	err := row.Scan(
+		&i.UserCode,
+		&i.ClientID,
+		&i.Scope,
+		&i.IssuedAt,
+		&i.ExpiresAt,
+		&i.IntervalSeconds,
+		&i.LastPolledAt,
+		&i.Status,
+		&i.UserID,
+		&i.DeviceCodeHash,
+	)
+	return &i, err
+}
+
+const addFederatedIdentity = `-- name: AddFederatedIdentity :exec
 INSERT INTO
-	identity.users (user_id, username, email, password_hash)
+	identity.federated_identities (issuer, subject, user_id, created_at)
 VALUES
 	($1, $2, $3, $4)
+`
+
+type AddFederatedIdentityParams struct {
+	Issuer    string
+	Subject   string
+	UserID    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) AddFederatedIdentity(ctx context.Context, arg AddFederatedIdentityParams) error {
+	_, err := q.exec(ctx, q.addFederatedIdentityStmt, addFederatedIdentity,
+		arg.Issuer,
+		arg.Subject,
+		arg.UserID,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const addIdentityUser = `-- name: AddIdentityUser :one
+INSERT INTO
+	identity.users (
+		user_id,
+		username,
+		email,
+		password_hash,
+		email_verified
+	)
+VALUES
+	($1, $2, $3, $4, $5)
 RETURNING
 	user_id,
 	username,
 	email,
-	password_hash
+	password_hash,
+	email_verified
 `
 
 type AddIdentityUserParams struct {
-	UserID       string
-	Username     string
-	Email        string
-	PasswordHash []byte
+	UserID        string
+	Username      string
+	Email         string
+	PasswordHash  []byte
+	EmailVerified bool
 }
 
 func (q *Queries) AddIdentityUser(ctx context.Context, arg AddIdentityUserParams) (*IdentityUser, error) {
@@ -166,6 +423,7 @@ func (q *Queries) AddIdentityUser(ctx context.Context, arg AddIdentityUserParams
 		arg.Username,
 		arg.Email,
 		arg.PasswordHash,
+		arg.EmailVerified,
 	)
 	var i IdentityUser
 	err := row.Scan(
@@ -173,46 +431,327 @@ func (q *Queries) AddIdentityUser(ctx context.Context, arg AddIdentityUserParams
 		&i.Username,
 		&i.Email,
 		&i.PasswordHash,
+		&i.EmailVerified,
 	)
 	return &i, err
 }
 
-const addRefreshToken = `-- name: AddRefreshToken :one
+const addInvite = `-- name: AddInvite :exec
+INSERT INTO
+	identity.invites (
+		invite_id,
+		code_hash,
+		created_by,
+		created_at,
+		expires_at
+	)
+VALUES
+	($1, $2, $3, $4, $5)
+`
+
+type AddInviteParams struct {
+	InviteID  string
+	CodeHash  []byte
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (q *Queries) AddInvite(ctx context.Context, arg AddInviteParams) error {
+	_, err := q.exec(ctx, q.addInviteStmt, addInvite,
+		arg.InviteID,
+		arg.CodeHash,
+		arg.CreatedBy,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const addPasswordReset = `-- name: AddPasswordReset :exec
 INSERT INTO
-	identity.refresh_tokens (token_id, client_id, jwt, revoked)
+	identity.password_resets (reset_id, user_id, created_at, expires_at)
 VALUES
 	($1, $2, $3, $4)
+`
+
+type AddPasswordResetParams struct {
+	ResetID   string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (q *Queries) AddPasswordReset(ctx context.Context, arg AddPasswordResetParams) error {
+	_, err := q.exec(ctx, q.addPasswordResetStmt, addPasswordReset,
+		arg.ResetID,
+		arg.UserID,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const addPersonalAccessToken = `-- name: AddPersonalAccessToken :exec
+INSERT INTO
+	identity.personal_access_tokens (
+		token_id,
+		user_id,
+		name,
+		token_hash,
+		scope,
+		created_at,
+		expires_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7)
+`
+
+type AddPersonalAccessTokenParams struct {
+	TokenID   string
+	UserID    string
+	Name      string
+	TokenHash []byte
+	Scope     string
+	CreatedAt time.Time
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) AddPersonalAccessToken(ctx context.Context, arg AddPersonalAccessTokenParams) error {
+	_, err := q.exec(ctx, q.addPersonalAccessTokenStmt, addPersonalAccessToken,
+		arg.TokenID,
+		arg.UserID,
+		arg.Name,
+		arg.TokenHash,
+		arg.Scope,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const addRecoveryCode = `-- name: AddRecoveryCode :exec
+INSERT INTO
+	identity.user_recovery_codes (user_id, code_hash)
+VALUES
+	($1, $2)
+`
+
+type AddRecoveryCodeParams struct {
+	UserID   string
+	CodeHash []byte
+}
+
+func (q *Queries) AddRecoveryCode(ctx context.Context, arg AddRecoveryCodeParams) error {
+	_, err := q.exec(ctx, q.addRecoveryCodeStmt, addRecoveryCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const addRefreshToken = `-- name: AddRefreshToken :one
+INSERT INTO
+	identity.refresh_tokens (
+		token_id,
+		client_id,
+		revoked,
+		user_id,
+		scope,
+		issued_at,
+		family_id,
+		rotated,
+		token_hash,
+		expires_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING
 	token_id,
 	client_id,
-	jwt,
-	revoked
+	revoked,
+	user_id,
+	scope,
+	issued_at,
+	family_id,
+	rotated,
+	token_hash,
+	expires_at
 `
 
 type AddRefreshTokenParams struct {
-	TokenID  string
-	ClientID string
-	Jwt      string
-	Revoked  bool
+	TokenID   string
+	ClientID  string
+	Revoked   bool
+	UserID    string
+	Scope     string
+	IssuedAt  time.Time
+	FamilyID  string
+	Rotated   bool
+	TokenHash []byte
+	ExpiresAt sql.NullTime
 }
 
 func (q *Queries) AddRefreshToken(ctx context.Context, arg AddRefreshTokenParams) (*IdentityRefreshToken, error) {
 	row := q.queryRow(ctx, q.addRefreshTokenStmt, addRefreshToken,
 		arg.TokenID,
 		arg.ClientID,
-		arg.Jwt,
 		arg.Revoked,
+		arg.UserID,
+		arg.Scope,
+		arg.IssuedAt,
+		arg.FamilyID,
+		arg.Rotated,
+		arg.TokenHash,
+		arg.ExpiresAt,
 	)
 	var i IdentityRefreshToken
 	err := row.Scan(
 		&i.TokenID,
 		&i.ClientID,
-		&i.Jwt,
 		&i.Revoked,
+		&i.UserID,
+		&i.Scope,
+		&i.IssuedAt,
+		&i.FamilyID,
+		&i.Rotated,
+		&i.TokenHash,
+		&i.ExpiresAt,
 	)
 	return &i, err
 }
 
+const addWebAuthnChallenge = `-- name: AddWebAuthnChallenge :exec
+INSERT INTO
+	identity.webauthn_challenges (challenge, ceremony, user_id, expires_at)
+VALUES
+	($1, $2, $3, $4)
+`
+
+type AddWebAuthnChallengeParams struct {
+	Challenge string
+	Ceremony  string
+	UserID    sql.NullString
+	ExpiresAt time.Time
+}
+
+func (q *Queries) AddWebAuthnChallenge(ctx context.Context, arg AddWebAuthnChallengeParams) error {
+	_, err := q.exec(ctx, q.addWebAuthnChallengeStmt, addWebAuthnChallenge,
+		arg.Challenge,
+		arg.Ceremony,
+		arg.UserID,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const addWebAuthnCredential = `-- name: AddWebAuthnCredential :exec
+INSERT INTO
+	identity.webauthn_credentials (
+		credential_id,
+		user_id,
+		public_key,
+		attestation_type,
+		aaguid,
+		sign_count,
+		transports,
+		backup_eligible,
+		backup_state,
+		created_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+`
+
+type AddWebAuthnCredentialParams struct {
+	CredentialID    []byte
+	UserID          string
+	PublicKey       []byte
+	AttestationType string
+	Aaguid          []byte
+	SignCount       int64
+	Transports      string
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+}
+
+func (q *Queries) AddWebAuthnCredential(ctx context.Context, arg AddWebAuthnCredentialParams) error {
+	_, err := q.exec(ctx, q.addWebAuthnCredentialStmt, addWebAuthnCredential,
+		arg.CredentialID,
+		arg.UserID,
+		arg.PublicKey,
+		arg.AttestationType,
+		arg.Aaguid,
+		arg.SignCount,
+		arg.Transports,
+		arg.BackupEligible,
+		arg.BackupState,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const confirmUserTOTP = `-- name: ConfirmUserTOTP :execrows
+UPDATE identity.user_totp
+SET
+	confirmed_at = $2,
+	last_used_step = $3
+WHERE
+	user_id = $1
+	AND confirmed_at IS NULL
+`
+
+type ConfirmUserTOTPParams struct {
+	UserID       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+}
+
+func (q *Queries) ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (int64, error) {
+	result, err := q.exec(ctx, q.confirmUserTOTPStmt, confirmUserTOTP, arg.UserID, arg.ConfirmedAt, arg.LastUsedStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countPasswordResets = `-- name: CountPasswordResets :one
+SELECT
+	COUNT(*)
+FROM
+	identity.password_resets
+WHERE
+	user_id = $1
+	AND created_at > $2
+`
+
+type CountPasswordResetsParams struct {
+	UserID    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountPasswordResets(ctx context.Context, arg CountPasswordResetsParams) (int64, error) {
+	row := q.queryRow(ctx, q.countPasswordResetsStmt, countPasswordResets, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRecoveryCodes = `-- name: CountRecoveryCodes :one
+SELECT
+	COUNT(*)
+FROM
+	identity.user_recovery_codes
+WHERE
+	user_id = $1
+	AND used_at IS NULL
+`
+
+func (q *Queries) CountRecoveryCodes(ctx context.Context, userID string) (int64, error) {
+	row := q.queryRow(ctx, q.countRecoveryCodesStmt, countRecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteAccessTokenByID = `-- name: DeleteAccessTokenByID :exec
 DELETE FROM identity.access_tokens
 WHERE
@@ -235,6 +774,28 @@ func (q *Queries) DeleteClientByID(ctx context.Context, clientID string) error {
 	return err
 }
 
+const deleteClientRedirectURIs = `-- name: DeleteClientRedirectURIs :exec
+DELETE FROM identity.client_redirect_uris
+WHERE
+	client_id = $1
+`
+
+func (q *Queries) DeleteClientRedirectURIs(ctx context.Context, clientID string) error {
+	_, err := q.exec(ctx, q.deleteClientRedirectURIsStmt, deleteClientRedirectURIs, clientID)
+	return err
+}
+
+const deleteExpiredWebAuthnChallenges = `-- name: DeleteExpiredWebAuthnChallenges :exec
+DELETE FROM identity.webauthn_challenges
+WHERE
+	expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredWebAuthnChallenges(ctx context.Context, expiresAt time.Time) error {
+	_, err := q.exec(ctx, q.deleteExpiredWebAuthnChallengesStmt, deleteExpiredWebAuthnChallenges, expiresAt)
+	return err
+}
+
 const deleteIdentityUserByID = `-- name: DeleteIdentityUserByID :exec
 DELETE FROM identity.users
 WHERE
@@ -246,64 +807,340 @@ func (q *Queries) DeleteIdentityUserByID(ctx context.Context, userID string) err
 	return err
 }
 
-const deleteRefreshTokenByID = `-- name: DeleteRefreshTokenByID :exec
-DELETE FROM identity.refresh_tokens
+const deleteInvite = `-- name: DeleteInvite :execrows
+DELETE FROM identity.invites
 WHERE
-	token_id = $1
+	invite_id = $1
 `
 
-func (q *Queries) DeleteRefreshTokenByID(ctx context.Context, tokenID string) error {
-	_, err := q.exec(ctx, q.deleteRefreshTokenByIDStmt, deleteRefreshTokenByID, tokenID)
-	return err
+func (q *Queries) DeleteInvite(ctx context.Context, inviteID string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteInviteStmt, deleteInvite, inviteID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const getAccessTokenByJWT = `-- name: GetAccessTokenByJWT :one
-SELECT
-	token_id,
-	refresh_token_id,
-	client_id,
-	user_id,
-	jwt,
-	revoked,
-	expires_in_seconds,
-	issued_at,
-	scope,
-	type
-FROM
-	identity.access_tokens
+const deleteLoginAttempts = `-- name: DeleteLoginAttempts :exec
+DELETE FROM identity.login_attempts
 WHERE
-	jwt = $1
-LIMIT
-	1
+	kind = $1
+	AND subject = $2
 `
 
-type GetAccessTokenByJWTRow struct {
-	TokenID          string
-	RefreshTokenID   sql.NullString
+type DeleteLoginAttemptsParams struct {
+	Kind    string
+	Subject string
+}
+
+func (q *Queries) DeleteLoginAttempts(ctx context.Context, arg DeleteLoginAttemptsParams) error {
+	_, err := q.exec(ctx, q.deleteLoginAttemptsStmt, deleteLoginAttempts, arg.Kind, arg.Subject)
+	return err
+}
+
+const deletePasswordResets = `-- name: DeletePasswordResets :exec
+DELETE FROM identity.password_resets
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) DeletePasswordResets(ctx context.Context, userID string) error {
+	_, err := q.exec(ctx, q.deletePasswordResetsStmt, deletePasswordResets, userID)
+	return err
+}
+
+const deletePersonalAccessToken = `-- name: DeletePersonalAccessToken :execrows
+DELETE FROM identity.personal_access_tokens
+WHERE
+	user_id = $1
+	AND token_id = $2
+`
+
+type DeletePersonalAccessTokenParams struct {
+	UserID  string
+	TokenID string
+}
+
+func (q *Queries) DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error) {
+	result, err := q.exec(ctx, q.deletePersonalAccessTokenStmt, deletePersonalAccessToken, arg.UserID, arg.TokenID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePersonalAccessTokens = `-- name: DeletePersonalAccessTokens :exec
+DELETE FROM identity.personal_access_tokens
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) DeletePersonalAccessTokens(ctx context.Context, userID string) error {
+	_, err := q.exec(ctx, q.deletePersonalAccessTokensStmt, deletePersonalAccessTokens, userID)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM identity.user_recovery_codes
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	_, err := q.exec(ctx, q.deleteRecoveryCodesStmt, deleteRecoveryCodes, userID)
+	return err
+}
+
+const deleteRefreshTokenByID = `-- name: DeleteRefreshTokenByID :exec
+DELETE FROM identity.refresh_tokens
+WHERE
+	token_id = $1
+`
+
+func (q *Queries) DeleteRefreshTokenByID(ctx context.Context, tokenID string) error {
+	_, err := q.exec(ctx, q.deleteRefreshTokenByIDStmt, deleteRefreshTokenByID, tokenID)
+	return err
+}
+
+const deleteUserTOTP = `-- name: DeleteUserTOTP :exec
+DELETE FROM identity.user_totp
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) DeleteUserTOTP(ctx context.Context, userID string) error {
+	_, err := q.exec(ctx, q.deleteUserTOTPStmt, deleteUserTOTP, userID)
+	return err
+}
+
+const deleteWebAuthnCredential = `-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM identity.webauthn_credentials
+WHERE
+	user_id = $1
+	AND credential_id = $2
+`
+
+type DeleteWebAuthnCredentialParams struct {
+	UserID       string
+	CredentialID []byte
+}
+
+func (q *Queries) DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteWebAuthnCredentialStmt, deleteWebAuthnCredential, arg.UserID, arg.CredentialID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAccessTokenByID = `-- name: GetAccessTokenByID :one
+SELECT
+	token_id,
+	refresh_token_id,
+	client_id,
+	user_id,
+	revoked,
+	expires_in_seconds,
+	issued_at,
+	scope,
+	type,
+	grant_type,
+	token_hash
+FROM
+	identity.access_tokens
+WHERE
+	token_id = $1
+`
+
+type GetAccessTokenByIDRow struct {
+	TokenID          string
+	RefreshTokenID   sql.NullString
 	ClientID         string
 	UserID           string
-	Jwt              string
 	Revoked          bool
 	ExpiresInSeconds int64
 	IssuedAt         time.Time
 	Scope            string
 	Type             string
+	GrantType        string
+	TokenHash        []byte
 }
 
-func (q *Queries) GetAccessTokenByJWT(ctx context.Context, jwt string) (*GetAccessTokenByJWTRow, error) {
-	row := q.queryRow(ctx, q.getAccessTokenByJWTStmt, getAccessTokenByJWT, jwt)
-	var i GetAccessTokenByJWTRow
+func (q *Queries) GetAccessTokenByID(ctx context.Context, tokenID string) (*GetAccessTokenByIDRow, error) {
+	row := q.queryRow(ctx, q.getAccessTokenByIDStmt, getAccessTokenByID, tokenID)
+	var i GetAccessTokenByIDRow
 	err := row.Scan(
 		&i.TokenID,
 		&i.RefreshTokenID,
 		&i.ClientID,
 		&i.UserID,
-		&i.Jwt,
 		&i.Revoked,
 		&i.ExpiresInSeconds,
 		&i.IssuedAt,
 		&i.Scope,
 		&i.Type,
+		&i.GrantType,
+		&i.TokenHash,
+	)
+	return &i, err
+}
+
+const getAccessTokenFamilyID = `-- name: GetAccessTokenFamilyID :one
+SELECT
+	r.family_id
+FROM
+	identity.access_tokens AS a
+	JOIN identity.refresh_tokens AS r ON r.token_id = a.refresh_token_id
+WHERE
+	a.token_id = $1
+`
+
+func (q *Queries) GetAccessTokenFamilyID(ctx context.Context, tokenID string) (string, error) {
+	row := q.queryRow(ctx, q.getAccessTokenFamilyIDStmt, getAccessTokenFamilyID, tokenID)
+	var family_id string
+	err := row.Scan(&family_id)
+	return family_id, err
+}
+
+const getActiveSessions = `-- name: GetActiveSessions :many
+SELECT
+	s.family_id,
+	s.user_id,
+	s.client_id,
+	s.user_agent,
+	s.client_ip,
+	s.created_at,
+	s.last_used_at,
+	c.name AS client_name
+FROM
+	identity.sessions AS s
+	JOIN identity.clients AS c ON c.client_id = s.client_id
+WHERE
+	s.user_id = $1
+	AND EXISTS (
+		SELECT
+			1
+		FROM
+			identity.refresh_tokens AS r
+		WHERE
+			r.family_id = s.family_id
+			AND NOT r.revoked
+			AND (
+				r.expires_at IS NULL
+				OR r.expires_at > $2
+			)
+	)
+ORDER BY
+	s.last_used_at DESC
+`
+
+type GetActiveSessionsParams struct {
+	UserID    string
+	ExpiresAt sql.NullTime
+}
+
+type GetActiveSessionsRow struct {
+	FamilyID   string
+	UserID     string
+	ClientID   string
+	UserAgent  string
+	ClientIp   string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ClientName string
+}
+
+func (q *Queries) GetActiveSessions(ctx context.Context, arg GetActiveSessionsParams) ([]*GetActiveSessionsRow, error) {
+	rows, err := q.query(ctx, q.getActiveSessionsStmt, getActiveSessions, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*GetActiveSessionsRow
+	for rows.Next() {
+		var i GetActiveSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserID,
+			&i.ClientID,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ClientName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAppUserByID = `-- name: GetAppUserByID :one
+SELECT
+	user_id,
+	is_admin,
+	username
+FROM
+	wallabago.users
+WHERE
+	user_id = $1
+LIMIT
+	1
+`
+
+func (q *Queries) GetAppUserByID(ctx context.Context, userID string) (*WallabagoUser, error) {
+	row := q.queryRow(ctx, q.getAppUserByIDStmt, getAppUserByID, userID)
+	var i WallabagoUser
+	err := row.Scan(&i.UserID, &i.IsAdmin, &i.Username)
+	return &i, err
+}
+
+const getAuthorizationCode = `-- name: GetAuthorizationCode :one
+SELECT
+	client_id,
+	user_id,
+	redirect_uri,
+	scope,
+	code_challenge,
+	code_challenge_method,
+	issued_at,
+	expires_at,
+	used,
+	token_family_id,
+	code_hash,
+	nonce
+FROM
+	identity.authorization_codes
+WHERE
+	code_hash = $1
+LIMIT
+	1
+FOR UPDATE
+`
+
+func (q *Queries) GetAuthorizationCode(ctx context.Context, codeHash []byte) (*IdentityAuthorizationCode, error) {
+	row := q.queryRow(ctx, q.getAuthorizationCodeStmt, getAuthorizationCode, codeHash)
+	var i IdentityAuthorizationCode
+	err := row.Scan(
+		&i.ClientID,
+		&i.UserID,
+		&i.RedirectUri,
+		&i.Scope,
+		&i.CodeChallenge,
+		&i.CodeChallengeMethod,
+		&i.IssuedAt,
+		&i.ExpiresAt,
+		&i.Used,
+		&i.TokenFamilyID,
+		&i.CodeHash,
+		&i.Nonce,
 	)
 	return &i, err
 }
@@ -342,7 +1179,18 @@ func (q *Queries) GetBoostrapConditions(ctx context.Context) ([]*WallabagoBootst
 const getClientByID = `-- name: GetClientByID :one
 SELECT
 	client_id,
-	client_secret
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
 FROM
 	identity.clients
 WHERE
@@ -354,62 +1202,711 @@ LIMIT
 func (q *Queries) GetClientByID(ctx context.Context, clientID string) (*IdentityClient, error) {
 	row := q.queryRow(ctx, q.getClientByIDStmt, getClientByID, clientID)
 	var i IdentityClient
-	err := row.Scan(&i.ClientID, &i.ClientSecret)
+	err := row.Scan(
+		&i.ClientID,
+		&i.ServiceAccountUserID,
+		&i.ServiceAccountScope,
+		&i.ClientSecretHash,
+		&i.OwnerUserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.GrantTypes,
+		&i.AllowedScope,
+		&i.Public,
+		&i.AccessTokenLifetimeSeconds,
+		&i.RefreshTokenLifetimeSeconds,
+		&i.RegistrationAccessTokenHash,
+	)
 	return &i, err
 }
 
-const getIdentityUserByUsername = `-- name: GetIdentityUserByUsername :one
+const getClientRedirectURIs = `-- name: GetClientRedirectURIs :many
+SELECT
+	redirect_uri
+FROM
+	identity.client_redirect_uris
+WHERE
+	client_id = $1
+ORDER BY
+	redirect_uri
+`
+
+func (q *Queries) GetClientRedirectURIs(ctx context.Context, clientID string) ([]string, error) {
+	rows, err := q.query(ctx, q.getClientRedirectURIsStmt, getClientRedirectURIs, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var redirect_uri string
+		if err := rows.Scan(&redirect_uri); err != nil {
+			return nil, err
+		}
+		items = append(items, redirect_uri)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClients = `-- name: GetClients :many
+SELECT
+	client_id,
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
+FROM
+	identity.clients
+ORDER BY
+	created_at,
+	client_id
+`
+
+func (q *Queries) GetClients(ctx context.Context) ([]*IdentityClient, error) {
+	rows, err := q.query(ctx, q.getClientsStmt, getClients)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*IdentityClient
+	for rows.Next() {
+		var i IdentityClient
+		if err := rows.Scan(
+			&i.ClientID,
+			&i.ServiceAccountUserID,
+			&i.ServiceAccountScope,
+			&i.ClientSecretHash,
+			&i.OwnerUserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.GrantTypes,
+			&i.AllowedScope,
+			&i.Public,
+			&i.AccessTokenLifetimeSeconds,
+			&i.RefreshTokenLifetimeSeconds,
+			&i.RegistrationAccessTokenHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClientsByOwnerUserID = `-- name: GetClientsByOwnerUserID :many
+SELECT
+	client_id,
+	service_account_user_id,
+	service_account_scope,
+	client_secret_hash,
+	owner_user_id,
+	name,
+	created_at,
+	grant_types,
+	allowed_scope,
+	public,
+	access_token_lifetime_seconds,
+	refresh_token_lifetime_seconds,
+	registration_access_token_hash
+FROM
+	identity.clients
+WHERE
+	owner_user_id = $1
+ORDER BY
+	created_at,
+	client_id
+`
+
+func (q *Queries) GetClientsByOwnerUserID(ctx context.Context, ownerUserID sql.NullString) ([]*IdentityClient, error) {
+	rows, err := q.query(ctx, q.getClientsByOwnerUserIDStmt, getClientsByOwnerUserID, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*IdentityClient
+	for rows.Next() {
+		var i IdentityClient
+		if err := rows.Scan(
+			&i.ClientID,
+			&i.ServiceAccountUserID,
+			&i.ServiceAccountScope,
+			&i.ClientSecretHash,
+			&i.OwnerUserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.GrantTypes,
+			&i.AllowedScope,
+			&i.Public,
+			&i.AccessTokenLifetimeSeconds,
+			&i.RefreshTokenLifetimeSeconds,
+			&i.RegistrationAccessTokenHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeviceCodeByDeviceCodeHash = `-- name: GetDeviceCodeByDeviceCodeHash :one
 SELECT
+	user_code,
+	client_id,
+	scope,
+	issued_at,
+	expires_at,
+	interval_seconds,
+	last_polled_at,
+	status,
 	user_id,
-	username,
-	email,
-	password_hash
+	device_code_hash
 FROM
-	identity.users
+	identity.device_codes
 WHERE
-	username = $1
+	device_code_hash = $1
 LIMIT
 	1
+FOR UPDATE
 `
 
-func (q *Queries) GetIdentityUserByUsername(ctx context.Context, username string) (*IdentityUser, error) {
-	row := q.queryRow(ctx, q.getIdentityUserByUsernameStmt, getIdentityUserByUsername, username)
-	var i IdentityUser
+func (q *Queries) GetDeviceCodeByDeviceCodeHash(ctx context.Context, deviceCodeHash []byte) (*IdentityDeviceCode, error) {
+	row := q.queryRow(ctx, q.getDeviceCodeByDeviceCodeHashStmt, getDeviceCodeByDeviceCodeHash, deviceCodeHash)
+	var i IdentityDeviceCode
 	err := row.Scan(
+		&i.UserCode,
+		&i.ClientID,
+		&i.Scope,
+		&i.IssuedAt,
+		&i.ExpiresAt,
+		&i.IntervalSeconds,
+		&i.LastPolledAt,
+		&i.Status,
 		&i.UserID,
-		&i.Username,
-		&i.Email,
-		&i.PasswordHash,
+		&i.DeviceCodeHash,
 	)
 	return &i, err
 }
 
-const getRefreshTokenByJWT = `-- name: GetRefreshTokenByJWT :one
+const getDeviceCodeByUserCode = `-- name: GetDeviceCodeByUserCode :one
 SELECT
-	token_id,
+	user_code,
 	client_id,
-	jwt,
-	revoked
+	scope,
+	issued_at,
+	expires_at,
+	interval_seconds,
+	last_polled_at,
+	status,
+	user_id,
+	device_code_hash
 FROM
-	identity.refresh_tokens
+	identity.device_codes
 WHERE
-	jwt = $1
+	user_code = $1
 LIMIT
 	1
+FOR UPDATE
 `
 
-func (q *Queries) GetRefreshTokenByJWT(ctx context.Context, jwt string) (*IdentityRefreshToken, error) {
-	row := q.queryRow(ctx, q.getRefreshTokenByJWTStmt, getRefreshTokenByJWT, jwt)
-	var i IdentityRefreshToken
+func (q *Queries) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*IdentityDeviceCode, error) {
+	row := q.queryRow(ctx, q.getDeviceCodeByUserCodeStmt, getDeviceCodeByUserCode, userCode)
+	var i IdentityDeviceCode
 	err := row.Scan(
-		&i.TokenID,
+		&i.UserCode,
 		&i.ClientID,
-		&i.Jwt,
-		&i.Revoked,
+		&i.Scope,
+		&i.IssuedAt,
+		&i.ExpiresAt,
+		&i.IntervalSeconds,
+		&i.LastPolledAt,
+		&i.Status,
+		&i.UserID,
+		&i.DeviceCodeHash,
 	)
 	return &i, err
 }
 
+const getFederatedIdentity = `-- name: GetFederatedIdentity :one
+SELECT
+	issuer,
+	subject,
+	user_id,
+	created_at
+FROM
+	identity.federated_identities
+WHERE
+	issuer = $1
+	AND subject = $2
+LIMIT
+	1
+`
+
+type GetFederatedIdentityParams struct {
+	Issuer  string
+	Subject string
+}
+
+func (q *Queries) GetFederatedIdentity(ctx context.Context, arg GetFederatedIdentityParams) (*IdentityFederatedIdentity, error) {
+	row := q.queryRow(ctx, q.getFederatedIdentityStmt, getFederatedIdentity, arg.Issuer, arg.Subject)
+	var i IdentityFederatedIdentity
+	err := row.Scan(
+		&i.Issuer,
+		&i.Subject,
+		&i.UserID,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const getIdentityUserByEmail = `-- name: GetIdentityUserByEmail :one
+SELECT
+	user_id,
+	username,
+	email,
+	password_hash,
+	email_verified
+FROM
+	identity.users
+WHERE
+	email = $1
+LIMIT
+	1
+`
+
+func (q *Queries) GetIdentityUserByEmail(ctx context.Context, email string) (*IdentityUser, error) {
+	row := q.queryRow(ctx, q.getIdentityUserByEmailStmt, getIdentityUserByEmail, email)
+	var i IdentityUser
+	err := row.Scan(
+		&i.UserID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.EmailVerified,
+	)
+	return &i, err
+}
+
+const getIdentityUserByID = `-- name: GetIdentityUserByID :one
+SELECT
+	user_id,
+	username,
+	email,
+	password_hash,
+	email_verified
+FROM
+	identity.users
+WHERE
+	user_id = $1
+LIMIT
+	1
+`
+
+func (q *Queries) GetIdentityUserByID(ctx context.Context, userID string) (*IdentityUser, error) {
+	row := q.queryRow(ctx, q.getIdentityUserByIDStmt, getIdentityUserByID, userID)
+	var i IdentityUser
+	err := row.Scan(
+		&i.UserID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.EmailVerified,
+	)
+	return &i, err
+}
+
+const getIdentityUserByUsername = `-- name: GetIdentityUserByUsername :one
+SELECT
+	user_id,
+	username,
+	email,
+	password_hash,
+	email_verified
+FROM
+	identity.users
+WHERE
+	username = $1
+LIMIT
+	1
+`
+
+func (q *Queries) GetIdentityUserByUsername(ctx context.Context, username string) (*IdentityUser, error) {
+	row := q.queryRow(ctx, q.getIdentityUserByUsernameStmt, getIdentityUserByUsername, username)
+	var i IdentityUser
+	err := row.Scan(
+		&i.UserID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.EmailVerified,
+	)
+	return &i, err
+}
+
+const getInvites = `-- name: GetInvites :many
+SELECT
+	invite_id, code_hash, created_by, created_at, expires_at, used_at
+FROM
+	identity.invites
+ORDER BY
+	created_at DESC
+`
+
+func (q *Queries) GetInvites(ctx context.Context) ([]*IdentityInvite, error) {
+	rows, err := q.query(ctx, q.getInvitesStmt, getInvites)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*IdentityInvite
+	for rows.Next() {
+		var i IdentityInvite
+		if err := rows.Scan(
+			&i.InviteID,
+			&i.CodeHash,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.UsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLoginAttempts = `-- name: GetLoginAttempts :one
+SELECT
+	kind,
+	subject,
+	failed_attempts,
+	last_failed_at,
+	locked_until
+FROM
+	identity.login_attempts
+WHERE
+	kind = $1
+	AND subject = $2
+LIMIT
+	1
+FOR UPDATE
+`
+
+type GetLoginAttemptsParams struct {
+	Kind    string
+	Subject string
+}
+
+func (q *Queries) GetLoginAttempts(ctx context.Context, arg GetLoginAttemptsParams) (*IdentityLoginAttempt, error) {
+	row := q.queryRow(ctx, q.getLoginAttemptsStmt, getLoginAttempts, arg.Kind, arg.Subject)
+	var i IdentityLoginAttempt
+	err := row.Scan(
+		&i.Kind,
+		&i.Subject,
+		&i.FailedAttempts,
+		&i.LastFailedAt,
+		&i.LockedUntil,
+	)
+	return &i, err
+}
+
+const getPersonalAccessTokenByHash = `-- name: GetPersonalAccessTokenByHash :one
+SELECT
+	token_id, user_id, name, token_hash, scope, created_at, expires_at, last_used_at
+FROM
+	identity.personal_access_tokens
+WHERE
+	token_hash = $1
+`
+
+func (q *Queries) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash []byte) (*IdentityPersonalAccessToken, error) {
+	row := q.queryRow(ctx, q.getPersonalAccessTokenByHashStmt, getPersonalAccessTokenByHash, tokenHash)
+	var i IdentityPersonalAccessToken
+	err := row.Scan(
+		&i.TokenID,
+		&i.UserID,
+		&i.Name,
+		&i.TokenHash,
+		&i.Scope,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+	)
+	return &i, err
+}
+
+const getPersonalAccessTokens = `-- name: GetPersonalAccessTokens :many
+SELECT
+	token_id, user_id, name, token_hash, scope, created_at, expires_at, last_used_at
+FROM
+	identity.personal_access_tokens
+WHERE
+	user_id = $1
+ORDER BY
+	created_at
+`
+
+func (q *Queries) GetPersonalAccessTokens(ctx context.Context, userID string) ([]*IdentityPersonalAccessToken, error) {
+	rows, err := q.query(ctx, q.getPersonalAccessTokensStmt, getPersonalAccessTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*IdentityPersonalAccessToken
+	for rows.Next() {
+		var i IdentityPersonalAccessToken
+		if err := rows.Scan(
+			&i.TokenID,
+			&i.UserID,
+			&i.Name,
+			&i.TokenHash,
+			&i.Scope,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokenByID = `-- name: GetRefreshTokenByID :one
+SELECT
+	token_id,
+	client_id,
+	revoked,
+	user_id,
+	scope,
+	issued_at,
+	family_id,
+	rotated,
+	token_hash,
+	expires_at
+FROM
+	identity.refresh_tokens
+WHERE
+	token_id = $1
+`
+
+func (q *Queries) GetRefreshTokenByID(ctx context.Context, tokenID string) (*IdentityRefreshToken, error) {
+	row := q.queryRow(ctx, q.getRefreshTokenByIDStmt, getRefreshTokenByID, tokenID)
+	var i IdentityRefreshToken
+	err := row.Scan(
+		&i.TokenID,
+		&i.ClientID,
+		&i.Revoked,
+		&i.UserID,
+		&i.Scope,
+		&i.IssuedAt,
+		&i.FamilyID,
+		&i.Rotated,
+		&i.TokenHash,
+		&i.ExpiresAt,
+	)
+	return &i, err
+}
+
+const getSession = `-- name: GetSession :one
+SELECT
+	family_id, user_id, client_id, user_agent, client_ip, created_at, last_used_at
+FROM
+	identity.sessions
+WHERE
+	family_id = $1
+`
+
+func (q *Queries) GetSession(ctx context.Context, familyID string) (*IdentitySession, error) {
+	row := q.queryRow(ctx, q.getSessionStmt, getSession, familyID)
+	var i IdentitySession
+	err := row.Scan(
+		&i.FamilyID,
+		&i.UserID,
+		&i.ClientID,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return &i, err
+}
+
+const getSetting = `-- name: GetSetting :one
+SELECT
+	value
+FROM
+	identity.settings
+WHERE
+	name = $1
+`
+
+func (q *Queries) GetSetting(ctx context.Context, name string) (string, error) {
+	row := q.queryRow(ctx, q.getSettingStmt, getSetting, name)
+	var value string
+	err := row.Scan(&value)
+	return value, err
+}
+
+const getUserTOTP = `-- name: GetUserTOTP :one
+SELECT
+	user_id,
+	secret,
+	confirmed_at,
+	last_used_step,
+	created_at
+FROM
+	identity.user_totp
+WHERE
+	user_id = $1
+LIMIT
+	1
+`
+
+func (q *Queries) GetUserTOTP(ctx context.Context, userID string) (*IdentityUserTotp, error) {
+	row := q.queryRow(ctx, q.getUserTOTPStmt, getUserTOTP, userID)
+	var i IdentityUserTotp
+	err := row.Scan(
+		&i.UserID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const getWebAuthnCredential = `-- name: GetWebAuthnCredential :one
+SELECT
+	credential_id, user_id, public_key, attestation_type, aaguid, sign_count, transports, backup_eligible, backup_state, created_at, last_used_at
+FROM
+	identity.webauthn_credentials
+WHERE
+	credential_id = $1
+`
+
+func (q *Queries) GetWebAuthnCredential(ctx context.Context, credentialID []byte) (*IdentityWebauthnCredential, error) {
+	row := q.queryRow(ctx, q.getWebAuthnCredentialStmt, getWebAuthnCredential, credentialID)
+	var i IdentityWebauthnCredential
+	err := row.Scan(
+		&i.CredentialID,
+		&i.UserID,
+		&i.PublicKey,
+		&i.AttestationType,
+		&i.Aaguid,
+		&i.SignCount,
+		&i.Transports,
+		&i.BackupEligible,
+		&i.BackupState,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return &i, err
+}
+
+const getWebAuthnCredentials = `-- name: GetWebAuthnCredentials :many
+SELECT
+	credential_id, user_id, public_key, attestation_type, aaguid, sign_count, transports, backup_eligible, backup_state, created_at, last_used_at
+FROM
+	identity.webauthn_credentials
+WHERE
+	user_id = $1
+ORDER BY
+	created_at
+`
+
+func (q *Queries) GetWebAuthnCredentials(ctx context.Context, userID string) ([]*IdentityWebauthnCredential, error) {
+	rows, err := q.query(ctx, q.getWebAuthnCredentialsStmt, getWebAuthnCredentials, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*IdentityWebauthnCredential
+	for rows.Next() {
+		var i IdentityWebauthnCredential
+		if err := rows.Scan(
+			&i.CredentialID,
+			&i.UserID,
+			&i.PublicKey,
+			&i.AttestationType,
+			&i.Aaguid,
+			&i.SignCount,
+			&i.Transports,
+			&i.BackupEligible,
+			&i.BackupState,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAuthorizationCodeUsed = `-- name: MarkAuthorizationCodeUsed :exec
+UPDATE identity.authorization_codes
+SET
+	used = TRUE,
+	token_family_id = $2
+WHERE
+	code_hash = $1
+`
+
+type MarkAuthorizationCodeUsedParams struct {
+	CodeHash      []byte
+	TokenFamilyID sql.NullString
+}
+
+func (q *Queries) MarkAuthorizationCodeUsed(ctx context.Context, arg MarkAuthorizationCodeUsedParams) error {
+	_, err := q.exec(ctx, q.markAuthorizationCodeUsedStmt, markAuthorizationCodeUsed, arg.CodeHash, arg.TokenFamilyID)
+	return err
+}
+
 const markBootstrapConditionSatisfied = `-- name: MarkBootstrapConditionSatisfied :one
 INSERT INTO
 	wallabago.bootstrap (condition_name, satisfied)
@@ -441,12 +1938,13 @@ RETURNING
 	refresh_token_id,
 	client_id,
 	user_id,
-	jwt,
 	revoked,
 	expires_in_seconds,
 	issued_at,
 	scope,
-	type
+	type,
+	grant_type,
+	token_hash
 `
 
 type RevokeAccessTokenByIDRow struct {
@@ -454,12 +1952,13 @@ type RevokeAccessTokenByIDRow struct {
 	RefreshTokenID   sql.NullString
 	ClientID         string
 	UserID           string
-	Jwt              string
 	Revoked          bool
 	ExpiresInSeconds int64
 	IssuedAt         time.Time
 	Scope            string
 	Type             string
+	GrantType        string
+	TokenHash        []byte
 }
 
 func (q *Queries) RevokeAccessTokenByID(ctx context.Context, tokenID string) (*RevokeAccessTokenByIDRow, error) {
@@ -470,16 +1969,50 @@ func (q *Queries) RevokeAccessTokenByID(ctx context.Context, tokenID string) (*R
 		&i.RefreshTokenID,
 		&i.ClientID,
 		&i.UserID,
-		&i.Jwt,
 		&i.Revoked,
 		&i.ExpiresInSeconds,
 		&i.IssuedAt,
 		&i.Scope,
 		&i.Type,
+		&i.GrantType,
+		&i.TokenHash,
 	)
 	return &i, err
 }
 
+const revokeAccessTokensByRefreshTokenFamilyID = `-- name: RevokeAccessTokensByRefreshTokenFamilyID :exec
+UPDATE identity.access_tokens
+SET
+	revoked = TRUE
+WHERE
+	refresh_token_id IN (
+		SELECT
+			token_id
+		FROM
+			identity.refresh_tokens
+		WHERE
+			family_id = $1
+	)
+`
+
+func (q *Queries) RevokeAccessTokensByRefreshTokenFamilyID(ctx context.Context, familyID string) error {
+	_, err := q.exec(ctx, q.revokeAccessTokensByRefreshTokenFamilyIDStmt, revokeAccessTokensByRefreshTokenFamilyID, familyID)
+	return err
+}
+
+const revokeAccessTokensByRefreshTokenID = `-- name: RevokeAccessTokensByRefreshTokenID :exec
+UPDATE identity.access_tokens
+SET
+	revoked = TRUE
+WHERE
+	refresh_token_id = $1
+`
+
+func (q *Queries) RevokeAccessTokensByRefreshTokenID(ctx context.Context, refreshTokenID sql.NullString) error {
+	_, err := q.exec(ctx, q.revokeAccessTokensByRefreshTokenIDStmt, revokeAccessTokensByRefreshTokenID, refreshTokenID)
+	return err
+}
+
 const revokeRefreshTokenByID = `-- name: RevokeRefreshTokenByID :one
 UPDATE identity.refresh_tokens
 SET
@@ -489,8 +2022,14 @@ WHERE
 RETURNING
 	token_id,
 	client_id,
-	jwt,
-	revoked
+	revoked,
+	user_id,
+	scope,
+	issued_at,
+	family_id,
+	rotated,
+	token_hash,
+	expires_at
 `
 
 func (q *Queries) RevokeRefreshTokenByID(ctx context.Context, tokenID string) (*IdentityRefreshToken, error) {
@@ -499,8 +2038,489 @@ func (q *Queries) RevokeRefreshTokenByID(ctx context.Context, tokenID string) (*
 	err := row.Scan(
 		&i.TokenID,
 		&i.ClientID,
-		&i.Jwt,
 		&i.Revoked,
+		&i.UserID,
+		&i.Scope,
+		&i.IssuedAt,
+		&i.FamilyID,
+		&i.Rotated,
+		&i.TokenHash,
+		&i.ExpiresAt,
+	)
+	return &i, err
+}
+
+const revokeRefreshTokensByFamilyID = `-- name: RevokeRefreshTokensByFamilyID :exec
+UPDATE identity.refresh_tokens
+SET
+	revoked = TRUE
+WHERE
+	family_id = $1
+`
+
+func (q *Queries) RevokeRefreshTokensByFamilyID(ctx context.Context, familyID string) error {
+	_, err := q.exec(ctx, q.revokeRefreshTokensByFamilyIDStmt, revokeRefreshTokensByFamilyID, familyID)
+	return err
+}
+
+const rotateRefreshTokenByID = `-- name: RotateRefreshTokenByID :execrows
+UPDATE identity.refresh_tokens
+SET
+	revoked = TRUE,
+	rotated = TRUE
+WHERE
+	token_id = $1
+	AND NOT rotated
+	AND NOT revoked
+`
+
+func (q *Queries) RotateRefreshTokenByID(ctx context.Context, tokenID string) (int64, error) {
+	result, err := q.exec(ctx, q.rotateRefreshTokenByIDStmt, rotateRefreshTokenByID, tokenID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setAppUserIsAdmin = `-- name: SetAppUserIsAdmin :exec
+UPDATE wallabago.users
+SET
+	is_admin = $2
+WHERE
+	user_id = $1
+`
+
+type SetAppUserIsAdminParams struct {
+	UserID  string
+	IsAdmin bool
+}
+
+func (q *Queries) SetAppUserIsAdmin(ctx context.Context, arg SetAppUserIsAdminParams) error {
+	_, err := q.exec(ctx, q.setAppUserIsAdminStmt, setAppUserIsAdmin, arg.UserID, arg.IsAdmin)
+	return err
+}
+
+const setClientSecretHash = `-- name: SetClientSecretHash :exec
+UPDATE identity.clients
+SET
+	client_secret_hash = $2
+WHERE
+	client_id = $1
+`
+
+type SetClientSecretHashParams struct {
+	ClientID         string
+	ClientSecretHash []byte
+}
+
+func (q *Queries) SetClientSecretHash(ctx context.Context, arg SetClientSecretHashParams) error {
+	_, err := q.exec(ctx, q.setClientSecretHashStmt, setClientSecretHash, arg.ClientID, arg.ClientSecretHash)
+	return err
+}
+
+const setClientServiceAccount = `-- name: SetClientServiceAccount :exec
+UPDATE identity.clients
+SET
+	service_account_user_id = $2,
+	service_account_scope = $3
+WHERE
+	client_id = $1
+`
+
+type SetClientServiceAccountParams struct {
+	ClientID             string
+	ServiceAccountUserID sql.NullString
+	ServiceAccountScope  string
+}
+
+func (q *Queries) SetClientServiceAccount(ctx context.Context, arg SetClientServiceAccountParams) error {
+	_, err := q.exec(ctx, q.setClientServiceAccountStmt, setClientServiceAccount, arg.ClientID, arg.ServiceAccountUserID, arg.ServiceAccountScope)
+	return err
+}
+
+const setDeviceCodeStatus = `-- name: SetDeviceCodeStatus :exec
+UPDATE identity.device_codes
+SET
+	status = $2,
+	user_id = $3
+WHERE
+	device_code_hash = $1
+`
+
+type SetDeviceCodeStatusParams struct {
+	DeviceCodeHash []byte
+	Status         string
+	UserID         sql.NullString
+}
+
+func (q *Queries) SetDeviceCodeStatus(ctx context.Context, arg SetDeviceCodeStatusParams) error {
+	_, err := q.exec(ctx, q.setDeviceCodeStatusStmt, setDeviceCodeStatus, arg.DeviceCodeHash, arg.Status, arg.UserID)
+	return err
+}
+
+const setIdentityUserEmailVerified = `-- name: SetIdentityUserEmailVerified :exec
+UPDATE identity.users
+SET
+	email_verified = TRUE
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) SetIdentityUserEmailVerified(ctx context.Context, userID string) error {
+	_, err := q.exec(ctx, q.setIdentityUserEmailVerifiedStmt, setIdentityUserEmailVerified, userID)
+	return err
+}
+
+const setIdentityUserPasswordHash = `-- name: SetIdentityUserPasswordHash :exec
+UPDATE identity.users
+SET
+	password_hash = $2
+WHERE
+	user_id = $1
+`
+
+type SetIdentityUserPasswordHashParams struct {
+	UserID       string
+	PasswordHash []byte
+}
+
+func (q *Queries) SetIdentityUserPasswordHash(ctx context.Context, arg SetIdentityUserPasswordHashParams) error {
+	_, err := q.exec(ctx, q.setIdentityUserPasswordHashStmt, setIdentityUserPasswordHash, arg.UserID, arg.PasswordHash)
+	return err
+}
+
+const setLoginAttempts = `-- name: SetLoginAttempts :exec
+INSERT INTO
+	identity.login_attempts (kind, subject, failed_attempts, last_failed_at, locked_until)
+VALUES
+	($1, $2, $3, $4, $5)
+ON CONFLICT (kind, subject) DO UPDATE
+SET
+	failed_attempts = EXCLUDED.failed_attempts,
+	last_failed_at = EXCLUDED.last_failed_at,
+	locked_until = EXCLUDED.locked_until
+`
+
+type SetLoginAttemptsParams struct {
+	Kind           string
+	Subject        string
+	FailedAttempts int32
+	LastFailedAt   time.Time
+	LockedUntil    sql.NullTime
+}
+
+func (q *Queries) SetLoginAttempts(ctx context.Context, arg SetLoginAttemptsParams) error {
+	_, err := q.exec(ctx, q.setLoginAttemptsStmt, setLoginAttempts,
+		arg.Kind,
+		arg.Subject,
+		arg.FailedAttempts,
+		arg.LastFailedAt,
+		arg.LockedUntil,
+	)
+	return err
+}
+
+const setSession = `-- name: SetSession :exec
+INSERT INTO
+	identity.sessions (
+		family_id,
+		user_id,
+		client_id,
+		user_agent,
+		client_ip,
+		created_at,
+		last_used_at
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (family_id) DO UPDATE
+SET
+	user_agent = excluded.user_agent,
+	client_ip = excluded.client_ip,
+	last_used_at = excluded.last_used_at
+`
+
+type SetSessionParams struct {
+	FamilyID   string
+	UserID     string
+	ClientID   string
+	UserAgent  string
+	ClientIp   string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+func (q *Queries) SetSession(ctx context.Context, arg SetSessionParams) error {
+	_, err := q.exec(ctx, q.setSessionStmt, setSession,
+		arg.FamilyID,
+		arg.UserID,
+		arg.ClientID,
+		arg.UserAgent,
+		arg.ClientIp,
+		arg.CreatedAt,
+		arg.LastUsedAt,
+	)
+	return err
+}
+
+const setSetting = `-- name: SetSetting :exec
+INSERT INTO
+	identity.settings (name, value)
+VALUES
+	($1, $2)
+ON CONFLICT (name) DO UPDATE
+SET
+	value = excluded.value
+`
+
+type SetSettingParams struct {
+	Name  string
+	Value string
+}
+
+func (q *Queries) SetSetting(ctx context.Context, arg SetSettingParams) error {
+	_, err := q.exec(ctx, q.setSettingStmt, setSetting, arg.Name, arg.Value)
+	return err
+}
+
+const setUserTOTP = `-- name: SetUserTOTP :exec
+INSERT INTO
+	identity.user_totp (user_id, secret, confirmed_at, last_used_step, created_at)
+VALUES
+	($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET
+	secret = EXCLUDED.secret,
+	confirmed_at = EXCLUDED.confirmed_at,
+	last_used_step = EXCLUDED.last_used_step,
+	created_at = EXCLUDED.created_at
+`
+
+type SetUserTOTPParams struct {
+	UserID       string
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+func (q *Queries) SetUserTOTP(ctx context.Context, arg SetUserTOTPParams) error {
+	_, err := q.exec(ctx, q.setUserTOTPStmt, setUserTOTP,
+		arg.UserID,
+		arg.Secret,
+		arg.ConfirmedAt,
+		arg.LastUsedStep,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const takeWebAuthnChallenge = `-- name: TakeWebAuthnChallenge :one
+DELETE FROM identity.webauthn_challenges
+WHERE
+	challenge = $1
+RETURNING
+	challenge, ceremony, user_id, expires_at
+`
+
+func (q *Queries) TakeWebAuthnChallenge(ctx context.Context, challenge string) (*IdentityWebauthnChallenge, error) {
+	row := q.queryRow(ctx, q.takeWebAuthnChallengeStmt, takeWebAuthnChallenge, challenge)
+	var i IdentityWebauthnChallenge
+	err := row.Scan(
+		&i.Challenge,
+		&i.Ceremony,
+		&i.UserID,
+		&i.ExpiresAt,
 	)
 	return &i, err
 }
+
+const updateClient = `-- name: UpdateClient :exec
+UPDATE identity.clients
+SET
+	client_secret_hash = $2,
+	name = $3,
+	grant_types = $4,
+	allowed_scope = $5,
+	public = $6
+WHERE
+	client_id = $1
+`
+
+type UpdateClientParams struct {
+	ClientID         string
+	ClientSecretHash []byte
+	Name             string
+	GrantTypes       string
+	AllowedScope     string
+	Public           bool
+}
+
+func (q *Queries) UpdateClient(ctx context.Context, arg UpdateClientParams) error {
+	_, err := q.exec(ctx, q.updateClientStmt, updateClient,
+		arg.ClientID,
+		arg.ClientSecretHash,
+		arg.Name,
+		arg.GrantTypes,
+		arg.AllowedScope,
+		arg.Public,
+	)
+	return err
+}
+
+const updateDeviceCodePolling = `-- name: UpdateDeviceCodePolling :exec
+UPDATE identity.device_codes
+SET
+	last_polled_at = $2,
+	interval_seconds = $3
+WHERE
+	device_code_hash = $1
+`
+
+type UpdateDeviceCodePollingParams struct {
+	DeviceCodeHash  []byte
+	LastPolledAt    sql.NullTime
+	IntervalSeconds int64
+}
+
+func (q *Queries) UpdateDeviceCodePolling(ctx context.Context, arg UpdateDeviceCodePollingParams) error {
+	_, err := q.exec(ctx, q.updateDeviceCodePollingStmt, updateDeviceCodePolling, arg.DeviceCodeHash, arg.LastPolledAt, arg.IntervalSeconds)
+	return err
+}
+
+const useInvite = `-- name: UseInvite :execrows
+UPDATE identity.invites
+SET
+	used_at = $2
+WHERE
+	code_hash = $1
+	AND used_at IS NULL
+	AND expires_at > $2
+`
+
+type UseInviteParams struct {
+	CodeHash []byte
+	UsedAt   sql.NullTime
+}
+
+func (q *Queries) UseInvite(ctx context.Context, arg UseInviteParams) (int64, error) {
+	result, err := q.exec(ctx, q.useInviteStmt, useInvite, arg.CodeHash, arg.UsedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :execrows
+UPDATE identity.password_resets
+SET
+	used_at = $2
+WHERE
+	reset_id = $1
+	AND used_at IS NULL
+	AND expires_at > $2
+`
+
+type UsePasswordResetParams struct {
+	ResetID string
+	UsedAt  sql.NullTime
+}
+
+func (q *Queries) UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (int64, error) {
+	result, err := q.exec(ctx, q.usePasswordResetStmt, usePasswordReset, arg.ResetID, arg.UsedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const usePersonalAccessToken = `-- name: UsePersonalAccessToken :exec
+UPDATE identity.personal_access_tokens
+SET
+	last_used_at = $2
+WHERE
+	token_id = $1
+`
+
+type UsePersonalAccessTokenParams struct {
+	TokenID    string
+	LastUsedAt sql.NullTime
+}
+
+func (q *Queries) UsePersonalAccessToken(ctx context.Context, arg UsePersonalAccessTokenParams) error {
+	_, err := q.exec(ctx, q.usePersonalAccessTokenStmt, usePersonalAccessToken, arg.TokenID, arg.LastUsedAt)
+	return err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE identity.user_recovery_codes
+SET
+	used_at = $3
+WHERE
+	user_id = $1
+	AND code_hash = $2
+	AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   string
+	CodeHash []byte
+	UsedAt   sql.NullTime
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.exec(ctx, q.useRecoveryCodeStmt, useRecoveryCode, arg.UserID, arg.CodeHash, arg.UsedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE identity.user_totp
+SET
+	last_used_step = $2
+WHERE
+	user_id = $1
+	AND last_used_step < $2
+`
+
+type UseUserTOTPStepParams struct {
+	UserID       string
+	LastUsedStep int64
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.exec(ctx, q.useUserTOTPStepStmt, useUserTOTPStep, arg.UserID, arg.LastUsedStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useWebAuthnCredential = `-- name: UseWebAuthnCredential :exec
+UPDATE identity.webauthn_credentials
+SET
+	sign_count = $2,
+	backup_state = $3,
+	last_used_at = $4
+WHERE
+	credential_id = $1
+`
+
+type UseWebAuthnCredentialParams struct {
+	CredentialID []byte
+	SignCount    int64
+	BackupState  bool
+	LastUsedAt   sql.NullTime
+}
+
+func (q *Queries) UseWebAuthnCredential(ctx context.Context, arg UseWebAuthnCredentialParams) error {
+	_, err := q.exec(ctx, q.useWebAuthnCredentialStmt, useWebAuthnCredential,
+		arg.CredentialID,
+		arg.SignCount,
+		arg.BackupState,
+		arg.LastUsedAt,
+	)
+	return err
+}
@@ -3,11 +3,11 @@ package engines
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type BootstrapStorage interface {
@@ -19,22 +19,30 @@ type BootstrapStorage interface {
 }
 
 type BootstrapEngine struct {
-	storage BootstrapStorage
+	storage   BootstrapStorage
+	passwords core.PasswordPolicy
 }
 
 func NewBoostrapEngine(
 	storage BootstrapStorage,
+	passwords core.PasswordPolicy,
 ) *BootstrapEngine {
 	return &BootstrapEngine{
-		storage: storage,
+		storage:   storage,
+		passwords: passwords,
 	}
 }
 
 func (e *BootstrapEngine) CreateAdminAccount(ctx context.Context, tx *sql.Tx, admin core.BootstrapAdminCredentials) error {
 	// TODO: get admin username and password from config?
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return errors.WithStack(err)
+	// without a password the admin logs in through the identity providers or resets the password
+	passwordHash := []byte{}
+	if admin.Password != "" {
+		var err error
+		passwordHash, err = e.passwords.Hash(admin.Password)
+		if err != nil {
+			return errors.WithMessage(err, "bootstrap admin password")
+		}
 	}
 
 	// create an identity with said username and password
@@ -45,7 +53,7 @@ func (e *BootstrapEngine) CreateAdminAccount(ctx context.Context, tx *sql.Tx, ad
 		PasswordHash: passwordHash,
 	}
 
-	err = e.storage.AddUserInfo(ctx, tx, adminUser)
+	err := e.storage.AddUserInfo(ctx, tx, adminUser)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -68,7 +76,14 @@ func (e *BootstrapEngine) CreateAdminAccount(ctx context.Context, tx *sql.Tx, ad
 }
 
 func (e *BootstrapEngine) CreateInitialClient(ctx context.Context, tx *sql.Tx, client core.Client) error {
-	err := e.storage.AddClient(ctx, tx, client)
+	secretHash, err := core.HashClientSecret(client.Secret)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	client.SecretHash = secretHash
+	client.CreatedAt = time.Now()
+
+	err = e.storage.AddClient(ctx, tx, client)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -0,0 +1,196 @@
+package federation
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// ldapAdminClaim carries the membership of the administrator group,
+// the group names are compared here since the distinguished names ignore the case.
+const ldapAdminClaim = "ldap_admin"
+
+// LDAPVerifier checks the passwords by binding as the user found in the directory.
+type LDAPVerifier struct {
+	config  core.LDAPConfig
+	timeout time.Duration
+	// tlsConfig is used for the ldaps:// and the StartTLS connections
+	tlsConfig *tls.Config
+}
+
+var _ managers.CredentialVerifier = (*LDAPVerifier)(nil)
+
+// NewLDAPVerifier creates the verifier, the missing attributes default to core.DefaultLDAPAttributes
+// and the missing filter matches the username attribute.
+func NewLDAPVerifier(config core.LDAPConfig, timeout time.Duration, tlsConfig *tls.Config) (*LDAPVerifier, error) {
+	location, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if location.Scheme != "ldap" && location.Scheme != "ldaps" {
+		return nil, errors.Errorf("unsupported ldap url scheme: %s", location.Scheme)
+	}
+	defaults := core.DefaultLDAPAttributes()
+	if config.Attributes.Username == "" {
+		config.Attributes.Username = defaults.Username
+	}
+	if config.Attributes.Email == "" {
+		config.Attributes.Email = defaults.Email
+	}
+	if config.Attributes.Groups == "" {
+		config.Attributes.Groups = defaults.Groups
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = fmt.Sprintf("(%s=%%s)", config.Attributes.Username)
+	}
+	if strings.Count(config.UserFilter, "%s") != 1 {
+		return nil, errors.New("ldap user filter has to contain a single %s for the username")
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: location.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	return &LDAPVerifier{
+		config:    config,
+		timeout:   timeout,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+func (v *LDAPVerifier) AdminMapping() core.AdminClaimMapping {
+	if v.config.AdminGroup == "" {
+		return core.AdminClaimMapping{}
+	}
+	return core.AdminClaimMapping{Claim: ldapAdminClaim}
+}
+
+func (v *LDAPVerifier) dial(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: v.timeout}
+	conn, err := ldap.DialURL(v.config.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(v.tlsConfig))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	timeout := v.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	conn.SetTimeout(timeout)
+	if v.config.StartTLS {
+		err = conn.StartTLS(v.tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+	return conn, nil
+}
+
+// searchUser binds as the service account and looks the user up, two entries
+// are returned at most since they are enough to tell that the username is ambiguous.
+func (v *LDAPVerifier) searchUser(conn *ldap.Conn, username string) ([]*ldap.Entry, error) {
+	if v.config.BindDN != "" {
+		err := conn.Bind(v.config.BindDN, v.config.BindPassword)
+		if err != nil {
+			return nil, errors.Wrap(err, "ldap service account bind failed")
+		}
+	}
+	attributes := v.config.Attributes
+	result, err := conn.Search(ldap.NewSearchRequest(
+		v.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(v.timeout.Seconds()),
+		false,
+		fmt.Sprintf(v.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{attributes.Username, attributes.Email, attributes.Groups},
+		nil,
+	))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return result.Entries, nil
+}
+
+// KnowsUsername searches for the user with the service account,
+// the ambiguous usernames are known as well.
+func (v *LDAPVerifier) KnowsUsername(ctx context.Context, username string) (bool, error) {
+	if username == "" {
+		return false, nil
+	}
+	conn, err := v.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	entries, err := v.searchUser(conn, username)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(entries) > 0, nil
+}
+
+// VerifyPassword searches for the user with the service account and then binds as the user.
+func (v *LDAPVerifier) VerifyPassword(ctx context.Context, username, password string) (*core.UpstreamClaims, error) {
+	// the empty password is an unauthenticated bind which most servers accept,
+	// see https://datatracker.ietf.org/doc/html/rfc4513#section-5.1.2
+	if username == "" || password == "" {
+		return nil, errors.WithStack(core.ErrInvalidCredentials)
+	}
+	conn, err := v.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries, err := v.searchUser(conn, username)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, errors.WithStack(core.ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, errors.WithStack(core.ErrInvalidCredentials)
+	}
+	entry := entries[0]
+	attributes := v.config.Attributes
+
+	err = conn.Bind(entry.DN, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, errors.WithStack(core.ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	isAdmin := false
+	for _, group := range entry.GetAttributeValues(attributes.Groups) {
+		if v.config.AdminGroup != "" && strings.EqualFold(group, v.config.AdminGroup) {
+			isAdmin = true
+		}
+	}
+	return &core.UpstreamClaims{
+		Issuer:            v.config.URL,
+		Subject:           entry.DN,
+		Email:             entry.GetAttributeValue(attributes.Email),
+		PreferredUsername: entry.GetAttributeValue(attributes.Username),
+		// the directory is run by the administrators, so its emails are trusted
+		EmailVerified: true,
+		Raw: map[string]any{
+			ldapAdminClaim: isAdmin,
+		},
+	}, nil
+}
@@ -0,0 +1,216 @@
+package federation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/federation"
+	"github.com/andriihomiak/wallabago/internal/federation/ldaptest"
+)
+
+const (
+	serviceDN       = "cn=wallabago,ou=services,dc=example,dc=com"
+	servicePassword = "service-secret"
+	adminGroupDN    = "cn=admins,ou=groups,dc=example,dc=com"
+)
+
+func newTestDirectory(t *testing.T) *ldaptest.Server {
+	t.Helper()
+	server, err := ldaptest.NewServer(
+		ldaptest.Entry{DN: serviceDN, Password: servicePassword},
+		ldaptest.Entry{
+			DN:       "uid=alice,ou=people,dc=example,dc=com",
+			Password: "alice-secret",
+			Attributes: map[string][]string{
+				"uid":      {"alice"},
+				"mail":     {"alice@example.com"},
+				"memberOf": {"CN=Admins,OU=Groups,DC=example,DC=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN:       "uid=bob,ou=people,dc=example,dc=com",
+			Password: "bob-secret",
+			Attributes: map[string][]string{
+				"uid":  {"bob"},
+				"mail": {"bob@example.com"},
+			},
+		},
+		// the same username twice can not tell the users apart
+		ldaptest.Entry{DN: "uid=twin,ou=people,dc=example,dc=com", Password: "twin-secret", Attributes: map[string][]string{"uid": {"twin"}}},
+		ldaptest.Entry{DN: "uid=twin,ou=contractors,dc=example,dc=com", Password: "twin-secret", Attributes: map[string][]string{"uid": {"twin"}}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestVerifier(t *testing.T, config core.LDAPConfig) *federation.LDAPVerifier {
+	t.Helper()
+	verifier, err := federation.NewLDAPVerifier(config, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return verifier
+}
+
+func TestLDAPVerifierVerifyPassword(t *testing.T) {
+	server := newTestDirectory(t)
+	verifier := newTestVerifier(t, core.LDAPConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: servicePassword,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(&(uid=*)(uid=%s))",
+		AdminGroup:   adminGroupDN,
+	})
+
+	cases := []struct {
+		username      string
+		password      string
+		expectedError error
+		expected      *core.UpstreamClaims
+	}{
+		{
+			username: "alice",
+			password: "alice-secret",
+			expected: &core.UpstreamClaims{
+				Issuer:            server.URL(),
+				Subject:           "uid=alice,ou=people,dc=example,dc=com",
+				Email:             "alice@example.com",
+				EmailVerified:     true,
+				PreferredUsername: "alice",
+			},
+		},
+		{
+			username: "bob",
+			password: "bob-secret",
+			expected: &core.UpstreamClaims{
+				Issuer:            server.URL(),
+				Subject:           "uid=bob,ou=people,dc=example,dc=com",
+				Email:             "bob@example.com",
+				EmailVerified:     true,
+				PreferredUsername: "bob",
+			},
+		},
+		{username: "alice", password: "bob-secret", expectedError: core.ErrInvalidCredentials},
+		{username: "alice", password: "", expectedError: core.ErrInvalidCredentials},
+		{username: "carol", password: "alice-secret", expectedError: core.ErrInvalidCredentials},
+		{username: "twin", password: "twin-secret", expectedError: core.ErrInvalidCredentials},
+		{username: "*", password: "alice-secret", expectedError: core.ErrInvalidCredentials},
+		{username: "alice)(uid=bob", password: "bob-secret", expectedError: core.ErrInvalidCredentials},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.username+"_"+testCase.password, func(t *testing.T) {
+			claims, err := verifier.VerifyPassword(context.Background(), testCase.username, testCase.password)
+			if testCase.expectedError != nil {
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error %s but got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if claims.Issuer != testCase.expected.Issuer || claims.Subject != testCase.expected.Subject ||
+				claims.Email != testCase.expected.Email || claims.EmailVerified != testCase.expected.EmailVerified ||
+				claims.PreferredUsername != testCase.expected.PreferredUsername {
+				t.Fatalf("Expected %#v but got %#v", testCase.expected, claims)
+			}
+		})
+	}
+}
+
+func TestLDAPVerifierAdminMapping(t *testing.T) {
+	server := newTestDirectory(t)
+	config := core.LDAPConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: servicePassword,
+		BaseDN:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+	}
+	if newTestVerifier(t, config).AdminMapping().Enabled() {
+		t.Fatalf("Expected admin mapping to be disabled without admin group")
+	}
+
+	config.AdminGroup = adminGroupDN
+	verifier := newTestVerifier(t, config)
+	mapping := verifier.AdminMapping()
+	for username, expected := range map[string]bool{"alice": true, "bob": false} {
+		claims, err := verifier.VerifyPassword(context.Background(), username, username+"-secret")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if result := mapping.IsAdmin(claims.Raw); result != expected {
+			t.Fatalf("Expected admin %t for %s but got %t", expected, username, result)
+		}
+	}
+}
+
+func TestLDAPVerifierServiceAccount(t *testing.T) {
+	server := newTestDirectory(t)
+	config := core.LDAPConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: "wrong",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+	}
+	_, err := newTestVerifier(t, config).VerifyPassword(context.Background(), "alice", "alice-secret")
+	if err == nil || errors.Is(err, core.ErrInvalidCredentials) {
+		t.Fatalf("Expected the failed service bind to be an internal error but got %v", err)
+	}
+}
+
+func TestLDAPVerifierKnowsUsername(t *testing.T) {
+	server := newTestDirectory(t)
+	config := core.LDAPConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: servicePassword,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+	}
+	verifier := newTestVerifier(t, config)
+	for username, expected := range map[string]bool{"alice": true, "twin": true, "carol": false, "": false, "*": false} {
+		known, err := verifier.KnowsUsername(context.Background(), username)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if known != expected {
+			t.Fatalf("Expected known %t for %q but got %t", expected, username, known)
+		}
+	}
+
+	config.BindPassword = "wrong"
+	if _, err := newTestVerifier(t, config).KnowsUsername(context.Background(), "alice"); err == nil {
+		t.Fatalf("Expected the failed service bind to be an error")
+	}
+}
+
+func TestNewLDAPVerifier(t *testing.T) {
+	cases := []struct {
+		config core.LDAPConfig
+		valid  bool
+	}{
+		{config: core.LDAPConfig{URL: "ldap://localhost", UserFilter: "(uid=%s)"}, valid: true},
+		{config: core.LDAPConfig{URL: "ldap://localhost"}, valid: true},
+		{config: core.LDAPConfig{URL: "ldaps://localhost", UserFilter: "(uid=%s)"}, valid: true},
+		{config: core.LDAPConfig{URL: "http://localhost", UserFilter: "(uid=%s)"}, valid: false},
+		{config: core.LDAPConfig{URL: "ldap://localhost", UserFilter: "(uid=alice)"}, valid: false},
+		{config: core.LDAPConfig{URL: "ldap://localhost", UserFilter: "(|(uid=%s)(mail=%s))"}, valid: false},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.config.URL+testCase.config.UserFilter, func(t *testing.T) {
+			_, err := federation.NewLDAPVerifier(testCase.config, time.Second, nil)
+			if (err == nil) != testCase.valid {
+				t.Fatalf("Expected valid %t but got %v", testCase.valid, err)
+			}
+		})
+	}
+}
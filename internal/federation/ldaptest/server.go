@@ -0,0 +1,256 @@
+// Package ldaptest provides an in-process LDAP server for the tests, it understands
+// just enough of the protocol for the simple binds and the searches of the users.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// the operations and the result codes,
+// see https://datatracker.ietf.org/doc/html/rfc4511#section-4.2.
+const (
+	applicationBindRequest       = 0
+	applicationBindResponse      = 1
+	applicationUnbindRequest     = 2
+	applicationSearchRequest     = 3
+	applicationSearchResultEntry = 4
+	applicationSearchResultDone  = 5
+
+	resultSuccess                 = 0
+	resultSizeLimitExceeded       = 4
+	resultInvalidCredentials      = 49
+	resultInsufficientAccessRight = 50
+	resultUnwillingToPerform      = 53
+
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// Entry is an object of the directory, the entries with a password can bind.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+func (e Entry) values(attribute string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Server serves the entries until it is closed. Like most of the real servers
+// it accepts the unauthenticated binds, a name with an empty password, without checking anything.
+type Server struct {
+	listener net.Listener
+	entries  []Entry
+	wg       sync.WaitGroup
+}
+
+// NewServer starts the server on a random local port.
+func NewServer(entries ...Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener, entries: entries}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// URL is where the server listens, e.g. ldap://127.0.0.1:34567.
+func (s *Server) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Close stops the server and waits for the connections to finish.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	boundDN := ""
+	for {
+		request, err := ber.ReadPacket(conn)
+		if err != nil || len(request.Children) < 2 {
+			return
+		}
+		messageID, ok := request.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		operation := request.Children[1]
+		switch operation.Tag {
+		case applicationBindRequest:
+			var code int64
+			boundDN, code = s.bind(operation)
+			s.write(conn, messageID, result(applicationBindResponse, code))
+		case applicationSearchRequest:
+			if boundDN == "" {
+				s.write(conn, messageID, result(applicationSearchResultDone, resultInsufficientAccessRight))
+				continue
+			}
+			s.search(conn, messageID, operation)
+		case applicationUnbindRequest:
+			return
+		default:
+			s.write(conn, messageID, result(operation.Tag+1, resultUnwillingToPerform))
+		}
+	}
+}
+
+func (s *Server) write(conn net.Conn, messageID int64, operation *ber.Packet) {
+	response := ber.NewSequence("LDAP Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	response.AppendChild(operation)
+	_, err := conn.Write(response.Bytes())
+	if err != nil {
+		// the next read fails and ends the connection
+		conn.Close()
+	}
+}
+
+func result(tag ber.Tag, code int64) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return packet
+}
+
+// bind returns the name the connection is bound as, empty for the anonymous connections.
+func (s *Server) bind(operation *ber.Packet) (string, int64) {
+	if len(operation.Children) < 3 || operation.Children[2].Tag != 0 {
+		return "", resultUnwillingToPerform
+	}
+	name, _ := operation.Children[1].Value.(string)
+	password := operation.Children[2].Data.String()
+	if password == "" {
+		// the unauthenticated bind, see https://datatracker.ietf.org/doc/html/rfc4513#section-5.1.2
+		return "", resultSuccess
+	}
+	for _, entry := range s.entries {
+		if entry.Password != "" && strings.EqualFold(entry.DN, name) && entry.Password == password {
+			return entry.DN, resultSuccess
+		}
+	}
+	return "", resultInvalidCredentials
+}
+
+func (s *Server) search(conn net.Conn, messageID int64, operation *ber.Packet) {
+	if len(operation.Children) < 8 {
+		s.write(conn, messageID, result(applicationSearchResultDone, resultUnwillingToPerform))
+		return
+	}
+	baseDN, _ := operation.Children[0].Value.(string)
+	sizeLimit, _ := operation.Children[3].Value.(int64)
+	filter := operation.Children[6]
+	attributes := []string{}
+	for _, attribute := range operation.Children[7].Children {
+		if name, ok := attribute.Value.(string); ok {
+			attributes = append(attributes, name)
+		}
+	}
+
+	found := int64(0)
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), strings.ToLower(baseDN)) || !matches(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && found == sizeLimit {
+			s.write(conn, messageID, result(applicationSearchResultDone, resultSizeLimitExceeded))
+			return
+		}
+		found++
+		s.write(conn, messageID, searchResultEntry(entry, attributes))
+	}
+	s.write(conn, messageID, result(applicationSearchResultDone, resultSuccess))
+}
+
+func searchResultEntry(entry Entry, attributes []string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, applicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	list := ber.NewSequence("Attributes")
+	for _, name := range attributes {
+		values := entry.values(name)
+		if len(values) == 0 {
+			continue
+		}
+		attribute := ber.NewSequence("Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	packet.AppendChild(list)
+	return packet
+}
+
+// matches evaluates the filter on the entry, the filters other than
+// and, or, not, equality and presence never match.
+func matches(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matches(entry, child) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(entry, filter.Children[0])
+	case filterEquality:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name, _ := filter.Children[0].Value.(string)
+		expected, _ := filter.Children[1].Value.(string)
+		for _, value := range entry.values(name) {
+			if strings.EqualFold(value, expected) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		return len(entry.values(filter.Data.String())) > 0
+	}
+	return false
+}
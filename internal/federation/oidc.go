@@ -0,0 +1,236 @@
+// Package federation logs the users in at an upstream OpenID Connect provider.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// discoveryPath is appended to the issuer to find its metadata,
+// see https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationRequest.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// maxResponseSize limits the responses read from the provider.
+const maxResponseSize = 1 << 20
+
+// OIDCProvider is the client of the upstream provider using the authorization code flow with PKCE.
+// The metadata and the keys of the provider are fetched on the first login, so that
+// the server starts even when the provider is unavailable.
+type OIDCProvider struct {
+	config      core.UpstreamConfig
+	redirectURI string
+	httpClient  *http.Client
+
+	mu       sync.Mutex
+	metadata *core.AuthorizationServerMetadata
+	keys     *core.KeySet
+}
+
+var _ managers.UpstreamProvider = (*OIDCProvider)(nil)
+
+// NewOIDCProvider creates the client, the redirect uri has to be registered at the provider.
+func NewOIDCProvider(config core.UpstreamConfig, redirectURI string, httpClient *http.Client) *OIDCProvider {
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	if config.Scope == "" {
+		config.Scope = "openid profile email"
+	}
+	if config.Name == "" {
+		config.Name = config.Issuer
+	}
+	return &OIDCProvider{
+		config:      config,
+		redirectURI: redirectURI,
+		httpClient:  httpClient,
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *OIDCProvider) AdminMapping() core.AdminClaimMapping {
+	return p.config.Admin
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, location string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set(constants.HeaderAccept, constants.MimeApplicationJSON)
+	return p.doJSON(req, result)
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, result any) error {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s %s responded with %d: %s", req.Method, req.URL, resp.StatusCode, body)
+	}
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// getMetadata fetches the metadata once and keeps it.
+func (p *OIDCProvider) getMetadata(ctx context.Context) (*core.AuthorizationServerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	metadata := core.AuthorizationServerMetadata{}
+	err := p.getJSON(ctx, p.config.Issuer+discoveryPath, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	// the metadata of another issuer must not be trusted,
+	// see https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationValidation.
+	if metadata.Issuer != p.config.Issuer {
+		return nil, errors.Errorf("provider metadata belongs to another issuer: %s", metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("provider metadata is missing an endpoint")
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// getKeys fetches the keys of the provider, refresh drops the known ones
+// since the provider might have rotated them.
+func (p *OIDCProvider) getKeys(ctx context.Context, jwksURI string, refresh bool) (*core.KeySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keys != nil && !refresh {
+		return p.keys, nil
+	}
+	jwks := core.JWKSet{}
+	err := p.getJSON(ctx, jwksURI, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*core.SigningKey, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.SigningKey()
+		if err != nil {
+			// the provider might publish keys we do not support next to the ones we do
+			continue
+		}
+		keys = append(keys, key)
+	}
+	p.keys = core.NewVerificationKeySet(keys...)
+	return p.keys, nil
+}
+
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	location, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	query := location.Query()
+	query.Set("response_type", core.ResponseTypeCode)
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.redirectURI)
+	query.Set("scope", p.config.Scope)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", core.CodeChallengeMethodS256)
+	location.RawQuery = query.Encode()
+	return location.String(), nil
+}
+
+type tokenResponse struct {
+	IDToken core.JWT `json:"id_token"`
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (*core.UpstreamClaims, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {core.GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURI},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+	req.Header.Set(constants.HeaderAccept, constants.MimeApplicationJSON)
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	token := tokenResponse{}
+	err = p.doJSON(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("provider did not issue an id token")
+	}
+	return p.verifyIDToken(ctx, metadata.JWKSURI, token.IDToken)
+}
+
+// verifyIDToken checks the signature and the claims of the id token,
+// see https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, jwksURI string, idToken core.JWT) (*core.UpstreamClaims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	}
+	keys, err := p.getKeys(ctx, jwksURI, false)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := keys.Parse(idToken, options...)
+	if err != nil {
+		// the token might be signed by a key published after we fetched the keys
+		keys, err = p.getKeys(ctx, jwksURI, true)
+		if err != nil {
+			return nil, err
+		}
+		raw, err = keys.Parse(idToken, options...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the token issued to several clients has to name us as the authorized party
+	if azp, ok := raw["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, fmt.Errorf("id token was issued to another party: %s", azp)
+	}
+	return core.NewUpstreamClaims(raw)
+}
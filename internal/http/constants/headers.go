@@ -3,4 +3,11 @@ package constants
 const (
 	HeaderContentType   = "Content-Type"
 	HeaderAuthorization = "Authorization"
+	HeaderXFrameOptions = "X-Frame-Options"
+	HeaderCacheControl  = "Cache-Control"
+	HeaderAuthenticate  = "WWW-Authenticate"
+	HeaderRetryAfter    = "Retry-After"
+	HeaderPragma        = "Pragma"
+	HeaderAccept        = "Accept"
+	HeaderReferrer      = "Referrer-Policy"
 )
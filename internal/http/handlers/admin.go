@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+// AdminAPI exposes the operations reserved to administrators.
+type AdminAPI struct {
+	identity *managers.IdentityManager
+}
+
+func NewAdminAPI(identity *managers.IdentityManager) *AdminAPI {
+	return &AdminAPI{
+		identity: identity,
+	}
+}
+
+type serviceAccountRequest struct {
+	Username string `json:"username"`
+	Scope    string `json:"scope"`
+}
+
+// BindServiceAccount lets the client use the client_credentials grant
+// to act as the given user.
+func (a *AdminAPI) BindServiceAccount(w http.ResponseWriter, r *http.Request) {
+	body := serviceAccountRequest{}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if body.Username == "" {
+		response.RespondErrorPlain(w, r, fmt.Errorf("required field: %s", "username"), http.StatusBadRequest)
+		return
+	}
+
+	err = a.identity.BindServiceAccount(r.Context(), r.PathValue("clientID"), body.Username, body.Scope)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnbindServiceAccount stops the client from using the client_credentials grant.
+func (a *AdminAPI) UnbindServiceAccount(w http.ResponseWriter, r *http.Request) {
+	err := a.identity.BindServiceAccount(r.Context(), r.PathValue("clientID"), "", "")
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListClients returns the clients of every user.
+func (a *AdminAPI) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := a.identity.GetAllClients(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, newClientsResponse(clients))
+}
+
+// DeleteClient removes the client of any user.
+func (a *AdminAPI) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	err := a.identity.DeleteAnyClient(r.Context(), r.PathValue("clientID"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockUser lifts the lockout caused by the failed logins of the user.
+func (a *AdminAPI) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	err := a.identity.UnlockUser(r.Context(), r.PathValue("username"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetTOTP turns off the second factor of the user who lost it.
+func (a *AdminAPI) ResetTOTP(w http.ResponseWriter, r *http.Request) {
+	err := a.identity.ResetTOTP(r.Context(), r.PathValue("username"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// defaultInviteLifetime applies to the invites created without a lifetime.
+const defaultInviteLifetime = 7 * 24 * time.Hour
+
+type signupModeBody struct {
+	Mode core.SignupMode `json:"mode"`
+}
+
+// GetSignup returns whether the visitors can register accounts.
+func (a *AdminAPI) GetSignup(w http.ResponseWriter, r *http.Request) {
+	mode, err := a.identity.SignupMode(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, signupModeBody{Mode: mode})
+}
+
+// SetSignup opens the signup to everyone, to the invited visitors or closes it.
+func (a *AdminAPI) SetSignup(w http.ResponseWriter, r *http.Request) {
+	body := signupModeBody{}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = a.identity.SetSignupMode(r.Context(), string(body.Mode))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type createInviteRequest struct {
+	// ExpiresInSeconds falls back to a week when omitted
+	ExpiresInSeconds int64 `json:"expires_in"`
+}
+
+type inviteResponse struct {
+	ID string `json:"id"`
+	// Code is only present right after it was created
+	Code      string     `json:"code,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+func newInviteResponse(invite core.Invite) inviteResponse {
+	result := inviteResponse{
+		ID:        invite.ID,
+		Code:      invite.Code,
+		CreatedBy: invite.CreatedBy,
+		CreatedAt: invite.CreatedAt,
+		ExpiresAt: invite.ExpiresAt,
+	}
+	if !invite.UsedAt.IsZero() {
+		result.UsedAt = &invite.UsedAt
+	}
+	return result
+}
+
+// CreateInvite creates the single-use invite for the invite-only signup.
+func (a *AdminAPI) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	body := createInviteRequest{}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	expiresIn := defaultInviteLifetime
+	if body.ExpiresInSeconds != 0 {
+		expiresIn, err = lifetime("expires_in", body.ExpiresInSeconds, core.MaxInviteLifetime)
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+	}
+
+	invite, err := a.identity.CreateInvite(r.Context(), middleware.MustGetAccessToken(r).UserID, expiresIn)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondJSON(w, r, newInviteResponse(*invite), http.StatusCreated)
+}
+
+// ListInvites returns the invites, without the codes themselves.
+func (a *AdminAPI) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := a.identity.GetInvites(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	result := make([]inviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		result = append(result, newInviteResponse(invite))
+	}
+	response.RespondOKJSON(w, r, result)
+}
+
+// DeleteInvite withdraws the invite.
+func (a *AdminAPI) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	err := a.identity.DeleteInvite(r.Context(), r.PathValue("inviteID"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
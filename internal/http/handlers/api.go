@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 
+	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/andriihomiak/wallabago/internal/http/middleware"
 	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
 )
 
+// maxJSONBodySize limits the size of json request bodies.
+const maxJSONBodySize = 1 << 20
+
 type API struct{}
 
 func NewAPI() *API {
@@ -17,3 +24,33 @@ func (a *API) AuthInfo(w http.ResponseWriter, r *http.Request) {
 	token := middleware.MustGetAccessToken(r)
 	response.RespondOKJSON(w, r, token)
 }
+
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, body any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBodySize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// respondError maps the errors returned by managers to the response status.
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		response.RespondErrorPlain(w, r, err, http.StatusNotFound)
+	case errors.Is(err, core.ErrInvalidInput):
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+	case errors.Is(err, core.ErrForbidden):
+		response.RespondErrorPlain(w, r, err, http.StatusForbidden)
+	default:
+		response.RespondInternalErrorWithStack(w, r, err)
+	}
+}
+
+// errorMessage drops the sentinel from the error of the manager for the web pages,
+// the messages wrapping core.ErrInvalidInput and core.ErrForbidden are meant for the user.
+func errorMessage(err, sentinel error) string {
+	return strings.TrimSuffix(err.Error(), ": "+sentinel.Error())
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/pkg/errors"
+)
+
+// ClientsAPI lets the users manage the OAuth clients they registered.
+type ClientsAPI struct {
+	identity *managers.IdentityManager
+}
+
+func NewClientsAPI(identity *managers.IdentityManager) *ClientsAPI {
+	return &ClientsAPI{
+		identity: identity,
+	}
+}
+
+type createClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	// the fields below fall back to the default policy when omitted
+	GrantTypes                  []core.GrantType `json:"grant_types"`
+	Scope                       core.Scope       `json:"scope"`
+	Public                      bool             `json:"public"`
+	AccessTokenLifetimeSeconds  int64            `json:"access_token_lifetime"`
+	RefreshTokenLifetimeSeconds int64            `json:"refresh_token_lifetime"`
+}
+
+// lifetime converts the lifetime in seconds, checking the range first so the conversion can not overflow.
+func lifetime(field string, seconds int64, maxLifetime time.Duration) (time.Duration, error) {
+	if seconds < 0 || seconds > int64(maxLifetime/time.Second) {
+		return 0, errors.Wrapf(core.ErrInvalidInput, "%s must be between 0 and %d seconds", field, int64(maxLifetime/time.Second))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (r createClientRequest) policy() (core.ClientPolicy, error) {
+	policy := core.DefaultClientPolicy()
+	policy.RedirectURIs = r.RedirectURIs
+	if len(r.GrantTypes) > 0 {
+		policy.GrantTypes = r.GrantTypes
+	}
+	if r.Scope != "" {
+		policy.Scope = r.Scope
+	}
+	policy.Public = r.Public
+	var err error
+	policy.AccessTokenLifetime, err = lifetime("access_token_lifetime", r.AccessTokenLifetimeSeconds, core.MaxAccessTokenLifetime)
+	if err != nil {
+		return core.ClientPolicy{}, err
+	}
+	policy.RefreshTokenLifetime, err = lifetime("refresh_token_lifetime", r.RefreshTokenLifetimeSeconds, core.MaxRefreshTokenLifetime)
+	if err != nil {
+		return core.ClientPolicy{}, err
+	}
+	return policy, nil
+}
+
+type clientResponse struct {
+	ClientID string `json:"client_id"`
+	// ClientSecret is only present right after it was generated
+	ClientSecret                string           `json:"client_secret,omitempty"`
+	Name                        string           `json:"name"`
+	RedirectURIs                []string         `json:"redirect_uris"`
+	GrantTypes                  []core.GrantType `json:"grant_types"`
+	Scope                       core.Scope       `json:"scope"`
+	Public                      bool             `json:"public"`
+	AccessTokenLifetimeSeconds  int64            `json:"access_token_lifetime,omitempty"`
+	RefreshTokenLifetimeSeconds int64            `json:"refresh_token_lifetime,omitempty"`
+	OwnerUserID                 string           `json:"owner_user_id,omitempty"`
+	CreatedAt                   time.Time        `json:"created_at"`
+}
+
+func newClientResponse(client core.Client) clientResponse {
+	redirectURIs := client.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	grantTypes := client.GrantTypes
+	if grantTypes == nil {
+		grantTypes = []core.GrantType{}
+	}
+	return clientResponse{
+		ClientID:                    client.ID,
+		ClientSecret:                client.Secret,
+		Name:                        client.Name,
+		RedirectURIs:                redirectURIs,
+		GrantTypes:                  grantTypes,
+		Scope:                       client.Scope,
+		Public:                      client.Public,
+		AccessTokenLifetimeSeconds:  int64(client.AccessTokenLifetime.Seconds()),
+		RefreshTokenLifetimeSeconds: int64(client.RefreshTokenLifetime.Seconds()),
+		OwnerUserID:                 client.OwnerUserID,
+		CreatedAt:                   client.CreatedAt,
+	}
+}
+
+func newClientsResponse(clients []core.Client) []clientResponse {
+	result := make([]clientResponse, 0, len(clients))
+	for _, client := range clients {
+		result = append(result, newClientResponse(client))
+	}
+	return result
+}
+
+// CreateClient registers a new client owned by the user.
+func (a *ClientsAPI) CreateClient(w http.ResponseWriter, r *http.Request) {
+	body := createClientRequest{}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if body.Name == "" {
+		response.RespondErrorPlain(w, r, fmt.Errorf("required field: %s", "name"), http.StatusBadRequest)
+		return
+	}
+
+	policy, err := body.policy()
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	token := middleware.MustGetAccessToken(r)
+	client, err := a.identity.CreateClient(r.Context(), token.UserID, body.Name, policy)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondJSON(w, r, newClientResponse(*client), http.StatusCreated)
+}
+
+// ListClients returns the clients owned by the user.
+func (a *ClientsAPI) ListClients(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	clients, err := a.identity.GetClients(r.Context(), token.UserID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, newClientsResponse(clients))
+}
+
+// RotateClientSecret generates a new secret for the client owned by the user.
+func (a *ClientsAPI) RotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	client, err := a.identity.RotateClientSecret(r.Context(), token.UserID, r.PathValue("clientID"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, newClientResponse(*client))
+}
+
+// DeleteClient removes the client owned by the user.
+func (a *ClientsAPI) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	err := a.identity.DeleteClient(r.Context(), token.UserID, r.PathValue("clientID"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
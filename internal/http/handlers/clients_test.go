@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+)
+
+type clientsFixture struct {
+	*tokenConformanceFixture
+	// owner and other are allowed to manage their clients
+	owner, other string
+}
+
+func newClientsFixture(t *testing.T) *clientsFixture {
+	t.Helper()
+	f := newTokenConformanceFixture(t)
+	err := f.storage.AddUserInfo(context.Background(), nil, core.UserInfo{ID: "other-id", Username: "other", PasswordHash: []byte{}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return &clientsFixture{
+		tokenConformanceFixture: f,
+		owner:                   f.accessToken(t, core.Scope(core.ScopeClients)),
+		other:                   f.userAccessToken(t, "other-id", core.Scope(core.ScopeClients)),
+	}
+}
+
+func (f *clientsFixture) createClient(t *testing.T) clientResponse {
+	t.Helper()
+	w := f.authorized(http.MethodPost, "/api/clients", f.owner, `{"name":"Script","grant_types":["password"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	return decodeClient(t, w)
+}
+
+func (f *clientsFixture) listClients(t *testing.T, target, bearer string) []clientResponse {
+	t.Helper()
+	w := f.authorized(http.MethodGet, target, bearer, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	clients := []clientResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &clients)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return clients
+}
+
+// passwordGrant logs the user in through the client with the secret.
+func (f *clientsFixture) passwordGrant(clientID, clientSecret string) int {
+	return f.post(constants.MimeApplicationXWWWFormURLEncoded, url.Values{
+		OAuth2GrantType:    {core.GrantTypePassword},
+		OAuth2ClientID:     {clientID},
+		OAuth2ClientSecret: {clientSecret},
+		OAuth2Username:     {conformanceUsername},
+		OAuth2Password:     {conformancePassword},
+	}.Encode(), nil).Code
+}
+
+// serverClient adds the client created by the server like the one of the web UI.
+func (f *clientsFixture) serverClient(t *testing.T) *core.Client {
+	t.Helper()
+	policy := core.DefaultClientPolicy()
+	policy.RedirectURIs = []string{"http://localhost/callback"}
+	client, err := core.NewClient("", "Web UI", policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = f.storage.AddClient(context.Background(), nil, *client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return client
+}
+
+func decodeClient(t *testing.T, w *httptest.ResponseRecorder) clientResponse {
+	t.Helper()
+	client := clientResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return client
+}
+
+func containsClient(clients []clientResponse, clientID string) bool {
+	for _, client := range clients {
+		if client.ClientID == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClientsOwnerIsolation(t *testing.T) {
+	f := newClientsFixture(t)
+	created := f.createClient(t)
+	if created.ClientSecret == "" || created.OwnerUserID != "user-id" {
+		t.Fatalf("Expected the secret of the client owned by the user but got %#v", created)
+	}
+
+	if clients := f.listClients(t, "/api/clients", f.owner); !containsClient(clients, created.ClientID) || clients[0].ClientSecret != "" {
+		t.Fatalf("Expected the client to be listed without its secret but got %#v", clients)
+	}
+	if clients := f.listClients(t, "/api/clients", f.other); containsClient(clients, created.ClientID) {
+		t.Fatalf("Expected the client of another user to be hidden but got %#v", clients)
+	}
+	// the client of another user looks like it does not exist
+	if w := f.authorized(http.MethodPost, "/api/clients/"+created.ClientID+"/secret", f.other, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNotFound, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodDelete, "/api/clients/"+created.ClientID, f.other, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNotFound, w.Code, w.Body)
+	}
+	if status := f.passwordGrant(created.ClientID, created.ClientSecret); status != http.StatusOK {
+		t.Fatalf("Expected the client to be left untouched but got %d", status)
+	}
+
+	if w := f.authorized(http.MethodDelete, "/api/clients/"+created.ClientID, f.owner, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodDelete, "/api/clients/"+created.ClientID, f.owner, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNotFound, w.Code, w.Body)
+	}
+}
+
+func TestRotateClientSecret(t *testing.T) {
+	f := newClientsFixture(t)
+	created := f.createClient(t)
+
+	w := f.authorized(http.MethodPost, "/api/clients/"+created.ClientID+"/secret", f.owner, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	rotated := decodeClient(t, w)
+	if rotated.ClientSecret == "" || rotated.ClientSecret == created.ClientSecret {
+		t.Fatalf("Expected a new secret but got %#v", rotated)
+	}
+	if status := f.passwordGrant(created.ClientID, created.ClientSecret); status != http.StatusUnauthorized {
+		t.Fatalf("Expected the previous secret to be rejected but got %d", status)
+	}
+	if status := f.passwordGrant(created.ClientID, rotated.ClientSecret); status != http.StatusOK {
+		t.Fatalf("Expected the new secret to be accepted but got %d", status)
+	}
+
+	// the secret of the clients created by the server is managed by the server
+	server := f.serverClient(t)
+	if w := f.authorized(http.MethodPost, "/api/clients/"+server.ID+"/secret", f.owner, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNotFound, w.Code, w.Body)
+	}
+}
+
+func TestAdminClients(t *testing.T) {
+	f := newClientsFixture(t)
+	created := f.createClient(t)
+	server := f.serverClient(t)
+
+	// the admin scope alone is not enough without the admin role
+	nonAdmin := f.userAccessToken(t, "other-id", core.Scope(core.ScopeAdmin))
+	if w := f.authorized(http.MethodGet, "/api/admin/clients", nonAdmin, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusForbidden, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodDelete, "/api/admin/clients/"+created.ClientID, nonAdmin, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusForbidden, w.Code, w.Body)
+	}
+
+	admin := f.adminToken(t)
+	clients := f.listClients(t, "/api/admin/clients", admin)
+	if !containsClient(clients, created.ClientID) || !containsClient(clients, server.ID) {
+		t.Fatalf("Expected the clients of every user and of the server but got %#v", clients)
+	}
+	if w := f.authorized(http.MethodDelete, "/api/admin/clients/"+server.ID, admin, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the client of the server to be kept but got %d: %s", w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodDelete, "/api/admin/clients/"+created.ClientID, admin, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodDelete, "/api/admin/clients/"+created.ClientID, admin, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNotFound, w.Code, w.Body)
+	}
+	clients = f.listClients(t, "/api/admin/clients", admin)
+	if containsClient(clients, created.ClientID) || !containsClient(clients, server.ID) {
+		t.Fatalf("Expected only the client of the user to be removed but got %#v", clients)
+	}
+}
+
+func TestCreateClientLifetimes(t *testing.T) {
+	maxAccess := int64(core.MaxAccessTokenLifetime / time.Second)
+	maxRefresh := int64(core.MaxRefreshTokenLifetime / time.Second)
+	cases := []struct {
+		access, refresh int64
+		expectedStatus  int
+	}{
+		{access: maxAccess, refresh: maxRefresh, expectedStatus: http.StatusCreated},
+		{access: maxAccess + 1, expectedStatus: http.StatusBadRequest},
+		{refresh: maxRefresh + 1, expectedStatus: http.StatusBadRequest},
+		{access: -1, expectedStatus: http.StatusBadRequest},
+		// would overflow the duration when converted
+		{access: math.MaxInt64, expectedStatus: http.StatusBadRequest},
+		{refresh: math.MaxInt64 / 1000, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%d", tc.access, tc.refresh), func(t *testing.T) {
+			f := newClientsFixture(t)
+			body := `{"name":"Script","grant_types":["password"],"access_token_lifetime":` + strconv.FormatInt(tc.access, 10) +
+				`,"refresh_token_lifetime":` + strconv.FormatInt(tc.refresh, 10) + `}`
+			w := f.authorized(http.MethodPost, "/api/clients", f.owner, body)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			created := decodeClient(t, w)
+			if created.AccessTokenLifetimeSeconds != tc.access || created.RefreshTokenLifetimeSeconds != tc.refresh {
+				t.Fatalf("Expected the lifetimes to be kept but got %#v", created)
+			}
+		})
+	}
+}
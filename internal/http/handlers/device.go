@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	OAuth2DeviceCode = "device_code"
+	OAuth2UserCode   = "user_code"
+
+	// DevicePath is the verification uri the user visits to approve a device.
+	DevicePath = "/device"
+)
+
+func requiredDeviceAuthorizationRequest(r *http.Request) (*core.DeviceAuthorizationRequest, error) {
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.DeviceAuthorizationRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scope:        r.PostForm.Get(OAuth2Scope),
+	}, nil
+}
+
+// DeviceAuthorizationEndpoint issues the codes the device shows to the user,
+// see https://datatracker.ietf.org/doc/html/rfc8628#section-3.1.
+func (h *OAuth2Handler) DeviceAuthorizationEndpoint(w http.ResponseWriter, r *http.Request) {
+	req, requiredFieldErr := requiredDeviceAuthorizationRequest(r)
+	if requiredFieldErr != nil {
+		response.RespondErrorPlain(w, r, requiredFieldErr, http.StatusBadRequest)
+		return
+	}
+
+	code, err := h.manager.DeviceAuthorization(r.Context(), *req)
+	if err != nil {
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			response.RespondJSON(w, r, authError, http.StatusUnauthorized)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	verificationURI := h.publicURL + DevicePath
+	userCode := core.FormatUserCode(code.UserCode)
+	response.RespondOKJSON(w, r, core.DeviceAuthorizationResponse{
+		DeviceCode:              code.DeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{OAuth2UserCode: []string{userCode}}.Encode(),
+		ExpiresIn:               int64(code.ExpiresAt.Sub(code.IssuedAt).Seconds()),
+		Interval:                int64(code.Interval.Seconds()),
+	})
+}
+
+func requiredDeviceCodeFlowRequest(r *http.Request) (*core.DeviceCodeFlowRequest, error) {
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	deviceCode, requiredErr := requiredPostFormField(r, OAuth2DeviceCode)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.DeviceCodeFlowRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		DeviceCode:   deviceCode,
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+	}, nil
+}
+
+func (h *OAuth2Handler) handleDeviceCodeFlow(w http.ResponseWriter, r *http.Request) {
+	req, requiredFieldErr := requiredDeviceCodeFlowRequest(r)
+	if requiredFieldErr != nil {
+		respondTokenError(w, r, invalidTokenRequest(requiredFieldErr))
+		return
+	}
+
+	token, err := h.manager.DeviceCodeFlow(r.Context(), *req)
+	if err != nil {
+		respondTokenError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, token)
+}
+
+type devicePage struct {
+	UserCode string
+	Error    string
+}
+
+type deviceConsentPage struct {
+	UserCode string
+	Code     core.DeviceCode
+}
+
+type deviceDonePage struct {
+	Approved bool
+}
+
+// DevicePage asks the logged in user for the code shown on the device
+// and, once it is known, for the approval of the request.
+func (s *WebUI) DevicePage(w http.ResponseWriter, r *http.Request) {
+	// the consent page must never be embedded to prevent clickjacking
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+
+	userCode := r.URL.Query().Get(OAuth2UserCode)
+	if userCode == "" {
+		response.RespondHTML(w, r, templates, "device.html", devicePage{}, http.StatusOK)
+		return
+	}
+
+	code, err := s.identity.GetPendingDeviceCode(r.Context(), userCode)
+	if errors.Is(err, core.ErrNotFound) {
+		response.RespondHTML(w, r, templates, "device.html", devicePage{
+			UserCode: userCode,
+			Error:    "Unknown or expired code",
+		}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	response.RespondHTML(w, r, templates, "device_consent.html", deviceConsentPage{
+		UserCode: core.FormatUserCode(code.UserCode),
+		Code:     *code,
+	}, http.StatusOK)
+}
+
+// DeviceDecision handles the submission of the device consent page.
+func (s *WebUI) DeviceDecision(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	token := middleware.MustGetAccessToken(r)
+	userCode := r.PostForm.Get(OAuth2UserCode)
+	approve := r.PostForm.Get("decision") == "approve"
+
+	err = s.identity.DecideDeviceCode(r.Context(), token.UserID, userCode, approve)
+	if errors.Is(err, core.ErrNotFound) {
+		response.RespondHTML(w, r, templates, "device.html", devicePage{
+			UserCode: userCode,
+			Error:    "Unknown or expired code",
+		}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	response.RespondHTML(w, r, templates, "device_done.html", deviceDonePage{
+		Approved: approve,
+	}, http.StatusOK)
+}
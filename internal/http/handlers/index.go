@@ -1,36 +1,152 @@
 package handlers
 
 import (
-	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/pkg/errors"
 )
 
-// todo: use template/html
-const indexPage = `<!DOCTYPE html>
-<html lang="en">
-    <head>
-        <meta charset="utf-8">
-        <title>Wallabago</title>
-    </head>
-    <body>
-        <h1>Wallabago</h1>
-        <p>%s</p>
-    </body>
-</html>`
+type WebUI struct {
+	identity *managers.IdentityManager
+	// the web ui acts as an oauth2 client itself
+	client core.Client
+}
 
-type WebUI struct{}
+func NewWebUI(identity *managers.IdentityManager, client core.Client) *WebUI {
+	return &WebUI{
+		identity: identity,
+		client:   client,
+	}
+}
 
-func NewWebUI() *WebUI {
-	return &WebUI{}
+type indexPage struct {
+	Now string
 }
 
 func (s *WebUI) Index(w http.ResponseWriter, r *http.Request) {
 	slog.DebugContext(r.Context(), "index", "pattern", r.Pattern)
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set(constants.HeaderContentType, constants.MimeTextHTML)
-	fmt.Fprintf(w, indexPage, time.Now().UTC().Format(time.Layout))
+	response.RespondHTML(w, r, templates, "index.html", indexPage{
+		Now: time.Now().UTC().Format(time.Layout),
+	}, http.StatusOK)
+}
+
+type loginPage struct {
+	Next     string
+	Username string
+	Error    string
+	// UpstreamName is the identity provider offered next to the password, empty when there is none
+	UpstreamName string
+	// OTPRequired asks for the one-time password of the users with a second factor
+	OTPRequired bool
+	// PasswordReset offers the reset of the forgotten password when the emails can be sent
+	PasswordReset bool
+	// Signup links to the signup unless it is closed
+	Signup bool
+}
+
+// safeNext makes sure that we only ever redirect to our own pages after login.
+func safeNext(next string) string {
+	parsed, err := url.Parse(next)
+	if err != nil || parsed.IsAbs() || parsed.Host != "" ||
+		!strings.HasPrefix(parsed.Path, "/") || strings.HasPrefix(parsed.Path, "//") ||
+		strings.Contains(next, "\\") {
+		return "/"
+	}
+	return parsed.RequestURI()
+}
+
+func (s *WebUI) LoginPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	response.RespondHTML(w, r, templates, "login.html", loginPage{
+		Next:          safeNext(r.URL.Query().Get("next")),
+		UpstreamName:  s.identity.UpstreamLoginName(),
+		PasswordReset: s.identity.PasswordResetEnabled(),
+		Signup:        s.signupOffered(r),
+	}, http.StatusOK)
+}
+
+func (s *WebUI) Login(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	page := loginPage{
+		Next:          safeNext(r.PostForm.Get("next")),
+		Username:      r.PostForm.Get(OAuth2Username),
+		UpstreamName:  s.identity.UpstreamLoginName(),
+		PasswordReset: s.identity.PasswordResetEnabled(),
+		Signup:        s.signupOffered(r),
+	}
+
+	token, err := s.identity.PasswordFlow(r.Context(), core.PasswordFlowRequest{
+		ClientID:     s.client.ID,
+		ClientSecret: s.client.Secret,
+		Username:     page.Username,
+		Password:     r.PostForm.Get(OAuth2Password),
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+		OTP:          r.PostForm.Get(OAuth2OTP),
+	})
+	if err != nil {
+		throttledError := &core.LoginThrottledError{}
+		if errors.As(err, &throttledError) {
+			page.Error = "Too many failed logins, try again later"
+			setRetryAfter(w, throttledError.RetryAfter)
+			w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+			response.RespondHTML(w, r, templates, "login.html", page, http.StatusTooManyRequests)
+			return
+		}
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			switch {
+			case authError.ErrorName == core.AuthErrorEmailUnverified:
+				page.Error = authError.ErrorDescription
+			case authError.ErrorName == core.AuthErrorMFARequired:
+				page.OTPRequired = true
+				page.Error = "Enter the code of your authenticator app"
+			case r.PostForm.Get(OAuth2OTP) != "":
+				// once the password was right the form keeps asking for the code
+				page.OTPRequired = true
+				page.Error = "Invalid username, password or one-time password"
+			default:
+				page.Error = "Invalid username or password"
+			}
+			w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+			response.RespondHTML(w, r, templates, "login.html", page, http.StatusUnauthorized)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	middleware.SetSessionCookie(w, r, &token.AccessToken)
+	http.Redirect(w, r, page.Next, http.StatusSeeOther)
+}
+
+func (s *WebUI) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
+		// the cookie might have been copied, so neither the token nor the refresh
+		// token of the session it was issued with may outlive the logout
+		err = s.identity.Logout(r.Context(), core.RevocationRequest{
+			ClientID:      s.client.ID,
+			ClientSecret:  s.client.Secret,
+			Token:         cookie.Value,
+			TokenTypeHint: core.TokenTypeHintAccessToken,
+		})
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to revoke session token on logout", "cause", err.Error())
+		}
+	}
+	middleware.ClearSessionCookie(w, r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+func TestSafeNext(t *testing.T) {
+	cases := []struct {
+		next     string
+		expected string
+	}{
+		{next: "", expected: "/"},
+		{next: "/oauth/v2/authorize?client_id=web", expected: "/oauth/v2/authorize?client_id=web"},
+		{next: "https://evil.example/", expected: "/"},
+		{next: "//evil.example/", expected: "/"},
+		{next: "/\\evil.example/", expected: "/"},
+		{next: "relative", expected: "/"},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestSafeNext_%d_%#v", i, testCase.next), func(t *testing.T) {
+			if result := safeNext(testCase.next); result != testCase.expected {
+				t.Fatalf("Expected %#v but got %#v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoginPage(t *testing.T) {
+	manager, _ := newTestIdentityManager(t, newMemoryStorage(), managers.IdentityOptions{})
+	req := httptest.NewRequest(http.MethodGet, "/login?next=/protected", http.NoBody)
+	recorder := serve(newTestRouter(manager, core.Client{}), req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected %d but got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+}
+
+// webLogin logs the user in through the login page and returns the session cookie.
+func (f *tokenConformanceFixture) webLogin(t *testing.T) *http.Cookie {
+	t.Helper()
+	form := url.Values{OAuth2Username: {conformanceUsername}, OAuth2Password: {conformancePassword}}
+	req := httptest.NewRequest(http.MethodPost, middleware.LoginPath, strings.NewReader(form.Encode()))
+	req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+	w := serve(f.router, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusSeeOther, w.Code, w.Body)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName {
+			return cookie
+		}
+	}
+	t.Fatalf("Expected the session cookie but got %v", w.Result().Cookies())
+	return nil
+}
+
+func TestLogout(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	bearer := f.accessToken(t, core.Scope(core.ScopeSessions))
+	sessions := func() []sessionResponse {
+		w := f.authorized(http.MethodGet, "/api/user/sessions", bearer, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		sessions := []sessionResponse{}
+		err := json.Unmarshal(w.Body.Bytes(), &sessions)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return sessions
+	}
+	cookie := f.webLogin(t)
+	other := f.webLogin(t)
+	if listed := sessions(); len(listed) != 2 {
+		t.Fatalf("Expected the sessions of both logins but got %#v", listed)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", http.NoBody)
+	req.AddCookie(cookie)
+	if w := serve(f.router, req); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusSeeOther, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodGet, "/protected", cookie.Value, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the token of the session to be revoked but got %d", w.Code)
+	}
+	// the refresh token of the session is revoked too, so only the other login is left
+	if listed := sessions(); len(listed) != 1 {
+		t.Fatalf("Expected only the session of the other login but got %#v", listed)
+	}
+	if w := f.authorized(http.MethodGet, "/protected", other.Value, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the other session to be left intact but got %d: %s", w.Code, w.Body)
+	}
+}
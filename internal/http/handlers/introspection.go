@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+func requiredIntrospectionRequest(r *http.Request) (*core.IntrospectionRequest, error) {
+	token, requiredErr := requiredPostFormField(r, OAuth2Token)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.IntrospectionRequest{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		Token:         token,
+		TokenTypeHint: core.TokenTypeHint(r.PostForm.Get(OAuth2TokenTypeHint)),
+	}, nil
+}
+
+// IntrospectionEndpoint lets resource servers check the tokens presented to them,
+// see https://datatracker.ietf.org/doc/html/rfc7662#section-2.
+func (h *OAuth2Handler) IntrospectionEndpoint(w http.ResponseWriter, r *http.Request) {
+	if mediaType := r.Header.Get(constants.HeaderContentType); mediaType != constants.MimeApplicationXWWWFormURLEncoded {
+		respondClientError(w, r, invalidTokenRequest(fmt.Errorf("unsupported media type: '%s', expected: '%s'", mediaType, constants.MimeApplicationXWWWFormURLEncoded)))
+		return
+	}
+
+	req, requiredFieldErr := requiredIntrospectionRequest(r)
+	if requiredFieldErr != nil {
+		respondClientError(w, r, invalidTokenRequest(requiredFieldErr))
+		return
+	}
+
+	introspection, err := h.manager.Introspect(r.Context(), *req)
+	if err != nil {
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			respondClientError(w, r, authError)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, introspection)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+)
+
+// introspect asks about the token as the client with the given credentials.
+func (f *tokenConformanceFixture) introspect(clientID, clientSecret string, values url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, IntrospectionPath, strings.NewReader(values.Encode()))
+	req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+	req.SetBasicAuth(clientID, clientSecret)
+	return serve(f.router, req)
+}
+
+// revoke revokes the token as the client it was issued to.
+func (f *tokenConformanceFixture) revoke(t *testing.T, token string) {
+	t.Helper()
+	if w := f.revocation(f.client.ID, f.client.Secret, url.Values{OAuth2Token: {token}}); w.Code != http.StatusOK {
+		t.Fatalf("Expected the revocation to succeed but got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	cases := []struct {
+		name string
+		// token returns the introspected token and its type hint
+		token          func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint)
+		expectedActive bool
+	}{
+		{
+			name: "active access token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				return string(f.tokens(t).AccessToken.Token), ""
+			},
+			expectedActive: true,
+		},
+		{
+			name: "active refresh token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				return f.refreshToken(t), core.TokenTypeHintRefreshToken
+			},
+			expectedActive: true,
+		},
+		{
+			name: "refresh token with the access token hint",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				return f.refreshToken(t), core.TokenTypeHintAccessToken
+			},
+			expectedActive: true,
+		},
+		{
+			name: "access token with the refresh token hint",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				return string(f.tokens(t).AccessToken.Token), core.TokenTypeHintRefreshToken
+			},
+			expectedActive: true,
+		},
+		{
+			name: "revoked access token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				token := string(f.tokens(t).AccessToken.Token)
+				f.revoke(t, token)
+				return token, ""
+			},
+		},
+		{
+			name: "revoked refresh token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				token := f.refreshToken(t)
+				f.revoke(t, token)
+				return token, core.TokenTypeHintRefreshToken
+			},
+		},
+		{
+			name: "rotated refresh token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				token := f.refreshToken(t)
+				w := f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+					OAuth2GrantType:    {core.GrantTypeRefreshToken},
+					OAuth2RefreshToken: {token},
+				}).Encode(), nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected the refresh to succeed but got %d: %s", w.Code, w.Body)
+				}
+				return token, core.TokenTypeHintRefreshToken
+			},
+		},
+		{
+			name: "expired access token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				token := string(f.tokens(t).AccessToken.Token)
+				for id, accessToken := range f.storage.accessTokens {
+					accessToken.ExpiresInSeconds = 0
+					f.storage.accessTokens[id] = accessToken
+				}
+				return token, ""
+			},
+		},
+		{
+			name: "expired refresh token",
+			token: func(t *testing.T, f *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				token := f.refreshToken(t)
+				for id, refreshToken := range f.storage.refreshTokens {
+					refreshToken.ExpiresAt = time.Now().Add(-time.Minute)
+					f.storage.refreshTokens[id] = refreshToken
+				}
+				return token, core.TokenTypeHintRefreshToken
+			},
+		},
+		{
+			name: "unknown token",
+			token: func(*testing.T, *tokenConformanceFixture) (string, core.TokenTypeHint) {
+				return "unknown", ""
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			token, hint := tc.token(t, f)
+			values := url.Values{OAuth2Token: {token}}
+			if hint != "" {
+				values.Set(OAuth2TokenTypeHint, string(hint))
+			}
+			// the resource server introspects the tokens issued to other clients
+			w := f.introspect(f.credentialClient.ID, f.credentialClient.Secret, values)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+			}
+			introspection := core.IntrospectionResponse{}
+			err := json.Unmarshal(w.Body.Bytes(), &introspection)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if introspection.Active != tc.expectedActive {
+				t.Fatalf("Expected active %t but got %s", tc.expectedActive, w.Body)
+			}
+			if !introspection.Active {
+				// nothing but the state is disclosed about the inactive tokens
+				if strings.TrimSpace(w.Body.String()) != `{"active":false}` {
+					t.Fatalf("Expected only the inactive state but got %s", w.Body)
+				}
+				return
+			}
+			if introspection.ClientID != f.client.ID || introspection.Subject != "user-id" || introspection.Scope == "" {
+				t.Fatalf("Unexpected introspection %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestIntrospectionRejected(t *testing.T) {
+	cases := []struct {
+		name           string
+		credentials    func(t *testing.T, f *tokenConformanceFixture) (string, string)
+		values         url.Values
+		expectedStatus int
+		expectedError  core.AuthErrorName
+	}{
+		{
+			name: "wrong client secret",
+			credentials: func(_ *testing.T, f *tokenConformanceFixture) (string, string) {
+				return f.credentialClient.ID, "wrong"
+			},
+			values:         url.Values{OAuth2Token: {"token"}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidClient,
+		},
+		{
+			name: "public client",
+			credentials: func(t *testing.T, f *tokenConformanceFixture) (string, string) {
+				client, err := core.NewClient("user-id", "App", core.ClientPolicy{
+					Public:     true,
+					GrantTypes: []core.GrantType{core.GrantTypePassword},
+					Scope:      *core.DefaultScope(),
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				err = f.storage.AddClient(context.Background(), nil, *client)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return client.ID, ""
+			},
+			values:         url.Values{OAuth2Token: {"token"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorUnauthorizedClient,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			clientID, clientSecret := tc.credentials(t, f)
+			w := f.introspect(clientID, clientSecret, tc.values)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+			authError := core.AuthError{}
+			err := json.Unmarshal(w.Body.Bytes(), &authError)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if authError.ErrorName != tc.expectedError {
+				t.Fatalf("Expected error %s but got %s", tc.expectedError, w.Body)
+			}
+			challenged := w.Header().Get(constants.HeaderAuthenticate) != ""
+			if challenged != (tc.expectedStatus == http.StatusUnauthorized) {
+				t.Fatalf("Expected the challenge only for the failed client authentication but got %q", w.Header().Get(constants.HeaderAuthenticate))
+			}
+		})
+	}
+}
+
+func TestIntrospectionMissingToken(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	w := f.introspect(f.credentialClient.ID, f.credentialClient.Secret, url.Values{})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+	authError := core.AuthError{}
+	err := json.Unmarshal(w.Body.Bytes(), &authError)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if authError.ErrorName != core.AuthErrorInvaidRequest {
+		t.Fatalf("Expected error %s but got %s", core.AuthErrorInvaidRequest, w.Body)
+	}
+}
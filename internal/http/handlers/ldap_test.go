@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/federation"
+	"github.com/andriihomiak/wallabago/internal/federation/ldaptest"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+type ldapFixture struct {
+	*tokenConformanceFixture
+}
+
+// newTestLDAPVerifier serves the directory with the users alice, local and user.
+func newTestLDAPVerifier(t *testing.T) *federation.LDAPVerifier {
+	t.Helper()
+	directory, err := ldaptest.NewServer(
+		ldaptest.Entry{DN: "cn=wallabago,dc=example,dc=com", Password: "service-secret"},
+		ldaptest.Entry{
+			DN:       "uid=alice,ou=people,dc=example,dc=com",
+			Password: "alice-secret",
+			Attributes: map[string][]string{
+				"uid":      {"alice"},
+				"mail":     {"alice@example.com"},
+				"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN:         "uid=local,ou=people,dc=example,dc=com",
+			Password:   "directory-secret",
+			Attributes: map[string][]string{"uid": {"local"}, "mail": {"local@example.com"}},
+		},
+		ldaptest.Entry{
+			DN:         "uid=user,ou=people,dc=example,dc=com",
+			Password:   "directory-secret",
+			Attributes: map[string][]string{"uid": {conformanceUsername}, "mail": {"user@example.com"}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	t.Cleanup(directory.Close)
+	verifier, err := federation.NewLDAPVerifier(core.LDAPConfig{
+		URL:          directory.URL(),
+		BindDN:       "cn=wallabago,dc=example,dc=com",
+		BindPassword: "service-secret",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+		AdminGroup:   "cn=admins,ou=groups,dc=example,dc=com",
+	}, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return verifier
+}
+
+func newLDAPFixture(t *testing.T) *ldapFixture {
+	t.Helper()
+	verifier := newTestLDAPVerifier(t)
+	f := newTokenConformanceFixture(t)
+	// the user provisioned earlier by the upstream login has no local password
+	err := f.storage.AddUserInfo(context.Background(), nil, core.UserInfo{ID: "local-id", Username: "local", Email: "local@example.com", PasswordHash: []byte{}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.setIdentityOptions(t, managers.IdentityOptions{Credentials: verifier})
+	return &ldapFixture{tokenConformanceFixture: f}
+}
+
+func (f *ldapFixture) passwordGrant(t *testing.T, username, password string) (int, string) {
+	t.Helper()
+	w := f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType: {core.GrantTypePassword},
+		OAuth2Username:  {username},
+		OAuth2Password:  {password},
+	}).Encode(), nil)
+	if w.Code != http.StatusOK {
+		return w.Code, ""
+	}
+	token := core.AccessTokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	accessToken, err := f.manager.Authenticate(context.Background(), string(token.AccessToken.Token))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return w.Code, accessToken.UserID
+}
+
+func TestPasswordGrantLDAP(t *testing.T) {
+	cases := []struct {
+		name           string
+		username       string
+		password       string
+		expectedStatus int
+		expectedUserID string
+	}{
+		{name: "local password", username: conformanceUsername, password: conformancePassword, expectedStatus: http.StatusOK, expectedUserID: "user-id"},
+		{name: "local password wins over directory", username: conformanceUsername, password: "directory-secret", expectedStatus: http.StatusBadRequest},
+		{name: "directory user without local password", username: "local", password: "directory-secret", expectedStatus: http.StatusOK, expectedUserID: "local-id"},
+		{name: "directory user provisioned", username: "alice", password: "alice-secret", expectedStatus: http.StatusOK},
+		{name: "wrong directory password", username: "alice", password: "wrong", expectedStatus: http.StatusBadRequest},
+		{name: "unknown user", username: "carol", password: "alice-secret", expectedStatus: http.StatusBadRequest},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			f := newLDAPFixture(t)
+			status, userID := f.passwordGrant(t, testCase.username, testCase.password)
+			if status != testCase.expectedStatus {
+				t.Fatalf("Expected status %d but got %d", testCase.expectedStatus, status)
+			}
+			if testCase.expectedUserID != "" && userID != testCase.expectedUserID {
+				t.Fatalf("Expected user %s but got %s", testCase.expectedUserID, userID)
+			}
+		})
+	}
+}
+
+func TestPasswordGrantLDAPProvisioning(t *testing.T) {
+	f := newLDAPFixture(t)
+	ctx := context.Background()
+	_, first := f.passwordGrant(t, "alice", "alice-secret")
+	user, err := f.storage.GetUserByID(ctx, nil, first)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if user.Username != "alice" || !user.IsAdmin {
+		t.Fatalf("Expected provisioned administrator alice but got %#v", user)
+	}
+	// the provisioned user has no local password, so the directory keeps checking it
+	_, second := f.passwordGrant(t, "alice", "alice-secret")
+	if second != first {
+		t.Fatalf("Expected user %s but got %s", first, second)
+	}
+	if status, _ := f.passwordGrant(t, "alice", ""); status != http.StatusBadRequest {
+		t.Fatalf("Expected empty password to be rejected but got %d", status)
+	}
+}
+
+func TestPasswordGrantLDAPUnverifiedEmail(t *testing.T) {
+	f := newLDAPFixture(t)
+	ctx := context.Background()
+	passwordHash, err := core.DefaultPasswordPolicy().Hash("squatter-password")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// the account signed up with the email of alice before she logged in for the first time
+	err = f.storage.AddUserInfo(ctx, nil, core.UserInfo{
+		ID:              "squatter-id",
+		Username:        "squatter",
+		Email:           "alice@example.com",
+		PasswordHash:    passwordHash,
+		EmailUnverified: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if status, _ := f.passwordGrant(t, "alice", "alice-secret"); status != http.StatusBadRequest {
+		t.Fatalf("Expected the login to be denied but got %d", status)
+	}
+	for _, identity := range f.storage.federated {
+		if identity.UserID == "squatter-id" {
+			t.Fatalf("Expected the unverified account to stay unlinked but got %#v", identity)
+		}
+	}
+}
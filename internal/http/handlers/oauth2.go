@@ -2,10 +2,18 @@ package handlers
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
 	"github.com/andriihomiak/wallabago/internal/http/response"
 	"github.com/andriihomiak/wallabago/internal/managers"
 	"github.com/pkg/errors"
@@ -13,13 +21,17 @@ import (
 
 type OAuth2Handler struct {
 	manager *managers.IdentityManager
+	// publicURL is the base url the server is reachable at by the users
+	publicURL string
 }
 
 func NewOAuth2Handler(
 	manager *managers.IdentityManager,
+	publicURL string,
 ) *OAuth2Handler {
 	return &OAuth2Handler{
-		manager: manager,
+		manager:   manager,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
 	}
 }
 
@@ -29,8 +41,22 @@ const (
 	OAuth2ClientSecret = "client_secret"
 	OAuth2Username     = "username"
 	OAuth2Password     = "password"
+	OAuth2OTP          = "otp"
 	OAuth2Scope        = "scope"
 	OAuth2RefreshToken = "refresh_token"
+
+	OAuth2ResponseType        = "response_type"
+	OAuth2RedirectURI         = "redirect_uri"
+	OAuth2State               = "state"
+	OAuth2Code                = "code"
+	OAuth2CodeChallenge       = "code_challenge"
+	OAuth2CodeChallengeMethod = "code_challenge_method"
+	OAuth2CodeVerifier        = "code_verifier"
+	OAuth2Nonce               = "nonce"
+	OAuth2Error               = "error"
+
+	// tokenEndpointRealm is sent to the clients that failed to authenticate
+	tokenEndpointRealm = "wallabago"
 )
 
 func requiredPostFormField(r *http.Request, key string) (string, error) {
@@ -46,11 +72,7 @@ func requiredPostFormField(r *http.Request, key string) (string, error) {
 }
 
 func requiredPasswordFlowRequest(r *http.Request) (*core.PasswordFlowRequest, error) {
-	clientID, requiredErr := requiredPostFormField(r, OAuth2ClientID)
-	if requiredErr != nil {
-		return nil, requiredErr
-	}
-	clientSecret, requiredErr := requiredPostFormField(r, OAuth2ClientSecret)
+	clientID, clientSecret, requiredErr := clientCredentials(r)
 	if requiredErr != nil {
 		return nil, requiredErr
 	}
@@ -68,49 +90,350 @@ func requiredPasswordFlowRequest(r *http.Request) (*core.PasswordFlowRequest, er
 		Username:     username,
 		ClientSecret: clientSecret,
 		Password:     password,
+		Scope:        r.PostForm.Get(OAuth2Scope),
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+		OTP:          r.PostForm.Get(OAuth2OTP),
 	}, nil
 }
 
+// clientIP is the address the request came from, the forwarding headers
+// are not trusted since anyone could set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// setRetryAfter rounds the wait up, so that the client does not retry too early.
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set(constants.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
 func (h *OAuth2Handler) handlePasswordFlow(w http.ResponseWriter, r *http.Request) {
 	req, requiredFieldErr := requiredPasswordFlowRequest(r)
 	if requiredFieldErr != nil {
-		response.RespondErrorPlain(w, r, requiredFieldErr, http.StatusBadRequest)
+		respondTokenError(w, r, invalidTokenRequest(requiredFieldErr))
 		return
 	}
 
 	token, err := h.manager.PasswordFlow(r.Context(), *req)
 	if err != nil {
-		authError := &core.AuthError{}
-		if errors.As(err, &authError) {
-			response.RespondJSON(w, r, authError, http.StatusUnauthorized)
-			return
-		}
-		response.RespondInternalErrorWithStack(w, r, err)
+		respondTokenError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, token)
+}
+
+func requiredRefreshTokenFlowRequest(r *http.Request) (*core.RefreshTokenFlowRequest, error) {
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	refreshToken, requiredErr := requiredPostFormField(r, OAuth2RefreshToken)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.RefreshTokenFlowRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+		Scope:        r.PostForm.Get(OAuth2Scope),
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+	}, nil
+}
+
+func (h *OAuth2Handler) handleRefreshTokenFlow(w http.ResponseWriter, r *http.Request) {
+	req, requiredFieldErr := requiredRefreshTokenFlowRequest(r)
+	if requiredFieldErr != nil {
+		respondTokenError(w, r, invalidTokenRequest(requiredFieldErr))
+		return
+	}
+
+	token, err := h.manager.RefreshTokenFlow(r.Context(), *req)
+	if err != nil {
+		respondTokenError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, token)
+}
+
+func requiredAuthorizationCodeFlowRequest(r *http.Request) (*core.AuthorizationCodeFlowRequest, error) {
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	code, requiredErr := requiredPostFormField(r, OAuth2Code)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	redirectURI, requiredErr := requiredPostFormField(r, OAuth2RedirectURI)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	codeVerifier, requiredErr := requiredPostFormField(r, OAuth2CodeVerifier)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.AuthorizationCodeFlowRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: codeVerifier,
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+	}, nil
+}
+
+func (h *OAuth2Handler) handleAuthorizationCodeFlow(w http.ResponseWriter, r *http.Request) {
+	req, requiredFieldErr := requiredAuthorizationCodeFlowRequest(r)
+	if requiredFieldErr != nil {
+		respondTokenError(w, r, invalidTokenRequest(requiredFieldErr))
+		return
+	}
+
+	token, err := h.manager.AuthorizationCodeFlow(r.Context(), *req)
+	if err != nil {
+		respondTokenError(w, r, err)
+		return
 	}
 	response.RespondOKJSON(w, r, token)
 }
 
+func requiredClientCredentialsFlowRequest(r *http.Request) (*core.ClientCredentialsFlowRequest, error) {
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.ClientCredentialsFlowRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scope:        r.PostForm.Get(OAuth2Scope),
+	}, nil
+}
+
+func (h *OAuth2Handler) handleClientCredentialsFlow(w http.ResponseWriter, r *http.Request) {
+	req, requiredFieldErr := requiredClientCredentialsFlowRequest(r)
+	if requiredFieldErr != nil {
+		respondTokenError(w, r, invalidTokenRequest(requiredFieldErr))
+		return
+	}
+
+	token, err := h.manager.ClientCredentialsFlow(r.Context(), *req)
+	if err != nil {
+		respondTokenError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, token)
+}
+
+// TokenEndpoint exchanges the grants for tokens, the errors are reported
+// as described in https://datatracker.ietf.org/doc/html/rfc6749#section-5.2.
 func (h *OAuth2Handler) TokenEndpoint(w http.ResponseWriter, r *http.Request) {
+	// neither the tokens nor the errors about them may be cached,
+	// see https://datatracker.ietf.org/doc/html/rfc6749#section-5.1.
+	w.Header().Set(constants.HeaderCacheControl, "no-store")
+	w.Header().Set(constants.HeaderPragma, "no-cache")
+
 	if mediaType := r.Header.Get(constants.HeaderContentType); mediaType != constants.MimeApplicationXWWWFormURLEncoded {
-		response.RespondErrorPlain(w, r, fmt.Errorf("unsupported media type: '%s', expected: '%s'", mediaType, constants.MimeApplicationXWWWFormURLEncoded), http.StatusUnsupportedMediaType)
+		respondTokenError(w, r, invalidTokenRequest(fmt.Errorf("unsupported media type: '%s', expected: '%s'", mediaType, constants.MimeApplicationXWWWFormURLEncoded)))
 		return
 	}
+	err := r.ParseForm()
+	if err != nil {
+		respondTokenError(w, r, invalidTokenRequest(errors.New("malformed request body")))
+		return
+	}
+	for key, values := range r.PostForm {
+		if len(values) > 1 {
+			respondTokenError(w, r, invalidTokenRequest(fmt.Errorf("repeated field: %s", key)))
+			return
+		}
+	}
 
 	grantType, err := requiredPostFormField(r, OAuth2GrantType)
 	if err != nil {
-		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		respondTokenError(w, r, invalidTokenRequest(err))
 		return
 	}
 
 	switch grantType {
 	case "":
-		response.RespondErrorPlain(w, r, fmt.Errorf("required field: %s", OAuth2GrantType), http.StatusBadRequest)
+		respondTokenError(w, r, invalidTokenRequest(fmt.Errorf("required field: %s", OAuth2GrantType)))
 		return
 	case core.GrantTypePassword:
 		h.handlePasswordFlow(w, r)
 		return
+	case core.GrantTypeRefreshToken:
+		h.handleRefreshTokenFlow(w, r)
+		return
+	case core.GrantTypeAuthorizationCode:
+		h.handleAuthorizationCodeFlow(w, r)
+		return
+	case core.GrantTypeClientCredentials:
+		h.handleClientCredentialsFlow(w, r)
+		return
+	case core.GrantTypeDeviceCode:
+		h.handleDeviceCodeFlow(w, r)
+		return
 	default:
-		response.RespondInternalErrorWithStack(w, r, fmt.Errorf("grant type '%s' is not implemented", grantType))
+		respondTokenError(w, r, &core.AuthError{
+			ErrorName:        core.AuthErrorUnsupportedGrantType,
+			ErrorDescription: fmt.Sprintf("Grant type '%s' is not supported", grantType),
+		})
+		return
+	}
+}
+
+// invalidTokenRequest reports the malformed requests, the message
+// of the error is expected to be safe to show to the client.
+func invalidTokenRequest(err error) *core.AuthError {
+	return &core.AuthError{
+		ErrorName:        core.AuthErrorInvaidRequest,
+		ErrorDescription: err.Error(),
+	}
+}
+
+// tokenErrorStatus only uses 401 for the failed client authentication,
+// every other error of the token endpoint is a bad request.
+func tokenErrorStatus(authError *core.AuthError) int {
+	if authError.ErrorName == core.AuthErrorInvalidClient {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
+
+// respondClientError sends the error of the endpoints the clients authenticate to,
+// the clients that failed to authenticate are challenged for their credentials.
+func respondClientError(w http.ResponseWriter, r *http.Request, authError *core.AuthError) {
+	if authError.ErrorName == core.AuthErrorInvalidClient {
+		w.Header().Set(constants.HeaderAuthenticate, fmt.Sprintf("Basic realm=%q", tokenEndpointRealm))
+	}
+	response.RespondJSON(w, r, authError, tokenErrorStatus(authError))
+}
+
+// respondTokenError sends the error response of the token endpoint,
+// the details of the unexpected errors are logged instead of being sent to the client.
+func respondTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	throttledError := &core.LoginThrottledError{}
+	if errors.As(err, &throttledError) {
+		setRetryAfter(w, throttledError.RetryAfter)
+		response.RespondJSON(w, r, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Too many failed logins, retry later",
+		}, http.StatusTooManyRequests)
+		return
+	}
+	authError := &core.AuthError{}
+	if errors.As(err, &authError) {
+		respondClientError(w, r, authError)
+		return
+	}
+	slog.ErrorContext(r.Context(), "Token request failed", "cause", fmt.Sprintf("%+v", err))
+	response.RespondJSON(w, r, &core.AuthError{
+		ErrorName:        core.AuthErrorServerError,
+		ErrorDescription: "Internal server error",
+	}, http.StatusInternalServerError)
+}
+
+func authorizationRequestFromValues(values url.Values) core.AuthorizationRequest {
+	return core.AuthorizationRequest{
+		ResponseType:        values.Get(OAuth2ResponseType),
+		ClientID:            values.Get(OAuth2ClientID),
+		RedirectURI:         values.Get(OAuth2RedirectURI),
+		Scope:               values.Get(OAuth2Scope),
+		State:               values.Get(OAuth2State),
+		CodeChallenge:       values.Get(OAuth2CodeChallenge),
+		CodeChallengeMethod: values.Get(OAuth2CodeChallengeMethod),
+		Nonce:               values.Get(OAuth2Nonce),
+	}
+}
+
+// respondAuthorizationError either sends the error back to the client
+// or, when the redirect uri can not be trusted, shows it to the user.
+func respondAuthorizationError(w http.ResponseWriter, r *http.Request, err error) {
+	redirectableError := &core.RedirectableAuthError{}
+	if errors.As(err, &redirectableError) {
+		location, locationErr := redirectableError.Location()
+		if locationErr != nil {
+			response.RespondInternalErrorWithStack(w, r, locationErr)
+			return
+		}
+		http.Redirect(w, r, location, http.StatusSeeOther)
+		return
+	}
+	authError := &core.AuthError{}
+	if errors.As(err, &authError) {
+		response.RespondHTML(w, r, templates, "error.html", errorPage{
+			Description: authError.ErrorDescription,
+		}, http.StatusBadRequest)
+		return
+	}
+	response.RespondInternalErrorWithStack(w, r, err)
+}
+
+type consentPage struct {
+	Request core.AuthorizationRequest
+	Scope   core.Scope
+}
+
+// AuthorizationEndpoint asks the logged in user to approve the authorization request.
+func (h *OAuth2Handler) AuthorizationEndpoint(w http.ResponseWriter, r *http.Request) {
+	req, err := h.manager.ValidateAuthorizationRequest(r.Context(), authorizationRequestFromValues(r.URL.Query()))
+	if err != nil {
+		respondAuthorizationError(w, r, err)
+		return
+	}
+	// the consent page must never be embedded to prevent clickjacking
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	response.RespondHTML(w, r, templates, "consent.html", consentPage{
+		Request: *req,
+		Scope:   core.Scope(req.Scope),
+	}, http.StatusOK)
+}
+
+// AuthorizationDecision handles the submission of the consent page.
+func (h *OAuth2Handler) AuthorizationDecision(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	token := middleware.MustGetAccessToken(r)
+	req := authorizationRequestFromValues(r.PostForm)
+
+	if r.PostForm.Get("decision") != "approve" {
+		validated, err := h.manager.ValidateAuthorizationRequest(r.Context(), req)
+		if err != nil {
+			respondAuthorizationError(w, r, err)
+			return
+		}
+		respondAuthorizationError(w, r, &core.RedirectableAuthError{
+			AuthError: core.AuthError{
+				ErrorName:        core.AuthErrorAccessDenied,
+				ErrorDescription: "The user denied the request",
+			},
+			RedirectURI: validated.RedirectURI,
+			State:       validated.State,
+		})
+		return
+	}
+
+	code, err := h.manager.Authorize(r.Context(), token.UserID, req)
+	if err != nil {
+		respondAuthorizationError(w, r, err)
+		return
+	}
+	location, err := core.AuthorizationRedirectLocation(code.RedirectURI, url.Values{
+		OAuth2Code: []string{code.Code},
+	}, req.State)
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
 		return
 	}
+	http.Redirect(w, r, location, http.StatusSeeOther)
 }
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PasskeyLoginOptionsPath starts the passkey login.
+	PasskeyLoginOptionsPath = "/login/passkey/options"
+	// PasskeyLoginPath finishes the passkey login with the answer of the authenticator.
+	PasskeyLoginPath = "/login/passkey"
+	// PasskeysPath is where the logged in user manages the passkeys.
+	PasskeysPath = "/account/passkeys"
+	// PasskeyRegistrationOptionsPath starts the registration of a passkey.
+	PasskeyRegistrationOptionsPath = PasskeysPath + "/options"
+)
+
+// readCredential returns the JSON of the PublicKeyCredential sent by the browser.
+func readCredential(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	credential, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONBodySize))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return credential, nil
+}
+
+// PasskeyLoginOptions returns the options of navigator.credentials.get.
+func (s *WebUI) PasskeyLoginOptions(w http.ResponseWriter, r *http.Request) {
+	options, err := s.identity.BeginPasskeyLogin(r.Context())
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, options)
+}
+
+// PasskeyLogin starts the session of the user the passkey belongs to,
+// the page sends the user on once the cookie is set.
+func (s *WebUI) PasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	credential, err := readCredential(w, r)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.identity.PasskeyLogin(r.Context(), core.PasskeyLoginRequest{
+		ClientID:     s.client.ID,
+		ClientSecret: s.client.Secret,
+		Credential:   credential,
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+	})
+	if err != nil {
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			response.RespondJSON(w, r, authError, http.StatusUnauthorized)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	middleware.SetSessionCookie(w, r, &token.AccessToken)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type passkeysPage struct {
+	Passkeys []core.Passkey
+}
+
+// PasskeysPage lists the passkeys of the user.
+func (s *WebUI) PasskeysPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	passkeys, err := s.identity.GetPasskeys(r.Context(), middleware.MustGetAccessToken(r).UserID)
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	response.RespondHTML(w, r, templates, "passkeys.html", passkeysPage{
+		Passkeys: passkeys,
+	}, http.StatusOK)
+}
+
+// PasskeyRegistrationOptions returns the options of navigator.credentials.create.
+func (s *WebUI) PasskeyRegistrationOptions(w http.ResponseWriter, r *http.Request) {
+	options, err := s.identity.BeginPasskeyRegistration(r.Context(), middleware.MustGetAccessToken(r).UserID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, options)
+}
+
+type passkeyResponse struct {
+	ID string `json:"id"`
+}
+
+// RegisterPasskey stores the passkey created by the authenticator.
+func (s *WebUI) RegisterPasskey(w http.ResponseWriter, r *http.Request) {
+	credential, err := readCredential(w, r)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := s.identity.FinishPasskeyRegistration(r.Context(), middleware.MustGetAccessToken(r).UserID, credential)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondJSON(w, r, passkeyResponse{ID: passkey.ID()}, http.StatusCreated)
+}
+
+// DeletePasskey removes the passkey of the user.
+func (s *WebUI) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	credentialID, err := core.ParsePasskeyID(r.PathValue("passkeyID"))
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.identity.DeletePasskey(r.Context(), middleware.MustGetAccessToken(r).UserID, credentialID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, PasskeysPath, http.StatusSeeOther)
+}
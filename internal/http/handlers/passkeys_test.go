@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/managers/passkeytest"
+)
+
+type passkeyFixture struct {
+	*tokenConformanceFixture
+	// sessionCookie belongs to the user with the password
+	sessionCookie *http.Cookie
+}
+
+func newPasskeyFixture(t *testing.T) *passkeyFixture {
+	t.Helper()
+	f := newTokenConformanceFixture(t)
+	token, err := f.manager.PasswordFlow(context.Background(), core.PasswordFlowRequest{
+		ClientID:     f.client.ID,
+		ClientSecret: f.client.Secret,
+		Username:     conformanceUsername,
+		Password:     conformancePassword,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return &passkeyFixture{
+		tokenConformanceFixture: f,
+		sessionCookie:           &http.Cookie{Name: middleware.SessionCookieName, Value: string(token.AccessToken.Token)},
+	}
+}
+
+func (f *passkeyFixture) serve(path string, body []byte, withSession bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if withSession {
+		req.AddCookie(f.sessionCookie)
+	}
+	return serve(f.router, req)
+}
+
+// register runs the registration ceremony of the logged in user with the authenticator.
+func (f *passkeyFixture) register(t *testing.T, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+	t.Helper()
+	w := f.serve(PasskeyRegistrationOptionsPath, nil, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	credential, err := authenticator.Create(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return f.serve(PasskeysPath, credential, true)
+}
+
+// loginCredential answers the options of a new passkey login with the authenticator.
+func (f *passkeyFixture) loginCredential(t *testing.T, authenticator *passkeytest.Authenticator) []byte {
+	t.Helper()
+	w := f.serve(PasskeyLoginOptionsPath, nil, false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	credential, err := authenticator.Get(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return credential
+}
+
+func (f *passkeyFixture) login(t *testing.T, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+	t.Helper()
+	return f.serve(PasskeyLoginPath, f.loginCredential(t, authenticator), false)
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	f := newPasskeyFixture(t)
+	authenticator := passkeytest.NewAuthenticator("http://localhost")
+	if w := f.register(t, authenticator); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+
+	for range 2 {
+		w := f.login(t, authenticator)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != middleware.SessionCookieName {
+			t.Fatalf("Expected session cookie but got %v", cookies)
+		}
+		accessToken, err := f.manager.Authenticate(context.Background(), cookies[0].Value)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if accessToken.UserID != "user-id" || accessToken.GrantType != core.GrantTypePasskey {
+			t.Fatalf("Expected passkey session of user-id but got %#v", accessToken)
+		}
+	}
+
+	passkeys, err := f.manager.GetPasskeys(context.Background(), "user-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(passkeys) != 1 || passkeys[0].SignCount != 2 || passkeys[0].LastUsedAt.IsZero() {
+		t.Fatalf("Expected the passkey to record its use but got %#v", passkeys)
+	}
+}
+
+func TestPasskeyLoginRejected(t *testing.T) {
+	cases := []struct {
+		name  string
+		login func(t *testing.T, f *passkeyFixture, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder
+	}{
+		{
+			name: "other origin",
+			login: func(t *testing.T, f *passkeyFixture, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+				authenticator.Origin = "https://wallabago.example.com"
+				return f.login(t, authenticator)
+			},
+		},
+		{
+			name: "user not verified",
+			login: func(t *testing.T, f *passkeyFixture, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+				authenticator.SkipUserVerification = true
+				return f.login(t, authenticator)
+			},
+		},
+		{
+			name: "replayed answer",
+			login: func(t *testing.T, f *passkeyFixture, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+				credential := f.loginCredential(t, authenticator)
+				if w := f.serve(PasskeyLoginPath, credential, false); w.Code != http.StatusNoContent {
+					t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+				}
+				return f.serve(PasskeyLoginPath, credential, false)
+			},
+		},
+		{
+			name: "cloned authenticator",
+			login: func(t *testing.T, f *passkeyFixture, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+				clone := authenticator.Clone()
+				if w := f.login(t, authenticator); w.Code != http.StatusNoContent {
+					t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+				}
+				return f.login(t, clone)
+			},
+		},
+		{
+			name: "removed passkey",
+			login: func(t *testing.T, f *passkeyFixture, authenticator *passkeytest.Authenticator) *httptest.ResponseRecorder {
+				passkeys, err := f.manager.GetPasskeys(context.Background(), "user-id")
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				w := f.serve(PasskeysPath+"/"+passkeys[0].ID()+"/delete", nil, true)
+				if w.Code != http.StatusSeeOther {
+					t.Fatalf("Expected status %d but got %d: %s", http.StatusSeeOther, w.Code, w.Body)
+				}
+				return f.login(t, authenticator)
+			},
+		},
+		{
+			name: "malformed answer",
+			login: func(_ *testing.T, f *passkeyFixture, _ *passkeytest.Authenticator) *httptest.ResponseRecorder {
+				return f.serve(PasskeyLoginPath, []byte(`{"id":"AAAA"}`), false)
+			},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			f := newPasskeyFixture(t)
+			authenticator := passkeytest.NewAuthenticator("http://localhost")
+			if w := f.register(t, authenticator); w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+			}
+			w := testCase.login(t, f, authenticator)
+			if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), core.AuthErrorInvalidGrant) {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusUnauthorized, w.Code, w.Body)
+			}
+			if len(w.Result().Cookies()) != 0 {
+				t.Fatalf("Expected no session cookie but got %v", w.Result().Cookies())
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	f := newPasskeyFixture(t)
+	authenticator := passkeytest.NewAuthenticator("http://localhost")
+	if w := f.register(t, authenticator); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+
+	// the options exclude the passkeys the user already has
+	w := f.serve(PasskeyRegistrationOptionsPath, nil, true)
+	if _, err := authenticator.Create(w.Body.Bytes()); err == nil {
+		t.Fatalf("Expected the authenticator to refuse a second passkey")
+	}
+
+	phished := passkeytest.NewAuthenticator("https://wallabago.example.com")
+	if w := f.register(t, phished); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+
+	w = f.serve(PasskeyRegistrationOptionsPath, nil, true)
+	credential, err := passkeytest.NewAuthenticator("http://localhost").Create(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if w := f.serve(PasskeysPath, credential, true); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	if w := f.serve(PasskeysPath, credential, true); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the replayed registration to be rejected but got %d: %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, PasskeysPath, http.NoBody)
+	req.AddCookie(f.sessionCookie)
+	page := serve(f.router, req)
+	if page.Code != http.StatusOK || strings.Count(page.Body.String(), "/delete") != 2 {
+		t.Fatalf("Expected two passkeys to be listed but got %d: %s", page.Code, page.Body)
+	}
+}
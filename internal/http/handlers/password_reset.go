@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PasswordResetPath is where the users who forgot the password ask for the reset link.
+	PasswordResetPath = "/password-reset"
+	// PasswordResetConfirmPath is where the emailed link leads to.
+	PasswordResetConfirmPath = PasswordResetPath + "/confirm"
+
+	formEmail = "email"
+	// formPasswordConfirmation repeats the new password next to OAuth2Password
+	formPasswordConfirmation = "password_confirmation"
+)
+
+type passwordResetPage struct {
+	Email string
+	// Sent replaces the form with the message that the link is on the way
+	Sent bool
+}
+
+type passwordResetConfirmPage struct {
+	Token string
+	Error string
+}
+
+// setPasswordResetHeaders keeps the pages out of the frames and the caches,
+// the reset link must not leak to anywhere the page links to either.
+func setPasswordResetHeaders(w http.ResponseWriter) {
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	w.Header().Set(constants.HeaderCacheControl, "no-store")
+	w.Header().Set(constants.HeaderReferrer, "no-referrer")
+}
+
+// PasswordResetPage asks for the email address of the account.
+func (s *WebUI) PasswordResetPage(w http.ResponseWriter, r *http.Request) {
+	if !s.identity.PasswordResetEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+	setPasswordResetHeaders(w)
+	response.RespondHTML(w, r, templates, "password_reset.html", passwordResetPage{}, http.StatusOK)
+}
+
+// RequestPasswordReset emails the reset link. The page looks the same whether
+// the account exists or not, so that it can not be used to find out who has one.
+func (s *WebUI) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	page := passwordResetPage{Email: r.PostForm.Get(formEmail), Sent: true}
+
+	err = s.identity.RequestPasswordReset(r.Context(), page.Email, PasswordResetConfirmPath)
+	if errors.Is(err, core.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		// the error page would tell that the account exists
+		slog.ErrorContext(r.Context(), "Failed to send the password reset link", "cause", err.Error())
+	}
+	setPasswordResetHeaders(w)
+	response.RespondHTML(w, r, templates, "password_reset.html", page, http.StatusOK)
+}
+
+// PasswordResetConfirmPage asks for the new password, the emailed token is carried in the form.
+func (s *WebUI) PasswordResetConfirmPage(w http.ResponseWriter, r *http.Request) {
+	if !s.identity.PasswordResetEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+	setPasswordResetHeaders(w)
+	response.RespondHTML(w, r, templates, "password_reset_confirm.html", passwordResetConfirmPage{
+		Token: r.URL.Query().Get(core.PasswordResetLinkParameter),
+	}, http.StatusOK)
+}
+
+// ResetPassword sets the new password and logs the user out everywhere.
+func (s *WebUI) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	page := passwordResetConfirmPage{Token: r.PostForm.Get(core.PasswordResetLinkParameter)}
+	setPasswordResetHeaders(w)
+
+	password := r.PostForm.Get(OAuth2Password)
+	if password != r.PostForm.Get(formPasswordConfirmation) {
+		page.Error = "The passwords do not match"
+		response.RespondHTML(w, r, templates, "password_reset_confirm.html", page, http.StatusBadRequest)
+		return
+	}
+	err = s.identity.ResetPassword(r.Context(), core.JWT(page.Token), password)
+	if errors.Is(err, core.ErrInvalidInput) {
+		page.Error = errorMessage(err, core.ErrInvalidInput)
+		response.RespondHTML(w, r, templates, "password_reset_confirm.html", page, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	response.RespondHTML(w, r, templates, "password_reset_done.html", nil, http.StatusOK)
+}
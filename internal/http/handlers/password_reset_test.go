@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/mailer"
+	"github.com/andriihomiak/wallabago/internal/mailer/mailtest"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+const resetEmail = "user@example.com"
+
+var resetLink = regexp.MustCompile(`http://localhost/password-reset/confirm\?token=\S+`)
+
+type passwordResetFixture struct {
+	*tokenConformanceFixture
+	smtp *mailtest.Server
+	// mailer sends through the smtp server, for the tests changing the other options
+	mailer managers.Mailer
+}
+
+func newPasswordResetFixture(t *testing.T) *passwordResetFixture {
+	t.Helper()
+	f := newTokenConformanceFixture(t)
+	user := f.storage.users["user-id"]
+	user.Email = resetEmail
+	err := f.storage.AddUserInfo(context.Background(), nil, user)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	server, err := mailtest.NewServer("", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	t.Cleanup(server.Close)
+	sender, err := mailer.NewSMTPSender("Wallabago <no-reply@example.com>", core.SMTPConfig{Addr: server.Addr()}, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.setIdentityOptions(t, managers.IdentityOptions{Mailer: sender})
+	return &passwordResetFixture{
+		tokenConformanceFixture: f,
+		smtp:                    server,
+		mailer:                  sender,
+	}
+}
+
+// page requests the page of the web ui, the form is posted when it is set.
+func (f *passwordResetFixture) page(method, target string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+	}
+	return serve(f.router, req)
+}
+
+func (f *passwordResetFixture) requestReset(t *testing.T, email string) {
+	t.Helper()
+	w := f.page(http.MethodPost, PasswordResetPath, url.Values{"email": {email}})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "If an account") {
+		t.Fatalf("Expected the generic answer but got %d: %s", w.Code, w.Body)
+	}
+}
+
+// link returns the reset link of the last email.
+func (f *passwordResetFixture) link(t *testing.T) string {
+	t.Helper()
+	return f.emailedLink(t, resetLink)
+}
+
+// emailedLink finds the link in the body of the last email.
+func (f *passwordResetFixture) emailedLink(t *testing.T, pattern *regexp.Regexp) string {
+	t.Helper()
+	messages := f.smtp.Messages()
+	if len(messages) == 0 {
+		t.Fatalf("Expected an email")
+	}
+	message, err := mail.ReadMessage(strings.NewReader(messages[len(messages)-1].Data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	link := pattern.FindString(string(body))
+	if link == "" {
+		t.Fatalf("Expected a link matching %s but got %s", pattern, body)
+	}
+	return link
+}
+
+// token requests the reset and returns the token of the emailed link.
+func (f *passwordResetFixture) token(t *testing.T) string {
+	t.Helper()
+	f.requestReset(t, resetEmail)
+	link, err := url.Parse(f.link(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return link.Query().Get(core.PasswordResetLinkParameter)
+}
+
+func (f *passwordResetFixture) confirm(t *testing.T, token string, status int) {
+	t.Helper()
+	w := f.page(http.MethodPost, PasswordResetConfirmPath, url.Values{
+		"token":                 {token},
+		"password":              {"new-password"},
+		"password_confirmation": {"new-password"},
+	})
+	if w.Code != status {
+		t.Fatalf("Expected status %d but got %d: %s", status, w.Code, w.Body)
+	}
+}
+
+func (f *passwordResetFixture) passwordGrant(password string) *httptest.ResponseRecorder {
+	return f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType: {core.GrantTypePassword},
+		OAuth2Username:  {conformanceUsername},
+		OAuth2Password:  {password},
+	}).Encode(), nil)
+}
+
+func TestPasswordReset(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	w := f.passwordGrant(conformancePassword)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", w.Code, w.Body)
+	}
+	session := core.AccessTokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &session)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	w = f.page(http.MethodGet, middleware.LoginPath, nil)
+	if !strings.Contains(w.Body.String(), `href="/password-reset"`) {
+		t.Fatalf("Expected the login page to offer the reset but got %s", w.Body)
+	}
+	f.requestReset(t, resetEmail)
+	messages := f.smtp.Messages()
+	if len(messages) != 1 || messages[0].To[0] != resetEmail {
+		t.Fatalf("Expected an email to %s but got %#v", resetEmail, messages)
+	}
+	link, err := url.Parse(f.link(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	token := link.Query().Get(core.PasswordResetLinkParameter)
+
+	w = f.page(http.MethodGet, link.RequestURI(), nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), token) {
+		t.Fatalf("Expected the form with the token but got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get(constants.HeaderReferrer) != "no-referrer" {
+		t.Fatalf("Expected the link to stay out of the referrer but got %q", w.Header().Get(constants.HeaderReferrer))
+	}
+	w = f.page(http.MethodPost, PasswordResetConfirmPath, url.Values{
+		"token":                 {token},
+		"password":              {"new-password"},
+		"password_confirmation": {"other-password"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the mismatched passwords to be rejected but got %d", w.Code)
+	}
+	f.confirm(t, token, http.StatusOK)
+
+	if w := f.authorized(http.MethodGet, "/protected", string(session.AccessToken.Token), ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the sessions to be logged out but got %d", w.Code)
+	}
+	w = f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType:    {core.GrantTypeRefreshToken},
+		OAuth2RefreshToken: {string(session.RefreshToken)},
+	}).Encode(), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the refresh token to be revoked but got %d", w.Code)
+	}
+	// the link can be used once
+	f.confirm(t, token, http.StatusBadRequest)
+	if w := f.passwordGrant(conformancePassword); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the old password to be rejected but got %d", w.Code)
+	}
+	if w := f.passwordGrant("new-password"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the new password to work but got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestPasswordResetRevokesCredentials(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	ctx := context.Background()
+	personalToken, err := core.NewPersonalAccessToken("user-id", "backup", "entries", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = f.storage.AddPersonalAccessToken(ctx, nil, *personalToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = f.storage.SetLoginAttempts(ctx, nil, core.LoginAttempts{
+		Kind:           core.LoginAttemptsKindUsername,
+		Subject:        conformanceUsername,
+		FailedAttempts: 10,
+		LastFailedAt:   time.Now(),
+		LockedUntil:    time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if w := f.passwordGrant(conformancePassword); w.Code == http.StatusOK {
+		t.Fatalf("Expected the username to be locked out but got %d", w.Code)
+	}
+
+	f.confirm(t, f.token(t), http.StatusOK)
+
+	if w := f.authorized(http.MethodGet, "/protected", personalToken.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the personal access token to be revoked but got %d", w.Code)
+	}
+	if w := f.passwordGrant("new-password"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the lockout to be lifted but got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestPasswordResetNotSent(t *testing.T) {
+	cases := []struct {
+		name    string
+		email   string
+		prepare func(t *testing.T, f *passwordResetFixture)
+	}{
+		{
+			name:  "unknown email",
+			email: "unknown@example.com",
+		},
+		{
+			name:  "user without a local password",
+			email: "federated@example.com",
+			prepare: func(t *testing.T, f *passwordResetFixture) {
+				err := f.storage.AddUserInfo(context.Background(), nil, core.UserInfo{
+					ID:       "federated-id",
+					Username: "federated",
+					Email:    "federated@example.com",
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			name:  "reset requested moments ago",
+			email: resetEmail,
+			prepare: func(t *testing.T, f *passwordResetFixture) {
+				f.requestReset(t, resetEmail)
+				if len(f.smtp.Messages()) != 1 {
+					t.Fatalf("Expected the first email to be sent")
+				}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newPasswordResetFixture(t)
+			if tc.prepare != nil {
+				tc.prepare(t, f)
+			}
+			sent := len(f.smtp.Messages())
+			f.requestReset(t, tc.email)
+			if messages := f.smtp.Messages(); len(messages) != sent {
+				t.Fatalf("Expected no email but got %#v", messages[sent:])
+			}
+		})
+	}
+}
+
+func TestPasswordResetRejected(t *testing.T) {
+	cases := []struct {
+		name  string
+		token func(t *testing.T, f *passwordResetFixture) string
+	}{
+		{
+			name:  "missing token",
+			token: func(*testing.T, *passwordResetFixture) string { return "" },
+		},
+		{
+			name:  "tampered token",
+			token: func(t *testing.T, f *passwordResetFixture) string { return f.token(t) + "x" },
+		},
+		{
+			name: "superseded token",
+			token: func(t *testing.T, f *passwordResetFixture) string {
+				token := f.token(t)
+				// resetting the password with one link voids the others
+				reset := core.NewPasswordReset("user-id", time.Minute)
+				err := f.storage.AddPasswordReset(context.Background(), nil, *reset)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				f.confirm(t, token, http.StatusOK)
+				sealed, err := reset.Seal("http://localhost", f.keys)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return string(*sealed)
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newPasswordResetFixture(t)
+			f.confirm(t, tc.token(t, f), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestPasswordResetDisabled(t *testing.T) {
+	manager, _ := newTestIdentityManager(t, newMemoryStorage(), managers.IdentityOptions{})
+	router := newTestRouter(manager, core.Client{})
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, PasswordResetPath, http.NoBody),
+		httptest.NewRequest(http.MethodPost, PasswordResetPath, http.NoBody),
+		httptest.NewRequest(http.MethodGet, PasswordResetConfirmPath, http.NoBody),
+	} {
+		w := serve(router, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, w.Code)
+		}
+	}
+}
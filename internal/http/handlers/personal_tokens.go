@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+// PersonalAccessTokensAPI lets the users manage the tokens of their scripts.
+type PersonalAccessTokensAPI struct {
+	identity *managers.IdentityManager
+}
+
+func NewPersonalAccessTokensAPI(identity *managers.IdentityManager) *PersonalAccessTokensAPI {
+	return &PersonalAccessTokensAPI{
+		identity: identity,
+	}
+}
+
+type createTokenRequest struct {
+	Name string `json:"name"`
+	// Scope falls back to the default scope when omitted
+	Scope core.Scope `json:"scope"`
+	// ExpiresInSeconds creates a token that does not expire when omitted
+	ExpiresInSeconds int64 `json:"expires_in"`
+}
+
+type tokenResponse struct {
+	ID string `json:"id"`
+	// Token is only present right after it was created
+	Token      string     `json:"token,omitempty"`
+	Name       string     `json:"name"`
+	Scope      core.Scope `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newTokenResponse(token core.PersonalAccessToken) tokenResponse {
+	result := tokenResponse{
+		ID:        token.ID,
+		Token:     token.Token,
+		Name:      token.Name,
+		Scope:     token.Scope,
+		CreatedAt: token.CreatedAt,
+	}
+	if !token.ExpiresAt.IsZero() {
+		result.ExpiresAt = &token.ExpiresAt
+	}
+	if !token.LastUsedAt.IsZero() {
+		result.LastUsedAt = &token.LastUsedAt
+	}
+	return result
+}
+
+func newTokensResponse(tokens []core.PersonalAccessToken) []tokenResponse {
+	result := make([]tokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, newTokenResponse(token))
+	}
+	return result
+}
+
+// CreateToken creates a personal access token of the user.
+func (a *PersonalAccessTokensAPI) CreateToken(w http.ResponseWriter, r *http.Request) {
+	body := createTokenRequest{}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if body.Name == "" {
+		response.RespondErrorPlain(w, r, fmt.Errorf("required field: %s", "name"), http.StatusBadRequest)
+		return
+	}
+
+	expiresIn, err := lifetime("expires_in", body.ExpiresInSeconds, core.MaxPersonalAccessTokenLifetime)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	token, err := a.identity.CreatePersonalAccessToken(r.Context(), middleware.MustGetAccessToken(r), body.Name, body.Scope, expiresIn)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondJSON(w, r, newTokenResponse(*token), http.StatusCreated)
+}
+
+// ListTokens returns the personal access tokens of the user, without the tokens themselves.
+func (a *PersonalAccessTokensAPI) ListTokens(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	tokens, err := a.identity.GetPersonalAccessTokens(r.Context(), token.UserID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, newTokensResponse(tokens))
+}
+
+// RevokeToken removes the personal access token of the user.
+func (a *PersonalAccessTokensAPI) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	err := a.identity.RevokePersonalAccessToken(r.Context(), token.UserID, r.PathValue("tokenID"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
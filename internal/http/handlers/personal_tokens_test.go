@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+)
+
+func (f *tokenConformanceFixture) createToken(t *testing.T, bearer, body string) tokenResponse {
+	t.Helper()
+	w := f.authorized(http.MethodPost, "/api/tokens", bearer, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	created := tokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return created
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	bearer := f.accessToken(t, "entries tokens")
+
+	created := f.createToken(t, bearer, `{"name":"backup","scope":"entries:read","expires_in":3600}`)
+	if !core.IsPersonalAccessToken(created.Token) {
+		t.Fatalf("Expected a personal access token but got %q", created.Token)
+	}
+	if created.Scope != "entries:read" || created.ExpiresAt == nil {
+		t.Fatalf("Unexpected token %#v", created)
+	}
+
+	w := f.authorized(http.MethodGet, "/protected", created.Token, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = f.authorized(http.MethodGet, "/api/tokens", created.Token, "")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the scope of the token to be enforced but got %d", w.Code)
+	}
+
+	w = f.authorized(http.MethodGet, "/api/tokens", bearer, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	listed := []tokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &listed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID || listed[0].Token != "" || listed[0].LastUsedAt == nil {
+		t.Fatalf("Expected the used token without its value but got %#v", listed)
+	}
+
+	w = f.authorized(http.MethodDelete, "/api/tokens/"+created.ID, bearer, "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	w = f.authorized(http.MethodGet, "/protected", created.Token, "")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the revoked token to be rejected but got %d", w.Code)
+	}
+	w = f.authorized(http.MethodDelete, "/api/tokens/"+created.ID, bearer, "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPersonalAccessTokenRejected(t *testing.T) {
+	cases := []struct {
+		name  string
+		token func(t *testing.T, f *tokenConformanceFixture) string
+	}{
+		{
+			name: "unknown token",
+			token: func(*testing.T, *tokenConformanceFixture) string {
+				return core.PersonalAccessTokenPrefix + "unknown"
+			},
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T, f *tokenConformanceFixture) string {
+				token, err := core.NewPersonalAccessToken("user-id", "expired", "entries", time.Hour)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				token.ExpiresAt = time.Now().Add(-time.Minute)
+				err = f.storage.AddPersonalAccessToken(context.Background(), nil, *token)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return token.Token
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			w := f.authorized(http.MethodGet, "/protected", tc.token(t, f), "")
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status %d but got %d", http.StatusUnauthorized, w.Code)
+			}
+			if !strings.Contains(w.Header().Get(constants.HeaderAuthenticate), string(core.AuthErrorInvalidToken)) {
+				t.Fatalf("Expected an invalid_token challenge but got %q", w.Header().Get(constants.HeaderAuthenticate))
+			}
+		})
+	}
+}
+
+func TestCreatePersonalAccessTokenRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		// bearer defaults to an access token with the entries and tokens scopes
+		bearer func(t *testing.T, f *tokenConformanceFixture) string
+	}{
+		{
+			name: "missing name",
+			body: `{"scope":"entries"}`,
+		},
+		{
+			name: "unknown scope",
+			body: `{"name":"backup","scope":"everything"}`,
+		},
+		{
+			name: "scope beyond the access token",
+			body: `{"name":"backup","scope":"entries admin"}`,
+		},
+		{
+			name: "negative lifetime",
+			body: `{"name":"backup","expires_in":-1}`,
+		},
+		{
+			name: "too long lifetime",
+			body: `{"name":"backup","expires_in":` + strconv.FormatInt(int64(core.MaxPersonalAccessTokenLifetime/time.Second)+1, 10) + `}`,
+		},
+		{
+			// would overflow the duration when converted
+			name: "overflowing lifetime",
+			body: `{"name":"backup","expires_in":` + strconv.FormatInt(math.MaxInt64, 10) + `}`,
+		},
+		{
+			name: "created by a personal access token",
+			body: `{"name":"backup"}`,
+			bearer: func(t *testing.T, f *tokenConformanceFixture) string {
+				return f.createToken(t, f.accessToken(t, "entries tokens"), `{"name":"parent","scope":"entries tokens"}`).Token
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			bearer := f.accessToken(t, "entries tokens")
+			if tc.bearer != nil {
+				bearer = tc.bearer(t, f)
+			}
+			w := f.authorized(http.MethodPost, "/api/tokens", bearer, tc.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPersonalAccessTokenSession(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	created := f.createToken(t, f.accessToken(t, "entries tokens"), `{"name":"backup"}`)
+
+	req := httptest.NewRequest(http.MethodGet, TOTPPath, http.NoBody)
+	req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: created.Token})
+	w := serve(f.router, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected the web ui to reject the personal access token but got %d", w.Code)
+	}
+}
+
+func TestPersonalAccessTokenIntrospection(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	created := f.createToken(t, f.accessToken(t, "entries tokens"), `{"name":"backup","scope":"entries:read","expires_in":3600}`)
+	introspect := func(token string) core.IntrospectionResponse {
+		t.Helper()
+		// the hint does not matter for the personal access tokens
+		values := url.Values{OAuth2Token: {token}, OAuth2TokenTypeHint: {string(core.TokenTypeHintRefreshToken)}}
+		w := f.introspect(f.credentialClient.ID, f.credentialClient.Secret, values)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		introspection := core.IntrospectionResponse{}
+		err := json.Unmarshal(w.Body.Bytes(), &introspection)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return introspection
+	}
+
+	introspection := introspect(created.Token)
+	if !introspection.Active || introspection.Subject != "user-id" || introspection.Scope != "entries:read" ||
+		introspection.JWTID != created.ID || introspection.ClientID != "" || introspection.ExpiresAt == 0 {
+		t.Fatalf("Unexpected introspection %#v", introspection)
+	}
+	if introspection := introspect(core.PersonalAccessTokenPrefix + "unknown"); introspection.Active {
+		t.Fatalf("Expected the unknown token to be inactive but got %#v", introspection)
+	}
+
+	// any client presenting the token revokes it
+	f.revoke(t, created.Token)
+	if introspection := introspect(created.Token); introspection.Active {
+		t.Fatalf("Expected the revoked token to be inactive but got %#v", introspection)
+	}
+	if w := f.authorized(http.MethodGet, "/protected", created.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the revoked token to be rejected but got %d", w.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+// RegistrationPath is where the clients register themselves,
+// each registration is managed at its own path below it.
+const RegistrationPath = "/oauth/v2/register"
+
+// clientInformationResponse describes the registered client,
+// see https://datatracker.ietf.org/doc/html/rfc7591#section-3.2.1.
+type clientInformationResponse struct {
+	ClientID string `json:"client_id"`
+	// ClientSecret and RegistrationAccessToken are only present right after they were generated
+	ClientSecret     string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt int64  `json:"client_id_issued_at"`
+	// ClientSecretExpiresAt is zero for the confidential clients, since the secrets do not expire
+	ClientSecretExpiresAt   *int64 `json:"client_secret_expires_at,omitempty"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+	core.ClientMetadata
+}
+
+func (h *OAuth2Handler) newClientInformationResponse(client core.Client) clientInformationResponse {
+	information := clientInformationResponse{
+		ClientID:                client.ID,
+		ClientSecret:            client.Secret,
+		ClientIDIssuedAt:        client.CreatedAt.Unix(),
+		RegistrationAccessToken: client.RegistrationAccessToken,
+		RegistrationClientURI:   h.publicURL + RegistrationPath + "/" + client.ID,
+		ClientMetadata:          core.NewClientMetadata(client),
+	}
+	if !client.Public {
+		var neverExpires int64
+		information.ClientSecretExpiresAt = &neverExpires
+	}
+	return information
+}
+
+// decodeClientMetadata reads the json body of the registration requests, unlike
+// the other endpoints the unknown fields are ignored as the specification demands.
+func decodeClientMetadata(w http.ResponseWriter, r *http.Request, body any) error {
+	contentType := r.Header.Get(constants.HeaderContentType)
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != constants.MimeApplicationJSON {
+		return fmt.Errorf("unsupported media type: '%s', expected: '%s'", contentType, constants.MimeApplicationJSON)
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBodySize)).Decode(body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// bearerToken returns the token from the authorization header, empty if there is none.
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get(constants.HeaderAuthorization), " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+	return token
+}
+
+// respondRegistrationError maps the errors of the registration endpoints to the response status.
+func respondRegistrationError(w http.ResponseWriter, r *http.Request, err error) {
+	authError := &core.AuthError{}
+	if !errors.As(err, &authError) {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	switch authError.ErrorName {
+	case core.AuthErrorInvalidToken:
+		w.Header().Set(constants.HeaderAuthenticate, fmt.Sprintf("Bearer error=%q", authError.ErrorName))
+		response.RespondJSON(w, r, authError, http.StatusUnauthorized)
+	case core.AuthErrorAccessDenied:
+		response.RespondJSON(w, r, authError, http.StatusForbidden)
+	default:
+		response.RespondJSON(w, r, authError, http.StatusBadRequest)
+	}
+}
+
+// RegistrationEndpoint registers a new client, the initial access token is
+// expected as a bearer token unless the registration is open,
+// see https://datatracker.ietf.org/doc/html/rfc7591#section-3.
+func (h *OAuth2Handler) RegistrationEndpoint(w http.ResponseWriter, r *http.Request) {
+	metadata := core.ClientMetadata{}
+	err := decodeClientMetadata(w, r, &metadata)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.manager.RegisterClient(r.Context(), bearerToken(r), metadata)
+	if err != nil {
+		respondRegistrationError(w, r, err)
+		return
+	}
+	response.RespondJSON(w, r, h.newClientInformationResponse(*client), http.StatusCreated)
+}
+
+// ClientConfigurationEndpoint returns the registered client,
+// see https://datatracker.ietf.org/doc/html/rfc7592#section-2.1.
+func (h *OAuth2Handler) ClientConfigurationEndpoint(w http.ResponseWriter, r *http.Request) {
+	client, err := h.manager.GetRegisteredClient(r.Context(), r.PathValue("clientID"), bearerToken(r))
+	if err != nil {
+		respondRegistrationError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, h.newClientInformationResponse(*client))
+}
+
+// ClientUpdateEndpoint replaces the metadata of the registered client,
+// see https://datatracker.ietf.org/doc/html/rfc7592#section-2.2.
+func (h *OAuth2Handler) ClientUpdateEndpoint(w http.ResponseWriter, r *http.Request) {
+	req := core.ClientUpdateRequest{}
+	err := decodeClientMetadata(w, r, &req)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.manager.UpdateRegisteredClient(r.Context(), r.PathValue("clientID"), bearerToken(r), req)
+	if err != nil {
+		respondRegistrationError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, h.newClientInformationResponse(*client))
+}
+
+// ClientDeleteEndpoint removes the registered client,
+// see https://datatracker.ietf.org/doc/html/rfc7592#section-2.3.
+func (h *OAuth2Handler) ClientDeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	err := h.manager.DeleteRegisteredClient(r.Context(), r.PathValue("clientID"), bearerToken(r))
+	if err != nil {
+		respondRegistrationError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+const (
+	testInitialAccessToken = "initial-access-token"
+	testClientMetadata     = `{"client_name":"App","redirect_uris":["https://app.example.com/callback"]}`
+)
+
+// registration calls the registration endpoint, or the configuration endpoint of the client when the id is set.
+func (f *tokenConformanceFixture) registration(method, clientID, bearer, body string) *httptest.ResponseRecorder {
+	target := RegistrationPath
+	if clientID != "" {
+		target += "/" + clientID
+	}
+	return f.authorized(method, target, bearer, body)
+}
+
+// registerClient registers the client through the open registration.
+func (f *tokenConformanceFixture) registerClient(t *testing.T) clientInformationResponse {
+	t.Helper()
+	f.setIdentityOptions(t, managers.IdentityOptions{Registration: core.NewRegistrationPolicy(true, "")})
+	w := f.registration(http.MethodPost, "", "", testClientMetadata)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	return decodeClientInformation(t, w)
+}
+
+func decodeClientInformation(t *testing.T, w *httptest.ResponseRecorder) clientInformationResponse {
+	t.Helper()
+	information := clientInformationResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &information)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return information
+}
+
+// expectRegistrationError checks the status and the error name of the rejected registration request.
+func expectRegistrationError(t *testing.T, w *httptest.ResponseRecorder, expectedStatus int, expectedError core.AuthErrorName) {
+	t.Helper()
+	if w.Code != expectedStatus {
+		t.Fatalf("Expected status %d but got %d: %s", expectedStatus, w.Code, w.Body)
+	}
+	authError := core.AuthError{}
+	err := json.Unmarshal(w.Body.Bytes(), &authError)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if authError.ErrorName != expectedError {
+		t.Fatalf("Expected error %s but got %s", expectedError, w.Body)
+	}
+	challenged := w.Header().Get(constants.HeaderAuthenticate) != ""
+	if challenged != (expectedStatus == http.StatusUnauthorized) {
+		t.Fatalf("Expected the challenge only for the bad tokens but got %q", w.Header().Get(constants.HeaderAuthenticate))
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	cases := []struct {
+		name           string
+		policy         core.RegistrationPolicy
+		bearer         string
+		body           string
+		expectedStatus int
+		expectedError  core.AuthErrorName
+	}{
+		{
+			name:           "disabled",
+			body:           testClientMetadata,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  core.AuthErrorAccessDenied,
+		},
+		{
+			name:           "open",
+			policy:         core.NewRegistrationPolicy(true, ""),
+			body:           testClientMetadata,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "initial access token",
+			policy:         core.NewRegistrationPolicy(false, testInitialAccessToken),
+			bearer:         testInitialAccessToken,
+			body:           testClientMetadata,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing initial access token",
+			policy:         core.NewRegistrationPolicy(false, testInitialAccessToken),
+			body:           testClientMetadata,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidToken,
+		},
+		{
+			name:           "wrong initial access token",
+			policy:         core.NewRegistrationPolicy(false, testInitialAccessToken),
+			bearer:         "wrong",
+			body:           testClientMetadata,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidToken,
+		},
+		{
+			name:           "missing redirect uri",
+			policy:         core.NewRegistrationPolicy(true, ""),
+			body:           `{"client_name":"App"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidRedirectURI,
+		},
+		{
+			name:           "unsupported auth method",
+			policy:         core.NewRegistrationPolicy(true, ""),
+			body:           `{"redirect_uris":["https://app.example.com/callback"],"token_endpoint_auth_method":"private_key_jwt"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidClientMetadata,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			f.setIdentityOptions(t, managers.IdentityOptions{Registration: tc.policy})
+			w := f.registration(http.MethodPost, "", tc.bearer, tc.body)
+			if tc.expectedError != "" {
+				expectRegistrationError(t, w, tc.expectedStatus, tc.expectedError)
+				return
+			}
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+			information := decodeClientInformation(t, w)
+			if information.ClientSecret == "" || information.RegistrationAccessToken == "" ||
+				information.RegistrationClientURI != testPublicURL+RegistrationPath+"/"+information.ClientID {
+				t.Fatalf("Expected the credentials of the registered client but got %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestClientConfiguration(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	registered := f.registerClient(t)
+	other := f.registerClient(t)
+
+	for _, bearer := range []string{"", "wrong", other.RegistrationAccessToken} {
+		expectRegistrationError(t, f.registration(http.MethodGet, registered.ClientID, bearer, ""), http.StatusUnauthorized, core.AuthErrorInvalidToken)
+	}
+	// the unknown clients look like the bad tokens
+	expectRegistrationError(t, f.registration(http.MethodGet, "unknown", registered.RegistrationAccessToken, ""), http.StatusUnauthorized, core.AuthErrorInvalidToken)
+
+	w := f.registration(http.MethodGet, registered.ClientID, registered.RegistrationAccessToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	information := decodeClientInformation(t, w)
+	if information.ClientID != registered.ClientID || information.ClientName != "App" {
+		t.Fatalf("Expected the registered client but got %s", w.Body)
+	}
+	if information.ClientSecret != "" || information.RegistrationAccessToken != "" {
+		t.Fatalf("Expected the credentials to be returned only when issued but got %s", w.Body)
+	}
+}
+
+func TestClientUpdate(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	registered := f.registerClient(t)
+	update := func(clientID, clientSecret string) string {
+		return `{"client_id":"` + clientID + `","client_secret":"` + clientSecret +
+			`","client_name":"Renamed","redirect_uris":["https://app.example.com/callback"]}`
+	}
+
+	for _, bearer := range []string{"", "wrong"} {
+		w := f.registration(http.MethodPut, registered.ClientID, bearer, update(registered.ClientID, ""))
+		expectRegistrationError(t, w, http.StatusUnauthorized, core.AuthErrorInvalidToken)
+	}
+	w := f.registration(http.MethodPut, registered.ClientID, registered.RegistrationAccessToken, update("another-client", ""))
+	expectRegistrationError(t, w, http.StatusBadRequest, core.AuthErrorInvaidRequest)
+	w = f.registration(http.MethodPut, registered.ClientID, registered.RegistrationAccessToken, update(registered.ClientID, "wrong"))
+	expectRegistrationError(t, w, http.StatusBadRequest, core.AuthErrorInvalidClientMetadata)
+
+	w = f.registration(http.MethodGet, registered.ClientID, registered.RegistrationAccessToken, "")
+	if information := decodeClientInformation(t, w); information.ClientName != "App" {
+		t.Fatalf("Expected the rejected updates to leave the client untouched but got %s", w.Body)
+	}
+
+	w = f.registration(http.MethodPut, registered.ClientID, registered.RegistrationAccessToken, update(registered.ClientID, registered.ClientSecret))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if information := decodeClientInformation(t, w); information.ClientID != registered.ClientID || information.ClientName != "Renamed" {
+		t.Fatalf("Expected the client to be renamed but got %s", w.Body)
+	}
+}
+
+func TestClientDelete(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	registered := f.registerClient(t)
+
+	for _, bearer := range []string{"", "wrong"} {
+		expectRegistrationError(t, f.registration(http.MethodDelete, registered.ClientID, bearer, ""), http.StatusUnauthorized, core.AuthErrorInvalidToken)
+	}
+	if w := f.registration(http.MethodGet, registered.ClientID, registered.RegistrationAccessToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the client to be kept but got %d: %s", w.Code, w.Body)
+	}
+
+	if w := f.registration(http.MethodDelete, registered.ClientID, registered.RegistrationAccessToken, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	expectRegistrationError(t, f.registration(http.MethodGet, registered.ClientID, registered.RegistrationAccessToken, ""), http.StatusUnauthorized, core.AuthErrorInvalidToken)
+}
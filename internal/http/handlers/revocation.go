@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	OAuth2Token         = "token"
+	OAuth2TokenTypeHint = "token_type_hint"
+)
+
+// clientCredentials reads the client credentials either from the basic
+// authorization header or from the request body. The secret is optional
+// since public clients only identify themselves,
+// see https://datatracker.ietf.org/doc/html/rfc6749#section-2.3.1.
+// Only one of the ways may be used in a single request.
+func clientCredentials(r *http.Request) (string, string, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		if r.PostForm.Has(OAuth2ClientSecret) {
+			return "", "", errors.New("multiple client authentication methods")
+		}
+		// the credentials are form encoded before being put into the header
+		clientID, err := url.QueryUnescape(id)
+		if err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		clientSecret, err := url.QueryUnescape(secret)
+		if err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		return clientID, clientSecret, nil
+	}
+	clientID, requiredErr := requiredPostFormField(r, OAuth2ClientID)
+	if requiredErr != nil {
+		return "", "", requiredErr
+	}
+	return clientID, r.PostForm.Get(OAuth2ClientSecret), nil
+}
+
+func requiredRevocationRequest(r *http.Request) (*core.RevocationRequest, error) {
+	token, requiredErr := requiredPostFormField(r, OAuth2Token)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	clientID, clientSecret, requiredErr := clientCredentials(r)
+	if requiredErr != nil {
+		return nil, requiredErr
+	}
+	return &core.RevocationRequest{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		Token:         token,
+		TokenTypeHint: core.TokenTypeHint(r.PostForm.Get(OAuth2TokenTypeHint)),
+	}, nil
+}
+
+// RevocationEndpoint lets clients invalidate tokens they no longer need,
+// see https://datatracker.ietf.org/doc/html/rfc7009#section-2.
+func (h *OAuth2Handler) RevocationEndpoint(w http.ResponseWriter, r *http.Request) {
+	if mediaType := r.Header.Get(constants.HeaderContentType); mediaType != constants.MimeApplicationXWWWFormURLEncoded {
+		respondClientError(w, r, invalidTokenRequest(fmt.Errorf("unsupported media type: '%s', expected: '%s'", mediaType, constants.MimeApplicationXWWWFormURLEncoded)))
+		return
+	}
+
+	req, requiredFieldErr := requiredRevocationRequest(r)
+	if requiredFieldErr != nil {
+		respondClientError(w, r, invalidTokenRequest(requiredFieldErr))
+		return
+	}
+
+	err := h.manager.Revoke(r.Context(), *req)
+	if err != nil {
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			respondClientError(w, r, authError)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	// invalid tokens do not cause an error, the client could not handle it anyway
+	w.WriteHeader(http.StatusOK)
+}
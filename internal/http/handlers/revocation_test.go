@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+)
+
+// revocation revokes the token as the client with the given credentials.
+func (f *tokenConformanceFixture) revocation(clientID, clientSecret string, values url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, RevocationPath, strings.NewReader(values.Encode()))
+	req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+	req.SetBasicAuth(clientID, clientSecret)
+	return serve(f.router, req)
+}
+
+// active asks the introspection endpoint whether the token can still be used.
+func (f *tokenConformanceFixture) active(t *testing.T, token string) bool {
+	t.Helper()
+	w := f.introspect(f.credentialClient.ID, f.credentialClient.Secret, url.Values{OAuth2Token: {token}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	introspection := core.IntrospectionResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &introspection)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return introspection.Active
+}
+
+func TestClientCredentials(t *testing.T) {
+	cases := []struct {
+		basicID, basicSecret string
+		form                 url.Values
+		expectedID           string
+		expectedSecret       string
+		expectErr            bool
+	}{
+		{basicID: "web", basicSecret: "secret", expectedID: "web", expectedSecret: "secret"},
+		{basicID: "my%3Aclient", basicSecret: "p%40ss+word", expectedID: "my:client", expectedSecret: "p@ss word"},
+		{form: url.Values{"client_id": {"web"}, "client_secret": {"secret"}}, expectedID: "web", expectedSecret: "secret"},
+		{form: url.Values{"client_id": {"web"}}, expectedID: "web", expectedSecret: ""},
+		{form: url.Values{}, expectErr: true},
+		{basicID: "web", basicSecret: "secret", form: url.Values{"client_secret": {"secret"}}, expectErr: true},
+	}
+	for i, testCase := range cases {
+		t.Run(fmt.Sprintf("TestClientCredentials_%d", i), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/oauth/v2/revoke", strings.NewReader(testCase.form.Encode()))
+			req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+			if testCase.basicID != "" {
+				req.SetBasicAuth(testCase.basicID, testCase.basicSecret)
+			}
+			clientID, clientSecret, err := clientCredentials(req)
+			if testCase.expectErr {
+				if err == nil {
+					t.Fatalf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if clientID != testCase.expectedID || clientSecret != testCase.expectedSecret {
+				t.Fatalf("Expected %#v:%#v but got %#v:%#v", testCase.expectedID, testCase.expectedSecret, clientID, clientSecret)
+			}
+		})
+	}
+}
+
+func TestRevokeUnknownToken(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	// the client can not do anything about the unknown tokens, so they are not an error
+	for _, hint := range []core.TokenTypeHint{"", core.TokenTypeHintAccessToken, core.TokenTypeHintRefreshToken} {
+		w := f.revocation(f.client.ID, f.client.Secret, url.Values{OAuth2Token: {"unknown"}, OAuth2TokenTypeHint: {string(hint)}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+	}
+}
+
+func TestRevokeTokenOfAnotherClient(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	tokens := f.tokens(t)
+	other, err := core.NewClient("user-id", "Other", core.ClientPolicy{
+		GrantTypes: []core.GrantType{core.GrantTypePassword},
+		Scope:      *core.DefaultScope(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = f.storage.AddClient(context.Background(), nil, *other)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, token := range []string{string(tokens.AccessToken.Token), string(tokens.RefreshToken)} {
+		// the client must not learn that the token exists
+		if w := f.revocation(other.ID, other.Secret, url.Values{OAuth2Token: {token}}); w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		if !f.active(t, token) {
+			t.Fatalf("Expected the token of another client to be left intact")
+		}
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	tokens := f.tokens(t)
+	f.revoke(t, string(tokens.AccessToken.Token))
+	if f.active(t, string(tokens.AccessToken.Token)) {
+		t.Fatalf("Expected the access token to be revoked")
+	}
+	if !f.active(t, string(tokens.RefreshToken)) {
+		t.Fatalf("Expected the refresh token to be left intact")
+	}
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	first := f.tokens(t)
+	w := f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType:    {core.GrantTypeRefreshToken},
+		OAuth2RefreshToken: {string(first.RefreshToken)},
+	}).Encode(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the refresh to succeed but got %d: %s", w.Code, w.Body)
+	}
+	second := core.AccessTokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// an unrelated login of the same user is not a part of the family
+	unrelated := f.tokens(t)
+
+	f.revoke(t, string(second.RefreshToken))
+	for _, token := range []core.JWT{first.AccessToken.Token, second.AccessToken.Token, second.RefreshToken} {
+		if f.active(t, string(token)) {
+			t.Fatalf("Expected every token of the family to be revoked")
+		}
+	}
+	w = f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType:    {core.GrantTypeRefreshToken},
+		OAuth2RefreshToken: {string(second.RefreshToken)},
+	}).Encode(), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the revoked refresh token to be rejected but got %d: %s", w.Code, w.Body)
+	}
+	if !f.active(t, string(unrelated.AccessToken.Token)) || !f.active(t, string(unrelated.RefreshToken)) {
+		t.Fatalf("Expected the tokens of another login to be left intact")
+	}
+}
+
+func TestRevocationRejected(t *testing.T) {
+	cases := []struct {
+		name           string
+		contentType    string
+		body           func(f *tokenConformanceFixture) string
+		clientSecret   func(f *tokenConformanceFixture) string
+		expectedStatus int
+		expectedError  core.AuthErrorName
+	}{
+		{
+			name:        "wrong content type",
+			contentType: constants.MimeApplicationJSON,
+			body: func(*tokenConformanceFixture) string {
+				return `{"token":"token"}`
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "missing token",
+			body: func(*tokenConformanceFixture) string {
+				return ""
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "multiple client authentication methods",
+			body: func(f *tokenConformanceFixture) string {
+				return url.Values{OAuth2Token: {"token"}, OAuth2ClientSecret: {f.client.Secret}}.Encode()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "wrong client secret",
+			body: func(*tokenConformanceFixture) string {
+				return url.Values{OAuth2Token: {"token"}}.Encode()
+			},
+			clientSecret: func(*tokenConformanceFixture) string {
+				return "wrong"
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidClient,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			req := httptest.NewRequest(http.MethodPost, RevocationPath, strings.NewReader(tc.body(f)))
+			contentType := constants.MimeApplicationXWWWFormURLEncoded
+			if tc.contentType != "" {
+				contentType = tc.contentType
+			}
+			req.Header.Set(constants.HeaderContentType, contentType)
+			clientSecret := f.client.Secret
+			if tc.clientSecret != nil {
+				clientSecret = tc.clientSecret(f)
+			}
+			req.SetBasicAuth(f.client.ID, clientSecret)
+			w := serve(f.router, req)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+			authError := core.AuthError{}
+			err := json.Unmarshal(w.Body.Bytes(), &authError)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if authError.ErrorName != tc.expectedError {
+				t.Fatalf("Expected error %s but got %s", tc.expectedError, w.Body)
+			}
+			challenged := w.Header().Get(constants.HeaderAuthenticate) != ""
+			if challenged != (tc.expectedStatus == http.StatusUnauthorized) {
+				t.Fatalf("Expected the challenge only for the failed client authentication but got %q", w.Header().Get(constants.HeaderAuthenticate))
+			}
+		})
+	}
+}
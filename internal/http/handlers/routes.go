@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/handlers/docs"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+// NewRouter registers the routes of the server with their authentication and scopes,
+// the web UI logs the users in through the web client.
+func NewRouter(identity *managers.IdentityManager, publicURL string, webClient core.Client) http.Handler {
+	mux := http.NewServeMux()
+
+	auth := middleware.NewOAuth2Middleware(identity)
+	session := middleware.NewSessionMiddleware(identity)
+
+	oauth2 := NewOAuth2Handler(identity, publicURL)
+	mux.HandleFunc("POST "+TokenPath, oauth2.TokenEndpoint)
+	mux.HandleFunc("POST "+RevocationPath, oauth2.RevocationEndpoint)
+	mux.HandleFunc("POST "+IntrospectionPath, oauth2.IntrospectionEndpoint)
+	mux.HandleFunc("GET "+JWKSPath, oauth2.JWKSEndpoint)
+	mux.HandleFunc("GET "+OpenIDConfigurationPath, oauth2.MetadataEndpoint)
+	mux.HandleFunc("GET "+AuthorizationServerMetadataPath, oauth2.MetadataEndpoint)
+	mux.HandleFunc("POST "+DeviceAuthorizationPath, oauth2.DeviceAuthorizationEndpoint)
+	mux.HandleFunc("POST "+RegistrationPath, oauth2.RegistrationEndpoint)
+	mux.HandleFunc("GET "+RegistrationPath+"/{clientID}", oauth2.ClientConfigurationEndpoint)
+	mux.HandleFunc("PUT "+RegistrationPath+"/{clientID}", oauth2.ClientUpdateEndpoint)
+	mux.HandleFunc("DELETE "+RegistrationPath+"/{clientID}", oauth2.ClientDeleteEndpoint)
+	mux.Handle("GET "+AuthorizationPath, session.Wrap(http.HandlerFunc(oauth2.AuthorizationEndpoint)))
+	mux.Handle("POST "+AuthorizationPath, session.Wrap(http.HandlerFunc(oauth2.AuthorizationDecision)))
+	openID := middleware.NewChain(auth, middleware.RequireScopes(core.ScopeOpenID))
+	mux.Handle("GET "+UserInfoPath, openID.Wrap(http.HandlerFunc(oauth2.UserInfoEndpoint)))
+	mux.Handle("POST "+UserInfoPath, openID.Wrap(http.HandlerFunc(oauth2.UserInfoEndpoint)))
+
+	ui := NewWebUI(identity, webClient)
+	api := NewAPI()
+
+	mux.HandleFunc("/", ui.Index)
+	mux.HandleFunc("GET "+middleware.LoginPath, ui.LoginPage)
+	mux.HandleFunc("POST "+middleware.LoginPath, ui.Login)
+	mux.HandleFunc("POST /logout", ui.Logout)
+	mux.HandleFunc("GET "+UpstreamLoginPath, ui.UpstreamLogin)
+	mux.HandleFunc("GET "+UpstreamCallbackPath, ui.UpstreamCallback)
+	mux.HandleFunc("POST "+PasskeyLoginOptionsPath, ui.PasskeyLoginOptions)
+	mux.HandleFunc("POST "+PasskeyLoginPath, ui.PasskeyLogin)
+	mux.HandleFunc("GET "+PasswordResetPath, ui.PasswordResetPage)
+	mux.HandleFunc("POST "+PasswordResetPath, ui.RequestPasswordReset)
+	mux.HandleFunc("GET "+PasswordResetConfirmPath, ui.PasswordResetConfirmPage)
+	mux.HandleFunc("POST "+PasswordResetConfirmPath, ui.ResetPassword)
+	mux.HandleFunc("GET "+SignupPath, ui.SignupPage)
+	mux.HandleFunc("POST "+SignupPath, ui.Signup)
+	mux.HandleFunc("GET "+SignupVerifyPath, ui.VerifyEmail)
+	mux.Handle("GET "+DevicePath, session.Wrap(http.HandlerFunc(ui.DevicePage)))
+	mux.Handle("POST "+DevicePath, session.Wrap(http.HandlerFunc(ui.DeviceDecision)))
+	mux.Handle("GET "+TOTPPath, session.Wrap(http.HandlerFunc(ui.TOTPPage)))
+	mux.Handle("POST "+TOTPPath, session.Wrap(http.HandlerFunc(ui.TOTPConfirm)))
+	mux.Handle("POST "+TOTPDisablePath, session.Wrap(http.HandlerFunc(ui.TOTPDisable)))
+	mux.Handle("GET "+PasskeysPath, session.Wrap(http.HandlerFunc(ui.PasskeysPage)))
+	mux.Handle("POST "+PasskeysPath, session.Wrap(http.HandlerFunc(ui.RegisterPasskey)))
+	mux.Handle("POST "+PasskeyRegistrationOptionsPath, session.Wrap(http.HandlerFunc(ui.PasskeyRegistrationOptions)))
+	mux.Handle("POST "+PasskeysPath+"/{passkeyID}/delete", session.Wrap(http.HandlerFunc(ui.DeletePasskey)))
+	mux.Handle("/docs/", http.StripPrefix("/docs/", docs.OpenAPI))
+	entriesRead := middleware.NewChain(auth, middleware.RequireScopes(core.ScopeEntriesRead))
+	mux.Handle("/protected", entriesRead.Wrap(http.HandlerFunc(api.AuthInfo)))
+
+	clients := NewClientsAPI(identity)
+	clientsScope := middleware.NewChain(auth, middleware.RequireScopes(core.ScopeClients))
+	mux.Handle("GET /api/clients", clientsScope.Wrap(http.HandlerFunc(clients.ListClients)))
+	mux.Handle("POST /api/clients", clientsScope.Wrap(http.HandlerFunc(clients.CreateClient)))
+	mux.Handle("POST /api/clients/{clientID}/secret", clientsScope.Wrap(http.HandlerFunc(clients.RotateClientSecret)))
+	mux.Handle("DELETE /api/clients/{clientID}", clientsScope.Wrap(http.HandlerFunc(clients.DeleteClient)))
+
+	tokens := NewPersonalAccessTokensAPI(identity)
+	tokensScope := middleware.NewChain(auth, middleware.RequireScopes(core.ScopeTokens))
+	mux.Handle("GET /api/tokens", tokensScope.Wrap(http.HandlerFunc(tokens.ListTokens)))
+	mux.Handle("POST /api/tokens", tokensScope.Wrap(http.HandlerFunc(tokens.CreateToken)))
+	mux.Handle("DELETE /api/tokens/{tokenID}", tokensScope.Wrap(http.HandlerFunc(tokens.RevokeToken)))
+
+	signup := NewSignupAPI(identity)
+	mux.HandleFunc("PUT /api/user", signup.Signup)
+
+	sessions := NewSessionsAPI(identity)
+	sessionsScope := middleware.NewChain(auth, middleware.RequireScopes(core.ScopeSessions))
+	mux.Handle("GET /api/user/sessions", sessionsScope.Wrap(http.HandlerFunc(sessions.ListSessions)))
+	mux.Handle("DELETE /api/user/sessions", sessionsScope.Wrap(http.HandlerFunc(sessions.RevokeSessions)))
+	mux.Handle("DELETE /api/user/sessions/{sessionID}", sessionsScope.Wrap(http.HandlerFunc(sessions.RevokeSession)))
+
+	admin := NewAdminAPI(identity)
+	adminOnly := middleware.NewChain(
+		auth,
+		middleware.RequireScopes(core.ScopeAdmin),
+		middleware.NewAdminMiddleware(identity),
+	)
+	mux.Handle("PUT /api/admin/clients/{clientID}/service-account", adminOnly.Wrap(http.HandlerFunc(admin.BindServiceAccount)))
+	mux.Handle("DELETE /api/admin/clients/{clientID}/service-account", adminOnly.Wrap(http.HandlerFunc(admin.UnbindServiceAccount)))
+	mux.Handle("GET /api/admin/clients", adminOnly.Wrap(http.HandlerFunc(admin.ListClients)))
+	mux.Handle("DELETE /api/admin/clients/{clientID}", adminOnly.Wrap(http.HandlerFunc(admin.DeleteClient)))
+	mux.Handle("DELETE /api/admin/users/{username}/lockout", adminOnly.Wrap(http.HandlerFunc(admin.UnlockUser)))
+	mux.Handle("DELETE /api/admin/users/{username}/totp", adminOnly.Wrap(http.HandlerFunc(admin.ResetTOTP)))
+	mux.Handle("GET /api/admin/signup", adminOnly.Wrap(http.HandlerFunc(admin.GetSignup)))
+	mux.Handle("PUT /api/admin/signup", adminOnly.Wrap(http.HandlerFunc(admin.SetSignup)))
+	mux.Handle("GET /api/admin/invites", adminOnly.Wrap(http.HandlerFunc(admin.ListInvites)))
+	mux.Handle("POST /api/admin/invites", adminOnly.Wrap(http.HandlerFunc(admin.CreateInvite)))
+	mux.Handle("DELETE /api/admin/invites/{inviteID}", adminOnly.Wrap(http.HandlerFunc(admin.DeleteInvite)))
+
+	return mux
+}
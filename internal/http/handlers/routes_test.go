@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+// testPublicURL is the public url and the issuer of the server under test.
+const testPublicURL = "http://localhost"
+
+// newTestIdentityManager builds the identity manager over the storage with a fresh signing key.
+func newTestIdentityManager(t *testing.T, storage *memoryStorage, options managers.IdentityOptions) (*managers.IdentityManager, *core.KeySet) {
+	t.Helper()
+	signingKey, err := core.NewEphemeralSigningKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	keys, err := core.NewKeySet(signingKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return managers.NewIdentityManager(storage, keys, testPublicURL, options), keys
+}
+
+// newTestRouter serves the identity manager through the routes of the server.
+func newTestRouter(identity *managers.IdentityManager, webClient core.Client) http.Handler {
+	return NewRouter(identity, testPublicURL, webClient)
+}
+
+// serve sends the request through the router and records the response.
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouterRequiresScopes(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	token := f.accessToken(t, core.Scope(core.ScopeTags))
+	for _, target := range []string{"/protected", "/api/clients", "/api/tokens", "/api/user/sessions", "/api/admin/clients", UserInfoPath} {
+		t.Run(target, func(t *testing.T) {
+			if w := f.authorized(http.MethodGet, target, "", ""); w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status %d without a token but got %d", http.StatusUnauthorized, w.Code)
+			}
+			if w := f.authorized(http.MethodGet, target, token, ""); w.Code != http.StatusForbidden {
+				t.Fatalf("Expected status %d without the scope but got %d: %s", http.StatusForbidden, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestExpiredAccessToken(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	bearer := f.accessToken(t, *core.DefaultScope())
+	// the stored lifetime is enforced even while the signed claims are still valid
+	for id, token := range f.storage.accessTokens {
+		token.ExpiresInSeconds = 0
+		f.storage.accessTokens[id] = token
+	}
+	w := f.authorized(http.MethodGet, "/protected", bearer, "")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusUnauthorized, w.Code, w.Body)
+	}
+}
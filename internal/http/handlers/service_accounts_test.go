@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+)
+
+// bindServiceAccount calls the administration api for the service account of the client.
+func (f *tokenConformanceFixture) bindServiceAccount(method, clientID, bearer, body string) *httptest.ResponseRecorder {
+	return f.authorized(method, "/api/admin/clients/"+clientID+"/service-account", bearer, body)
+}
+
+// clientCredentialsGrant requests a token as the client with the credentials.
+func (f *tokenConformanceFixture) clientCredentialsGrant(clientID, clientSecret, scope string) *httptest.ResponseRecorder {
+	values := url.Values{
+		OAuth2GrantType:    {core.GrantTypeClientCredentials},
+		OAuth2ClientID:     {clientID},
+		OAuth2ClientSecret: {clientSecret},
+	}
+	if scope != "" {
+		values.Set(OAuth2Scope, scope)
+	}
+	return f.post(constants.MimeApplicationXWWWFormURLEncoded, values.Encode(), nil)
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	cases := []struct {
+		name string
+		// prepare binds the service account and returns the credentials of the client
+		prepare        func(t *testing.T, f *tokenConformanceFixture) (string, string)
+		scope          string
+		expectedStatus int
+		expectedError  core.AuthErrorName
+		expectedScope  core.Scope
+	}{
+		{
+			name: "bound client",
+			prepare: func(t *testing.T, f *tokenConformanceFixture) (string, string) {
+				f.bindCredentialClient(t, "entries:read")
+				return f.credentialClient.ID, f.credentialClient.Secret
+			},
+			expectedStatus: http.StatusOK,
+			expectedScope:  "entries:read",
+		},
+		{
+			name: "scope narrowed by the request",
+			prepare: func(t *testing.T, f *tokenConformanceFixture) (string, string) {
+				f.bindCredentialClient(t, "entries")
+				return f.credentialClient.ID, f.credentialClient.Secret
+			},
+			scope:          "entries:write",
+			expectedStatus: http.StatusOK,
+			expectedScope:  "entries:write",
+		},
+		{
+			name: "scope beyond the service account",
+			prepare: func(t *testing.T, f *tokenConformanceFixture) (string, string) {
+				f.bindCredentialClient(t, "entries:read")
+				return f.credentialClient.ID, f.credentialClient.Secret
+			},
+			scope:          "entries",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidScope,
+		},
+		{
+			name: "unbound client",
+			prepare: func(_ *testing.T, f *tokenConformanceFixture) (string, string) {
+				return f.credentialClient.ID, f.credentialClient.Secret
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorUnauthorizedClient,
+		},
+		{
+			name: "wrong client secret",
+			prepare: func(t *testing.T, f *tokenConformanceFixture) (string, string) {
+				f.bindCredentialClient(t, "entries")
+				return f.credentialClient.ID, "wrong"
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidClient,
+		},
+		{
+			name: "public client",
+			prepare: func(t *testing.T, f *tokenConformanceFixture) (string, string) {
+				client, err := core.NewClient("user-id", "App", core.ClientPolicy{
+					Public:     true,
+					GrantTypes: []core.GrantType{core.GrantTypePassword},
+					Scope:      *core.DefaultScope(),
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				err = f.storage.AddClient(context.Background(), nil, *client)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				w := f.bindServiceAccount(http.MethodPut, client.ID, f.adminToken(t), `{"username":"user","scope":"entries"}`)
+				if w.Code != http.StatusNoContent {
+					t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+				}
+				return client.ID, ""
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorUnauthorizedClient,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTokenConformanceFixture(t)
+			clientID, clientSecret := tc.prepare(t, f)
+			w := f.clientCredentialsGrant(clientID, clientSecret, tc.scope)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+			if tc.expectedError != "" {
+				authError := core.AuthError{}
+				err := json.Unmarshal(w.Body.Bytes(), &authError)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				if authError.ErrorName != tc.expectedError {
+					t.Fatalf("Expected error %s but got %s", tc.expectedError, w.Body)
+				}
+				return
+			}
+
+			token := core.AccessTokenResponse{}
+			err := json.Unmarshal(w.Body.Bytes(), &token)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if token.RefreshToken != "" {
+				t.Fatalf("Expected no refresh token but got %s", w.Body)
+			}
+			accessToken, err := f.manager.Authenticate(context.Background(), string(token.AccessToken.Token))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if accessToken.UserID != "user-id" || accessToken.ClientID != f.credentialClient.ID || accessToken.Scope != tc.expectedScope {
+				t.Fatalf("Expected a token of the service account with scope %q but got %#v", tc.expectedScope, accessToken)
+			}
+		})
+	}
+}
+
+// bindCredentialClient makes the credential client act as the user with the scope.
+func (f *tokenConformanceFixture) bindCredentialClient(t *testing.T, scope string) {
+	t.Helper()
+	w := f.bindServiceAccount(http.MethodPut, f.credentialClient.ID, f.adminToken(t), `{"username":"user","scope":"`+scope+`"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+}
+
+func TestServiceAccountBinding(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	// the admin scope alone is not enough without the admin role
+	nonAdmin := f.accessToken(t, core.Scope(core.ScopeAdmin))
+	w := f.bindServiceAccount(http.MethodPut, f.credentialClient.ID, nonAdmin, `{"username":"user","scope":"entries"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the non-admin to be rejected but got %d: %s", w.Code, w.Body)
+	}
+	if w := f.bindServiceAccount(http.MethodDelete, f.credentialClient.ID, nonAdmin, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the non-admin to be rejected but got %d: %s", w.Code, w.Body)
+	}
+
+	admin := f.adminToken(t)
+	for _, tc := range []struct {
+		clientID, body string
+		expectedStatus int
+	}{
+		{clientID: "unknown", body: `{"username":"user","scope":"entries"}`, expectedStatus: http.StatusNotFound},
+		{clientID: f.credentialClient.ID, body: `{"username":"unknown","scope":"entries"}`, expectedStatus: http.StatusNotFound},
+		{clientID: f.credentialClient.ID, body: `{"username":"user","scope":"everything"}`, expectedStatus: http.StatusBadRequest},
+		{clientID: f.credentialClient.ID, body: `{"scope":"entries"}`, expectedStatus: http.StatusBadRequest},
+	} {
+		if w := f.bindServiceAccount(http.MethodPut, tc.clientID, admin, tc.body); w.Code != tc.expectedStatus {
+			t.Fatalf("Expected status %d for %s but got %d: %s", tc.expectedStatus, tc.body, w.Code, w.Body)
+		}
+	}
+	if w := f.clientCredentialsGrant(f.credentialClient.ID, f.credentialClient.Secret, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the rejected bindings to leave the client unbound but got %d: %s", w.Code, w.Body)
+	}
+
+	if w := f.bindServiceAccount(http.MethodPut, f.credentialClient.ID, admin, `{"username":"user","scope":"entries"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	if w := f.clientCredentialsGrant(f.credentialClient.ID, f.credentialClient.Secret, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the bound client to be granted a token but got %d: %s", w.Code, w.Body)
+	}
+
+	if w := f.bindServiceAccount(http.MethodDelete, f.credentialClient.ID, admin, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	if w := f.clientCredentialsGrant(f.credentialClient.ID, f.credentialClient.Secret, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the unbound client to be rejected but got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+// SessionsAPI lets the users see where they are logged in and log out remotely.
+type SessionsAPI struct {
+	identity *managers.IdentityManager
+}
+
+func NewSessionsAPI(identity *managers.IdentityManager) *SessionsAPI {
+	return &SessionsAPI{
+		identity: identity,
+	}
+}
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func newSessionsResponse(sessions []core.Session) []sessionResponse {
+	result := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, sessionResponse{
+			ID:         session.ID,
+			ClientID:   session.ClientID,
+			ClientName: session.ClientName,
+			UserAgent:  session.Device.UserAgent,
+			IPAddress:  session.Device.ClientIP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+		})
+	}
+	return result
+}
+
+// ListSessions returns the active sessions of the user, the most recently used first.
+func (a *SessionsAPI) ListSessions(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	sessions, err := a.identity.GetSessions(r.Context(), token.UserID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	response.RespondOKJSON(w, r, newSessionsResponse(sessions))
+}
+
+// RevokeSession logs the user out of one session.
+func (a *SessionsAPI) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	err := a.identity.RevokeSession(r.Context(), token.UserID, r.PathValue("sessionID"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeSessions logs the user out of every session.
+func (a *SessionsAPI) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	err := a.identity.RevokeSessions(r.Context(), token.UserID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+)
+
+type sessionsFixture struct {
+	*tokenConformanceFixture
+	// bearer is allowed to manage the sessions without belonging to any of them
+	bearer string
+}
+
+func newSessionsFixture(t *testing.T) *sessionsFixture {
+	t.Helper()
+	f := newTokenConformanceFixture(t)
+	return &sessionsFixture{
+		tokenConformanceFixture: f,
+		bearer:                  f.accessToken(t, core.Scope(core.ScopeSessions)),
+	}
+}
+
+// grant requests tokens from the device with the user agent and the address.
+func (f *sessionsFixture) grant(t *testing.T, values url.Values, userAgent, remoteAddr string) core.AccessTokenResponse {
+	t.Helper()
+	w := f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(values).Encode(), func(r *http.Request) {
+		r.Header.Set("User-Agent", userAgent)
+		r.RemoteAddr = remoteAddr
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the grant to succeed but got %d: %s", w.Code, w.Body)
+	}
+	token := core.AccessTokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return token
+}
+
+func (f *sessionsFixture) login(t *testing.T, userAgent, remoteAddr string) core.AccessTokenResponse {
+	t.Helper()
+	return f.grant(t, url.Values{
+		OAuth2GrantType: {core.GrantTypePassword},
+		OAuth2Username:  {conformanceUsername},
+		OAuth2Password:  {conformancePassword},
+	}, userAgent, remoteAddr)
+}
+
+func (f *sessionsFixture) sessions(t *testing.T) []sessionResponse {
+	t.Helper()
+	w := f.authorized(http.MethodGet, "/api/user/sessions", f.bearer, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	sessions := []sessionResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &sessions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return sessions
+}
+
+func TestSessions(t *testing.T) {
+	f := newSessionsFixture(t)
+	phone := f.login(t, "Phone", "192.0.2.1:1234")
+	laptop := f.login(t, "Laptop", "192.0.2.2:1234")
+	// the refresh moves the session to the device that used it last
+	laptop = f.grant(t, url.Values{
+		OAuth2GrantType:    {core.GrantTypeRefreshToken},
+		OAuth2RefreshToken: {string(laptop.RefreshToken)},
+	}, "Laptop/2", "192.0.2.3:1234")
+
+	sessions := f.sessions(t)
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions but got %#v", sessions)
+	}
+	latest := sessions[0]
+	if latest.UserAgent != "Laptop/2" || latest.IPAddress != "192.0.2.3" || latest.ClientName != f.client.Name {
+		t.Fatalf("Expected the refreshed session first but got %#v", latest)
+	}
+	if latest.LastUsedAt.Before(latest.CreatedAt) {
+		t.Fatalf("Expected the session to be used after it was created but got %#v", latest)
+	}
+	if sessions[1].UserAgent != "Phone" || sessions[1].IPAddress != "192.0.2.1" {
+		t.Fatalf("Unexpected session %#v", sessions[1])
+	}
+
+	w := f.authorized(http.MethodDelete, "/api/user/sessions/"+sessions[1].ID, f.bearer, "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodGet, "/protected", string(phone.AccessToken.Token), ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the access token of the revoked session to be rejected but got %d", w.Code)
+	}
+	if w := f.authorized(http.MethodGet, "/protected", string(laptop.AccessToken.Token), ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the other session to stay usable but got %d", w.Code)
+	}
+	if sessions := f.sessions(t); len(sessions) != 1 || sessions[0].ID != latest.ID {
+		t.Fatalf("Expected only the laptop session to be left but got %#v", sessions)
+	}
+
+	w = f.authorized(http.MethodDelete, "/api/user/sessions", f.bearer, "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	if w := f.authorized(http.MethodGet, "/protected", string(laptop.AccessToken.Token), ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the access token of the revoked session to be rejected but got %d", w.Code)
+	}
+	w = f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType:    {core.GrantTypeRefreshToken},
+		OAuth2RefreshToken: {string(laptop.RefreshToken)},
+	}).Encode(), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the refresh token of the revoked session to be rejected but got %d", w.Code)
+	}
+	if sessions := f.sessions(t); len(sessions) != 0 {
+		t.Fatalf("Expected no sessions but got %#v", sessions)
+	}
+}
+
+func TestRevokeSessionNotFound(t *testing.T) {
+	cases := []struct {
+		name      string
+		sessionID func(t *testing.T, f *sessionsFixture) string
+	}{
+		{
+			name:      "unknown session",
+			sessionID: func(*testing.T, *sessionsFixture) string { return "unknown" },
+		},
+		{
+			name: "session of another user",
+			sessionID: func(t *testing.T, f *sessionsFixture) string {
+				err := f.storage.SetSession(context.Background(), nil, core.Session{
+					ID:       "other-family",
+					UserID:   "other-user",
+					ClientID: f.client.ID,
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return "other-family"
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newSessionsFixture(t)
+			w := f.authorized(http.MethodDelete, "/api/user/sessions/"+tc.sessionID(t, f), f.bearer, "")
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("Expected status %d but got %d", http.StatusNotFound, w.Code)
+			}
+		})
+	}
+}
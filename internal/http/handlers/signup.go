@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignupPath is where the visitors register an account.
+	SignupPath = "/signup"
+	// SignupVerifyPath is where the emailed verification link leads to.
+	SignupVerifyPath = SignupPath + "/verify"
+
+	formInvite = "invite"
+)
+
+// SignupAPI registers the accounts of the apps, see PUT /api/user of the wallabag api.
+type SignupAPI struct {
+	identity *managers.IdentityManager
+}
+
+func NewSignupAPI(identity *managers.IdentityManager) *SignupAPI {
+	return &SignupAPI{
+		identity: identity,
+	}
+}
+
+type signupRequest struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Email      string `json:"email"`
+	ClientName string `json:"client_name"`
+	// Invite is required while the signup is invite-only
+	Invite string `json:"invite"`
+}
+
+type signupResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// DefaultClient is only present when the request named the client
+	DefaultClient *clientResponse `json:"default_client,omitempty"`
+}
+
+// Signup registers the account, the user can log in after verifying the email.
+func (a *SignupAPI) Signup(w http.ResponseWriter, r *http.Request) {
+	body := signupRequest{}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	user, client, err := a.identity.Signup(r.Context(), core.SignupRequest{
+		Username:   body.Username,
+		Email:      body.Email,
+		Password:   body.Password,
+		Invite:     body.Invite,
+		ClientName: body.ClientName,
+	}, SignupVerifyPath)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	created := signupResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+	}
+	if client != nil {
+		clientResponse := newClientResponse(*client)
+		created.DefaultClient = &clientResponse
+	}
+	response.RespondJSON(w, r, created, http.StatusCreated)
+}
+
+type signupPage struct {
+	Username string
+	Email    string
+	Invite   string
+	// InviteRequired asks for the invite code while the signup is invite-only
+	InviteRequired bool
+	Error          string
+	// Sent replaces the form with the message that the verification link is on the way
+	Sent bool
+}
+
+type signupVerifiedPage struct {
+	Error string
+}
+
+// signupOffered reports whether the login page links to the signup,
+// the failures only hide the link.
+func (s *WebUI) signupOffered(r *http.Request) bool {
+	mode, err := s.identity.SignupMode(r.Context())
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to get the signup mode", "cause", err.Error())
+		return false
+	}
+	return mode != core.SignupModeClosed
+}
+
+// respondSignupPage renders the form, the closed signup does not exist for the visitors.
+func (s *WebUI) respondSignupPage(w http.ResponseWriter, r *http.Request, page signupPage, status int) {
+	mode, err := s.identity.SignupMode(r.Context())
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	if mode == core.SignupModeClosed {
+		http.NotFound(w, r)
+		return
+	}
+	page.InviteRequired = mode == core.SignupModeInvite
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	response.RespondHTML(w, r, templates, "signup.html", page, status)
+}
+
+// SignupPage shows the form, the link of an invite fills in its code.
+func (s *WebUI) SignupPage(w http.ResponseWriter, r *http.Request) {
+	s.respondSignupPage(w, r, signupPage{Invite: r.URL.Query().Get(formInvite)}, http.StatusOK)
+}
+
+// Signup registers the account and emails the verification link.
+func (s *WebUI) Signup(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	page := signupPage{
+		Username: r.PostForm.Get(OAuth2Username),
+		Email:    r.PostForm.Get(formEmail),
+		Invite:   r.PostForm.Get(formInvite),
+	}
+
+	password := r.PostForm.Get(OAuth2Password)
+	if password != r.PostForm.Get(formPasswordConfirmation) {
+		page.Error = "The passwords do not match"
+		s.respondSignupPage(w, r, page, http.StatusBadRequest)
+		return
+	}
+	_, _, err = s.identity.Signup(r.Context(), core.SignupRequest{
+		Username: page.Username,
+		Email:    page.Email,
+		Password: password,
+		Invite:   page.Invite,
+	}, SignupVerifyPath)
+	switch {
+	case errors.Is(err, core.ErrInvalidInput):
+		page.Error = errorMessage(err, core.ErrInvalidInput)
+		s.respondSignupPage(w, r, page, http.StatusBadRequest)
+	case errors.Is(err, core.ErrForbidden):
+		page.Error = errorMessage(err, core.ErrForbidden)
+		s.respondSignupPage(w, r, page, http.StatusForbidden)
+	case err != nil:
+		response.RespondInternalErrorWithStack(w, r, err)
+	default:
+		page.Sent = true
+		s.respondSignupPage(w, r, page, http.StatusOK)
+	}
+}
+
+// VerifyEmail opens the emailed verification link.
+func (s *WebUI) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	page := signupVerifiedPage{}
+	status := http.StatusOK
+	err := s.identity.VerifyEmail(r.Context(), core.JWT(r.URL.Query().Get(core.EmailVerificationLinkParameter)))
+	if errors.Is(err, core.ErrInvalidInput) {
+		page.Error = errorMessage(err, core.ErrInvalidInput)
+		status = http.StatusBadRequest
+	} else if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	w.Header().Set(constants.HeaderReferrer, "no-referrer")
+	response.RespondHTML(w, r, templates, "signup_verified.html", page, status)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+var verifyLink = regexp.MustCompile(`http://localhost/signup/verify\?token=\S+`)
+
+type signupFixture struct {
+	*passwordResetFixture
+	// bearer is the access token of the administrator
+	bearer string
+}
+
+func newSignupFixture(t *testing.T) *signupFixture {
+	t.Helper()
+	f := newPasswordResetFixture(t)
+	return &signupFixture{
+		passwordResetFixture: f,
+		bearer:               f.adminToken(t),
+	}
+}
+
+func (f *signupFixture) setMode(t *testing.T, mode core.SignupMode) {
+	t.Helper()
+	w := f.authorized(http.MethodPut, "/api/admin/signup", f.bearer, `{"mode":"`+string(mode)+`"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+}
+
+func (f *signupFixture) signup(body string) *httptest.ResponseRecorder {
+	return f.authorized(http.MethodPut, "/api/user", "", body)
+}
+
+func (f *signupFixture) grant(username, password string) *httptest.ResponseRecorder {
+	return f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType: {core.GrantTypePassword},
+		OAuth2Username:  {username},
+		OAuth2Password:  {password},
+	}).Encode(), nil)
+}
+
+func TestSignup(t *testing.T) {
+	f := newSignupFixture(t)
+	f.setMode(t, core.SignupModeOpen)
+	w := f.page(http.MethodGet, middleware.LoginPath, nil)
+	if !strings.Contains(w.Body.String(), `href="/signup"`) {
+		t.Fatalf("Expected the login page to offer the signup but got %s", w.Body)
+	}
+
+	w = f.signup(`{"username":"reader","email":"reader@example.com","password":"reader-password","client_name":"Phone"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	created := signupResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if created.Username != "reader" || created.DefaultClient == nil || created.DefaultClient.ClientSecret == "" {
+		t.Fatalf("Expected the user with its client but got %#v", created)
+	}
+	if _, ok := f.storage.admins[created.ID]; !ok {
+		t.Fatalf("Expected the wallabago user to be added")
+	}
+	messages := f.smtp.Messages()
+	if len(messages) != 1 || messages[0].To[0] != "reader@example.com" {
+		t.Fatalf("Expected the verification email but got %#v", messages)
+	}
+
+	w = f.grant("reader", "reader-password")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), string(core.AuthErrorEmailUnverified)) {
+		t.Fatalf("Expected the unverified user to be rejected but got %d: %s", w.Code, w.Body)
+	}
+	link, err := url.Parse(f.emailedLink(t, verifyLink))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	w = f.page(http.MethodGet, link.RequestURI(), nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Email verified") {
+		t.Fatalf("Expected the email to be verified but got %d: %s", w.Code, w.Body)
+	}
+	if w := f.grant("reader", "reader-password"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the verified user to log in but got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestSignupWebUI(t *testing.T) {
+	f := newSignupFixture(t)
+	f.setMode(t, core.SignupModeOpen)
+	form := url.Values{
+		"username":              {"reader"},
+		"email":                 {"reader@example.com"},
+		"password":              {"reader-password"},
+		"password_confirmation": {"other-password"},
+	}
+	w := f.page(http.MethodPost, SignupPath, form)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the mismatched passwords to be rejected but got %d", w.Code)
+	}
+	form.Set("password_confirmation", "reader-password")
+	w = f.page(http.MethodPost, SignupPath, form)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "We sent a link to reader@example.com") {
+		t.Fatalf("Expected the verification to be sent but got %d: %s", w.Code, w.Body)
+	}
+	if len(f.smtp.Messages()) != 1 {
+		t.Fatalf("Expected the verification email")
+	}
+}
+
+func TestSignupInvite(t *testing.T) {
+	f := newSignupFixture(t)
+	f.setMode(t, core.SignupModeInvite)
+	w := f.page(http.MethodGet, SignupPath+"?invite=code", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `value="code"`) {
+		t.Fatalf("Expected the form with the invite but got %d: %s", w.Code, w.Body)
+	}
+
+	w = f.authorized(http.MethodPost, "/api/admin/invites", f.bearer, `{"expires_in":3600}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	invite := inviteResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &invite)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if invite.Code == "" || invite.CreatedBy != "user-id" {
+		t.Fatalf("Unexpected invite %#v", invite)
+	}
+
+	if w := f.signup(`{"username":"reader","email":"reader@example.com","password":"reader-password"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the signup without the invite to be rejected but got %d", w.Code)
+	}
+	w = f.signup(`{"username":"reader","email":"reader@example.com","password":"reader-password","invite":"` + invite.Code + `"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	w = f.signup(`{"username":"other","email":"other@example.com","password":"other-password","invite":"` + invite.Code + `"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the used invite to be rejected but got %d", w.Code)
+	}
+
+	w = f.authorized(http.MethodGet, "/api/admin/invites", f.bearer, "")
+	listed := []inviteResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), &listed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(listed) != 1 || listed[0].Code != "" || listed[0].UsedAt == nil {
+		t.Fatalf("Expected the used invite without its code but got %#v", listed)
+	}
+}
+
+func TestCreateInviteLifetimes(t *testing.T) {
+	maxLifetime := int64(core.MaxInviteLifetime / time.Second)
+	cases := []struct {
+		expiresIn      int64
+		expectedStatus int
+	}{
+		// the default lifetime applies
+		{expiresIn: 0, expectedStatus: http.StatusCreated},
+		{expiresIn: maxLifetime, expectedStatus: http.StatusCreated},
+		{expiresIn: maxLifetime + 1, expectedStatus: http.StatusBadRequest},
+		{expiresIn: -1, expectedStatus: http.StatusBadRequest},
+		// would overflow the duration when converted
+		{expiresIn: math.MaxInt64, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(strconv.FormatInt(tc.expiresIn, 10), func(t *testing.T) {
+			f := newSignupFixture(t)
+			w := f.authorized(http.MethodPost, "/api/admin/invites", f.bearer, `{"expires_in":`+strconv.FormatInt(tc.expiresIn, 10)+`}`)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestSignupRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		// mode stays closed when empty
+		mode   core.SignupMode
+		body   string
+		status int
+	}{
+		{
+			name:   "closed signup",
+			body:   `{"username":"reader","email":"reader@example.com","password":"reader-password"}`,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "username taken",
+			mode:   core.SignupModeOpen,
+			body:   `{"username":"user","email":"reader@example.com","password":"reader-password"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "email taken",
+			mode:   core.SignupModeOpen,
+			body:   `{"username":"reader","email":"user@example.com","password":"reader-password"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid username",
+			mode:   core.SignupModeOpen,
+			body:   `{"username":"a b","email":"reader@example.com","password":"reader-password"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "short password",
+			mode:   core.SignupModeOpen,
+			body:   `{"username":"reader","email":"reader@example.com","password":"short"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing password",
+			mode:   core.SignupModeOpen,
+			body:   `{"username":"reader","email":"reader@example.com"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newSignupFixture(t)
+			if tc.mode != "" {
+				f.setMode(t, tc.mode)
+			}
+			w := f.signup(tc.body)
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %d but got %d: %s", tc.status, w.Code, w.Body)
+			}
+			if messages := f.smtp.Messages(); len(messages) != 0 {
+				t.Fatalf("Expected no email but got %#v", messages)
+			}
+		})
+	}
+}
+
+func TestSignupDirectoryUsername(t *testing.T) {
+	f := newSignupFixture(t)
+	f.setIdentityOptions(t, managers.IdentityOptions{Mailer: f.mailer, Credentials: newTestLDAPVerifier(t)})
+	f.bearer = f.adminToken(t)
+	f.setMode(t, core.SignupModeOpen)
+
+	// otherwise the local password would be checked instead of the one in the directory
+	w := f.signup(`{"username":"alice","email":"reader@example.com","password":"reader-password"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the username of the directory to be rejected but got %d: %s", w.Code, w.Body)
+	}
+	if w := f.signup(`{"username":"reader","email":"reader@example.com","password":"reader-password"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+}
+
+func TestSignupClosedPage(t *testing.T) {
+	f := newSignupFixture(t)
+	if w := f.page(http.MethodGet, SignupPath, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d", http.StatusNotFound, w.Code)
+	}
+	if w := f.page(http.MethodGet, middleware.LoginPath, nil); strings.Contains(w.Body.String(), `href="/signup"`) {
+		t.Fatalf("Expected the login page to hide the closed signup")
+	}
+}
+
+func TestVerifyEmailRejected(t *testing.T) {
+	f := newSignupFixture(t)
+	w := f.page(http.MethodGet, SignupVerifyPath+"?token=invalid", nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid or expired") {
+		t.Fatalf("Expected the invalid link to be rejected but got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,893 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/pkg/errors"
+)
+
+// noopConnector hands out transactions that do nothing, the state
+// of memoryStorage is changed right away and never rolled back.
+type noopConnector struct{}
+
+func (noopConnector) Connect(context.Context) (driver.Conn, error) { return noopConn{}, nil }
+func (noopConnector) Driver() driver.Driver                        { return noopDriver{} }
+
+type noopDriver struct{}
+
+func (noopDriver) Open(string) (driver.Conn, error) { return noopConn{}, nil }
+
+type noopConn struct{}
+
+func (noopConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (noopConn) Close() error                        { return nil }
+func (noopConn) Begin() (driver.Tx, error)           { return noopConn{}, nil }
+func (noopConn) Commit() error                       { return nil }
+func (noopConn) Rollback() error                     { return nil }
+
+// memoryStorage keeps the identity state in memory for the tests of the handlers.
+type memoryStorage struct {
+	db *sql.DB
+
+	mu                 sync.Mutex
+	clients            map[string]core.Client
+	users              map[string]core.UserInfo
+	admins             map[string]bool
+	federated          map[string]core.FederatedIdentity
+	accessTokens       map[string]core.AccessToken
+	refreshTokens      map[string]core.RefreshToken
+	authorizationCodes []core.AuthorizationCode
+	deviceCodes        []core.DeviceCode
+	loginAttempts      map[core.LoginAttemptsKind]map[string]core.LoginAttempts
+	totps              map[string]core.TOTP
+	// recoveryCodes maps the users to the hashes of their codes and whether they were used
+	recoveryCodes map[string]map[string]bool
+	// passkeys are keyed by the credential id
+	passkeys   map[string]core.Passkey
+	challenges map[string]core.WebAuthnChallenge
+	// personal access tokens are keyed by the token id
+	personal map[string]core.PersonalAccessToken
+	// sessions are keyed by the refresh token family
+	sessions map[string]core.Session
+	// issuedFrom maps the access tokens to the refresh token they were issued from
+	issuedFrom map[string]string
+	// resets are keyed by the reset id, the used ones are removed
+	resets map[string]core.PasswordReset
+	// signupMode is empty until an administrator sets it
+	signupMode core.SignupMode
+	invites    map[string]core.Invite
+}
+
+var _ managers.IdentityStorage = (*memoryStorage)(nil)
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		db:            sql.OpenDB(noopConnector{}),
+		clients:       map[string]core.Client{},
+		users:         map[string]core.UserInfo{},
+		admins:        map[string]bool{},
+		federated:     map[string]core.FederatedIdentity{},
+		accessTokens:  map[string]core.AccessToken{},
+		refreshTokens: map[string]core.RefreshToken{},
+		loginAttempts: map[core.LoginAttemptsKind]map[string]core.LoginAttempts{},
+		totps:         map[string]core.TOTP{},
+		recoveryCodes: map[string]map[string]bool{},
+		passkeys:      map[string]core.Passkey{},
+		challenges:    map[string]core.WebAuthnChallenge{},
+		personal:      map[string]core.PersonalAccessToken{},
+		sessions:      map[string]core.Session{},
+		issuedFrom:    map[string]string{},
+		resets:        map[string]core.PasswordReset{},
+		invites:       map[string]core.Invite{},
+	}
+}
+
+func (s *memoryStorage) Begin(ctx context.Context) (*sql.Tx, error) {
+	return s.db.BeginTx(ctx, nil)
+}
+
+func (s *memoryStorage) AddClient(_ context.Context, _ *sql.Tx, client core.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client.Secret = ""
+	client.RegistrationAccessToken = ""
+	s.clients[client.ID] = client
+	return nil
+}
+
+func (s *memoryStorage) GetClientByID(_ context.Context, _ *sql.Tx, id string) (*core.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[id]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &client, nil
+}
+
+func (s *memoryStorage) GetClients(_ context.Context, _ *sql.Tx) ([]core.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clients := make([]core.Client, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+func (s *memoryStorage) GetClientsByOwnerUserID(_ context.Context, _ *sql.Tx, ownerUserID string) ([]core.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clients := []core.Client{}
+	for _, client := range s.clients {
+		if client.OwnerUserID == ownerUserID {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+func (s *memoryStorage) SetClientSecretHash(_ context.Context, _ *sql.Tx, clientID string, secretHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client := s.clients[clientID]
+	client.SecretHash = secretHash
+	s.clients[clientID] = client
+	return nil
+}
+
+func (s *memoryStorage) UpdateClient(ctx context.Context, tx *sql.Tx, client core.Client) error {
+	return s.AddClient(ctx, tx, client)
+}
+
+func (s *memoryStorage) SetClientServiceAccount(_ context.Context, _ *sql.Tx, clientID, userID string, scope core.Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client := s.clients[clientID]
+	client.ServiceAccountUserID = userID
+	client.ServiceAccountScope = scope
+	s.clients[clientID] = client
+	return nil
+}
+
+func (s *memoryStorage) DeleteClientByID(_ context.Context, _ *sql.Tx, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, id)
+	return nil
+}
+
+func (s *memoryStorage) AddAccessToken(_ context.Context, _ *sql.Tx, refreshTokenID string, token core.AccessToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token.Token = ""
+	s.accessTokens[token.ID] = token
+	s.issuedFrom[token.ID] = refreshTokenID
+	return nil
+}
+
+func (s *memoryStorage) GetAccessTokenByID(_ context.Context, _ *sql.Tx, id string) (*core.AccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.accessTokens[id]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &token, nil
+}
+
+func (s *memoryStorage) RevokeAccessTokenByID(_ context.Context, _ *sql.Tx, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := s.accessTokens[id]
+	token.Revoked = true
+	s.accessTokens[id] = token
+	return nil
+}
+
+func (s *memoryStorage) DeleteAccessTokenByID(_ context.Context, _ *sql.Tx, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.accessTokens, id)
+	return nil
+}
+
+func (s *memoryStorage) GetAccessTokenFamilyID(_ context.Context, _ *sql.Tx, id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refreshToken, ok := s.refreshTokens[s.issuedFrom[id]]
+	if !ok {
+		return "", errors.WithStack(sql.ErrNoRows)
+	}
+	return refreshToken.FamilyID, nil
+}
+
+// the tests do not rely on the access tokens being revoked together with the refresh tokens.
+func (s *memoryStorage) RevokeAccessTokensByRefreshTokenID(_ context.Context, _ *sql.Tx, refreshTokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.accessTokens {
+		if s.issuedFrom[id] == refreshTokenID {
+			token.Revoked = true
+			s.accessTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) RevokeAccessTokensByRefreshTokenFamilyID(_ context.Context, _ *sql.Tx, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.accessTokens {
+		if refreshToken, ok := s.refreshTokens[s.issuedFrom[id]]; ok && refreshToken.FamilyID == familyID {
+			token.Revoked = true
+			s.accessTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) AddRefreshToken(_ context.Context, _ *sql.Tx, token core.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token.Token = ""
+	s.refreshTokens[token.ID] = token
+	return nil
+}
+
+func (s *memoryStorage) GetRefreshTokenByID(_ context.Context, _ *sql.Tx, id string) (*core.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.refreshTokens[id]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &token, nil
+}
+
+func (s *memoryStorage) updateRefreshTokens(matches func(core.RefreshToken) bool, update func(*core.RefreshToken)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.refreshTokens {
+		if matches(token) {
+			update(&token)
+			s.refreshTokens[id] = token
+		}
+	}
+}
+
+func (s *memoryStorage) RevokeRefreshTokenByID(_ context.Context, _ *sql.Tx, id string) error {
+	s.updateRefreshTokens(
+		func(token core.RefreshToken) bool { return token.ID == id },
+		func(token *core.RefreshToken) { token.Revoked = true },
+	)
+	return nil
+}
+
+func (s *memoryStorage) RotateRefreshTokenByID(_ context.Context, _ *sql.Tx, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.refreshTokens[id]
+	if !ok || token.Rotated || token.Revoked {
+		return false, nil
+	}
+	token.Revoked = true
+	token.Rotated = true
+	s.refreshTokens[id] = token
+	return true, nil
+}
+
+func (s *memoryStorage) RevokeRefreshTokensByFamilyID(_ context.Context, _ *sql.Tx, familyID string) error {
+	s.updateRefreshTokens(
+		func(token core.RefreshToken) bool { return token.FamilyID == familyID },
+		func(token *core.RefreshToken) { token.Revoked = true },
+	)
+	return nil
+}
+
+func (s *memoryStorage) DeleteRefreshTokenByID(_ context.Context, _ *sql.Tx, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.refreshTokens, id)
+	return nil
+}
+
+func (s *memoryStorage) AddAuthorizationCode(_ context.Context, _ *sql.Tx, code core.AuthorizationCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code.Code = ""
+	s.authorizationCodes = append(s.authorizationCodes, code)
+	return nil
+}
+
+func (s *memoryStorage) GetAuthorizationCode(_ context.Context, _ *sql.Tx, codeHash []byte) (*core.AuthorizationCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range s.authorizationCodes {
+		if bytes.Equal(code.CodeHash, codeHash) {
+			return &code, nil
+		}
+	}
+	return nil, errors.WithStack(sql.ErrNoRows)
+}
+
+func (s *memoryStorage) MarkAuthorizationCodeUsed(_ context.Context, _ *sql.Tx, codeHash []byte, tokenFamilyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.authorizationCodes {
+		if bytes.Equal(s.authorizationCodes[i].CodeHash, codeHash) {
+			s.authorizationCodes[i].Used = true
+			s.authorizationCodes[i].TokenFamilyID = tokenFamilyID
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) AddDeviceCode(_ context.Context, _ *sql.Tx, code core.DeviceCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code.DeviceCode = ""
+	s.deviceCodes = append(s.deviceCodes, code)
+	return nil
+}
+
+func (s *memoryStorage) findDeviceCode(matches func(core.DeviceCode) bool) (*core.DeviceCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range s.deviceCodes {
+		if matches(code) {
+			return &code, nil
+		}
+	}
+	return nil, errors.WithStack(sql.ErrNoRows)
+}
+
+func (s *memoryStorage) updateDeviceCode(deviceCodeHash []byte, update func(*core.DeviceCode)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.deviceCodes {
+		if bytes.Equal(s.deviceCodes[i].DeviceCodeHash, deviceCodeHash) {
+			update(&s.deviceCodes[i])
+		}
+	}
+}
+
+func (s *memoryStorage) GetDeviceCodeByDeviceCodeHash(_ context.Context, _ *sql.Tx, deviceCodeHash []byte) (*core.DeviceCode, error) {
+	return s.findDeviceCode(func(code core.DeviceCode) bool { return bytes.Equal(code.DeviceCodeHash, deviceCodeHash) })
+}
+
+func (s *memoryStorage) GetDeviceCodeByUserCode(_ context.Context, _ *sql.Tx, userCode string) (*core.DeviceCode, error) {
+	return s.findDeviceCode(func(code core.DeviceCode) bool { return code.UserCode == userCode })
+}
+
+func (s *memoryStorage) UpdateDeviceCodePolling(_ context.Context, _ *sql.Tx, deviceCodeHash []byte, polledAt time.Time, interval time.Duration) error {
+	s.updateDeviceCode(deviceCodeHash, func(code *core.DeviceCode) {
+		code.LastPolledAt = polledAt
+		code.Interval = interval
+	})
+	return nil
+}
+
+func (s *memoryStorage) SetDeviceCodeStatus(_ context.Context, _ *sql.Tx, deviceCodeHash []byte, status core.DeviceCodeStatus, userID string) error {
+	s.updateDeviceCode(deviceCodeHash, func(code *core.DeviceCode) {
+		code.Status = status
+		code.UserID = userID
+	})
+	return nil
+}
+
+func (s *memoryStorage) AddUserInfo(_ context.Context, _ *sql.Tx, user core.UserInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *memoryStorage) GetUserInfoByUsername(_ context.Context, _ *sql.Tx, username string) (*core.UserInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, errors.WithStack(sql.ErrNoRows)
+}
+
+func (s *memoryStorage) GetUserInfoByID(_ context.Context, _ *sql.Tx, id string) (*core.UserInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &user, nil
+}
+
+func (s *memoryStorage) DeleteUserInfoByID(_ context.Context, _ *sql.Tx, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, id)
+	return nil
+}
+
+func (s *memoryStorage) GetUserByID(_ context.Context, _ *sql.Tx, id string) (*core.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &core.User{ID: user.ID, Username: user.Username, IsAdmin: s.admins[id]}, nil
+}
+
+func (s *memoryStorage) GetUserInfoByEmail(_ context.Context, _ *sql.Tx, email string) (*core.UserInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, errors.WithStack(sql.ErrNoRows)
+}
+
+func (s *memoryStorage) AddUser(_ context.Context, _ *sql.Tx, user core.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admins[user.ID] = user.IsAdmin
+	return nil
+}
+
+func (s *memoryStorage) SetUserAdmin(_ context.Context, _ *sql.Tx, userID string, isAdmin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admins[userID] = isAdmin
+	return nil
+}
+
+func (s *memoryStorage) SetUserPasswordHash(_ context.Context, _ *sql.Tx, userID string, passwordHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user := s.users[userID]
+	user.PasswordHash = passwordHash
+	s.users[userID] = user
+	return nil
+}
+
+func (s *memoryStorage) SetUserEmailVerified(_ context.Context, _ *sql.Tx, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user := s.users[userID]
+	user.EmailUnverified = false
+	s.users[userID] = user
+	return nil
+}
+
+func (s *memoryStorage) GetFederatedIdentity(_ context.Context, _ *sql.Tx, issuer, subject string) (*core.FederatedIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	identity, ok := s.federated[issuer+" "+subject]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &identity, nil
+}
+
+func (s *memoryStorage) AddFederatedIdentity(_ context.Context, _ *sql.Tx, identity core.FederatedIdentity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.federated[identity.Issuer+" "+identity.Subject] = identity
+	return nil
+}
+
+func (s *memoryStorage) GetUserTOTP(_ context.Context, _ *sql.Tx, userID string) (*core.TOTP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	totp, ok := s.totps[userID]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &totp, nil
+}
+
+func (s *memoryStorage) SetUserTOTP(_ context.Context, _ *sql.Tx, totp core.TOTP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totps[totp.UserID] = totp
+	return nil
+}
+
+func (s *memoryStorage) ConfirmUserTOTP(_ context.Context, _ *sql.Tx, userID string, confirmedAt time.Time, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	totp, ok := s.totps[userID]
+	if !ok || totp.Confirmed() {
+		return false, nil
+	}
+	totp.ConfirmedAt = confirmedAt
+	totp.LastUsedStep = step
+	s.totps[userID] = totp
+	return true, nil
+}
+
+func (s *memoryStorage) UseUserTOTPStep(_ context.Context, _ *sql.Tx, userID string, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	totp, ok := s.totps[userID]
+	if !ok || totp.LastUsedStep >= step {
+		return false, nil
+	}
+	totp.LastUsedStep = step
+	s.totps[userID] = totp
+	return true, nil
+}
+
+func (s *memoryStorage) DeleteUserTOTP(_ context.Context, _ *sql.Tx, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.totps, userID)
+	return nil
+}
+
+func (s *memoryStorage) AddRecoveryCodes(_ context.Context, _ *sql.Tx, userID string, codeHashes [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recoveryCodes[userID] == nil {
+		s.recoveryCodes[userID] = map[string]bool{}
+	}
+	for _, codeHash := range codeHashes {
+		s.recoveryCodes[userID][string(codeHash)] = false
+	}
+	return nil
+}
+
+func (s *memoryStorage) UseRecoveryCode(_ context.Context, _ *sql.Tx, userID string, codeHash []byte, _ time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	used, ok := s.recoveryCodes[userID][string(codeHash)]
+	if !ok || used {
+		return false, nil
+	}
+	s.recoveryCodes[userID][string(codeHash)] = true
+	return true, nil
+}
+
+func (s *memoryStorage) CountRecoveryCodes(_ context.Context, _ *sql.Tx, userID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := int64(0)
+	for _, used := range s.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStorage) DeleteRecoveryCodes(_ context.Context, _ *sql.Tx, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.recoveryCodes, userID)
+	return nil
+}
+
+func (s *memoryStorage) GetPasskeys(_ context.Context, _ *sql.Tx, userID string) ([]core.Passkey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passkeys := []core.Passkey{}
+	for _, passkey := range s.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (s *memoryStorage) GetPasskey(_ context.Context, _ *sql.Tx, credentialID []byte) (*core.Passkey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passkey, ok := s.passkeys[string(credentialID)]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &passkey, nil
+}
+
+func (s *memoryStorage) AddPasskey(_ context.Context, _ *sql.Tx, passkey core.Passkey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passkeys[string(passkey.CredentialID)] = passkey
+	return nil
+}
+
+func (s *memoryStorage) UsePasskey(_ context.Context, _ *sql.Tx, credentialID []byte, signCount uint32, backupState bool, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passkey, ok := s.passkeys[string(credentialID)]
+	if ok {
+		passkey.SignCount = signCount
+		passkey.BackupState = backupState
+		passkey.LastUsedAt = usedAt
+		s.passkeys[string(credentialID)] = passkey
+	}
+	return nil
+}
+
+func (s *memoryStorage) DeletePasskey(_ context.Context, _ *sql.Tx, userID string, credentialID []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passkey, ok := s.passkeys[string(credentialID)]
+	if !ok || passkey.UserID != userID {
+		return false, nil
+	}
+	delete(s.passkeys, string(credentialID))
+	return true, nil
+}
+
+func (s *memoryStorage) AddWebAuthnChallenge(_ context.Context, _ *sql.Tx, challenge core.WebAuthnChallenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.challenges[challenge.Challenge] = challenge
+	return nil
+}
+
+func (s *memoryStorage) TakeWebAuthnChallenge(_ context.Context, _ *sql.Tx, challenge string) (*core.WebAuthnChallenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, ok := s.challenges[challenge]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	delete(s.challenges, challenge)
+	return &pending, nil
+}
+
+func (s *memoryStorage) DeleteExpiredWebAuthnChallenges(_ context.Context, _ *sql.Tx, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for challenge, pending := range s.challenges {
+		if pending.ExpiresAt.Before(now) {
+			delete(s.challenges, challenge)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) GetLoginAttempts(_ context.Context, _ *sql.Tx, kind core.LoginAttemptsKind, subject string) (*core.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.loginAttempts[kind][subject]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &attempts, nil
+}
+
+func (s *memoryStorage) SetLoginAttempts(_ context.Context, _ *sql.Tx, attempts core.LoginAttempts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loginAttempts[attempts.Kind] == nil {
+		s.loginAttempts[attempts.Kind] = map[string]core.LoginAttempts{}
+	}
+	s.loginAttempts[attempts.Kind][attempts.Subject] = attempts
+	return nil
+}
+
+func (s *memoryStorage) DeleteLoginAttempts(_ context.Context, _ *sql.Tx, kind core.LoginAttemptsKind, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.loginAttempts[kind], subject)
+	return nil
+}
+
+func (s *memoryStorage) GetPersonalAccessTokens(_ context.Context, _ *sql.Tx, userID string) ([]core.PersonalAccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := []core.PersonalAccessToken{}
+	for _, token := range s.personal {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *memoryStorage) GetPersonalAccessTokenByHash(_ context.Context, _ *sql.Tx, tokenHash []byte) (*core.PersonalAccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.personal {
+		if bytes.Equal(token.TokenHash, tokenHash) {
+			return &token, nil
+		}
+	}
+	return nil, errors.WithStack(sql.ErrNoRows)
+}
+
+func (s *memoryStorage) AddPersonalAccessToken(_ context.Context, _ *sql.Tx, token core.PersonalAccessToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token.Token = ""
+	s.personal[token.ID] = token
+	return nil
+}
+
+func (s *memoryStorage) UsePersonalAccessToken(_ context.Context, _ *sql.Tx, tokenID string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.personal[tokenID]
+	if ok {
+		token.LastUsedAt = usedAt
+		s.personal[tokenID] = token
+	}
+	return nil
+}
+
+func (s *memoryStorage) DeletePersonalAccessToken(_ context.Context, _ *sql.Tx, userID, tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.personal[tokenID]
+	if !ok || token.UserID != userID {
+		return false, nil
+	}
+	delete(s.personal, tokenID)
+	return true, nil
+}
+
+func (s *memoryStorage) DeletePersonalAccessTokens(_ context.Context, _ *sql.Tx, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.personal {
+		if token.UserID == userID {
+			delete(s.personal, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) SetSession(_ context.Context, _ *sql.Tx, session core.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.sessions[session.ID]; ok {
+		session.CreatedAt = existing.CreatedAt
+	}
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *memoryStorage) GetSession(_ context.Context, _ *sql.Tx, id string) (*core.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, errors.WithStack(sql.ErrNoRows)
+	}
+	return &session, nil
+}
+
+func (s *memoryStorage) GetActiveSessions(_ context.Context, _ *sql.Tx, userID string, now time.Time) ([]core.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := []core.Session{}
+	for _, session := range s.sessions {
+		if session.UserID != userID {
+			continue
+		}
+		for _, token := range s.refreshTokens {
+			if token.FamilyID == session.ID && !token.Revoked && !token.Expired(now) {
+				session.ClientName = s.clients[session.ClientID].Name
+				sessions = append(sessions, session)
+				break
+			}
+		}
+	}
+	slices.SortFunc(sessions, func(a, b core.Session) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+	return sessions, nil
+}
+
+func (s *memoryStorage) AddPasswordReset(_ context.Context, _ *sql.Tx, reset core.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resets[reset.ID] = reset
+	return nil
+}
+
+func (s *memoryStorage) UsePasswordReset(_ context.Context, _ *sql.Tx, id string, usedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset, ok := s.resets[id]
+	if !ok || !reset.ExpiresAt.After(usedAt) {
+		return false, nil
+	}
+	delete(s.resets, id)
+	return true, nil
+}
+
+func (s *memoryStorage) CountPasswordResets(_ context.Context, _ *sql.Tx, userID string, since time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := int64(0)
+	for _, reset := range s.resets {
+		if reset.UserID == userID && reset.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStorage) DeletePasswordResets(_ context.Context, _ *sql.Tx, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, reset := range s.resets {
+		if reset.UserID == userID {
+			delete(s.resets, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) GetSignupMode(context.Context, *sql.Tx) (core.SignupMode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.signupMode == "" {
+		return "", errors.WithStack(sql.ErrNoRows)
+	}
+	return s.signupMode, nil
+}
+
+func (s *memoryStorage) SetSignupMode(_ context.Context, _ *sql.Tx, mode core.SignupMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signupMode = mode
+	return nil
+}
+
+func (s *memoryStorage) AddInvite(_ context.Context, _ *sql.Tx, invite core.Invite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invite.Code = ""
+	s.invites[invite.ID] = invite
+	return nil
+}
+
+func (s *memoryStorage) GetInvites(context.Context, *sql.Tx) ([]core.Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invites := make([]core.Invite, 0, len(s.invites))
+	for _, invite := range s.invites {
+		invites = append(invites, invite)
+	}
+	slices.SortFunc(invites, func(a, b core.Invite) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return invites, nil
+}
+
+func (s *memoryStorage) UseInvite(_ context.Context, _ *sql.Tx, codeHash []byte, usedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, invite := range s.invites {
+		if bytes.Equal(invite.CodeHash, codeHash) && invite.UsedAt.IsZero() && invite.ExpiresAt.After(usedAt) {
+			invite.UsedAt = usedAt
+			s.invites[id] = invite
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStorage) DeleteInvite(_ context.Context, _ *sql.Tx, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.invites[id]
+	delete(s.invites, id)
+	return ok, nil
+}
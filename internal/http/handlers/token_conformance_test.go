@@ -0,0 +1,588 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+const (
+	conformanceUsername = "user"
+	conformancePassword = "password"
+)
+
+type tokenConformanceFixture struct {
+	router           http.Handler
+	storage          *memoryStorage
+	keys             *core.KeySet
+	manager          *managers.IdentityManager
+	client           *core.Client
+	credentialClient *core.Client
+}
+
+func newTokenConformanceFixture(t *testing.T) *tokenConformanceFixture {
+	t.Helper()
+	ctx := context.Background()
+	storage := newMemoryStorage()
+	passwordHash, err := core.HashClientSecret(conformancePassword)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = storage.AddUserInfo(ctx, nil, core.UserInfo{ID: "user-id", Username: conformanceUsername, PasswordHash: passwordHash})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	client, err := core.NewClient("user-id", "Client", core.ClientPolicy{
+		RedirectURIs: []string{"https://example.com/callback"},
+		GrantTypes: []core.GrantType{
+			core.GrantTypePassword,
+			core.GrantTypeRefreshToken,
+			core.GrantTypeAuthorizationCode,
+			core.GrantTypeDeviceCode,
+		},
+		Scope: *core.DefaultScope(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	credentialClient, err := core.NewClient("user-id", "Service", core.ClientPolicy{
+		GrantTypes: []core.GrantType{core.GrantTypeClientCredentials},
+		Scope:      *core.DefaultScope(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, c := range []*core.Client{client, credentialClient} {
+		err = storage.AddClient(ctx, nil, *c)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	f := &tokenConformanceFixture{
+		storage:          storage,
+		client:           client,
+		credentialClient: credentialClient,
+	}
+	f.setIdentityOptions(t, managers.IdentityOptions{})
+	return f
+}
+
+// setIdentityOptions replaces the identity manager by the one with the optional collaborators.
+func (f *tokenConformanceFixture) setIdentityOptions(t *testing.T, options managers.IdentityOptions) {
+	t.Helper()
+	f.manager, f.keys = newTestIdentityManager(t, f.storage, options)
+	f.router = newTestRouter(f.manager, *f.client)
+}
+
+// accessToken issues an access token of the user with the given scope.
+func (f *tokenConformanceFixture) accessToken(t *testing.T, scope core.Scope) string {
+	t.Helper()
+	return f.userAccessToken(t, "user-id", scope)
+}
+
+// userAccessToken issues an access token of any user with the given scope.
+func (f *tokenConformanceFixture) userAccessToken(t *testing.T, userID string, scope core.Scope) string {
+	t.Helper()
+	token, err := core.NewAccessToken(testPublicURL, userID, f.client.ID, scope, time.Hour, f.keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = f.storage.AddAccessToken(context.Background(), nil, "", *token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return string(token.Token)
+}
+
+// adminToken makes the user an administrator and issues its access token with the admin scope.
+func (f *tokenConformanceFixture) adminToken(t *testing.T) string {
+	t.Helper()
+	err := f.storage.SetUserAdmin(context.Background(), nil, "user-id", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return f.accessToken(t, core.Scope(core.ScopeAdmin))
+}
+
+func (f *tokenConformanceFixture) clientForm(values url.Values) url.Values {
+	form := url.Values{
+		OAuth2ClientID:     {f.client.ID},
+		OAuth2ClientSecret: {f.client.Secret},
+	}
+	for key, value := range values {
+		form[key] = value
+	}
+	return form
+}
+
+func (f *tokenConformanceFixture) post(contentType, body string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(constants.HeaderContentType, contentType)
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	return serve(f.router, req)
+}
+
+// authorized calls the api with the bearer token, the body is sent as JSON when it is set.
+func (f *tokenConformanceFixture) authorized(method, target, bearer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(constants.HeaderContentType, constants.MimeApplicationJSON)
+	}
+	if bearer != "" {
+		req.Header.Set(constants.HeaderAuthorization, "Bearer "+bearer)
+	}
+	return serve(f.router, req)
+}
+
+func (f *tokenConformanceFixture) pendingDeviceCode(t *testing.T) string {
+	t.Helper()
+	code, err := core.NewDeviceCode(f.client.ID, *core.DefaultScope(), time.Minute, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = f.storage.AddDeviceCode(context.Background(), nil, *code)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return code.DeviceCode
+}
+
+func (f *tokenConformanceFixture) refreshToken(t *testing.T) string {
+	t.Helper()
+	return string(f.tokens(t).RefreshToken)
+}
+
+// tokens logs the user in with the password grant.
+func (f *tokenConformanceFixture) tokens(t *testing.T) core.AccessTokenResponse {
+	t.Helper()
+	w := f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+		OAuth2GrantType: {core.GrantTypePassword},
+		OAuth2Username:  {conformanceUsername},
+		OAuth2Password:  {conformancePassword},
+	}).Encode(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected password grant to succeed but got %d: %s", w.Code, w.Body)
+	}
+	token := core.AccessTokenResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return token
+}
+
+// TestTokenEndpointConformance pins the responses of the token endpoint
+// to https://datatracker.ietf.org/doc/html/rfc6749#section-5.
+func TestTokenEndpointConformance(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		// form is built once the fixture is known, so that it can use its clients
+		form           func(t *testing.T, f *tokenConformanceFixture) url.Values
+		body           string
+		prepare        func(f *tokenConformanceFixture, r *http.Request)
+		expectedStatus int
+		expectedError  core.AuthErrorName
+	}{
+		{
+			name:           "wrong content type",
+			contentType:    constants.MimeApplicationJSON,
+			body:           `{"grant_type":"password"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "missing grant type",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "unknown grant type",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{OAuth2GrantType: {"implicit"}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorUnsupportedGrantType,
+		},
+		{
+			name: "repeated parameter",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Username:  {conformanceUsername, "other"},
+					OAuth2Password:  {conformancePassword},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "unknown client",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return url.Values{
+					OAuth2GrantType:    {core.GrantTypePassword},
+					OAuth2ClientID:     {"unknown"},
+					OAuth2ClientSecret: {"secret"},
+					OAuth2Username:     {conformanceUsername},
+					OAuth2Password:     {conformancePassword},
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidClient,
+		},
+		{
+			name: "bad client secret",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:    {core.GrantTypePassword},
+					OAuth2ClientSecret: {"wrong"},
+					OAuth2Username:     {conformanceUsername},
+					OAuth2Password:     {conformancePassword},
+				})
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidClient,
+		},
+		{
+			name: "bad client secret with basic auth",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Username:  {conformanceUsername},
+					OAuth2Password:  {conformancePassword},
+				}
+			},
+			prepare: func(f *tokenConformanceFixture, r *http.Request) {
+				r.SetBasicAuth(f.client.ID, "wrong")
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  core.AuthErrorInvalidClient,
+		},
+		{
+			name: "multiple client authentication methods",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return url.Values{
+					OAuth2GrantType:    {core.GrantTypePassword},
+					OAuth2ClientSecret: {f.client.Secret},
+					OAuth2Username:     {conformanceUsername},
+					OAuth2Password:     {conformancePassword},
+				}
+			},
+			prepare: func(f *tokenConformanceFixture, r *http.Request) {
+				r.SetBasicAuth(f.client.ID, f.client.Secret)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "password grant",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Username:  {conformanceUsername},
+					OAuth2Password:  {conformancePassword},
+				})
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "password grant with wrong password",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Username:  {conformanceUsername},
+					OAuth2Password:  {"wrong"},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name: "password grant with unknown user",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Username:  {"unknown"},
+					OAuth2Password:  {conformancePassword},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name: "password grant without username",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Password:  {conformancePassword},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "password grant with invalid scope",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType: {core.GrantTypePassword},
+					OAuth2Username:  {conformanceUsername},
+					OAuth2Password:  {conformancePassword},
+					OAuth2Scope:     {"bogus"},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidScope,
+		},
+		{
+			name: "password grant not allowed for the client",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return url.Values{
+					OAuth2GrantType:    {core.GrantTypePassword},
+					OAuth2ClientID:     {f.credentialClient.ID},
+					OAuth2ClientSecret: {f.credentialClient.Secret},
+					OAuth2Username:     {conformanceUsername},
+					OAuth2Password:     {conformancePassword},
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorUnauthorizedClient,
+		},
+		{
+			name: "refresh token grant",
+			form: func(t *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:    {core.GrantTypeRefreshToken},
+					OAuth2RefreshToken: {f.refreshToken(t)},
+				})
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "refresh token grant with invalid token",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:    {core.GrantTypeRefreshToken},
+					OAuth2RefreshToken: {"invalid"},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name: "refresh token grant without token",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{OAuth2GrantType: {core.GrantTypeRefreshToken}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "authorization code grant with unknown code",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:    {core.GrantTypeAuthorizationCode},
+					OAuth2Code:         {"unknown"},
+					OAuth2RedirectURI:  {"https://example.com/callback"},
+					OAuth2CodeVerifier: {strings.Repeat("a", 43)},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name: "authorization code grant without code verifier",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:   {core.GrantTypeAuthorizationCode},
+					OAuth2Code:        {"unknown"},
+					OAuth2RedirectURI: {"https://example.com/callback"},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvaidRequest,
+		},
+		{
+			name: "client credentials grant without service account",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return url.Values{
+					OAuth2GrantType:    {core.GrantTypeClientCredentials},
+					OAuth2ClientID:     {f.credentialClient.ID},
+					OAuth2ClientSecret: {f.credentialClient.Secret},
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorUnauthorizedClient,
+		},
+		{
+			name: "device code grant with unknown code",
+			form: func(_ *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:  {core.GrantTypeDeviceCode},
+					OAuth2DeviceCode: {"unknown"},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name: "device code grant while pending",
+			form: func(t *testing.T, f *tokenConformanceFixture) url.Values {
+				return f.clientForm(url.Values{
+					OAuth2GrantType:  {core.GrantTypeDeviceCode},
+					OAuth2DeviceCode: {f.pendingDeviceCode(t)},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorAuthorizationPending,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(fmt.Sprintf("TestTokenEndpointConformance_%s", testCase.name), func(t *testing.T) {
+			fixture := newTokenConformanceFixture(t)
+			contentType := testCase.contentType
+			if contentType == "" {
+				contentType = constants.MimeApplicationXWWWFormURLEncoded
+			}
+			body := testCase.body
+			if testCase.form != nil {
+				body = testCase.form(t, fixture).Encode()
+			}
+			var prepare func(*http.Request)
+			if testCase.prepare != nil {
+				prepare = func(r *http.Request) { testCase.prepare(fixture, r) }
+			}
+
+			w := fixture.post(contentType, body, prepare)
+
+			if w.Code != testCase.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", testCase.expectedStatus, w.Code, w.Body)
+			}
+			if cacheControl := w.Header().Get(constants.HeaderCacheControl); cacheControl != "no-store" {
+				t.Fatalf("Expected Cache-Control no-store but got %#v", cacheControl)
+			}
+			if pragma := w.Header().Get(constants.HeaderPragma); pragma != "no-cache" {
+				t.Fatalf("Expected Pragma no-cache but got %#v", pragma)
+			}
+			if contentType := w.Header().Get(constants.HeaderContentType); !strings.HasPrefix(contentType, constants.MimeApplicationJSON) {
+				t.Fatalf("Expected JSON response but got %#v", contentType)
+			}
+			response := map[string]any{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if testCase.expectedError == "" {
+				if _, ok := response["access_token"]; !ok {
+					t.Fatalf("Expected access token but got %s", w.Body)
+				}
+				return
+			}
+
+			if response["error"] != string(testCase.expectedError) {
+				t.Fatalf("Expected error %#v but got %s", testCase.expectedError, w.Body)
+			}
+			description, _ := response["error_description"].(string)
+			for _, leaked := range []string{"bcrypt", "sql", "stack"} {
+				if strings.Contains(strings.ToLower(description), leaked) {
+					t.Fatalf("Error description leaks internals: %#v", description)
+				}
+			}
+			authenticate := w.Header().Get(constants.HeaderAuthenticate)
+			if testCase.expectedStatus == http.StatusUnauthorized && !strings.HasPrefix(authenticate, "Basic ") {
+				t.Fatalf("Expected WWW-Authenticate challenge but got %#v", authenticate)
+			}
+		})
+	}
+}
+
+func TestPasswordRehash(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	grant := func() int {
+		return f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+			OAuth2GrantType: {core.GrantTypePassword},
+			OAuth2Username:  {conformanceUsername},
+			OAuth2Password:  {conformancePassword},
+		}).Encode(), nil).Code
+	}
+	if status := grant(); status != http.StatusOK {
+		t.Fatalf("Expected the bcrypt hash to be accepted but got %d", status)
+	}
+	// the bcrypt hash is replaced by the argon2id one on the login
+	if passwordHash := string(f.storage.users["user-id"].PasswordHash); !strings.HasPrefix(passwordHash, "$argon2id$") {
+		t.Fatalf("Expected the password to be rehashed but got %s", passwordHash)
+	}
+	if status := grant(); status != http.StatusOK {
+		t.Fatalf("Expected the argon2id hash to be accepted but got %d", status)
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	f := newTokenConformanceFixture(t)
+	refreshToken := f.refreshToken(t)
+	refresh := func(token string) *httptest.ResponseRecorder {
+		return f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(url.Values{
+			OAuth2GrantType:    {core.GrantTypeRefreshToken},
+			OAuth2RefreshToken: {token},
+		}).Encode(), nil)
+	}
+
+	responses := make([]*httptest.ResponseRecorder, 2)
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			responses[i] = refresh(refreshToken)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	issued := ""
+	for _, w := range responses {
+		if w.Code != http.StatusOK {
+			if !strings.Contains(w.Body.String(), string(core.AuthErrorInvalidGrant)) {
+				t.Fatalf("Expected invalid_grant but got %d: %s", w.Code, w.Body)
+			}
+			continue
+		}
+		if issued != "" {
+			t.Fatalf("Expected only one of the refreshes to succeed")
+		}
+		token := core.AccessTokenResponse{}
+		err := json.Unmarshal(w.Body.Bytes(), &token)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		issued = string(token.RefreshToken)
+	}
+	if issued == "" {
+		t.Fatalf("Expected one of the refreshes to succeed")
+	}
+	// the reuse revoked the family including the refresh token of the winner
+	if w := refresh(issued); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the token family to be revoked but got %d: %s", w.Code, w.Body)
+	}
+}
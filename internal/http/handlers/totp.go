@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TOTPPath is where the logged in user manages the second factor.
+	TOTPPath = "/account/totp"
+	// TOTPDisablePath turns the second factor off.
+	TOTPDisablePath = TOTPPath + "/disable"
+)
+
+type totpPage struct {
+	Status     core.TOTPStatus
+	Enrollment *core.TOTPEnrollment
+	Error      string
+}
+
+type totpRecoveryCodesPage struct {
+	RecoveryCodes []string
+}
+
+func (s *WebUI) respondTOTPPage(w http.ResponseWriter, r *http.Request, userID, message string, status int) {
+	// the page shows the secret, so it must never be embedded
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	w.Header().Set(constants.HeaderCacheControl, "no-store")
+
+	totpStatus, err := s.identity.TOTPStatus(r.Context(), userID)
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	page := totpPage{Status: *totpStatus, Error: message}
+	if !totpStatus.Enabled {
+		page.Enrollment, err = s.identity.EnrollTOTP(r.Context(), userID)
+		if err != nil {
+			response.RespondInternalErrorWithStack(w, r, err)
+			return
+		}
+	}
+	response.RespondHTML(w, r, templates, "totp.html", page, status)
+}
+
+// TOTPPage starts the enrollment of the second factor or shows the enabled one.
+func (s *WebUI) TOTPPage(w http.ResponseWriter, r *http.Request) {
+	s.respondTOTPPage(w, r, middleware.MustGetAccessToken(r).UserID, "", http.StatusOK)
+}
+
+// TOTPConfirm enables the second factor and shows the recovery codes.
+func (s *WebUI) TOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	userID := middleware.MustGetAccessToken(r).UserID
+
+	codes, err := s.identity.ConfirmTOTP(r.Context(), userID, r.PostForm.Get(OAuth2OTP))
+	if errors.Is(err, core.ErrInvalidInput) || errors.Is(err, core.ErrNotFound) {
+		s.respondTOTPPage(w, r, userID, "Invalid one-time password", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+	w.Header().Set(constants.HeaderCacheControl, "no-store")
+	response.RespondHTML(w, r, templates, "totp_recovery_codes.html", totpRecoveryCodesPage{
+		RecoveryCodes: codes,
+	}, http.StatusOK)
+}
+
+// TOTPDisable turns the second factor off after checking it one last time.
+func (s *WebUI) TOTPDisable(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.RespondErrorPlain(w, r, err, http.StatusBadRequest)
+		return
+	}
+	userID := middleware.MustGetAccessToken(r).UserID
+
+	err = s.identity.DisableTOTP(r.Context(), userID, r.PostForm.Get(OAuth2OTP))
+	if errors.Is(err, core.ErrInvalidInput) {
+		s.respondTOTPPage(w, r, userID, "Invalid one-time password", http.StatusBadRequest)
+		return
+	}
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	http.Redirect(w, r, TOTPPath, http.StatusSeeOther)
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+)
+
+type totpFixture struct {
+	*tokenConformanceFixture
+	secret string
+	// step is the period the enrollment was confirmed in, its code is already used
+	step          int64
+	recoveryCodes []string
+}
+
+func newTOTPFixture(t *testing.T) *totpFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := newTokenConformanceFixture(t)
+	enrollment, err := f.manager.EnrollTOTP(ctx, "user-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	step := time.Now().Unix() / 30
+	recoveryCodes, err := f.manager.ConfirmTOTP(ctx, "user-id", totpCode(t, enrollment.Secret, step))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return &totpFixture{
+		tokenConformanceFixture: f,
+		secret:                  enrollment.Secret,
+		step:                    step,
+		recoveryCodes:           recoveryCodes,
+	}
+}
+
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := core.TOTPCode(secret, step)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return code
+}
+
+func (f *totpFixture) passwordGrant(password, otp string) (int, core.AuthError) {
+	values := url.Values{
+		OAuth2GrantType: {core.GrantTypePassword},
+		OAuth2Username:  {conformanceUsername},
+		OAuth2Password:  {password},
+	}
+	if otp != "" {
+		values.Set(OAuth2OTP, otp)
+	}
+	w := f.post(constants.MimeApplicationXWWWFormURLEncoded, f.clientForm(values).Encode(), nil)
+	authError := core.AuthError{}
+	if w.Code != http.StatusOK {
+		_ = json.Unmarshal(w.Body.Bytes(), &authError)
+	}
+	return w.Code, authError
+}
+
+func TestPasswordGrantTOTP(t *testing.T) {
+	cases := []struct {
+		name           string
+		password       string
+		otp            func(f *totpFixture) string
+		expectedStatus int
+		expectedError  core.AuthErrorName
+	}{
+		{
+			name:           "missing one-time password",
+			password:       conformancePassword,
+			otp:            func(*totpFixture) string { return "" },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorMFARequired,
+		},
+		{
+			name:           "one-time password of the next period",
+			password:       conformancePassword,
+			otp:            func(f *totpFixture) string { return totpCode(t, f.secret, f.step+1) },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "one-time password used by the enrollment",
+			password:       conformancePassword,
+			otp:            func(f *totpFixture) string { return totpCode(t, f.secret, f.step) },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name:           "one-time password out of the window",
+			password:       conformancePassword,
+			otp:            func(f *totpFixture) string { return totpCode(t, f.secret, f.step+5) },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name:           "recovery code",
+			password:       conformancePassword,
+			otp:            func(f *totpFixture) string { return strings.ToUpper(f.recoveryCodes[0]) },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown recovery code",
+			password:       conformancePassword,
+			otp:            func(*totpFixture) string { return "aaaa-bbbb-cccc" },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name:           "wrong password is not asked for the one-time password",
+			password:       "wrong",
+			otp:            func(*totpFixture) string { return "" },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+		{
+			name:           "wrong password with the one-time password",
+			password:       "wrong",
+			otp:            func(f *totpFixture) string { return totpCode(t, f.secret, f.step+1) },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.AuthErrorInvalidGrant,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			f := newTOTPFixture(t)
+			status, authError := f.passwordGrant(testCase.password, testCase.otp(f))
+			if status != testCase.expectedStatus {
+				t.Fatalf("Expected status %d but got %d", testCase.expectedStatus, status)
+			}
+			if authError.ErrorName != testCase.expectedError {
+				t.Fatalf("Expected error %q but got %q", testCase.expectedError, authError.ErrorName)
+			}
+		})
+	}
+}
+
+func TestPasswordGrantTOTPReplay(t *testing.T) {
+	f := newTOTPFixture(t)
+	for _, otp := range []string{totpCode(t, f.secret, f.step+1), f.recoveryCodes[1]} {
+		if status, _ := f.passwordGrant(conformancePassword, otp); status != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, status)
+		}
+		if status, _ := f.passwordGrant(conformancePassword, otp); status != http.StatusBadRequest {
+			t.Fatalf("Expected replayed %s to be rejected but got %d", otp, status)
+		}
+	}
+	status, err := f.manager.TOTPStatus(context.Background(), "user-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !status.Enabled || status.RecoveryCodesLeft != int64(len(f.recoveryCodes)-1) {
+		t.Fatalf("Expected one recovery code to be used but got %#v", status)
+	}
+}
+
+func TestWebLoginTOTP(t *testing.T) {
+	f := newTOTPFixture(t)
+	login := func(otp string) *httptest.ResponseRecorder {
+		form := url.Values{OAuth2Username: {conformanceUsername}, OAuth2Password: {conformancePassword}, OAuth2OTP: {otp}}
+		req := httptest.NewRequest(http.MethodPost, middleware.LoginPath, strings.NewReader(form.Encode()))
+		req.Header.Set(constants.HeaderContentType, constants.MimeApplicationXWWWFormURLEncoded)
+		return serve(f.router, req)
+	}
+
+	for _, otp := range []string{"", "000000"} {
+		w := login(otp)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `name="otp"`) {
+			t.Fatalf("Expected the one-time password to be asked for but got %d: %s", w.Code, w.Body)
+		}
+	}
+	if w := login(totpCode(t, f.secret, f.step+1)); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusSeeOther, w.Code, w.Body)
+	}
+}
+
+func TestAdminResetTOTP(t *testing.T) {
+	f := newTOTPFixture(t)
+	bearer := f.adminToken(t)
+	reset := func(username string) int {
+		return f.authorized(http.MethodDelete, "/api/admin/users/"+username+"/totp", bearer, "").Code
+	}
+
+	if status := reset("unknown"); status != http.StatusNotFound {
+		t.Fatalf("Expected status %d but got %d", http.StatusNotFound, status)
+	}
+	if status := reset(conformanceUsername); status != http.StatusNoContent {
+		t.Fatalf("Expected status %d but got %d", http.StatusNoContent, status)
+	}
+	if status, _ := f.passwordGrant(conformancePassword, ""); status != http.StatusOK {
+		t.Fatalf("Expected the password alone to be enough after the reset but got %d", status)
+	}
+	status, err := f.manager.TOTPStatus(context.Background(), "user-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if status.Enabled || status.RecoveryCodesLeft != 0 {
+		t.Fatalf("Expected the second factor to be removed but got %#v", status)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	// UpstreamLoginPath sends the user to the upstream identity provider.
+	UpstreamLoginPath = "/login/upstream"
+	// UpstreamCallbackPath is the redirect uri registered at the upstream identity provider.
+	UpstreamCallbackPath = "/login/upstream/callback"
+
+	// upstreamLoginCookieName is the cookie holding the sealed login
+	// while the user is at the provider.
+	upstreamLoginCookieName = "wallabago_upstream_login"
+)
+
+func setUpstreamLoginCookie(w http.ResponseWriter, r *http.Request, sealed *core.JWT) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     upstreamLoginCookieName,
+		Value:    string(*sealed),
+		Path:     UpstreamLoginPath,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// the provider sends the user back with a top level navigation, which lax mode allows
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearUpstreamLoginCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     upstreamLoginCookieName,
+		Value:    "",
+		Path:     UpstreamLoginPath,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// UpstreamLogin sends the user to the upstream identity provider. The user who already
+// has a session links the account at the provider to it instead.
+func (s *WebUI) UpstreamLogin(w http.ResponseWriter, r *http.Request) {
+	linkUserID := ""
+	if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
+		if accessToken, err := s.identity.Authenticate(r.Context(), cookie.Value); err == nil {
+			linkUserID = accessToken.UserID
+		}
+	}
+	location, sealed, err := s.identity.StartUpstreamLogin(r.Context(), safeNext(r.URL.Query().Get("next")), linkUserID)
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	setUpstreamLoginCookie(w, r, sealed)
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
+
+// UpstreamCallback finishes the login once the provider sends the user back.
+func (s *WebUI) UpstreamCallback(w http.ResponseWriter, r *http.Request) {
+	sealed := ""
+	if cookie, err := r.Cookie(upstreamLoginCookieName); err == nil {
+		sealed = cookie.Value
+	}
+	clearUpstreamLoginCookie(w, r)
+
+	query := r.URL.Query()
+	token, login, err := s.identity.FinishUpstreamLogin(r.Context(), core.UpstreamCallbackRequest{
+		ClientID:     s.client.ID,
+		ClientSecret: s.client.Secret,
+		SealedLogin:  core.JWT(sealed),
+		State:        query.Get(OAuth2State),
+		Code:         query.Get(OAuth2Code),
+		Error:        query.Get(OAuth2Error),
+		ClientIP:     clientIP(r),
+		UserAgent:    r.UserAgent(),
+	})
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			page := loginPage{
+				Next:          "/",
+				Error:         authError.ErrorDescription,
+				UpstreamName:  s.identity.UpstreamLoginName(),
+				PasswordReset: s.identity.PasswordResetEnabled(),
+				Signup:        s.signupOffered(r),
+			}
+			if login != nil {
+				page.Next = safeNext(login.Next)
+			}
+			w.Header().Set(constants.HeaderXFrameOptions, "DENY")
+			response.RespondHTML(w, r, templates, "login.html", page, http.StatusUnauthorized)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+
+	middleware.SetSessionCookie(w, r, &token.AccessToken)
+	http.Redirect(w, r, safeNext(login.Next), http.StatusSeeOther)
+}
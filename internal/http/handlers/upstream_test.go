@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/federation"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+const (
+	upstreamClientID     = "wallabago"
+	upstreamClientSecret = "upstream-secret"
+)
+
+// mockProvider is the upstream OpenID Connect provider, it issues an id token
+// with the configured claims for the login it has seen last.
+type mockProvider struct {
+	server        *httptest.Server
+	keys          *core.KeySet
+	claims        map[string]any
+	nonce         string
+	codeChallenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	signingKey, err := core.NewEphemeralSigningKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	keys, err := core.NewKeySet(signingKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	provider := &mockProvider{keys: keys}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, core.AuthorizationServerMetadata{
+			Issuer:                provider.server.URL,
+			AuthorizationEndpoint: provider.server.URL + "/authorize",
+			TokenEndpoint:         provider.server.URL + "/token",
+			JWKSURI:               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, _ *http.Request) {
+		jwks, err := provider.keys.JWKS()
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		writeJSON(t, w, jwks)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != upstreamClientID || clientSecret != upstreamClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue(OAuth2Code) != "code" ||
+			!core.VerifyCodeVerifier(provider.codeChallenge, core.CodeChallengeMethodS256, r.PostFormValue(OAuth2CodeVerifier)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		claims := map[string]any{
+			"iss":   provider.server.URL,
+			"aud":   upstreamClientID,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": provider.nonce,
+		}
+		for key, value := range provider.claims {
+			claims[key] = value
+		}
+		idToken, err := provider.keys.Sign(claims)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		writeJSON(t, w, map[string]any{"id_token": idToken, "token_type": "Bearer"})
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, value any) {
+	w.Header().Set(constants.HeaderContentType, constants.MimeApplicationJSON)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+type upstreamFixture struct {
+	router   http.Handler
+	manager  *managers.IdentityManager
+	storage  *memoryStorage
+	provider *mockProvider
+}
+
+func newUpstreamFixture(t *testing.T) *upstreamFixture {
+	t.Helper()
+	ctx := context.Background()
+	provider := newMockProvider(t)
+	storage := newMemoryStorage()
+	for _, user := range []core.UserInfo{
+		{ID: "existing-id", Username: "existing", Email: "existing@example.com", PasswordHash: []byte{}},
+		{ID: "taken-id", Username: "alice", Email: "taken@example.com", PasswordHash: []byte{}},
+		{ID: "unverified-id", Username: "unverified", Email: "unverified@example.com", PasswordHash: []byte{}, EmailUnverified: true},
+	} {
+		err := storage.AddUserInfo(ctx, nil, user)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	client, err := core.NewClient("", "Web UI", core.ClientPolicy{
+		RedirectURIs: []string{"http://localhost/callback"},
+		GrantTypes:   core.SupportedGrantTypes(),
+		Scope:        *core.FullScope(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = storage.AddClient(ctx, nil, *client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	upstream := federation.NewOIDCProvider(core.UpstreamConfig{
+		Issuer:       provider.server.URL,
+		ClientID:     upstreamClientID,
+		ClientSecret: upstreamClientSecret,
+		Name:         "Company",
+		Admin:        core.AdminClaimMapping{Claim: "groups", Value: "wallabago-admins"},
+	}, testPublicURL+UpstreamCallbackPath, provider.server.Client())
+	manager, _ := newTestIdentityManager(t, storage, managers.IdentityOptions{Upstream: upstream})
+	return &upstreamFixture{
+		router:   newTestRouter(manager, core.Client{ID: client.ID, Secret: client.Secret}),
+		manager:  manager,
+		storage:  storage,
+		provider: provider,
+	}
+}
+
+// login goes through the redirects of the login, the provider returns the claims
+// and the tamper function may change the callback before it is sent.
+func (f *upstreamFixture) login(t *testing.T, session string, claims map[string]any, tamper func(url.Values)) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, UpstreamLoginPath+"?next=/entries", nil)
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: session})
+	}
+	w := serve(f.router, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect to the provider but got %d: %s", w.Code, w.Body)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	query := location.Query()
+	if query.Get(OAuth2ClientID) != upstreamClientID || query.Get(OAuth2RedirectURI) != "http://localhost"+UpstreamCallbackPath {
+		t.Fatalf("Unexpected authorization request: %s", location)
+	}
+	f.provider.nonce = query.Get(OAuth2Nonce)
+	f.provider.codeChallenge = query.Get(OAuth2CodeChallenge)
+	f.provider.claims = claims
+
+	callback := url.Values{OAuth2State: {query.Get(OAuth2State)}, OAuth2Code: {"code"}}
+	if tamper != nil {
+		tamper(callback)
+	}
+	req = httptest.NewRequest(http.MethodGet, UpstreamCallbackPath+"?"+callback.Encode(), nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	return serve(f.router, req)
+}
+
+func sessionUserID(t *testing.T, f *upstreamFixture, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName && cookie.Value != "" {
+			token, err := f.manager.Authenticate(context.Background(), cookie.Value)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			return token.UserID
+		}
+	}
+	t.Fatalf("Expected session cookie but got none")
+	return ""
+}
+
+func TestUpstreamLogin(t *testing.T) {
+	cases := []struct {
+		name           string
+		claims         map[string]any
+		tamper         func(url.Values)
+		expectedStatus int
+		expectedUserID string
+		expectedAdmin  bool
+	}{
+		{
+			name:           "provisions new user",
+			claims:         map[string]any{"sub": "new", "email": "new@example.com", "preferred_username": "alice", "groups": []any{"wallabago-admins"}},
+			expectedStatus: http.StatusSeeOther,
+			expectedAdmin:  true,
+		},
+		{
+			name:           "links user with verified email",
+			claims:         map[string]any{"sub": "verified", "email": "existing@example.com", "email_verified": true},
+			expectedStatus: http.StatusSeeOther,
+			expectedUserID: "existing-id",
+		},
+		{
+			name:           "denies user with unverified email",
+			claims:         map[string]any{"sub": "unverified", "email": "existing@example.com"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "denies verified email of unverified account",
+			claims:         map[string]any{"sub": "verified", "email": "unverified@example.com", "email_verified": true},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "denies user without email",
+			claims:         map[string]any{"sub": "anonymous"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "denies nonce mismatch",
+			claims:         map[string]any{"sub": "new", "email": "new@example.com", "nonce": "replayed"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "denies state mismatch",
+			claims:         map[string]any{"sub": "new", "email": "new@example.com"},
+			tamper:         func(values url.Values) { values.Set(OAuth2State, "forged") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "denies provider error",
+			claims:         map[string]any{"sub": "new", "email": "new@example.com"},
+			tamper:         func(values url.Values) { values.Set(OAuth2Error, core.AuthErrorAccessDenied) },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "denies another issuer",
+			claims:         map[string]any{"sub": "new", "email": "new@example.com", "iss": "https://evil.example.com"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			f := newUpstreamFixture(t)
+			w := f.login(t, "", testCase.claims, testCase.tamper)
+			if w.Code != testCase.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", testCase.expectedStatus, w.Code, w.Body)
+			}
+			if w.Code != http.StatusSeeOther {
+				return
+			}
+			if location := w.Header().Get("Location"); location != "/entries" {
+				t.Fatalf("Expected redirect to /entries but got %s", location)
+			}
+			userID := sessionUserID(t, f, w)
+			if testCase.expectedUserID != "" && userID != testCase.expectedUserID {
+				t.Fatalf("Expected user %s but got %s", testCase.expectedUserID, userID)
+			}
+			user, err := f.storage.GetUserByID(context.Background(), nil, userID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if user.IsAdmin != testCase.expectedAdmin {
+				t.Fatalf("Expected admin %t but got %t", testCase.expectedAdmin, user.IsAdmin)
+			}
+		})
+	}
+}
+
+func TestUpstreamLoginProvisionsFreeUsername(t *testing.T) {
+	f := newUpstreamFixture(t)
+	w := f.login(t, "", map[string]any{"sub": "new", "email": "alice@example.com"}, nil)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect but got %d: %s", w.Code, w.Body)
+	}
+	user, err := f.storage.GetUserInfoByID(context.Background(), nil, sessionUserID(t, f, w))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if user.Username != "alice2" {
+		t.Fatalf("Expected username alice2 but got %s", user.Username)
+	}
+}
+
+func TestUpstreamLoginKeepsLink(t *testing.T) {
+	f := newUpstreamFixture(t)
+	claims := map[string]any{"sub": "subject", "email": "first@example.com", "groups": []any{"wallabago-admins"}}
+	first := sessionUserID(t, f, f.login(t, "", claims, nil))
+
+	// the email changed at the provider and the user is no longer an administrator
+	claims = map[string]any{"sub": "subject", "email": "second@example.com"}
+	w := f.login(t, "", claims, nil)
+	if second := sessionUserID(t, f, w); second != first {
+		t.Fatalf("Expected user %s but got %s", first, second)
+	}
+	user, err := f.storage.GetUserByID(context.Background(), nil, first)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if user.IsAdmin {
+		t.Fatalf("Expected admin role to be revoked")
+	}
+}
+
+func TestUpstreamLoginLinksSessionUser(t *testing.T) {
+	f := newUpstreamFixture(t)
+	// the session of the existing user comes from the account linked by the verified email
+	existing := f.login(t, "", map[string]any{"sub": "verified", "email": "existing@example.com", "email_verified": true}, nil)
+	session := ""
+	for _, cookie := range existing.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName {
+			session = cookie.Value
+		}
+	}
+
+	w := f.login(t, session, map[string]any{"sub": "other", "email": "other@example.com"}, nil)
+	if userID := sessionUserID(t, f, w); userID != "existing-id" {
+		t.Fatalf("Expected account to be linked to existing-id but got %s", userID)
+	}
+	w = f.login(t, "", map[string]any{"sub": "other", "email": "other@example.com"}, nil)
+	if userID := sessionUserID(t, f, w); userID != "existing-id" {
+		t.Fatalf("Expected linked account to log in as existing-id but got %s", userID)
+	}
+
+	// the account linked to another user can not be taken over
+	provisioned := sessionUserID(t, f, f.login(t, "", map[string]any{"sub": "new", "email": "new@example.com"}, nil))
+	w = f.login(t, session, map[string]any{"sub": "new", "email": "new@example.com"}, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusUnauthorized, w.Code, w.Body)
+	}
+	identity, err := f.storage.GetFederatedIdentity(context.Background(), nil, f.provider.server.URL, "new")
+	if err != nil || identity.UserID != provisioned {
+		t.Fatalf("Expected identity linked to %s but got %#v, %v", provisioned, identity, err)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/middleware"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/pkg/errors"
+)
+
+// paths of the endpoints advertised in the server metadata.
+const (
+	AuthorizationPath       = "/oauth/v2/authorize"
+	TokenPath               = "/oauth/v2/token"
+	RevocationPath          = "/oauth/v2/revoke"
+	IntrospectionPath       = "/oauth/v2/introspect"
+	DeviceAuthorizationPath = "/oauth/v2/device_authorization"
+	UserInfoPath            = "/oauth/v2/userinfo"
+	JWKSPath                = "/.well-known/jwks.json"
+
+	OpenIDConfigurationPath         = "/.well-known/openid-configuration"
+	AuthorizationServerMetadataPath = "/.well-known/oauth-authorization-server"
+)
+
+// JWKSEndpoint publishes the keys the issued tokens can be verified with.
+func (h *OAuth2Handler) JWKSEndpoint(w http.ResponseWriter, r *http.Request) {
+	jwks, err := h.manager.JWKS()
+	if err != nil {
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	// verifiers are expected to refetch the set when they encounter an unknown kid
+	w.Header().Set(constants.HeaderCacheControl, "public, max-age=3600")
+	response.RespondOKJSON(w, r, jwks)
+}
+
+// MetadataEndpoint describes the server, the same document is served
+// for the OpenID Connect discovery and for the RFC 8414 clients.
+func (h *OAuth2Handler) MetadataEndpoint(w http.ResponseWriter, r *http.Request) {
+	metadata := h.manager.AuthorizationServerMetadata(core.ServerEndpoints{
+		Authorization:       h.publicURL + AuthorizationPath,
+		Token:               h.publicURL + TokenPath,
+		Revocation:          h.publicURL + RevocationPath,
+		Introspection:       h.publicURL + IntrospectionPath,
+		DeviceAuthorization: h.publicURL + DeviceAuthorizationPath,
+		Registration:        h.publicURL + RegistrationPath,
+		UserInfo:            h.publicURL + UserInfoPath,
+		JWKS:                h.publicURL + JWKSPath,
+	})
+	w.Header().Set(constants.HeaderCacheControl, "public, max-age=3600")
+	response.RespondOKJSON(w, r, metadata)
+}
+
+// UserInfoEndpoint returns the claims about the user of the access token,
+// it has to be wrapped by the authentication requiring the openid scope,
+// see https://openid.net/specs/openid-connect-core-1_0.html#UserInfo.
+func (h *OAuth2Handler) UserInfoEndpoint(w http.ResponseWriter, r *http.Request) {
+	token := middleware.MustGetAccessToken(r)
+	claims, err := h.manager.UserInfo(r.Context(), token)
+	if err != nil {
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			w.Header().Set(constants.HeaderAuthenticate, fmt.Sprintf("Bearer error=%q", authError.ErrorName))
+			response.RespondJSON(w, r, authError, http.StatusUnauthorized)
+			return
+		}
+		response.RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	w.Header().Set(constants.HeaderCacheControl, "no-store")
+	response.RespondOKJSON(w, r, claims)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/andriihomiak/wallabago/internal/http/response"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+type adminMiddleware struct {
+	identity *managers.IdentityManager
+}
+
+// NewAdminMiddleware only lets administrators through.
+// It has to be applied after the authentication middleware.
+func NewAdminMiddleware(identity *managers.IdentityManager) Middleware {
+	return &adminMiddleware{
+		identity: identity,
+	}
+}
+
+var _ Middleware = (*adminMiddleware)(nil)
+
+func (m *adminMiddleware) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := MustGetAccessToken(r)
+		user, err := m.identity.GetUser(r.Context(), token.UserID)
+		if err != nil || !user.IsAdmin {
+			response.RespondErrorPlain(w, r, fmt.Errorf("admin privileges required"), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -33,7 +33,7 @@ type contextAccessTokenKey struct{}
 
 var ctxTokenKey = contextAccessTokenKey{}
 
-func (m *oAuth2Middleware) withToken(r *http.Request, accessToken *core.AccessToken) *http.Request {
+func withToken(r *http.Request, accessToken *core.AccessToken) *http.Request {
 	return r.WithContext(context.WithValue(r.Context(), ctxTokenKey, accessToken))
 }
 
@@ -47,21 +47,49 @@ func MustGetAccessToken(r *http.Request) core.AccessToken {
 	return *token
 }
 
+// bearerChallenge tells the client how to authenticate and why its token was rejected,
+// along with the scope needed for the resource if it is known,
+// see https://datatracker.ietf.org/doc/html/rfc6750#section-3.
+func bearerChallenge(authError *core.AuthError, scope core.Scope) string {
+	if authError == nil {
+		return "Bearer"
+	}
+	challenge := fmt.Sprintf("Bearer error=%q", authError.ErrorName)
+	if authError.ErrorDescription != "" {
+		challenge += fmt.Sprintf(", error_description=%q", authError.ErrorDescription)
+	}
+	if scope != "" {
+		challenge += fmt.Sprintf(", scope=%q", scope)
+	}
+	return challenge
+}
+
 func (m *oAuth2Middleware) Wrap(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get(constants.HeaderAuthorization)
 		authHeaderParts := strings.Split(authHeader, " ")
 		if len(authHeaderParts) != 2 || !strings.EqualFold(authHeaderParts[0], "bearer") {
+			w.Header().Set(constants.HeaderAuthenticate, bearerChallenge(nil, ""))
 			response.RespondErrorPlain(w, r, fmt.Errorf("bad authorization header: '%s'", authHeader), http.StatusUnauthorized)
 			return
 		}
 		tokenPart := authHeaderParts[1]
-		accessToken, err := m.identity.Authenticate(r.Context(), tokenPart)
-		var authError *core.AuthError
-		if errors.As(err, &authError) {
-			response.RespondJSON(w, r, authError, http.StatusUnauthorized)
+		// unlike the web ui sessions the api also accepts the personal access tokens
+		authenticate := m.identity.Authenticate
+		if core.IsPersonalAccessToken(tokenPart) {
+			authenticate = m.identity.AuthenticatePersonalAccessToken
+		}
+		accessToken, err := authenticate(r.Context(), tokenPart)
+		if err != nil {
+			var authError *core.AuthError
+			if errors.As(err, &authError) {
+				w.Header().Set(constants.HeaderAuthenticate, bearerChallenge(authError, ""))
+				response.RespondJSON(w, r, authError, http.StatusUnauthorized)
+				return
+			}
+			response.RespondInternalErrorWithStack(w, r, err)
 			return
 		}
-		handler.ServeHTTP(w, m.withToken(r, accessToken))
+		handler.ServeHTTP(w, withToken(r, accessToken))
 	})
 }
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+	"github.com/andriihomiak/wallabago/internal/http/response"
+)
+
+type scopeMiddleware struct {
+	required []core.ScopeName
+}
+
+// RequireScopes only lets through the tokens granting all of the scopes.
+// It has to be applied after the authentication middleware.
+func RequireScopes(required ...core.ScopeName) Middleware {
+	return &scopeMiddleware{
+		required: required,
+	}
+}
+
+var _ Middleware = (*scopeMiddleware)(nil)
+
+func (m *scopeMiddleware) Wrap(handler http.Handler) http.Handler {
+	requiredScope, err := core.NewScope(m.required...)
+	if err != nil {
+		// routes are registered at startup, so this is a programming error
+		panic(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := MustGetAccessToken(r)
+		missing := token.Scope.Missing(m.required...)
+		if len(missing) > 0 {
+			names := make([]string, 0, len(missing))
+			for _, name := range missing {
+				names = append(names, string(name))
+			}
+			authError := &core.AuthError{
+				ErrorName:        core.AuthErrorInsufficientScope,
+				ErrorDescription: fmt.Sprintf("The access token is missing the scope: %s", strings.Join(names, " ")),
+			}
+			w.Header().Set(constants.HeaderAuthenticate, bearerChallenge(authError, *requiredScope))
+			response.RespondJSON(w, r, authError, http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/http/constants"
+)
+
+func TestRequireScopes(t *testing.T) {
+	cases := []struct {
+		name              string
+		tokenScope        core.Scope
+		required          []core.ScopeName
+		expectedStatus    int
+		expectedChallenge string
+	}{
+		{
+			name:           "granted scope",
+			tokenScope:     "entries:read tags",
+			required:       []core.ScopeName{core.ScopeEntriesRead, core.ScopeTags},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "implied scope",
+			tokenScope:     "entries",
+			required:       []core.ScopeName{core.ScopeEntriesWrite},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "missing scope",
+			tokenScope:        "entries:read",
+			required:          []core.ScopeName{core.ScopeEntriesWrite},
+			expectedStatus:    http.StatusForbidden,
+			expectedChallenge: `Bearer error="insufficient_scope", error_description="The access token is missing the scope: entries:write", scope="entries:write"`,
+		},
+		{
+			name:              "one of the scopes missing",
+			tokenScope:        "tags",
+			required:          []core.ScopeName{core.ScopeTags, core.ScopeExport},
+			expectedStatus:    http.StatusForbidden,
+			expectedChallenge: `Bearer error="insufficient_scope", error_description="The access token is missing the scope: export", scope="tags export"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := RequireScopes(tc.required...).Wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := withToken(httptest.NewRequest(http.MethodGet, "/", http.NoBody), &core.AccessToken{Scope: tc.tokenScope})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.expectedStatus, w.Code, w.Body)
+			}
+			if challenge := w.Header().Get(constants.HeaderAuthenticate); challenge != tc.expectedChallenge {
+				t.Fatalf("Expected challenge %q but got %q", tc.expectedChallenge, challenge)
+			}
+			if tc.expectedStatus == http.StatusOK {
+				return
+			}
+			authError := core.AuthError{}
+			err := json.Unmarshal(w.Body.Bytes(), &authError)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if authError.ErrorName != core.AuthErrorInsufficientScope {
+				t.Fatalf("Expected error %s but got %s", core.AuthErrorInsufficientScope, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/managers"
+)
+
+// SessionCookieName is the cookie holding the access token
+// of the web ui session.
+const SessionCookieName = "wallabago_session"
+
+// LoginPath is where users without a session are sent to.
+const LoginPath = "/login"
+
+type SessionMiddleware interface {
+	Middleware
+}
+
+type sessionMiddleware struct {
+	identity *managers.IdentityManager
+}
+
+func NewSessionMiddleware(identity *managers.IdentityManager) SessionMiddleware {
+	return &sessionMiddleware{
+		identity: identity,
+	}
+}
+
+var _ SessionMiddleware = (*sessionMiddleware)(nil)
+
+func (m *sessionMiddleware) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(SessionCookieName)
+		if err != nil {
+			redirectToLogin(w, r)
+			return
+		}
+		accessToken, err := m.identity.Authenticate(r.Context(), cookie.Value)
+		if err != nil {
+			ClearSessionCookie(w, r)
+			redirectToLogin(w, r)
+			return
+		}
+		handler.ServeHTTP(w, withToken(r, accessToken))
+	})
+}
+
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	next := url.Values{}
+	next.Set("next", r.URL.RequestURI())
+	http.Redirect(w, r, LoginPath+"?"+next.Encode(), http.StatusSeeOther)
+}
+
+// SetSessionCookie starts a web ui session backed by the access token.
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, token *core.AccessToken) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    string(token.Token),
+		Path:     "/",
+		MaxAge:   int(token.ExpiresInSeconds),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// lax mode keeps the cookie away from cross-site form posts
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -1,8 +1,10 @@
 package response
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"net/http"
 
 	"github.com/andriihomiak/wallabago/internal/http/constants"
@@ -36,3 +38,18 @@ func RespondErrorPlain(w http.ResponseWriter, _ *http.Request, err error, status
 	w.WriteHeader(status)
 	fmt.Fprint(w, err)
 }
+
+// RespondHTML renders the named template, the response is only written
+// once the template was executed successfully.
+func RespondHTML(w http.ResponseWriter, r *http.Request, templates *template.Template, name string, data any, status int) {
+	body := bytes.Buffer{}
+	err := templates.ExecuteTemplate(&body, name, data)
+	if err != nil {
+		RespondInternalErrorWithStack(w, r, err)
+		return
+	}
+	w.Header().Set(constants.HeaderContentType, constants.MimeTextHTML)
+	w.WriteHeader(status)
+	//nolint:errcheck //todo
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"net/mail"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/pkg/errors"
+)
+
+// FileSender appends the emails to the file instead of sending them, meant for the development
+// and the setups without an SMTP server where the administrator forwards the emails by hand.
+type FileSender struct {
+	from *mail.Address
+	path string
+	// mu keeps the emails of the concurrent requests apart
+	mu sync.Mutex
+}
+
+var _ managers.Mailer = (*FileSender)(nil)
+
+// NewFileSender creates the sender, the file is created on the first email.
+func NewFileSender(from, path string) (*FileSender, error) {
+	address, err := parseFrom(from)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSender{from: address, path: path}, nil
+}
+
+// Send appends the message to the file, the emails are separated by an empty line.
+func (s *FileSender) Send(_ context.Context, message core.MailMessage) error {
+	_, data, err := format(s.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	//nolint:gosec //the path comes from the operator
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = file.Write(append(data, "\r\n"...))
+	if err != nil {
+		file.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(file.Close())
+}
+
+// LogSender writes the emails to the log instead of sending them. The emails carry secrets,
+// like the password reset links, so it should never be used in the production.
+type LogSender struct{}
+
+var _ managers.Mailer = LogSender{}
+
+// Send logs the message.
+func (LogSender) Send(ctx context.Context, message core.MailMessage) error {
+	_, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return errors.Wrap(core.ErrInvalidInput, "invalid recipient address")
+	}
+	slog.InfoContext(ctx, "Email", "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
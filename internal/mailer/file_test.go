@@ -0,0 +1,39 @@
+package mailer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/mailer"
+)
+
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	sender, err := mailer.NewFileSender(from, path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, to := range []string{"first@example.com", "second@example.com"} {
+		err = sender.Send(context.Background(), core.MailMessage{To: to, Subject: message.Subject, Body: message.Body})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	emails := strings.Split(strings.TrimSuffix(string(content), "\r\n"), "\r\n\r\nFrom: ")
+	if len(emails) != 2 {
+		t.Fatalf("Expected 2 emails but got %q", content)
+	}
+	parsed, subject, body := readMessage(t, "From: "+strings.TrimPrefix(emails[1], "From: "))
+	if parsed.Header.Get("To") != "<second@example.com>" || subject != message.Subject || body != message.Body {
+		t.Fatalf("Unexpected email %q", emails[1])
+	}
+}
@@ -0,0 +1,171 @@
+// Package mailtest provides an in-process SMTP server for the tests, it understands
+// just enough of the protocol to accept the emails and keeps them in the memory.
+package mailtest
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is an email accepted by the server.
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Server accepts the emails until it is closed. When a username is set the emails
+// are only accepted after the client authenticated with the PLAIN mechanism, STARTTLS is never offered.
+type Server struct {
+	listener net.Listener
+	username string
+	password string
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts the server on a random local port.
+func NewServer(username, password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener, username: username, password: password}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr is where the server listens, e.g. 127.0.0.1:34567.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Messages returns the emails accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.messages...)
+}
+
+// Close stops the server and waits for the connections to finish.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.serve(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *Server) serve(conn *textproto.Conn) {
+	authenticated := s.username == ""
+	message := Message{}
+	reply := func(code int, text string) bool {
+		return conn.PrintfLine("%d %s", code, text) == nil
+	}
+	if !reply(220, "mailtest ready") {
+		return
+	}
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = conn.PrintfLine("250-mailtest") == nil && reply(250, "AUTH PLAIN")
+		case "HELO":
+			ok = reply(250, "mailtest")
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(argument, " ")
+			if strings.ToUpper(mechanism) != "PLAIN" {
+				ok = reply(504, "unrecognized authentication type")
+				break
+			}
+			if s.checkPlain(response) {
+				authenticated = true
+				ok = reply(235, "authentication successful")
+			} else {
+				ok = reply(535, "authentication credentials invalid")
+			}
+		case "MAIL":
+			if !authenticated {
+				ok = reply(530, "authentication required")
+				break
+			}
+			message = Message{From: address(argument)}
+			ok = reply(250, "ok")
+		case "RCPT":
+			message.To = append(message.To, address(argument))
+			ok = reply(250, "ok")
+		case "DATA":
+			if message.From == "" || len(message.To) == 0 {
+				ok = reply(503, "bad sequence of commands")
+				break
+			}
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			message = Message{}
+			ok = reply(250, "ok")
+		case "RSET":
+			message = Message{}
+			ok = reply(250, "ok")
+		case "NOOP":
+			ok = reply(250, "ok")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			ok = reply(502, "command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// checkPlain verifies the initial response of the PLAIN mechanism,
+// see https://datatracker.ietf.org/doc/html/rfc4616.
+func (s *Server) checkPlain(response string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	return len(parts) == 3 && parts[1] == s.username && parts[2] == s.password
+}
+
+// address extracts the address from the argument, e.g. FROM:<user@example.com>.
+func address(argument string) string {
+	_, path, _ := strings.Cut(argument, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
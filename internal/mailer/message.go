@@ -0,0 +1,65 @@
+// Package mailer sends the emails of the identity manager, see managers.Mailer.
+package mailer
+
+import (
+	"bytes"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// parseFrom validates the sender address of the configuration.
+func parseFrom(from string) (*mail.Address, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid sender address %q", from)
+	}
+	return address, nil
+}
+
+// format renders the message as a plain text email, see https://datatracker.ietf.org/doc/html/rfc5322.
+// The recipient is parsed so that it can not smuggle any headers into the email.
+func format(from *mail.Address, message core.MailMessage, now time.Time) (*mail.Address, []byte, error) {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return nil, nil, errors.Wrap(core.ErrInvalidInput, "invalid recipient address")
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	// the encoding also takes care of the line breaks
+	header("Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+uuid.New().String()+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	// the email has to end with a line break, the file sender also relies on it to separate the emails
+	text := strings.ReplaceAll(message.Body, "\r\n", "\n")
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	body := quotedprintable.NewWriter(&buf)
+	_, err = body.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n")))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	err = body.Close()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return to, buf.Bytes(), nil
+}
@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/managers"
+	"github.com/pkg/errors"
+)
+
+// SMTPSender relays the emails through the SMTP server, one connection per email.
+type SMTPSender struct {
+	from    *mail.Address
+	config  core.SMTPConfig
+	host    string
+	timeout time.Duration
+	// tlsConfig is used for the StartTLS upgrade
+	tlsConfig *tls.Config
+}
+
+var _ managers.Mailer = (*SMTPSender)(nil)
+
+// NewSMTPSender creates the sender, the missing TLS configuration verifies the certificate of the host.
+func NewSMTPSender(from string, config core.SMTPConfig, timeout time.Duration, tlsConfig *tls.Config) (*SMTPSender, error) {
+	address, err := parseFrom(from)
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid smtp address %q", config.Addr)
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	}
+	return &SMTPSender{
+		from:      address,
+		config:    config,
+		host:      host,
+		timeout:   timeout,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// Send delivers the message to the server, the whole conversation has to fit into the timeout.
+func (s *SMTPSender) Send(ctx context.Context, message core.MailMessage) error {
+	to, data, err := format(s.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		conn.Close()
+		return errors.WithStack(err)
+	}
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return errors.WithStack(err)
+	}
+	defer client.Close()
+
+	if s.config.StartTLS {
+		// never fall back to the plain connection, the credentials would be sent in the clear
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		err = client.StartTLS(s.tlsConfig)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if s.config.Username != "" {
+		// PlainAuth refuses the unencrypted connections to anything but the localhost
+		err = client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.host))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	err = client.Mail(s.from.Address)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = client.Rcpt(to.Address)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = w.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(client.Quit())
+}
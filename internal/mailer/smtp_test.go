@@ -0,0 +1,166 @@
+package mailer_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/andriihomiak/wallabago/internal/mailer"
+	"github.com/andriihomiak/wallabago/internal/mailer/mailtest"
+)
+
+const from = "Wallabago <no-reply@example.com>"
+
+var message = core.MailMessage{
+	To:      "Ümit <user@example.com>",
+	Subject: "Reset your password ✓",
+	Body:    "Choose a new password at\nhttps://example.com/password-reset/confirm?token=" + strings.Repeat("a", 100) + "\n",
+}
+
+// readMessage parses the email and decodes its subject and body.
+func readMessage(t *testing.T, data string) (*mail.Message, string, string) {
+	t.Helper()
+	parsed, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return parsed, subject, strings.ReplaceAll(string(body), "\r\n", "\n")
+}
+
+func TestSMTPSender(t *testing.T) {
+	server, err := mailtest.NewServer("wallabago", "secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer server.Close()
+	sender, err := mailer.NewSMTPSender(from, core.SMTPConfig{
+		Addr:     server.Addr(),
+		Username: "wallabago",
+		Password: "secret",
+	}, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	err = sender.Send(context.Background(), message)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message but got %d", len(messages))
+	}
+	sent := messages[0]
+	if sent.From != "no-reply@example.com" || len(sent.To) != 1 || sent.To[0] != "user@example.com" {
+		t.Fatalf("Unexpected envelope %#v", sent)
+	}
+	parsed, subject, body := readMessage(t, sent.Data)
+	if subject != message.Subject || body != message.Body {
+		t.Fatalf("Expected %#v but got subject %q and body %q", message, subject, body)
+	}
+	to, err := parsed.Header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != "Ümit" {
+		t.Fatalf("Unexpected recipient %#v: %v", to, err)
+	}
+	if parsed.Header.Get("Message-ID") == "" || parsed.Header.Get("Date") == "" {
+		t.Fatalf("Expected the message id and the date but got %#v", parsed.Header)
+	}
+}
+
+func TestSMTPSenderRejected(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  core.SMTPConfig
+		message core.MailMessage
+	}{
+		{
+			name:    "wrong password",
+			config:  core.SMTPConfig{Username: "wallabago", Password: "wrong"},
+			message: message,
+		},
+		{
+			name:    "no authentication",
+			message: message,
+		},
+		{
+			name:    "STARTTLS not offered",
+			config:  core.SMTPConfig{StartTLS: true, Username: "wallabago", Password: "secret"},
+			message: message,
+		},
+		{
+			name:   "header in the recipient",
+			config: core.SMTPConfig{Username: "wallabago", Password: "secret"},
+			message: core.MailMessage{
+				To:      "user@example.com\r\nBcc: other@example.com",
+				Subject: message.Subject,
+				Body:    message.Body,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := mailtest.NewServer("wallabago", "secret")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			defer server.Close()
+			tc.config.Addr = server.Addr()
+			sender, err := mailer.NewSMTPSender(from, tc.config, time.Second, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			err = sender.Send(context.Background(), tc.message)
+			if err == nil {
+				t.Fatalf("Expected error")
+			}
+			if messages := server.Messages(); len(messages) != 0 {
+				t.Fatalf("Expected no messages but got %#v", messages)
+			}
+		})
+	}
+}
+
+func TestSubjectLineBreak(t *testing.T) {
+	server, err := mailtest.NewServer("", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer server.Close()
+	sender, err := mailer.NewSMTPSender(from, core.SMTPConfig{Addr: server.Addr()}, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = sender.Send(context.Background(), core.MailMessage{
+		To:      "user@example.com",
+		Subject: "Hello\r\nBcc: other@example.com",
+		Body:    "Hello",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	parsed, _, _ := readMessage(t, server.Messages()[0].Data)
+	if bcc := parsed.Header.Get("Bcc"); bcc != "" {
+		t.Fatalf("Expected the line break to be encoded but got Bcc %q", bcc)
+	}
+}
+
+func TestNewSMTPSenderInvalidFrom(t *testing.T) {
+	_, err := mailer.NewSMTPSender("no-reply", core.SMTPConfig{Addr: "localhost:25"}, time.Second, nil)
+	if err == nil {
+		t.Fatalf("Expected error")
+	}
+}
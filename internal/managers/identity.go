@@ -3,49 +3,341 @@ package managers
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type IdentityStorage interface {
 	AddClient(ctx context.Context, tx *sql.Tx, client core.Client) error
 	GetClientByID(ctx context.Context, tx *sql.Tx, id string) (*core.Client, error)
+	GetClients(ctx context.Context, tx *sql.Tx) ([]core.Client, error)
+	GetClientsByOwnerUserID(ctx context.Context, tx *sql.Tx, ownerUserID string) ([]core.Client, error)
+	SetClientSecretHash(ctx context.Context, tx *sql.Tx, clientID string, secretHash []byte) error
+	UpdateClient(ctx context.Context, tx *sql.Tx, client core.Client) error
+	SetClientServiceAccount(ctx context.Context, tx *sql.Tx, clientID, userID string, scope core.Scope) error
 	DeleteClientByID(ctx context.Context, tx *sql.Tx, id string) error
 
 	AddAccessToken(ctx context.Context, tx *sql.Tx, refreshTokenID string, token core.AccessToken) error
-	GetAccessTokenByJWT(ctx context.Context, tx *sql.Tx, jwt core.JWT) (*core.AccessToken, error)
+	GetAccessTokenByID(ctx context.Context, tx *sql.Tx, id string) (*core.AccessToken, error)
 	RevokeAccessTokenByID(ctx context.Context, tx *sql.Tx, id string) error
 	DeleteAccessTokenByID(ctx context.Context, tx *sql.Tx, id string) error
+	GetAccessTokenFamilyID(ctx context.Context, tx *sql.Tx, id string) (string, error)
+
+	RevokeAccessTokensByRefreshTokenID(ctx context.Context, tx *sql.Tx, refreshTokenID string) error
+	RevokeAccessTokensByRefreshTokenFamilyID(ctx context.Context, tx *sql.Tx, familyID string) error
 
 	AddRefreshToken(ctx context.Context, tx *sql.Tx, token core.RefreshToken) error
-	GetRefreshTokenByJWT(ctx context.Context, tx *sql.Tx, refreshToken core.JWT) (*core.RefreshToken, error)
+	GetRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) (*core.RefreshToken, error)
 	RevokeRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) error
+	// RotateRefreshTokenByID returns false when the token was already rotated or revoked
+	RotateRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) (bool, error)
+	RevokeRefreshTokensByFamilyID(ctx context.Context, tx *sql.Tx, familyID string) error
 	DeleteRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) error
 
+	AddAuthorizationCode(ctx context.Context, tx *sql.Tx, code core.AuthorizationCode) error
+	GetAuthorizationCode(ctx context.Context, tx *sql.Tx, codeHash []byte) (*core.AuthorizationCode, error)
+	MarkAuthorizationCodeUsed(ctx context.Context, tx *sql.Tx, codeHash []byte, tokenFamilyID string) error
+
+	AddDeviceCode(ctx context.Context, tx *sql.Tx, code core.DeviceCode) error
+	GetDeviceCodeByDeviceCodeHash(ctx context.Context, tx *sql.Tx, deviceCodeHash []byte) (*core.DeviceCode, error)
+	GetDeviceCodeByUserCode(ctx context.Context, tx *sql.Tx, userCode string) (*core.DeviceCode, error)
+	UpdateDeviceCodePolling(ctx context.Context, tx *sql.Tx, deviceCodeHash []byte, polledAt time.Time, interval time.Duration) error
+	SetDeviceCodeStatus(ctx context.Context, tx *sql.Tx, deviceCodeHash []byte, status core.DeviceCodeStatus, userID string) error
+
 	AddUserInfo(ctx context.Context, tx *sql.Tx, user core.UserInfo) error
 	GetUserInfoByUsername(ctx context.Context, tx *sql.Tx, username string) (*core.UserInfo, error)
+	GetUserInfoByID(ctx context.Context, tx *sql.Tx, id string) (*core.UserInfo, error)
+	GetUserInfoByEmail(ctx context.Context, tx *sql.Tx, email string) (*core.UserInfo, error)
 	DeleteUserInfoByID(ctx context.Context, tx *sql.Tx, id string) error
 
+	AddUser(ctx context.Context, tx *sql.Tx, user core.User) error
+	GetUserByID(ctx context.Context, tx *sql.Tx, id string) (*core.User, error)
+	SetUserAdmin(ctx context.Context, tx *sql.Tx, userID string, isAdmin bool) error
+	SetUserPasswordHash(ctx context.Context, tx *sql.Tx, userID string, passwordHash []byte) error
+	SetUserEmailVerified(ctx context.Context, tx *sql.Tx, userID string) error
+
+	GetFederatedIdentity(ctx context.Context, tx *sql.Tx, issuer, subject string) (*core.FederatedIdentity, error)
+	AddFederatedIdentity(ctx context.Context, tx *sql.Tx, identity core.FederatedIdentity) error
+
+	GetUserTOTP(ctx context.Context, tx *sql.Tx, userID string) (*core.TOTP, error)
+	SetUserTOTP(ctx context.Context, tx *sql.Tx, totp core.TOTP) error
+	ConfirmUserTOTP(ctx context.Context, tx *sql.Tx, userID string, confirmedAt time.Time, step int64) (bool, error)
+	UseUserTOTPStep(ctx context.Context, tx *sql.Tx, userID string, step int64) (bool, error)
+	DeleteUserTOTP(ctx context.Context, tx *sql.Tx, userID string) error
+	AddRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, tx *sql.Tx, userID string, codeHash []byte, usedAt time.Time) (bool, error)
+	CountRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) error
+
+	GetPasskeys(ctx context.Context, tx *sql.Tx, userID string) ([]core.Passkey, error)
+	GetPasskey(ctx context.Context, tx *sql.Tx, credentialID []byte) (*core.Passkey, error)
+	AddPasskey(ctx context.Context, tx *sql.Tx, passkey core.Passkey) error
+	UsePasskey(ctx context.Context, tx *sql.Tx, credentialID []byte, signCount uint32, backupState bool, usedAt time.Time) error
+	DeletePasskey(ctx context.Context, tx *sql.Tx, userID string, credentialID []byte) (bool, error)
+	AddWebAuthnChallenge(ctx context.Context, tx *sql.Tx, challenge core.WebAuthnChallenge) error
+	TakeWebAuthnChallenge(ctx context.Context, tx *sql.Tx, challenge string) (*core.WebAuthnChallenge, error)
+	DeleteExpiredWebAuthnChallenges(ctx context.Context, tx *sql.Tx, now time.Time) error
+
+	GetPersonalAccessTokens(ctx context.Context, tx *sql.Tx, userID string) ([]core.PersonalAccessToken, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tx *sql.Tx, tokenHash []byte) (*core.PersonalAccessToken, error)
+	AddPersonalAccessToken(ctx context.Context, tx *sql.Tx, token core.PersonalAccessToken) error
+	UsePersonalAccessToken(ctx context.Context, tx *sql.Tx, tokenID string, usedAt time.Time) error
+	DeletePersonalAccessToken(ctx context.Context, tx *sql.Tx, userID, tokenID string) (bool, error)
+	DeletePersonalAccessTokens(ctx context.Context, tx *sql.Tx, userID string) error
+
+	SetSession(ctx context.Context, tx *sql.Tx, session core.Session) error
+	GetSession(ctx context.Context, tx *sql.Tx, id string) (*core.Session, error)
+	GetActiveSessions(ctx context.Context, tx *sql.Tx, userID string, now time.Time) ([]core.Session, error)
+
+	AddPasswordReset(ctx context.Context, tx *sql.Tx, reset core.PasswordReset) error
+	UsePasswordReset(ctx context.Context, tx *sql.Tx, id string, usedAt time.Time) (bool, error)
+	CountPasswordResets(ctx context.Context, tx *sql.Tx, userID string, since time.Time) (int64, error)
+	DeletePasswordResets(ctx context.Context, tx *sql.Tx, userID string) error
+
+	GetSignupMode(ctx context.Context, tx *sql.Tx) (core.SignupMode, error)
+	SetSignupMode(ctx context.Context, tx *sql.Tx, mode core.SignupMode) error
+	AddInvite(ctx context.Context, tx *sql.Tx, invite core.Invite) error
+	GetInvites(ctx context.Context, tx *sql.Tx) ([]core.Invite, error)
+	UseInvite(ctx context.Context, tx *sql.Tx, codeHash []byte, usedAt time.Time) (bool, error)
+	DeleteInvite(ctx context.Context, tx *sql.Tx, id string) (bool, error)
+
+	GetLoginAttempts(ctx context.Context, tx *sql.Tx, kind core.LoginAttemptsKind, subject string) (*core.LoginAttempts, error)
+	SetLoginAttempts(ctx context.Context, tx *sql.Tx, attempts core.LoginAttempts) error
+	DeleteLoginAttempts(ctx context.Context, tx *sql.Tx, kind core.LoginAttemptsKind, subject string) error
+
 	transactionStarter
 }
 
+// IdentityOptions are the optional collaborators of the IdentityManager,
+// the zero value leaves the features depending on them turned off.
+type IdentityOptions struct {
+	// Registration decides who may register clients dynamically
+	Registration core.RegistrationPolicy
+	// Passwords falls back to core.DefaultPasswordPolicy when nil
+	Passwords *core.PasswordPolicy
+	// Upstream is the provider the users can log in with
+	Upstream UpstreamProvider
+	// Credentials checks the passwords of the users without a local one
+	Credentials CredentialVerifier
+	// Mailer sends the password reset links and the email verifications
+	Mailer Mailer
+}
+
 func NewIdentityManager(
 	identityStorage IdentityStorage,
+	keys *core.KeySet,
+	issuer string,
+	options IdentityOptions,
 ) *IdentityManager {
+	passwords := core.DefaultPasswordPolicy()
+	if options.Passwords != nil {
+		passwords = *options.Passwords
+	}
 	return &IdentityManager{
-		storage:         identityStorage,
-		tokenExpiration: time.Hour * 24,
-		key:             []byte("wallabago"), // todo: pass from outside
+		storage:                     identityStorage,
+		tokenExpiration:             time.Hour * 24,
+		authorizationCodeExpiration: time.Minute,
+		deviceCodeExpiration:        time.Minute * 10,
+		deviceCodeInterval:          time.Second * 5,
+		keys:                        keys,
+		issuer:                      issuer,
+		registration:                options.Registration,
+		passwords:                   passwords,
+		dummyPasswordHash: sync.OnceValue(func() []byte {
+			// the random salt is the only thing that could fail, and crypto/rand does not
+			passwordHash, _ := passwords.Rehash("dummy password")
+			return passwordHash
+		}),
+		upstream:              options.Upstream,
+		credentials:           options.Credentials,
+		mailer:                options.Mailer,
+		usernameLoginThrottle: core.DefaultUsernameLoginThrottle(),
+		ipLoginThrottle:       core.DefaultIPLoginThrottle(),
 	}
 }
 
 type IdentityManager struct {
-	storage         IdentityStorage
-	key             []byte
-	tokenExpiration time.Duration
+	storage IdentityStorage
+	keys    *core.KeySet
+	// issuer identifies this server in the tokens it issues,
+	// the api served by it is the audience of the access tokens
+	issuer string
+	// registration decides who may register clients dynamically
+	registration core.RegistrationPolicy
+	// passwords checks and hashes the passwords chosen by the local users
+	passwords core.PasswordPolicy
+	// dummyPasswordHash is verified instead of the hash of the unknown users, it is
+	// hashed on the first use with the parameters of the policy so that it costs the same
+	dummyPasswordHash func() []byte
+	// upstream is the provider the users can log in with, nil when there is none
+	upstream UpstreamProvider
+	// credentials checks the passwords of the users without a local one, nil when there is none
+	credentials CredentialVerifier
+	// mailer sends the password reset links, nil when the emails are not configured
+	mailer                      Mailer
+	tokenExpiration             time.Duration
+	authorizationCodeExpiration time.Duration
+	deviceCodeExpiration        time.Duration
+	deviceCodeInterval          time.Duration
+	// the failed logins are throttled both per username and per client ip
+	usernameLoginThrottle core.LoginThrottle
+	ipLoginThrottle       core.LoginThrottle
+}
+
+func (m *IdentityManager) authenticateClient(ctx context.Context, tx *sql.Tx, clientID, clientSecret string) (*core.Client, error) {
+	client, err := m.storage.GetClientByID(ctx, tx, clientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+	// unknown clients are not told apart from the bad secrets
+	if err != nil || !client.Authenticate(clientSecret) {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidClient,
+			ErrorDescription: "Bad client credentials",
+		}
+	}
+	return client, nil
+}
+
+// checkGrantType rejects the grants the client is not allowed to use.
+func checkGrantType(client *core.Client, grantType core.GrantType) error {
+	if !client.AllowsGrantType(grantType) {
+		return &core.AuthError{
+			ErrorName:        core.AuthErrorUnauthorizedClient,
+			ErrorDescription: fmt.Sprintf("Client is not allowed to use the %s grant", grantType),
+		}
+	}
+	return nil
+}
+
+// accessTokenLifetime prefers the lifetime configured for the client.
+func (m *IdentityManager) accessTokenLifetime(client *core.Client) time.Duration {
+	if client.AccessTokenLifetime > 0 {
+		return client.AccessTokenLifetime
+	}
+	return m.tokenExpiration
+}
+
+// storedTokenID extracts the id of a token signed by this server. The claims
+// are not validated since the stored state decides whether the token is usable.
+func (m *IdentityManager) storedTokenID(token core.JWT) (string, bool) {
+	claims, err := m.keys.Parse(token, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return "", false
+	}
+	tokenID, ok := claims["jti"].(string)
+	return tokenID, ok && tokenID != ""
+}
+
+// getAccessToken finds the stored access token the presented one belongs to,
+// [core.ErrNotFound] is returned for tokens that are not known to this server.
+func (m *IdentityManager) getAccessToken(ctx context.Context, tx *sql.Tx, token core.JWT) (*core.AccessToken, error) {
+	tokenID, ok := m.storedTokenID(token)
+	if !ok {
+		return nil, errors.Wrap(core.ErrNotFound, "access token")
+	}
+	accessToken, err := m.storage.GetAccessTokenByID(ctx, tx, tokenID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrapf(core.ErrNotFound, "access token %s", tokenID)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// the id alone is not a secret, the hash proves the token is the one we issued
+	if !core.VerifyTokenHash(accessToken.TokenHash, string(token)) {
+		return nil, errors.Wrapf(core.ErrNotFound, "access token %s", tokenID)
+	}
+	accessToken.Token = token
+	return accessToken, nil
+}
+
+// getRefreshToken finds the stored refresh token the presented one belongs to,
+// [core.ErrNotFound] is returned for tokens that are not known to this server.
+func (m *IdentityManager) getRefreshToken(ctx context.Context, tx *sql.Tx, token core.JWT) (*core.RefreshToken, error) {
+	tokenID, ok := m.storedTokenID(token)
+	if !ok {
+		return nil, errors.Wrap(core.ErrNotFound, "refresh token")
+	}
+	refreshToken, err := m.storage.GetRefreshTokenByID(ctx, tx, tokenID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrapf(core.ErrNotFound, "refresh token %s", tokenID)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !core.VerifyTokenHash(refreshToken.TokenHash, string(token)) {
+		return nil, errors.Wrapf(core.ErrNotFound, "refresh token %s", tokenID)
+	}
+	refreshToken.Token = token
+	return refreshToken, nil
+}
+
+// issueTokenPair creates and saves a refresh token of the given family
+// together with an access token issued from it. The id token is added
+// for the openid scope, the nonce is only known for the authorization code.
+// The session of the family is started or marked as used from the device.
+func (m *IdentityManager) issueTokenPair(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID string,
+	client *core.Client,
+	familyID string,
+	scope core.Scope,
+	grantType core.GrantType,
+	nonce string,
+	device core.SessionDevice,
+) (*core.AccessTokenResponse, error) {
+	// create and save refresh token
+	refreshToken, err := core.NewRefreshToken(m.issuer, userID, client.ID, familyID, scope, client.RefreshTokenLifetime, m.keys)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = m.storage.AddRefreshToken(ctx, tx, *refreshToken)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = m.storage.SetSession(ctx, tx, core.Session{
+		ID:         refreshToken.FamilyID,
+		UserID:     userID,
+		ClientID:   client.ID,
+		Device:     device,
+		CreatedAt:  refreshToken.IssuedAt,
+		LastUsedAt: refreshToken.IssuedAt,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// create and save access token
+	accessToken, err := core.NewAccessToken(m.issuer, userID, client.ID, scope, m.accessTokenLifetime(client), m.keys)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	accessToken.GrantType = grantType
+	err = m.storage.AddAccessToken(ctx, tx, refreshToken.ID, *accessToken)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	response := core.AccessTokenResponse{
+		AccessToken:  *accessToken,
+		RefreshToken: refreshToken.Token,
+	}
+	if scope.Grants(core.ScopeOpenID) {
+		idToken, err := core.NewIDToken(m.issuer, userID, client.ID, nonce, accessToken.Token, m.accessTokenLifetime(client), m.keys)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		response.IDToken = *idToken
+	}
+	return &response, nil
 }
 
 func (m *IdentityManager) PasswordFlow(ctx context.Context, req core.PasswordFlowRequest) (*core.AccessTokenResponse, error) {
@@ -58,72 +350,283 @@ func (m *IdentityManager) PasswordFlow(ctx context.Context, req core.PasswordFlo
 	}()
 
 	// check client credentials
-	client, err := m.storage.GetClientByID(ctx, tx, req.ClientID)
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
 	if err != nil {
-		// todo: check error type
+		return nil, err
+	}
+	err = checkGrantType(client, core.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// slow down the guessing of the passwords before checking them
+	now := time.Now()
+	subjects, err := m.getLoginSubjects(ctx, tx, req)
+	if err != nil {
+		return nil, err
+	}
+	err = checkLoginThrottled(subjects, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// check user credentials
+	user, err := m.verifyPassword(ctx, tx, req.Username, req.Password)
+	failure := "Invalid username or password"
+	if err == nil {
+		// the second factor is only asked for once the password is right
+		failure = "Invalid one-time password"
+		err = m.verifySecondFactor(ctx, tx, user.ID, req.OTP, now)
+	}
+	if err != nil && !errors.Is(err, core.ErrInvalidCredentials) {
+		return nil, err
+	}
+	if err != nil {
+		// the failure has to be saved even though an error is returned
+		err = m.failLogin(ctx, tx, subjects, now)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// unknown users are not told apart from the bad passwords
 		return nil, &core.AuthError{
-			ErrorName:        core.AuthErrorInvalidClient,
-			ErrorDescription: errors.WithStack(err).Error(),
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: failure,
 		}
 	}
-	if client.Secret != req.ClientSecret {
+	// only the failures of the username are forgotten, otherwise a single
+	// known account would let the guessing from the same ip go on
+	err = m.storage.DeleteLoginAttempts(ctx, tx, core.LoginAttemptsKindUsername, req.Username)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	scope, err := client.GrantableScope(req.Scope)
+	if err != nil {
 		return nil, &core.AuthError{
-			ErrorName:        core.AuthErrorInvalidClient,
-			ErrorDescription: "Bad client credentials",
+			ErrorName:        core.AuthErrorInvalidScope,
+			ErrorDescription: err.Error(),
 		}
 	}
 
-	// check user credentials
-	user, err := m.storage.GetUserInfoByUsername(ctx, tx, req.Username)
+	// credentials correct at this point, issue a new token pair
+	response, err := m.issueTokenPair(ctx, tx, user.ID, client, "", *scope, core.GrantTypePassword, "", core.NewSessionDevice(req.UserAgent, req.ClientIP))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return response, nil
+}
+
+func (m *IdentityManager) RefreshTokenFlow(ctx context.Context, req core.RefreshTokenFlowRequest) (*core.AccessTokenResponse, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	err = checkGrantType(client, core.GrantTypeRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// check the refresh token itself
+	_, err = m.keys.Parse(core.JWT(req.RefreshToken), jwt.WithIssuer(m.issuer))
 	if err != nil {
-		// todo: check error type
 		return nil, &core.AuthError{
 			ErrorName:        core.AuthErrorInvalidGrant,
-			ErrorDescription: errors.WithStack(err).Error(),
+			ErrorDescription: "Invalid refresh token",
 		}
 	}
-
-	passwordErr := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(req.Password))
-	if passwordErr != nil {
-		// todo: check error type
+	refreshToken, err := m.getRefreshToken(ctx, tx, core.JWT(req.RefreshToken))
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
 		return nil, &core.AuthError{
 			ErrorName:        core.AuthErrorInvalidGrant,
-			ErrorDescription: errors.WithStack(passwordErr).Error(),
+			ErrorDescription: "Invalid refresh token",
+		}
+	}
+	if refreshToken.ClientID != client.ID {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Refresh token was issued to another client",
 		}
+		return nil, err
 	}
 
-	var scope *core.Scope
-	if req.Scope == "" {
-		scope = core.DefaultScope()
-	} else {
-		scope, err = core.NewScopeFromString(req.Scope)
+	if refreshToken.Rotated {
+		err = m.revokeReusedRefreshToken(ctx, tx, client.ID, refreshToken)
 		if err != nil {
-			return nil, &core.AuthError{
+			return nil, err
+		}
+		return nil, refreshTokenReused()
+	}
+	if refreshToken.Revoked {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Refresh token is revoked",
+		}
+		return nil, err
+	}
+	if refreshToken.Expired(time.Now()) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Refresh token expired",
+		}
+		return nil, err
+	}
+
+	// the new pair can narrow down the scope but never widen it
+	scope := refreshToken.Scope
+	if req.Scope != "" {
+		requestedScope, scopeErr := core.NewScopeFromString(req.Scope)
+		if scopeErr != nil || !refreshToken.Scope.Includes(*requestedScope) {
+			err = &core.AuthError{
 				ErrorName:        core.AuthErrorInvalidScope,
-				ErrorDescription: errors.WithStack(err).Error(),
+				ErrorDescription: "Requested scope exceeds the one originally granted",
 			}
+			return nil, err
+		}
+		scope = *requestedScope
+	}
+	// the policy of the client might have been narrowed since the grant
+	if !client.Scope.Includes(scope) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidScope,
+			ErrorDescription: "Scope exceeds the one allowed for the client",
 		}
+		return nil, err
 	}
 
-	// credentials correct at this point, issue a new token pair
+	rotated, err := m.storage.RotateRefreshTokenByID(ctx, tx, refreshToken.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !rotated {
+		// a concurrent request exchanged the token since it was read
+		err = m.revokeReusedRefreshToken(ctx, tx, client.ID, refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		return nil, refreshTokenReused()
+	}
+	// revoke previous access tokens of this refresh token
+	err = m.storage.RevokeAccessTokensByRefreshTokenID(ctx, tx, refreshToken.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 
-	// create and save refresh token
-	refreshToken, err := core.NewRefreshToken(user.ID, client.ID, m.key)
+	// issue a new token pair within the same family
+	response, err := m.issueTokenPair(ctx, tx, refreshToken.UserID, client, refreshToken.FamilyID, scope, core.GrantTypeRefreshToken, "", core.NewSessionDevice(req.UserAgent, req.ClientIP))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	err = m.storage.AddRefreshToken(ctx, tx, *refreshToken)
+	err = tx.Commit()
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	// create and save access token
-	accessStoken, err := core.NewAccessToken(user.ID, client.ID, *scope, m.tokenExpiration, m.key)
+	return response, nil
+}
+
+// revokeReusedRefreshToken commits the revocation of everything obtained from the same grant
+// as the refresh token exchanged before, either the client or an attacker holds a leaked copy.
+func (m *IdentityManager) revokeReusedRefreshToken(ctx context.Context, tx *sql.Tx, clientID string, refreshToken *core.RefreshToken) error {
+	slog.WarnContext(ctx, "Refresh token reuse detected, revoking token family",
+		"familyID", refreshToken.FamilyID,
+		"clientID", clientID,
+	)
+	err := m.revokeRefreshTokenFamily(ctx, tx, refreshToken.FamilyID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func refreshTokenReused() *core.AuthError {
+	return &core.AuthError{
+		ErrorName:        core.AuthErrorInvalidGrant,
+		ErrorDescription: "Refresh token reuse detected",
+	}
+}
+
+// ClientCredentialsFlow lets a client obtain an access token on behalf
+// of the service account it is bound to. No refresh token is issued
+// since the client can always repeat the grant.
+func (m *IdentityManager) ClientCredentialsFlow(ctx context.Context, req core.ClientCredentialsFlowRequest) (*core.AccessTokenResponse, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	err = checkGrantType(client, core.GrantTypeClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+	if client.ServiceAccountUserID == "" {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorUnauthorizedClient,
+			ErrorDescription: "Client is not bound to a service account",
+		}
+		return nil, err
+	}
+
+	scope := client.ServiceAccountScope
+	if req.Scope != "" {
+		requestedScope, scopeErr := core.NewScopeFromString(req.Scope)
+		if scopeErr != nil || !client.ServiceAccountScope.Includes(*requestedScope) {
+			err = &core.AuthError{
+				ErrorName:        core.AuthErrorInvalidScope,
+				ErrorDescription: "Requested scope exceeds the one of the service account",
+			}
+			return nil, err
+		}
+		scope = *requestedScope
+	}
+	if !client.Scope.Includes(scope) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidScope,
+			ErrorDescription: "Scope exceeds the one allowed for the client",
+		}
+		return nil, err
+	}
+
+	accessToken, err := core.NewAccessToken(m.issuer, client.ServiceAccountUserID, client.ID, scope, m.accessTokenLifetime(client), m.keys)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	err = m.storage.AddAccessToken(ctx, tx, refreshToken.ID, *accessStoken)
+	accessToken.GrantType = core.GrantTypeClientCredentials
+	err = m.storage.AddAccessToken(ctx, tx, "", *accessToken)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -134,59 +637,130 @@ func (m *IdentityManager) PasswordFlow(ctx context.Context, req core.PasswordFlo
 	}
 
 	return &core.AccessTokenResponse{
-		AccessToken:  *accessStoken,
-		RefreshToken: refreshToken.Token,
+		AccessToken: *accessToken,
 	}, nil
 }
 
-func (m *IdentityManager) RefreshTokenFlow(ctx context.Context, req core.RefreshTokenFlowRequest) (*core.AccessTokenResponse, error) {
+// BindServiceAccount lets the client act as the user in the client_credentials grant
+// within the given scope. Empty username removes the binding.
+func (m *IdentityManager) BindServiceAccount(ctx context.Context, clientID, username, scope string) error {
 	tx, err := m.storage.Begin(ctx)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return errors.WithStack(err)
 	}
 	defer func() {
 		rollbackOnError(ctx, err, tx.Rollback)
 	}()
 
-	// check client credentials
-	client, err := m.storage.GetClientByID(ctx, tx, req.ClientID)
+	_, err = m.storage.GetClientByID(ctx, tx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrapf(core.ErrNotFound, "client %s", clientID)
+	}
 	if err != nil {
-		// todo: check error type
-		return nil, &core.AuthError{
-			ErrorName:        core.AuthErrorInvalidClient,
-			ErrorDescription: errors.WithStack(err).Error(),
-		}
+		return errors.WithStack(err)
 	}
-	if client.Secret != req.ClientSecret {
-		return nil, &core.AuthError{
-			ErrorName:        core.AuthErrorInvalidClient,
-			ErrorDescription: errors.WithStack(err).Error(),
+
+	userID := ""
+	serviceAccountScope := core.Scope("")
+	if username != "" {
+		var user *core.UserInfo
+		user, err = m.storage.GetUserInfoByUsername(ctx, tx, username)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrapf(core.ErrNotFound, "user %s", username)
+		}
+		if err != nil {
+			return errors.WithStack(err)
 		}
+		userID = user.ID
+
+		parsedScope, scopeErr := core.NewScopeFromString(scope)
+		if scopeErr != nil {
+			err = errors.Wrap(core.ErrInvalidInput, scopeErr.Error())
+			return err
+		}
+		serviceAccountScope = *parsedScope
 	}
-	// revoke previous access tokens of this refresh token
-	// issue a new token
-	// save the token
-	// return
-	panic("todo")
+
+	err = m.storage.SetClientServiceAccount(ctx, tx, clientID, userID, serviceAccountScope)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
 }
 
-func (m *IdentityManager) Authenticate(ctx context.Context, accessToken string) (*core.AccessToken, error) {
-	// todo: check signature first
+// JWKS returns the public keys the issued tokens can be verified with.
+func (m *IdentityManager) JWKS() (*core.JWKSet, error) {
+	return m.keys.JWKS()
+}
+
+// GetUser returns the application user the identity belongs to.
+func (m *IdentityManager) GetUser(ctx context.Context, userID string) (*core.User, error) {
 	tx, err := m.storage.Begin(ctx)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	//nolint:errcheck //ok here since we dont do any writes
 	defer tx.Rollback()
-	token, err := m.storage.GetAccessTokenByJWT(ctx, tx, core.JWT(accessToken))
+	user, err := m.storage.GetUserByID(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return user, nil
+}
+
+func (m *IdentityManager) revokeRefreshTokenFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	err := m.storage.RevokeAccessTokensByRefreshTokenFamilyID(ctx, tx, familyID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.storage.RevokeRefreshTokensByFamilyID(ctx, tx, familyID)
 	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Authenticate verifies the access token presented to the api. The signature
+// and the claims are checked first so that only well-formed tokens reach the storage,
+// which is then consulted to see if the token was revoked.
+func (m *IdentityManager) Authenticate(ctx context.Context, accessToken string) (*core.AccessToken, error) {
+	tokenID, err := core.VerifyAccessToken(core.JWT(accessToken), m.keys, m.issuer, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+	token, err := m.storage.GetAccessTokenByID(ctx, tx, tokenID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !core.VerifyTokenHash(token.TokenHash, accessToken)) {
 		return nil, &core.AuthError{
-			ErrorName: core.AuthErrorUnauthorized,
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "The access token is unknown",
 		}
 	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	token.Token = core.JWT(accessToken)
 	if token.Revoked {
 		return nil, &core.AuthError{
-			ErrorName: core.AuthErrorUnauthorized,
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "The access token was revoked",
+		}
+	}
+	if !token.Active(time.Now()) {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "The access token expired",
 		}
 	}
 	return token, nil
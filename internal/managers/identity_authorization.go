@@ -0,0 +1,213 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ValidateAuthorizationRequest checks the request received by the authorization endpoint
+// and fills in the defaults for the redirect uri and scope.
+//
+// Errors about the client or its redirect uri must be shown to the user directly,
+// the rest are returned as [core.RedirectableAuthError].
+func (m *IdentityManager) ValidateAuthorizationRequest(ctx context.Context, req core.AuthorizationRequest) (*core.AuthorizationRequest, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	client, err := m.storage.GetClientByID(ctx, tx, req.ClientID)
+	if err != nil {
+		// todo: check error type
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvaidRequest,
+			ErrorDescription: "Unknown client",
+		}
+	}
+
+	if req.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		req.RedirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvaidRequest,
+			ErrorDescription: "Redirect URI is not registered for the client",
+		}
+	}
+
+	// from now on the errors can be sent to the client
+	redirectableError := func(name core.AuthErrorName, description string) error {
+		return &core.RedirectableAuthError{
+			AuthError: core.AuthError{
+				ErrorName:        name,
+				ErrorDescription: description,
+			},
+			RedirectURI: req.RedirectURI,
+			State:       req.State,
+		}
+	}
+
+	if req.ResponseType != core.ResponseTypeCode {
+		return nil, redirectableError(core.AuthErrorUnsupportedResponseType, "Only the code response type is supported")
+	}
+	if req.CodeChallenge == "" {
+		return nil, redirectableError(core.AuthErrorInvaidRequest, "PKCE code challenge is required")
+	}
+	if !core.ValidCodeChallenge(req.CodeChallenge) {
+		return nil, redirectableError(core.AuthErrorInvaidRequest, "Malformed PKCE code challenge")
+	}
+	if req.CodeChallengeMethod != core.CodeChallengeMethodS256 {
+		return nil, redirectableError(core.AuthErrorInvaidRequest, "Only the S256 code challenge method is supported")
+	}
+
+	if !client.AllowsGrantType(core.GrantTypeAuthorizationCode) {
+		return nil, redirectableError(core.AuthErrorUnauthorizedClient, "Client is not allowed to use the authorization_code grant")
+	}
+	scope, scopeErr := client.GrantableScope(req.Scope)
+	if scopeErr != nil {
+		return nil, redirectableError(core.AuthErrorInvalidScope, "Requested scope is unknown or exceeds the one allowed for the client")
+	}
+	req.Scope = string(*scope)
+
+	return &req, nil
+}
+
+// Authorize issues an authorization code after the user has given
+// their consent to the request.
+func (m *IdentityManager) Authorize(ctx context.Context, userID string, req core.AuthorizationRequest) (*core.AuthorizationCode, error) {
+	validated, err := m.ValidateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	code, err := core.NewAuthorizationCode(userID, *validated, core.Scope(validated.Scope), m.authorizationCodeExpiration)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = m.storage.AddAuthorizationCode(ctx, tx, *code)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return code, nil
+}
+
+func (m *IdentityManager) AuthorizationCodeFlow(ctx context.Context, req core.AuthorizationCodeFlowRequest) (*core.AccessTokenResponse, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	err = checkGrantType(client, core.GrantTypeAuthorizationCode)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := m.storage.GetAuthorizationCode(ctx, tx, core.HashToken(req.Code))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+	if err != nil {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Invalid authorization code",
+		}
+	}
+	if code.ClientID != client.ID {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Authorization code was issued to another client",
+		}
+		return nil, err
+	}
+
+	if code.Used {
+		// the code might have been intercepted, so the tokens
+		// issued for it can not be trusted anymore
+		slog.WarnContext(ctx, "Authorization code reuse detected, revoking issued tokens",
+			"familyID", code.TokenFamilyID,
+			"clientID", client.ID,
+		)
+		if code.TokenFamilyID != "" {
+			err = m.revokeRefreshTokenFamily(ctx, tx, code.TokenFamilyID)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		err = tx.Commit()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Authorization code was already used",
+		}
+	}
+	if code.Expired() {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Authorization code expired",
+		}
+		return nil, err
+	}
+	if code.RedirectURI != req.RedirectURI {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Redirect URI does not match the authorization request",
+		}
+		return nil, err
+	}
+	if !core.VerifyCodeVerifier(code.CodeChallenge, code.CodeChallengeMethod, req.CodeVerifier) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "PKCE verification failed",
+		}
+		return nil, err
+	}
+
+	familyID := uuid.New().String()
+	err = m.storage.MarkAuthorizationCodeUsed(ctx, tx, code.CodeHash, familyID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	response, err := m.issueTokenPair(ctx, tx, code.UserID, client, familyID, code.Scope, core.GrantTypeAuthorizationCode, code.Nonce, core.NewSessionDevice(req.UserAgent, req.ClientIP))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return response, nil
+}
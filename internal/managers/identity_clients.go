@@ -0,0 +1,178 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// CreateClient registers a client owned by the user.
+// The returned client is the only place its secret can be read from.
+func (m *IdentityManager) CreateClient(ctx context.Context, ownerUserID, name string, policy core.ClientPolicy) (*core.Client, error) {
+	client, err := core.NewClient(ownerUserID, name, policy)
+	if err != nil {
+		return nil, errors.Wrap(core.ErrInvalidInput, err.Error())
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.storage.AddClient(ctx, tx, *client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return client, nil
+}
+
+// GetClients lists the clients owned by the user.
+func (m *IdentityManager) GetClients(ctx context.Context, ownerUserID string) ([]core.Client, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	clients, err := m.storage.GetClientsByOwnerUserID(ctx, tx, ownerUserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return clients, nil
+}
+
+// GetAllClients lists the clients of every user, including the ones created by the server.
+func (m *IdentityManager) GetAllClients(ctx context.Context) ([]core.Client, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	clients, err := m.storage.GetClients(ctx, tx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return clients, nil
+}
+
+// RotateClientSecret replaces the secret of the client owned by the user.
+// The tokens issued so far stay valid.
+func (m *IdentityManager) RotateClientSecret(ctx context.Context, ownerUserID, clientID string) (*core.Client, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	client, err := m.getOwnedClient(ctx, tx, ownerUserID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	err = client.RotateSecret()
+	if err != nil {
+		err = errors.Wrap(core.ErrInvalidInput, err.Error())
+		return nil, err
+	}
+	err = m.storage.SetClientSecretHash(ctx, tx, client.ID, client.SecretHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return client, nil
+}
+
+// DeleteClient removes the client owned by the user together with the tokens issued to it.
+func (m *IdentityManager) DeleteClient(ctx context.Context, ownerUserID, clientID string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	_, err = m.getOwnedClient(ctx, tx, ownerUserID, clientID)
+	if err != nil {
+		return err
+	}
+	err = m.storage.DeleteClientByID(ctx, tx, clientID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DeleteAnyClient lets administrators remove the client of any user.
+// The clients created by the server can not be removed, since it depends on them,
+// unlike the ones that registered dynamically.
+func (m *IdentityManager) DeleteAnyClient(ctx context.Context, clientID string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	client, err := m.storage.GetClientByID(ctx, tx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.Wrapf(core.ErrNotFound, "client %s", clientID)
+		return err
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if client.OwnerUserID == "" && !client.DynamicallyRegistered() {
+		err = errors.Wrapf(core.ErrInvalidInput, "client %s is managed by the server", clientID)
+		return err
+	}
+	err = m.storage.DeleteClientByID(ctx, tx, clientID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// getOwnedClient hides the clients of other users as if they did not exist.
+func (m *IdentityManager) getOwnedClient(ctx context.Context, tx *sql.Tx, ownerUserID, clientID string) (*core.Client, error) {
+	client, err := m.storage.GetClientByID(ctx, tx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrapf(core.ErrNotFound, "client %s", clientID)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if client.OwnerUserID == "" || client.OwnerUserID != ownerUserID {
+		return nil, errors.Wrapf(core.ErrNotFound, "client %s", clientID)
+	}
+	return client, nil
+}
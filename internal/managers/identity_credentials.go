@@ -0,0 +1,93 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// CredentialVerifier checks the passwords of the users kept outside of wallabago, e.g. in a directory.
+type CredentialVerifier interface {
+	// VerifyPassword returns the claims of the user, core.ErrInvalidCredentials
+	// when the user is unknown or the password does not match.
+	VerifyPassword(ctx context.Context, username, password string) (*core.UpstreamClaims, error)
+	// KnowsUsername reports whether the user exists, so that nobody else can sign up with the username.
+	KnowsUsername(ctx context.Context, username string) (bool, error)
+	// AdminMapping decides which of the users are administrators.
+	AdminMapping() core.AdminClaimMapping
+}
+
+// verifyPassword checks the password of the local user. The users without a local password
+// are checked by the credential verifier and linked or provisioned on their first login.
+func (m *IdentityManager) verifyPassword(ctx context.Context, tx *sql.Tx, username, password string) (*core.UserInfo, error) {
+	user, err := m.storage.GetUserInfoByUsername(ctx, tx, username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+	if err == nil && len(user.PasswordHash) > 0 {
+		match, rehash := m.passwords.Verify(user.PasswordHash, password)
+		if !match {
+			return nil, errors.WithStack(core.ErrInvalidCredentials)
+		}
+		if user.EmailUnverified {
+			return nil, &core.AuthError{
+				ErrorName:        core.AuthErrorEmailUnverified,
+				ErrorDescription: "Open the link emailed to you to verify the email of the account first",
+			}
+		}
+		if rehash {
+			err = m.rehashPassword(ctx, tx, user, password)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+	// the unknown users and the ones without a local password are answered as slowly
+	// as the others, otherwise the time of the answer would tell which usernames exist
+	m.passwords.Verify(m.dummyPasswordHash(), password)
+	if m.credentials == nil {
+		return nil, errors.WithStack(core.ErrInvalidCredentials)
+	}
+
+	claims, err := m.credentials.VerifyPassword(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	user, err = m.federatedUser(ctx, tx, *claims, "")
+	if err != nil {
+		authError := &core.AuthError{}
+		if errors.As(err, &authError) {
+			// the password was right, but the account can not be used here
+			return nil, &core.AuthError{
+				ErrorName:        core.AuthErrorInvalidGrant,
+				ErrorDescription: authError.ErrorDescription,
+			}
+		}
+		return nil, err
+	}
+	err = m.applyAdminMapping(ctx, tx, user.ID, m.credentials.AdminMapping(), *claims)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// rehashPassword replaces the bcrypt hash or the argon2id hash with outdated parameters
+// once the user logged in with the password.
+func (m *IdentityManager) rehashPassword(ctx context.Context, tx *sql.Tx, user *core.UserInfo, password string) error {
+	passwordHash, err := m.passwords.Rehash(password)
+	if err != nil {
+		return err
+	}
+	err = m.storage.SetUserPasswordHash(ctx, tx, user.ID, passwordHash)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	user.PasswordHash = passwordHash
+	slog.InfoContext(ctx, "Rehashed the password of the user", "userID", user.ID)
+	return nil
+}
@@ -0,0 +1,219 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// DeviceAuthorization starts the device authorization grant for clients
+// running on devices without a browser or with limited input capabilities.
+func (m *IdentityManager) DeviceAuthorization(ctx context.Context, req core.DeviceAuthorizationRequest) (*core.DeviceCode, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	err = checkGrantType(client, core.GrantTypeDeviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := client.GrantableScope(req.Scope)
+	if err != nil {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidScope,
+			ErrorDescription: err.Error(),
+		}
+	}
+
+	code, err := core.NewDeviceCode(client.ID, *scope, m.deviceCodeExpiration, m.deviceCodeInterval)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = m.storage.AddDeviceCode(ctx, tx, *code)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return code, nil
+}
+
+// DeviceCodeFlow is polled by the device until the user has made a decision
+// about the request on another device.
+func (m *IdentityManager) DeviceCodeFlow(ctx context.Context, req core.DeviceCodeFlowRequest) (*core.AccessTokenResponse, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	err = checkGrantType(client, core.GrantTypeDeviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := m.storage.GetDeviceCodeByDeviceCodeHash(ctx, tx, core.HashToken(req.DeviceCode))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+	if err != nil {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Invalid device code",
+		}
+	}
+	if code.ClientID != client.ID {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Device code was issued to another client",
+		}
+		return nil, err
+	}
+	if code.Expired() {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorExpiredToken,
+			ErrorDescription: "Device code expired",
+		}
+		return nil, err
+	}
+
+	switch code.Status {
+	case core.DeviceCodeStatusPending:
+		// the polling state has to be saved even though an error is returned
+		now := time.Now()
+		interval := code.Interval
+		authError := &core.AuthError{
+			ErrorName:        core.AuthErrorAuthorizationPending,
+			ErrorDescription: "User has not yet made a decision",
+		}
+		if code.PolledTooEarly(now) {
+			interval += core.DeviceCodeSlowDownStep
+			authError = &core.AuthError{
+				ErrorName:        core.AuthErrorSlowDown,
+				ErrorDescription: "Polling too frequently, increase the interval",
+			}
+		}
+		err = m.storage.UpdateDeviceCodePolling(ctx, tx, code.DeviceCodeHash, now, interval)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		err = tx.Commit()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, authError
+	case core.DeviceCodeStatusDenied:
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorAccessDenied,
+			ErrorDescription: "User denied the request",
+		}
+		return nil, err
+	case core.DeviceCodeStatusApproved:
+		// continue below
+	default:
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidGrant,
+			ErrorDescription: "Device code was already used",
+		}
+		return nil, err
+	}
+
+	err = m.storage.SetDeviceCodeStatus(ctx, tx, code.DeviceCodeHash, core.DeviceCodeStatusConsumed, code.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	response, err := m.issueTokenPair(ctx, tx, code.UserID, client, "", code.Scope, core.GrantTypeDeviceCode, "", core.NewSessionDevice(req.UserAgent, req.ClientIP))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return response, nil
+}
+
+// GetPendingDeviceCode looks up the request the user wants to decide on
+// by the code displayed on the device.
+func (m *IdentityManager) GetPendingDeviceCode(ctx context.Context, userCode string) (*core.DeviceCode, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	return m.getPendingDeviceCode(ctx, tx, userCode)
+}
+
+func (m *IdentityManager) getPendingDeviceCode(ctx context.Context, tx *sql.Tx, userCode string) (*core.DeviceCode, error) {
+	code, err := m.storage.GetDeviceCodeByUserCode(ctx, tx, core.NormalizeUserCode(userCode))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrapf(core.ErrNotFound, "device code %s", userCode)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if code.Status != core.DeviceCodeStatusPending || code.Expired() {
+		return nil, errors.Wrapf(core.ErrNotFound, "device code %s", userCode)
+	}
+	return code, nil
+}
+
+// DecideDeviceCode records the decision of the user about the device request,
+// the device receives the tokens on its next poll.
+func (m *IdentityManager) DecideDeviceCode(ctx context.Context, userID, userCode string, approve bool) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	code, err := m.getPendingDeviceCode(ctx, tx, userCode)
+	if err != nil {
+		return err
+	}
+
+	status := core.DeviceCodeStatusDenied
+	if approve {
+		status = core.DeviceCodeStatusApproved
+	}
+	err = m.storage.SetDeviceCodeStatus(ctx, tx, code.DeviceCodeHash, status, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
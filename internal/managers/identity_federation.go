@@ -0,0 +1,255 @@
+package managers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// UpstreamProvider is the OpenID Connect provider the users can log in with
+// instead of a local password.
+type UpstreamProvider interface {
+	// Name is shown to the users on the login page.
+	Name() string
+	// AuthorizationURL sends the user to the provider to log in.
+	AuthorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems the authorization code and returns the verified claims of the id token.
+	Exchange(ctx context.Context, code, codeVerifier string) (*core.UpstreamClaims, error)
+	// AdminMapping decides which of the users are administrators.
+	AdminMapping() core.AdminClaimMapping
+}
+
+// upstreamLoginLifetime limits how long the user may take to log in at the provider.
+const upstreamLoginLifetime = 10 * time.Minute
+
+// maxUsernameAttempts limits the search for a free username of the provisioned users.
+const maxUsernameAttempts = 100
+
+// UpstreamLoginName returns the name of the provider, empty when the upstream login is disabled.
+func (m *IdentityManager) UpstreamLoginName() string {
+	if m.upstream == nil {
+		return ""
+	}
+	return m.upstream.Name()
+}
+
+// StartUpstreamLogin returns the location of the provider the user has to be sent to
+// and the sealed state of the login the browser has to keep until it comes back.
+// The account at the provider is linked to the user with the linkUserID once the login succeeds.
+func (m *IdentityManager) StartUpstreamLogin(ctx context.Context, next, linkUserID string) (string, *core.JWT, error) {
+	if m.upstream == nil {
+		return "", nil, errors.Wrap(core.ErrNotFound, "upstream login")
+	}
+	login, err := core.NewUpstreamLogin(next, linkUserID)
+	if err != nil {
+		return "", nil, err
+	}
+	location, err := m.upstream.AuthorizationURL(ctx, login.State, login.Nonce, core.NewCodeChallenge(login.CodeVerifier))
+	if err != nil {
+		return "", nil, err
+	}
+	sealed, err := login.Seal(m.issuer, upstreamLoginLifetime, m.keys)
+	if err != nil {
+		return "", nil, err
+	}
+	return location, sealed, nil
+}
+
+func upstreamLoginDenied(description string) *core.AuthError {
+	return &core.AuthError{
+		ErrorName:        core.AuthErrorAccessDenied,
+		ErrorDescription: description,
+	}
+}
+
+// FinishUpstreamLogin handles the user sent back by the provider. The user is found
+// by the linked account, linked by the verified email or provisioned on the first login,
+// and then gets a session of the web ui client.
+func (m *IdentityManager) FinishUpstreamLogin(ctx context.Context, req core.UpstreamCallbackRequest) (*core.AccessTokenResponse, *core.UpstreamLogin, error) {
+	if m.upstream == nil {
+		return nil, nil, errors.Wrap(core.ErrNotFound, "upstream login")
+	}
+	login, err := core.OpenUpstreamLogin(req.SealedLogin, m.issuer, m.keys)
+	if err != nil {
+		return nil, nil, upstreamLoginDenied("The login expired, please try again")
+	}
+	if subtle.ConstantTimeCompare([]byte(login.State), []byte(req.State)) != 1 {
+		return nil, nil, upstreamLoginDenied("The login does not match the one that was started")
+	}
+	if req.Error != "" {
+		return nil, login, upstreamLoginDenied(fmt.Sprintf("The identity provider rejected the login: %s", req.Error))
+	}
+	claims, err := m.upstream.Exchange(ctx, req.Code, login.CodeVerifier)
+	if err != nil {
+		slog.WarnContext(ctx, "Upstream login failed", "cause", fmt.Sprintf("%+v", err))
+		return nil, login, upstreamLoginDenied("The identity provider could not confirm the login")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(login.Nonce)) != 1 {
+		return nil, login, upstreamLoginDenied("The identity provider could not confirm the login")
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := m.federatedUser(ctx, tx, *claims, login.LinkUserID)
+	if err != nil {
+		return nil, login, err
+	}
+	err = m.applyAdminMapping(ctx, tx, user.ID, m.upstream.AdminMapping(), *claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope, err := client.GrantableScope("")
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	response, err := m.issueTokenPair(ctx, tx, user.ID, client, "", *scope, core.GrantTypeUpstream, "", core.NewSessionDevice(req.UserAgent, req.ClientIP))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return response, login, nil
+}
+
+// applyAdminMapping grants or revokes the administrator role according to the claims
+// when the role is managed outside of wallabago.
+func (m *IdentityManager) applyAdminMapping(ctx context.Context, tx *sql.Tx, userID string, mapping core.AdminClaimMapping, claims core.UpstreamClaims) error {
+	if !mapping.Enabled() {
+		return nil
+	}
+	err := m.storage.SetUserAdmin(ctx, tx, userID, mapping.IsAdmin(claims.Raw))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// federatedUser finds the local user of the account at the provider, linking
+// or provisioning one when the account is seen for the first time.
+func (m *IdentityManager) federatedUser(ctx context.Context, tx *sql.Tx, claims core.UpstreamClaims, linkUserID string) (*core.UserInfo, error) {
+	identity, err := m.storage.GetFederatedIdentity(ctx, tx, claims.Issuer, claims.Subject)
+	if err == nil {
+		if linkUserID != "" && linkUserID != identity.UserID {
+			return nil, upstreamLoginDenied("The account at the identity provider is linked to another user")
+		}
+		user, err := m.storage.GetUserInfoByID(ctx, tx, identity.UserID)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+
+	var user *core.UserInfo
+	switch {
+	case linkUserID != "":
+		user, err = m.storage.GetUserInfoByID(ctx, tx, linkUserID)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case claims.Email != "":
+		user, err = m.storage.GetUserInfoByEmail(ctx, tx, claims.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.WithStack(err)
+		}
+		// anyone could claim an unverified email, so such accounts have to be linked by their users
+		if err == nil && !claims.EmailVerified {
+			return nil, upstreamLoginDenied("An account with the email already exists, log in and link it first")
+		}
+		// whoever signed up did not prove to own the email either, the owner can
+		// take the account over by resetting its password and link it afterwards
+		if err == nil && user.EmailUnverified {
+			return nil, upstreamLoginDenied("An unverified account with the email already exists, reset its password and link it first")
+		}
+	}
+	if user == nil {
+		user, err = m.provisionUser(ctx, tx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = m.storage.AddFederatedIdentity(ctx, tx, core.FederatedIdentity{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	slog.InfoContext(ctx, "Linked upstream account", "issuer", claims.Issuer, "userID", user.ID)
+	return user, nil
+}
+
+// provisionUser creates the user for the account at the provider on its first login.
+func (m *IdentityManager) provisionUser(ctx context.Context, tx *sql.Tx, claims core.UpstreamClaims) (*core.UserInfo, error) {
+	if claims.Email == "" {
+		return nil, upstreamLoginDenied("The identity provider did not share the email of the account")
+	}
+	username, err := m.freeUsername(ctx, tx, claims.Username())
+	if err != nil {
+		return nil, err
+	}
+	user := core.UserInfo{
+		ID:       uuid.New().String(),
+		Username: username,
+		Email:    claims.Email,
+		// the empty hash never matches, the user can only log in at the provider
+		PasswordHash: []byte{},
+	}
+	err = m.storage.AddUserInfo(ctx, tx, user)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = m.storage.AddUser(ctx, tx, core.User{
+		ID:       user.ID,
+		Username: user.Username,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	slog.InfoContext(ctx, "Provisioned upstream user", "userID", user.ID, "username", user.Username)
+	return &user, nil
+}
+
+// freeUsername numbers the suggested username until it is not taken.
+func (m *IdentityManager) freeUsername(ctx context.Context, tx *sql.Tx, suggested string) (string, error) {
+	for i := range maxUsernameAttempts {
+		candidate := suggested
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%d", suggested, i+1)
+		}
+		_, err := m.storage.GetUserInfoByUsername(ctx, tx, candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+	}
+	return "", errors.Errorf("no free username for %s", suggested)
+}
@@ -0,0 +1,133 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// Introspect tells an authenticated client whether the token is currently active
+// and what it grants, so that resource servers do not need access to our storage.
+func (m *IdentityManager) Introspect(ctx context.Context, req core.IntrospectionRequest) (*core.IntrospectionResponse, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	// only the resource servers holding a secret may learn about the tokens of others
+	if client.Public {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorUnauthorizedClient,
+			ErrorDescription: "Public clients can not introspect tokens",
+		}
+	}
+
+	// the personal access tokens are not signed, so they are told apart
+	// by their prefix like the api does, whatever the hint says
+	if core.IsPersonalAccessToken(req.Token) {
+		return m.introspectPersonalAccessToken(ctx, tx, req.Token)
+	}
+
+	// the hint only decides the lookup order, the search
+	// is extended to the other type when nothing is found
+	lookups := []func(context.Context, *sql.Tx, core.JWT) (*core.IntrospectionResponse, bool, error){
+		m.introspectAccessToken,
+		m.introspectRefreshToken,
+	}
+	if req.TokenTypeHint == core.TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		response, found, lookupErr := lookup(ctx, tx, core.JWT(req.Token))
+		if lookupErr != nil {
+			return nil, errors.WithStack(lookupErr)
+		}
+		if found {
+			return response, nil
+		}
+	}
+	return &core.IntrospectionResponse{Active: false}, nil
+}
+
+func (m *IdentityManager) introspectAccessToken(ctx context.Context, tx *sql.Tx, token core.JWT) (*core.IntrospectionResponse, bool, error) {
+	accessToken, err := m.getAccessToken(ctx, tx, token)
+	if errors.Is(err, core.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	if !accessToken.Active(time.Now()) {
+		return &core.IntrospectionResponse{Active: false}, true, nil
+	}
+	return &core.IntrospectionResponse{
+		Active:    true,
+		Scope:     accessToken.Scope,
+		ClientID:  accessToken.ClientID,
+		Subject:   accessToken.UserID,
+		ExpiresAt: accessToken.ExpiresAt().Unix(),
+		IssuedAt:  accessToken.IssuedAt.Unix(),
+		JWTID:     accessToken.ID,
+	}, true, nil
+}
+
+func (m *IdentityManager) introspectRefreshToken(ctx context.Context, tx *sql.Tx, token core.JWT) (*core.IntrospectionResponse, bool, error) {
+	refreshToken, err := m.getRefreshToken(ctx, tx, token)
+	if errors.Is(err, core.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	if refreshToken.Revoked || refreshToken.Rotated || refreshToken.Expired(time.Now()) {
+		return &core.IntrospectionResponse{Active: false}, true, nil
+	}
+	response := &core.IntrospectionResponse{
+		Active:   true,
+		Scope:    refreshToken.Scope,
+		ClientID: refreshToken.ClientID,
+		Subject:  refreshToken.UserID,
+		IssuedAt: refreshToken.IssuedAt.Unix(),
+		JWTID:    refreshToken.ID,
+	}
+	// refresh tokens without a lifetime do not expire on their own
+	if !refreshToken.ExpiresAt.IsZero() {
+		response.ExpiresAt = refreshToken.ExpiresAt.Unix()
+	}
+	return response, true, nil
+}
+
+func (m *IdentityManager) introspectPersonalAccessToken(ctx context.Context, tx *sql.Tx, token string) (*core.IntrospectionResponse, error) {
+	personalToken, err := m.storage.GetPersonalAccessTokenByHash(ctx, tx, core.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return &core.IntrospectionResponse{Active: false}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if personalToken.Expired(time.Now()) {
+		return &core.IntrospectionResponse{Active: false}, nil
+	}
+	// the token belongs to the user rather than to a client
+	response := &core.IntrospectionResponse{
+		Active:   true,
+		Scope:    personalToken.Scope,
+		Subject:  personalToken.UserID,
+		IssuedAt: personalToken.CreatedAt.Unix(),
+		JWTID:    personalToken.ID,
+	}
+	if !personalToken.ExpiresAt.IsZero() {
+		response.ExpiresAt = personalToken.ExpiresAt.Unix()
+	}
+	return response, nil
+}
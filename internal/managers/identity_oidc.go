@@ -0,0 +1,42 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// UserInfo returns the claims about the user the access token was issued for,
+// limited to the ones its scope releases.
+func (m *IdentityManager) UserInfo(ctx context.Context, token core.AccessToken) (*core.UserInfoClaims, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	user, err := m.storage.GetUserInfoByID(ctx, tx, token.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "The user of the access token no longer exists",
+		}
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	claims := core.NewUserInfoClaims(*user, token.Scope)
+	return &claims, nil
+}
+
+// AuthorizationServerMetadata describes this server at the given endpoints,
+// the registration endpoint is only advertised when the registration is enabled.
+func (m *IdentityManager) AuthorizationServerMetadata(endpoints core.ServerEndpoints) *core.AuthorizationServerMetadata {
+	if !m.registration.Enabled() {
+		endpoints.Registration = ""
+	}
+	return core.NewAuthorizationServerMetadata(m.issuer, endpoints, m.keys.Algorithm())
+}
@@ -0,0 +1,431 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pkg/errors"
+)
+
+const (
+	// passkeyCeremonyLifetime limits how long the user may take to answer the authenticator.
+	passkeyCeremonyLifetime = 5 * time.Minute
+	// passkeyRelyingPartyName is shown to the users by the authenticators.
+	passkeyRelyingPartyName = "Wallabago"
+)
+
+// webAuthnUser presents the user and the passkeys to the WebAuthn library,
+// the user id is the user handle stored in the passkeys.
+type webAuthnUser struct {
+	user     core.UserInfo
+	passkeys []core.Passkey
+}
+
+func (u webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.user.ID)
+}
+
+func (u webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.passkeys))
+	for i, passkey := range u.passkeys {
+		transports := make([]protocol.AuthenticatorTransport, len(passkey.Transports))
+		for j, transport := range passkey.Transports {
+			transports[j] = protocol.AuthenticatorTransport(transport)
+		}
+		credentials[i] = webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		}
+	}
+	return credentials
+}
+
+// relyingParty binds the passkeys to the host of the issuer, which also serves the web ui.
+func (m *IdentityManager) relyingParty() (*webauthn.WebAuthn, error) {
+	issuer, err := url.Parse(m.issuer)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	relyingParty, err := webauthn.New(&webauthn.Config{
+		RPID:          issuer.Hostname(),
+		RPDisplayName: passkeyRelyingPartyName,
+		RPOrigins:     []string{issuer.Scheme + "://" + issuer.Host},
+		// the passkeys replace the password, so they have to verify the user themselves
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return relyingParty, nil
+}
+
+func (m *IdentityManager) getWebAuthnUser(ctx context.Context, tx *sql.Tx, userID string) (*webAuthnUser, error) {
+	user, err := m.storage.GetUserInfoByID(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	passkeys, err := m.storage.GetPasskeys(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &webAuthnUser{user: *user, passkeys: passkeys}, nil
+}
+
+// startCeremony remembers the challenge until the authenticator answers it,
+// the ceremonies nobody finished are forgotten along the way.
+func (m *IdentityManager) startCeremony(ctx context.Context, tx *sql.Tx, challenge core.WebAuthnChallenge) error {
+	err := m.storage.DeleteExpiredWebAuthnChallenges(ctx, tx, time.Now())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.storage.AddWebAuthnChallenge(ctx, tx, challenge)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// finishCeremony takes the challenge answered by the authenticator so that the answer
+// can not be used twice, core.ErrNotFound when it is unknown or belongs to another ceremony.
+func (m *IdentityManager) finishCeremony(ctx context.Context, tx *sql.Tx, challenge string, ceremony core.WebAuthnCeremony, userID string) error {
+	pending, err := m.storage.TakeWebAuthnChallenge(ctx, tx, challenge)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrap(core.ErrNotFound, "webauthn challenge")
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if pending.Ceremony != ceremony || pending.UserID != userID || pending.ExpiresAt.Before(time.Now()) {
+		return errors.Wrap(core.ErrNotFound, "webauthn challenge")
+	}
+	return nil
+}
+
+// BeginPasskeyRegistration returns the options of navigator.credentials.create for the new passkey of the user.
+func (m *IdentityManager) BeginPasskeyRegistration(ctx context.Context, userID string) (*protocol.CredentialCreation, error) {
+	relyingParty, err := m.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	user, err := m.getWebAuthnUser(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// the authenticator refuses to create a second passkey for the same account
+	creation, session, err := relyingParty.BeginRegistration(*user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = m.startCeremony(ctx, tx, core.WebAuthnChallenge{
+		Challenge: session.Challenge,
+		Ceremony:  core.WebAuthnCeremonyRegistration,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(passkeyCeremonyLifetime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return creation, nil
+}
+
+// FinishPasskeyRegistration stores the passkey created by the authenticator,
+// credential is the JSON of the PublicKeyCredential returned by navigator.credentials.create.
+func (m *IdentityManager) FinishPasskeyRegistration(ctx context.Context, userID string, credential []byte) (*core.Passkey, error) {
+	relyingParty, err := m.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(credential)
+	if err != nil {
+		return nil, errors.Wrapf(core.ErrInvalidInput, "passkey: %s", err)
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.finishCeremony(ctx, tx, parsed.Response.CollectedClientData.Challenge, core.WebAuthnCeremonyRegistration, userID)
+	if errors.Is(err, core.ErrNotFound) {
+		err = errors.Wrap(core.ErrInvalidInput, "unknown or expired passkey registration")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := m.getWebAuthnUser(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := relyingParty.CreateCredential(*user, webauthn.SessionData{
+		Challenge:        parsed.Response.CollectedClientData.Challenge,
+		RelyingPartyID:   relyingParty.Config.RPID,
+		UserID:           user.WebAuthnID(),
+		UserVerification: protocol.VerificationRequired,
+		CredParams:       webauthn.CredentialParametersDefault(),
+	}, parsed)
+	if err != nil {
+		slog.WarnContext(ctx, "Passkey registration failed", "cause", fmt.Sprintf("%+v", err))
+		err = errors.Wrap(core.ErrInvalidInput, "the passkey could not be verified")
+		return nil, err
+	}
+	_, err = m.storage.GetPasskey(ctx, tx, created.ID)
+	if err == nil {
+		err = errors.Wrap(core.ErrInvalidInput, "the passkey is already registered")
+		return nil, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+
+	transports := make([]string, len(created.Transport))
+	for i, transport := range created.Transport {
+		transports[i] = string(transport)
+	}
+	passkey := core.Passkey{
+		CredentialID:    created.ID,
+		UserID:          userID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		AAGUID:          created.Authenticator.AAGUID,
+		SignCount:       created.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  created.Flags.BackupEligible,
+		BackupState:     created.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+	err = m.storage.AddPasskey(ctx, tx, passkey)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &passkey, nil
+}
+
+// BeginPasskeyLogin returns the options of navigator.credentials.get, any passkey
+// of any user answers them since the passkey tells who the user is.
+func (m *IdentityManager) BeginPasskeyLogin(ctx context.Context) (*protocol.CredentialAssertion, error) {
+	relyingParty, err := m.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	assertion, session, err := relyingParty.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.startCeremony(ctx, tx, core.WebAuthnChallenge{
+		Challenge: session.Challenge,
+		Ceremony:  core.WebAuthnCeremonyLogin,
+		ExpiresAt: time.Now().Add(passkeyCeremonyLifetime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return assertion, nil
+}
+
+func passkeyLoginDenied() *core.AuthError {
+	return &core.AuthError{
+		ErrorName:        core.AuthErrorInvalidGrant,
+		ErrorDescription: "Invalid passkey",
+	}
+}
+
+// PasskeyLogin checks the answer of the authenticator and issues the same token pair
+// to the client as the password grant does.
+func (m *IdentityManager) PasskeyLogin(ctx context.Context, req core.PasskeyLoginRequest) (*core.AccessTokenResponse, error) {
+	relyingParty, err := m.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, passkeyLoginDenied()
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	err = m.finishCeremony(ctx, tx, parsed.Response.CollectedClientData.Challenge, core.WebAuthnCeremonyLogin, "")
+	if errors.Is(err, core.ErrNotFound) {
+		err = passkeyLoginDenied()
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the library hides the errors of the lookup, so they are kept aside
+	var lookupErr error
+	findUser := func(credentialID, userHandle []byte) (webauthn.User, error) {
+		passkey, err := m.storage.GetPasskey(ctx, tx, credentialID)
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		if !bytes.Equal([]byte(passkey.UserID), userHandle) {
+			return nil, errors.New("user handle of another user")
+		}
+		user, err := m.getWebAuthnUser(ctx, tx, passkey.UserID)
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		return *user, nil
+	}
+	found, credential, err := relyingParty.ValidatePasskeyLogin(findUser, webauthn.SessionData{
+		Challenge:        parsed.Response.CollectedClientData.Challenge,
+		RelyingPartyID:   relyingParty.Config.RPID,
+		UserVerification: protocol.VerificationRequired,
+	}, parsed)
+	if lookupErr != nil && !errors.Is(lookupErr, sql.ErrNoRows) {
+		err = lookupErr
+		return nil, err
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Passkey login failed", "cause", fmt.Sprintf("%+v", err))
+		err = passkeyLoginDenied()
+		return nil, err
+	}
+	// a counter going backwards means that the private key was copied
+	if credential.Authenticator.CloneWarning {
+		slog.WarnContext(ctx, "Passkey signature counter went backwards", "user_id", string(found.WebAuthnID()))
+		err = passkeyLoginDenied()
+		return nil, err
+	}
+	err = m.storage.UsePasskey(ctx, tx, credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState, time.Now())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	scope, err := client.GrantableScope("")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	response, err := m.issueTokenPair(ctx, tx, string(found.WebAuthnID()), client, "", *scope, core.GrantTypePasskey, "", core.NewSessionDevice(req.UserAgent, req.ClientIP))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return response, nil
+}
+
+// GetPasskeys returns the passkeys of the user.
+func (m *IdentityManager) GetPasskeys(ctx context.Context, userID string) ([]core.Passkey, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	passkeys, err := m.storage.GetPasskeys(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return passkeys, nil
+}
+
+// DeletePasskey removes the passkey of the user.
+func (m *IdentityManager) DeletePasskey(ctx context.Context, userID string, credentialID []byte) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	deleted, err := m.storage.DeletePasskey(ctx, tx, userID, credentialID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !deleted {
+		err = errors.Wrap(core.ErrNotFound, "passkey")
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+const (
+	passwordResetExpiration = time.Minute * 30
+	// passwordResetInterval keeps the reset form from flooding the inbox of the user
+	passwordResetInterval = time.Minute
+)
+
+// Mailer sends the emails of the identity manager.
+type Mailer interface {
+	Send(ctx context.Context, message core.MailMessage) error
+}
+
+// PasswordResetEnabled reports whether the users can reset their passwords by email.
+func (m *IdentityManager) PasswordResetEnabled() bool {
+	return m.mailer != nil
+}
+
+// RequestPasswordReset emails the link to the reset form to the user with the address. The link points
+// to the resetPath of the issuer, never to the host the request was sent to, so that it can not be redirected.
+// The unknown addresses and the users without a local password are ignored without telling the caller.
+func (m *IdentityManager) RequestPasswordReset(ctx context.Context, email, resetPath string) error {
+	if m.mailer == nil {
+		return errors.Wrap(core.ErrNotFound, "password reset is not enabled")
+	}
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	user, err := m.storage.GetUserInfoByEmail(ctx, tx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		return errors.WithStack(tx.Rollback())
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// the password of the federated and the directory users is managed elsewhere
+	if len(user.PasswordHash) == 0 {
+		return errors.WithStack(tx.Rollback())
+	}
+	recent, err := m.storage.CountPasswordResets(ctx, tx, user.ID, time.Now().Add(-passwordResetInterval))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if recent > 0 {
+		return errors.WithStack(tx.Rollback())
+	}
+
+	reset := core.NewPasswordReset(user.ID, passwordResetExpiration)
+	err = m.storage.AddPasswordReset(ctx, tx, *reset)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sealed, err := reset.Seal(m.issuer, m.keys)
+	if err != nil {
+		return err
+	}
+	link := m.issuer + resetPath + "?" + url.Values{core.PasswordResetLinkParameter: {string(*sealed)}}.Encode()
+	// the reset is only kept when the email went out
+	err = m.mailer.Send(ctx, core.MailMessage{
+		To:      user.Email,
+		Subject: "Reset your Wallabago password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"somebody asked to reset the password of your Wallabago account. "+
+			"If it was you, choose a new password at the link below within %d minutes:\n\n%s\n\n"+
+			"Otherwise you can ignore this email, your password stays the same.\n",
+			user.Username, int(passwordResetExpiration.Minutes()), link),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ResetPassword sets the new password of the user the sealed reset was emailed to. The reset can be used once,
+// all the sessions and the personal access tokens of the user are revoked since whoever knew the old password
+// might still be logged in, and the lockout of the username is lifted.
+// Receiving the link also verifies the email of the user.
+func (m *IdentityManager) ResetPassword(ctx context.Context, token core.JWT, password string) error {
+	passwordHash, err := m.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	reset, err := core.OpenPasswordReset(token, m.issuer, m.keys)
+	if err != nil {
+		return errors.Wrap(core.ErrInvalidInput, "the link is invalid or expired")
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	used, err := m.storage.UsePasswordReset(ctx, tx, reset.ID, time.Now())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !used {
+		err = errors.Wrap(core.ErrInvalidInput, "the link is invalid or expired")
+		return err
+	}
+	err = m.storage.SetUserPasswordHash(ctx, tx, reset.UserID, passwordHash)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// the link reached the inbox, so the users who lost the verification email are not stuck
+	err = m.storage.SetUserEmailVerified(ctx, tx, reset.UserID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// the other links still lead to the old account
+	err = m.storage.DeletePasswordResets(ctx, tx, reset.UserID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.revokeSessions(ctx, tx, reset.UserID)
+	if err != nil {
+		return err
+	}
+	// the personal access tokens could have been created by whoever knew the old password
+	err = m.storage.DeletePersonalAccessTokens(ctx, tx, reset.UserID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// the user who was locked out by the guessing can log in with the new password right away
+	user, err := m.storage.GetUserInfoByID(ctx, tx, reset.UserID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.storage.DeleteLoginAttempts(ctx, tx, core.LoginAttemptsKindUsername, user.Username)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// CreatePersonalAccessToken creates a long-lived token of the user authenticated with the given access token,
+// the token can not be granted more than the access token itself. Empty scope falls back to the default one.
+// The returned token is the only place the token can be read from.
+func (m *IdentityManager) CreatePersonalAccessToken(
+	ctx context.Context,
+	caller core.AccessToken,
+	name string,
+	scope core.Scope,
+	lifetime time.Duration,
+) (*core.PersonalAccessToken, error) {
+	// otherwise a token could keep creating tokens that outlive it
+	if caller.GrantType == core.GrantTypePersonalAccessToken {
+		return nil, errors.Wrap(core.ErrInvalidInput, "personal access tokens can not create other tokens")
+	}
+	if scope == "" {
+		scope = *core.DefaultScope()
+	}
+	requested, err := core.NewScopeFromString(string(scope))
+	if err != nil {
+		return nil, errors.Wrap(core.ErrInvalidInput, err.Error())
+	}
+	if !caller.Scope.Includes(*requested) {
+		return nil, errors.Wrapf(core.ErrInvalidInput, "scope %s exceeds the scope of the access token", *requested)
+	}
+	token, err := core.NewPersonalAccessToken(caller.UserID, name, *requested, lifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.storage.AddPersonalAccessToken(ctx, tx, *token)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return token, nil
+}
+
+// GetPersonalAccessTokens lists the personal access tokens of the user.
+func (m *IdentityManager) GetPersonalAccessTokens(ctx context.Context, userID string) ([]core.PersonalAccessToken, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	tokens, err := m.storage.GetPersonalAccessTokens(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return tokens, nil
+}
+
+// RevokePersonalAccessToken removes the personal access token of the user.
+func (m *IdentityManager) RevokePersonalAccessToken(ctx context.Context, userID, tokenID string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	deleted, err := m.storage.DeletePersonalAccessToken(ctx, tx, userID, tokenID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !deleted {
+		err = errors.Wrapf(core.ErrNotFound, "personal access token %s", tokenID)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// AuthenticatePersonalAccessToken is the counterpart of Authenticate for the personal access tokens,
+// the time of the use is recorded so that the users can spot the tokens nobody needs anymore.
+func (m *IdentityManager) AuthenticatePersonalAccessToken(ctx context.Context, token string) (*core.AccessToken, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	personalToken, err := m.storage.GetPersonalAccessTokenByHash(ctx, tx, core.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "The access token is unknown",
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	now := time.Now()
+	if personalToken.Expired(now) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "The access token expired",
+		}
+		return nil, err
+	}
+	err = m.storage.UsePersonalAccessToken(ctx, tx, personalToken.ID, now)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	personalToken.Token = token
+	return personalToken.AccessToken(), nil
+}
@@ -0,0 +1,153 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// RegisterClient lets a client register itself, e.g. every install of an app
+// gets its own client, see https://datatracker.ietf.org/doc/html/rfc7591#section-3.
+// The returned client is the only place its secret and registration access token can be read from.
+func (m *IdentityManager) RegisterClient(ctx context.Context, initialAccessToken string, metadata core.ClientMetadata) (*core.Client, error) {
+	if !m.registration.Enabled() {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorAccessDenied,
+			ErrorDescription: "Client registration is disabled",
+		}
+	}
+	if !m.registration.Allows(initialAccessToken) {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "Bad initial access token",
+		}
+	}
+	client, err := core.NewRegisteredClient(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.storage.AddClient(ctx, tx, *client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return client, nil
+}
+
+// GetRegisteredClient returns the client authenticated with its registration access token,
+// see https://datatracker.ietf.org/doc/html/rfc7592#section-2.1.
+func (m *IdentityManager) GetRegisteredClient(ctx context.Context, clientID, registrationAccessToken string) (*core.Client, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	return m.getRegisteredClient(ctx, tx, clientID, registrationAccessToken)
+}
+
+// UpdateRegisteredClient replaces the metadata of the client authenticated with
+// its registration access token, see https://datatracker.ietf.org/doc/html/rfc7592#section-2.2.
+func (m *IdentityManager) UpdateRegisteredClient(
+	ctx context.Context, clientID, registrationAccessToken string, req core.ClientUpdateRequest,
+) (*core.Client, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	client, err := m.getRegisteredClient(ctx, tx, clientID, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if req.ClientID != client.ID {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvaidRequest,
+			ErrorDescription: "The client_id does not match the registered client",
+		}
+		return nil, err
+	}
+	if req.ClientSecret != "" && !client.Authenticate(req.ClientSecret) {
+		err = &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidClientMetadata,
+			ErrorDescription: "The client_secret does not match the registered client",
+		}
+		return nil, err
+	}
+	err = client.UpdateMetadata(req.ClientMetadata)
+	if err != nil {
+		return nil, err
+	}
+	err = m.storage.UpdateClient(ctx, tx, *client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return client, nil
+}
+
+// DeleteRegisteredClient removes the client authenticated with its registration access token
+// together with the tokens issued to it, see https://datatracker.ietf.org/doc/html/rfc7592#section-2.3.
+func (m *IdentityManager) DeleteRegisteredClient(ctx context.Context, clientID, registrationAccessToken string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	_, err = m.getRegisteredClient(ctx, tx, clientID, registrationAccessToken)
+	if err != nil {
+		return err
+	}
+	err = m.storage.DeleteClientByID(ctx, tx, clientID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// getRegisteredClient does not tell apart the unknown clients from the bad tokens,
+// so that the registration endpoint can not be used to probe for the client ids.
+func (m *IdentityManager) getRegisteredClient(ctx context.Context, tx *sql.Tx, clientID, registrationAccessToken string) (*core.Client, error) {
+	client, err := m.storage.GetClientByID(ctx, tx, clientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+	if err != nil || !client.AuthenticateRegistration(registrationAccessToken) {
+		return nil, &core.AuthError{
+			ErrorName:        core.AuthErrorInvalidToken,
+			ErrorDescription: "Bad registration access token",
+		}
+	}
+	return client, nil
+}
@@ -0,0 +1,132 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// Revoke invalidates the token presented by the client it was issued to.
+// Revoking a refresh token also revokes every token obtained from the same grant.
+//
+// Unknown tokens and tokens of other clients are ignored, since the client
+// can not do anything about them and must not learn whether they exist.
+// The personal access tokens belong to no client, so any client presenting one revokes it.
+func (m *IdentityManager) Revoke(ctx context.Context, req core.RevocationRequest) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	// check client credentials
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	// the personal access tokens are not signed, so they are told apart
+	// by their prefix like the api does, whatever the hint says
+	if core.IsPersonalAccessToken(req.Token) {
+		err = m.revokePersonalAccessToken(ctx, tx, client, req.Token)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		// the hint only decides the lookup order, the search
+		// is extended to the other type when nothing is found
+		lookups := []func(context.Context, *sql.Tx, *core.Client, core.JWT) (bool, error){
+			m.revokeAccessToken,
+			m.revokeRefreshToken,
+		}
+		if req.TokenTypeHint == core.TokenTypeHintRefreshToken {
+			lookups[0], lookups[1] = lookups[1], lookups[0]
+		}
+		for _, lookup := range lookups {
+			var found bool
+			found, err = lookup(ctx, tx, client, core.JWT(req.Token))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if found {
+				break
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (m *IdentityManager) revokeAccessToken(ctx context.Context, tx *sql.Tx, client *core.Client, token core.JWT) (bool, error) {
+	accessToken, err := m.getAccessToken(ctx, tx, token)
+	if errors.Is(err, core.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if accessToken.ClientID != client.ID {
+		slog.WarnContext(ctx, "Client tried to revoke access token of another client",
+			"clientID", client.ID,
+			"tokenID", accessToken.ID,
+		)
+		return true, nil
+	}
+	err = m.storage.RevokeAccessTokenByID(ctx, tx, accessToken.ID)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+func (m *IdentityManager) revokeRefreshToken(ctx context.Context, tx *sql.Tx, client *core.Client, token core.JWT) (bool, error) {
+	refreshToken, err := m.getRefreshToken(ctx, tx, token)
+	if errors.Is(err, core.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if refreshToken.ClientID != client.ID {
+		slog.WarnContext(ctx, "Client tried to revoke refresh token of another client",
+			"clientID", client.ID,
+			"tokenID", refreshToken.ID,
+		)
+		return true, nil
+	}
+	err = m.revokeRefreshTokenFamily(ctx, tx, refreshToken.FamilyID)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// revokePersonalAccessToken removes the personal access token, it is not issued to any client
+// so presenting it is enough, e.g. when a script gives up its token or a leaked token is reported.
+func (m *IdentityManager) revokePersonalAccessToken(ctx context.Context, tx *sql.Tx, client *core.Client, token string) error {
+	personalToken, err := m.storage.GetPersonalAccessTokenByHash(ctx, tx, core.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	slog.InfoContext(ctx, "Client revoked personal access token",
+		"clientID", client.ID,
+		"tokenID", personalToken.ID,
+	)
+	_, err = m.storage.DeletePersonalAccessToken(ctx, tx, personalToken.UserID, personalToken.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// GetSessions lists the sessions of the user that still have a usable refresh token.
+func (m *IdentityManager) GetSessions(ctx context.Context, userID string) ([]core.Session, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	sessions, err := m.storage.GetActiveSessions(ctx, tx, userID, time.Now())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs the user out of the session, the refresh token family
+// is revoked together with the access tokens issued from it.
+func (m *IdentityManager) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	session, err := m.storage.GetSession(ctx, tx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && session.UserID != userID) {
+		err = errors.Wrapf(core.ErrNotFound, "session %s", sessionID)
+		return err
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.revokeRefreshTokenFamily(ctx, tx, session.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// RevokeSessions logs the user out everywhere, including the session making the request.
+func (m *IdentityManager) RevokeSessions(ctx context.Context, userID string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.revokeSessions(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// revokeSessions revokes the refresh token families of all the active sessions of the user.
+func (m *IdentityManager) revokeSessions(ctx context.Context, tx *sql.Tx, userID string) error {
+	sessions, err := m.storage.GetActiveSessions(ctx, tx, userID, time.Now())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, session := range sessions {
+		err = m.revokeRefreshTokenFamily(ctx, tx, session.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Logout ends the session of the web ui the access token was issued to. Unlike the revocation
+// of the access token alone, the refresh token family is revoked too, so the session no longer
+// shows up among the sessions of the user. Unknown tokens and tokens of other clients are ignored.
+func (m *IdentityManager) Logout(ctx context.Context, req core.RevocationRequest) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	client, err := m.authenticateClient(ctx, tx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+	accessToken, err := m.getAccessToken(ctx, tx, core.JWT(req.Token))
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		return errors.WithStack(err)
+	}
+	if err == nil && accessToken.ClientID == client.ID {
+		err = m.endSession(ctx, tx, accessToken)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// endSession revokes the refresh token family the access token was issued from,
+// or just the access token when it was issued without a refresh token.
+func (m *IdentityManager) endSession(ctx context.Context, tx *sql.Tx, accessToken *core.AccessToken) error {
+	familyID, err := m.storage.GetAccessTokenFamilyID(ctx, tx, accessToken.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = m.storage.RevokeAccessTokenByID(ctx, tx, accessToken.ID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return m.revokeRefreshTokenFamily(ctx, tx, familyID)
+}
@@ -0,0 +1,314 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const emailVerificationExpiration = time.Hour * 24
+
+// SignupMode returns who may register an account. The signup stays closed while
+// the emails are not configured since nobody could verify the email of the account.
+func (m *IdentityManager) SignupMode(ctx context.Context) (core.SignupMode, error) {
+	if m.mailer == nil {
+		return core.SignupModeClosed, nil
+	}
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	mode, err := m.storage.GetSignupMode(ctx, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.SignupModeClosed, nil
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return mode, nil
+}
+
+// SetSignupMode opens or closes the signup.
+func (m *IdentityManager) SetSignupMode(ctx context.Context, mode string) error {
+	signupMode, err := core.NewSignupMode(mode)
+	if err != nil {
+		return err
+	}
+	if signupMode != core.SignupModeClosed && m.mailer == nil {
+		return errors.Wrap(core.ErrInvalidInput, "the signup needs the emails to be configured")
+	}
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.storage.SetSignupMode(ctx, tx, signupMode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// CreateInvite creates an invite of the administrator for the invite-only signup.
+// The returned invite is the only place the code can be read from.
+func (m *IdentityManager) CreateInvite(ctx context.Context, createdBy string, lifetime time.Duration) (*core.Invite, error) {
+	invite, err := core.NewInvite(createdBy, lifetime)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	err = m.storage.AddInvite(ctx, tx, *invite)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return invite, nil
+}
+
+// GetInvites lists the invites of all the administrators.
+func (m *IdentityManager) GetInvites(ctx context.Context) ([]core.Invite, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	invites, err := m.storage.GetInvites(ctx, tx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return invites, nil
+}
+
+// DeleteInvite withdraws the invite.
+func (m *IdentityManager) DeleteInvite(ctx context.Context, inviteID string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	deleted, err := m.storage.DeleteInvite(ctx, tx, inviteID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !deleted {
+		err = errors.Wrapf(core.ErrNotFound, "invite %s", inviteID)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Signup registers the account and emails the link to the verifyPath of the issuer,
+// the user can log in once the link was opened. The client is created along
+// with the account when the request names it.
+func (m *IdentityManager) Signup(ctx context.Context, req core.SignupRequest, verifyPath string) (*core.UserInfo, *core.Client, error) {
+	mode, err := m.SignupMode(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode == core.SignupModeClosed {
+		return nil, nil, errors.Wrap(core.ErrForbidden, "the signup is closed")
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	req.Email = strings.TrimSpace(req.Email)
+	err = req.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+	passwordHash, err := m.passwords.Hash(req.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the users of the directory have no local account until they log in, so their
+	// usernames are reserved, otherwise the local password would be checked instead
+	if m.credentials != nil {
+		var known bool
+		known, err = m.credentials.KnowsUsername(ctx, req.Username)
+		if err != nil {
+			return nil, nil, err
+		}
+		if known {
+			return nil, nil, errors.Wrap(core.ErrInvalidInput, "the username is taken")
+		}
+	}
+	user := core.UserInfo{
+		ID:              uuid.New().String(),
+		Username:        req.Username,
+		Email:           req.Email,
+		PasswordHash:    passwordHash,
+		EmailUnverified: true,
+	}
+	var client *core.Client
+	if req.ClientName != "" {
+		// like wallabag, the client is meant for the apps logging in with the password
+		policy := core.DefaultClientPolicy()
+		policy.GrantTypes = []core.GrantType{core.GrantTypePassword, core.GrantTypeRefreshToken}
+		client, err = core.NewClient(user.ID, req.ClientName, policy)
+		if err != nil {
+			return nil, nil, errors.Wrap(core.ErrInvalidInput, err.Error())
+		}
+	}
+
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	if mode == core.SignupModeInvite {
+		var used bool
+		used, err = m.storage.UseInvite(ctx, tx, core.HashToken(req.Invite), time.Now())
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if !used {
+			err = errors.Wrap(core.ErrForbidden, "the invite is invalid, expired or used")
+			return nil, nil, err
+		}
+	}
+	err = m.checkSignupAvailable(ctx, tx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = m.storage.AddUserInfo(ctx, tx, user)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	err = m.storage.AddUser(ctx, tx, core.User{
+		ID:       user.ID,
+		Username: user.Username,
+	})
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if client != nil {
+		err = m.storage.AddClient(ctx, tx, *client)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+	// the account is only kept when the email went out
+	err = m.sendEmailVerification(ctx, user, verifyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	slog.InfoContext(ctx, "Registered user", "userID", user.ID, "username", user.Username)
+	return &user, client, nil
+}
+
+// checkSignupAvailable makes sure that the username and the email are not taken yet.
+func (m *IdentityManager) checkSignupAvailable(ctx context.Context, tx *sql.Tx, user core.UserInfo) error {
+	_, err := m.storage.GetUserInfoByUsername(ctx, tx, user.Username)
+	if err == nil {
+		return errors.Wrap(core.ErrInvalidInput, "the username is taken")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return errors.WithStack(err)
+	}
+	_, err = m.storage.GetUserInfoByEmail(ctx, tx, user.Email)
+	if err == nil {
+		return errors.Wrap(core.ErrInvalidInput, "an account with the email already exists")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (m *IdentityManager) sendEmailVerification(ctx context.Context, user core.UserInfo, verifyPath string) error {
+	sealed, err := core.EmailVerification{UserID: user.ID, Email: user.Email}.Seal(m.issuer, emailVerificationExpiration, m.keys)
+	if err != nil {
+		return err
+	}
+	link := m.issuer + verifyPath + "?" + url.Values{core.EmailVerificationLinkParameter: {string(*sealed)}}.Encode()
+	return m.mailer.Send(ctx, core.MailMessage{
+		To:      user.Email,
+		Subject: "Verify your Wallabago email",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"welcome to Wallabago! Open the link below within %d hours to verify your email, "+
+			"you can log in afterwards:\n\n%s\n\n"+
+			"If you did not sign up, you can ignore this email.\n",
+			user.Username, int(emailVerificationExpiration.Hours()), link),
+	})
+}
+
+// VerifyEmail lets the user who received the sealed verification log in.
+func (m *IdentityManager) VerifyEmail(ctx context.Context, token core.JWT) error {
+	verification, err := core.OpenEmailVerification(token, m.issuer, m.keys)
+	if err != nil {
+		return errors.Wrap(core.ErrInvalidInput, "the link is invalid or expired")
+	}
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	user, err := m.storage.GetUserInfoByID(ctx, tx, verification.UserID)
+	// the account might have been deleted since
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.Email != verification.Email) {
+		err = errors.Wrap(core.ErrInvalidInput, "the link is invalid or expired")
+		return err
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.storage.SetUserEmailVerified(ctx, tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
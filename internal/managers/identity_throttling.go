@@ -0,0 +1,95 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// loginSubject is something the failed logins are counted for.
+type loginSubject struct {
+	attempts core.LoginAttempts
+	throttle core.LoginThrottle
+}
+
+// getLoginSubjects loads the failed logins of the username and of the client ip,
+// the rows stay locked until the login is over so that parallel guesses have to wait.
+func (m *IdentityManager) getLoginSubjects(ctx context.Context, tx *sql.Tx, req core.PasswordFlowRequest) ([]loginSubject, error) {
+	subjects := []loginSubject{{
+		attempts: core.LoginAttempts{Kind: core.LoginAttemptsKindUsername, Subject: req.Username},
+		throttle: m.usernameLoginThrottle,
+	}}
+	if req.ClientIP != "" {
+		subjects = append(subjects, loginSubject{
+			attempts: core.LoginAttempts{Kind: core.LoginAttemptsKindIP, Subject: req.ClientIP},
+			throttle: m.ipLoginThrottle,
+		})
+	}
+	for i := range subjects {
+		attempts, err := m.storage.GetLoginAttempts(ctx, tx, subjects[i].attempts.Kind, subjects[i].attempts.Subject)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		subjects[i].attempts = *attempts
+	}
+	return subjects, nil
+}
+
+// checkLoginThrottled rejects the login until the longest of the waits is over.
+func checkLoginThrottled(subjects []loginSubject, now time.Time) error {
+	var retryAfter time.Duration
+	for _, subject := range subjects {
+		retryAfter = max(retryAfter, subject.throttle.RetryAfter(subject.attempts, now))
+	}
+	if retryAfter > 0 {
+		return &core.LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (m *IdentityManager) failLogin(ctx context.Context, tx *sql.Tx, subjects []loginSubject, now time.Time) error {
+	for _, subject := range subjects {
+		err := m.storage.SetLoginAttempts(ctx, tx, subject.throttle.Fail(subject.attempts, now))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// UnlockUser lets administrators lift the lockout of the account before it expires,
+// the failed logins of the account are forgotten as well.
+func (m *IdentityManager) UnlockUser(ctx context.Context, username string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	_, err = m.storage.GetUserInfoByUsername(ctx, tx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.Wrapf(core.ErrNotFound, "user %s", username)
+		return err
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.storage.DeleteLoginAttempts(ctx, tx, core.LoginAttemptsKindUsername, username)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package managers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andriihomiak/wallabago/internal/core"
+	"github.com/pkg/errors"
+)
+
+// getConfirmedTOTP returns the second factor of the user, core.ErrNotFound when there is none.
+func (m *IdentityManager) getConfirmedTOTP(ctx context.Context, tx *sql.Tx, userID string) (*core.TOTP, error) {
+	totp, err := m.storage.GetUserTOTP(ctx, tx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(core.ErrNotFound, "totp")
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !totp.Confirmed() {
+		return nil, errors.Wrap(core.ErrNotFound, "totp")
+	}
+	return totp, nil
+}
+
+// useSecondFactor accepts either the current one-time password or one of the unused recovery codes,
+// both are spent so that they can not be used again.
+func (m *IdentityManager) useSecondFactor(ctx context.Context, tx *sql.Tx, totp core.TOTP, otp string, now time.Time) error {
+	if step, ok := totp.Verify(otp, now); ok {
+		// the update fails when a parallel login used the code first
+		used, err := m.storage.UseUserTOTPStep(ctx, tx, totp.UserID, step)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if used {
+			return nil
+		}
+		return errors.WithStack(core.ErrInvalidCredentials)
+	}
+	used, err := m.storage.UseRecoveryCode(ctx, tx, totp.UserID, core.HashRecoveryCode(otp), now)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !used {
+		return errors.WithStack(core.ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// verifySecondFactor checks the one-time password of the users that enabled the second factor.
+func (m *IdentityManager) verifySecondFactor(ctx context.Context, tx *sql.Tx, userID, otp string, now time.Time) error {
+	totp, err := m.getConfirmedTOTP(ctx, tx, userID)
+	if errors.Is(err, core.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if otp == "" {
+		return &core.AuthError{
+			ErrorName:        core.AuthErrorMFARequired,
+			ErrorDescription: "One-time password required",
+		}
+	}
+	return m.useSecondFactor(ctx, tx, *totp, otp, now)
+}
+
+// TOTPStatus tells whether the user enabled the second factor.
+func (m *IdentityManager) TOTPStatus(ctx context.Context, userID string) (*core.TOTPStatus, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//nolint:errcheck //ok here since we dont do any writes
+	defer tx.Rollback()
+
+	_, err = m.getConfirmedTOTP(ctx, tx, userID)
+	if errors.Is(err, core.ErrNotFound) {
+		return &core.TOTPStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	count, err := m.storage.CountRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.TOTPStatus{
+		Enabled:           true,
+		RecoveryCodesLeft: count,
+	}, nil
+}
+
+// EnrollTOTP starts the enrollment of the second factor, the pending enrollment is kept
+// until it is confirmed so that the secret already added to the app stays valid.
+func (m *IdentityManager) EnrollTOTP(ctx context.Context, userID string) (*core.TOTPEnrollment, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	user, err := m.storage.GetUserInfoByID(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	totp, err := m.storage.GetUserTOTP(ctx, tx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+	if err == nil && totp.Confirmed() {
+		err = errors.Wrap(core.ErrInvalidInput, "totp is already enabled")
+		return nil, err
+	}
+	if err != nil {
+		totp, err = core.NewTOTP(userID)
+		if err != nil {
+			return nil, err
+		}
+		err = m.storage.SetUserTOTP(ctx, tx, *totp)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.TOTPEnrollment{
+		Secret:          totp.Secret,
+		ProvisioningURI: totp.ProvisioningURI(user.Username),
+	}, nil
+}
+
+// ConfirmTOTP enables the second factor once the user proves to have the secret,
+// the returned recovery codes are only ever shown this once.
+func (m *IdentityManager) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	totp, err := m.storage.GetUserTOTP(ctx, tx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.Wrap(core.ErrNotFound, "totp enrollment")
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	now := time.Now()
+	step, ok := totp.Verify(code, now)
+	if !ok {
+		err = errors.Wrap(core.ErrInvalidInput, "invalid one-time password")
+		return nil, err
+	}
+	confirmed, err := m.storage.ConfirmUserTOTP(ctx, tx, userID, now, step)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !confirmed {
+		err = errors.Wrap(core.ErrInvalidInput, "totp is already enabled")
+		return nil, err
+	}
+
+	codes, err := core.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	codeHashes := make([][]byte, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = core.HashRecoveryCode(code)
+	}
+	err = m.storage.DeleteRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = m.storage.AddRecoveryCodes(ctx, tx, userID, codeHashes)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns the second factor off, the user has to present it one last time.
+func (m *IdentityManager) DisableTOTP(ctx context.Context, userID, code string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	totp, err := m.getConfirmedTOTP(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	err = m.useSecondFactor(ctx, tx, *totp, code, time.Now())
+	if errors.Is(err, core.ErrInvalidCredentials) {
+		err = errors.Wrap(core.ErrInvalidInput, "invalid one-time password")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	err = m.deleteSecondFactor(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ResetTOTP lets administrators turn off the second factor of the user who lost it.
+func (m *IdentityManager) ResetTOTP(ctx context.Context, username string) error {
+	tx, err := m.storage.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		rollbackOnError(ctx, err, tx.Rollback)
+	}()
+
+	user, err := m.storage.GetUserInfoByUsername(ctx, tx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.Wrapf(core.ErrNotFound, "user %s", username)
+		return err
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.deleteSecondFactor(ctx, tx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (m *IdentityManager) deleteSecondFactor(ctx context.Context, tx *sql.Tx, userID string) error {
+	err := m.storage.DeleteUserTOTP(ctx, tx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = m.storage.DeleteRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
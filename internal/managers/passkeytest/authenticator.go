@@ -0,0 +1,281 @@
+// Package passkeytest provides a software authenticator for the tests, it creates the passkeys
+// and answers the ceremonies the way a platform authenticator behind the browser would.
+package passkeytest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// the flags of the authenticator data, see https://www.w3.org/TR/webauthn-3/#authdata-flags.
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+// the parameters of the ES256 keys, see https://datatracker.ietf.org/doc/html/rfc9053#section-7.1.1.
+const (
+	coseKeyType      = 1
+	coseAlgorithm    = 3
+	coseCurve        = -1
+	coseX            = -2
+	coseY            = -3
+	coseKeyTypeEC2   = 2
+	coseAlgorithmES  = -7
+	coseCurveP256    = 1
+	credentialIDSize = 16
+)
+
+var encoding = base64.RawURLEncoding
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// Authenticator keeps the passkeys it created, all of them are discoverable.
+type Authenticator struct {
+	// Origin is what the browser reports as the origin of the page running the ceremony.
+	Origin string
+	// SkipUserVerification answers as an authenticator without a pin or a biometric sensor.
+	SkipUserVerification bool
+
+	credentials []*credential
+}
+
+// NewAuthenticator returns an authenticator used by the pages of the origin.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Clone copies the authenticator with its private keys and signature counters.
+func (a *Authenticator) Clone() *Authenticator {
+	clone := &Authenticator{Origin: a.Origin, SkipUserVerification: a.SkipUserVerification}
+	for _, c := range a.credentials {
+		copied := *c
+		clone.credentials = append(clone.credentials, &copied)
+	}
+	return clone
+}
+
+type credentialDescriptor struct {
+	ID string `json:"id"`
+}
+
+type creationOptions struct {
+	PublicKey struct {
+		Challenge    string `json:"challenge"`
+		RelyingParty struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		ExcludeCredentials []credentialDescriptor `json:"excludeCredentials"`
+	} `json:"publicKey"`
+}
+
+type requestOptions struct {
+	PublicKey struct {
+		Challenge        string                 `json:"challenge"`
+		RelyingPartyID   string                 `json:"rpId"`
+		AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+	} `json:"publicKey"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type attestationObject struct {
+	Format               string         `cbor:"fmt"`
+	AttestationStatement map[string]any `cbor:"attStmt"`
+	AuthenticatorData    []byte         `cbor:"authData"`
+}
+
+type publicKeyCredential struct {
+	ID                     string         `json:"id"`
+	RawID                  string         `json:"rawId"`
+	Type                   string         `json:"type"`
+	Response               map[string]any `json:"response"`
+	ClientExtensionResults map[string]any `json:"clientExtensionResults"`
+}
+
+func (a *Authenticator) flags() byte {
+	if a.SkipUserVerification {
+		return flagUserPresent
+	}
+	return flagUserPresent | flagUserVerified
+}
+
+func authenticatorData(rpID string, flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+// Create answers the options of navigator.credentials.create
+// and returns the JSON of the PublicKeyCredential.
+func (a *Authenticator) Create(options []byte) ([]byte, error) {
+	parsed := creationOptions{}
+	err := json.Unmarshal(options, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	for _, excluded := range parsed.PublicKey.ExcludeCredentials {
+		for _, c := range a.credentials {
+			if encoding.EncodeToString(c.id) == excluded.ID {
+				return nil, errors.New("the authenticator already has a passkey of the account")
+			}
+		}
+	}
+	userHandle, err := encoding.DecodeString(parsed.PublicKey.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	c := &credential{
+		id:         make([]byte, credentialIDSize),
+		rpID:       parsed.PublicKey.RelyingParty.ID,
+		userHandle: userHandle,
+		key:        key,
+	}
+	_, err = rand.Read(c.id)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := key.PublicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	// the uncompressed point is 0x04 followed by the coordinates
+	point := publicKey.Bytes()
+	coseKey, err := cbor.Marshal(map[int]any{
+		coseKeyType:   coseKeyTypeEC2,
+		coseAlgorithm: coseAlgorithmES,
+		coseCurve:     coseCurveP256,
+		coseX:         point[1:33],
+		coseY:         point[33:],
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := authenticatorData(c.rpID, a.flags()|flagAttestedCredentialData, c.signCount)
+	// the aaguid of the authenticators that do not tell their model is all zeros
+	data = append(data, make([]byte, 16)...)
+	//nolint:gosec //the credential id is short
+	data = binary.BigEndian.AppendUint16(data, uint16(len(c.id)))
+	data = append(data, c.id...)
+	data = append(data, coseKey...)
+	attestation, err := cbor.Marshal(attestationObject{
+		Format:               "none",
+		AttestationStatement: map[string]any{},
+		AuthenticatorData:    data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	clientDataJSON, err := a.clientData("webauthn.create", parsed.PublicKey.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.credentials = append(a.credentials, c)
+	return json.Marshal(publicKeyCredential{
+		ID:    encoding.EncodeToString(c.id),
+		RawID: encoding.EncodeToString(c.id),
+		Type:  "public-key",
+		Response: map[string]any{
+			"clientDataJSON":    encoding.EncodeToString(clientDataJSON),
+			"attestationObject": encoding.EncodeToString(attestation),
+			"transports":        []string{"internal"},
+		},
+		ClientExtensionResults: map[string]any{},
+	})
+}
+
+// Get answers the options of navigator.credentials.get with the first passkey
+// of the relying party, the allowed credentials are honored when there are any.
+func (a *Authenticator) Get(options []byte) ([]byte, error) {
+	parsed := requestOptions{}
+	err := json.Unmarshal(options, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	c := a.find(parsed.PublicKey.RelyingPartyID, parsed.PublicKey.AllowCredentials)
+	if c == nil {
+		return nil, errors.New("the authenticator has no passkey of the relying party")
+	}
+	c.signCount++
+
+	clientDataJSON, err := a.clientData("webauthn.get", parsed.PublicKey.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	data := authenticatorData(c.rpID, a.flags(), c.signCount)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, data...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(publicKeyCredential{
+		ID:    encoding.EncodeToString(c.id),
+		RawID: encoding.EncodeToString(c.id),
+		Type:  "public-key",
+		Response: map[string]any{
+			"clientDataJSON":    encoding.EncodeToString(clientDataJSON),
+			"authenticatorData": encoding.EncodeToString(data),
+			"signature":         encoding.EncodeToString(signature),
+			"userHandle":        encoding.EncodeToString(c.userHandle),
+		},
+		ClientExtensionResults: map[string]any{},
+	})
+}
+
+func (a *Authenticator) find(rpID string, allowed []credentialDescriptor) *credential {
+	for _, c := range a.credentials {
+		if c.rpID != rpID {
+			continue
+		}
+		if len(allowed) == 0 {
+			return c
+		}
+		for _, descriptor := range allowed {
+			id, err := encoding.DecodeString(descriptor.ID)
+			if err == nil && bytes.Equal(id, c.id) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	return json.Marshal(clientData{
+		Type:      ceremony,
+		Challenge: challenge,
+		Origin:    a.Origin,
+	})
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/andriihomiak/wallabago/internal/core"
 	"github.com/andriihomiak/wallabago/internal/database"
@@ -55,27 +57,157 @@ func (s *PostgreSQLStorage) MarkBootstrapConditionSatisfied(
 func (s *PostgreSQLStorage) AddClient(ctx context.Context, tx *sql.Tx, client core.Client) error {
 	q := s.queries.WithTx(tx)
 	_, err := q.AddClient(ctx, database.AddClientParams{
-		ClientID:     client.ID,
-		ClientSecret: client.Secret,
+		ClientID:         client.ID,
+		ClientSecretHash: client.SecretHash,
+		OwnerUserID: sql.NullString{
+			Valid:  client.OwnerUserID != "",
+			String: client.OwnerUserID,
+		},
+		Name:                        client.Name,
+		CreatedAt:                   client.CreatedAt,
+		GrantTypes:                  core.FormatGrantTypes(client.GrantTypes),
+		AllowedScope:                string(client.Scope),
+		Public:                      client.Public,
+		AccessTokenLifetimeSeconds:  int64(client.AccessTokenLifetime.Seconds()),
+		RefreshTokenLifetimeSeconds: int64(client.RefreshTokenLifetime.Seconds()),
+		RegistrationAccessTokenHash: client.RegistrationAccessTokenHash,
 	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	return addClientRedirectURIs(ctx, q, client)
+}
+
+func addClientRedirectURIs(ctx context.Context, q *database.Queries, client core.Client) error {
+	for _, redirectURI := range client.RedirectURIs {
+		err := q.AddClientRedirectURI(ctx, database.AddClientRedirectURIParams{
+			ClientID:    client.ID,
+			RedirectUri: redirectURI,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return nil
 }
 
+// UpdateClient stores the name, the secret and the policy of the client,
+// the lifetimes and the service account are changed separately.
+func (s *PostgreSQLStorage) UpdateClient(ctx context.Context, tx *sql.Tx, client core.Client) error {
+	q := s.queries.WithTx(tx)
+	err := q.UpdateClient(ctx, database.UpdateClientParams{
+		ClientID:         client.ID,
+		ClientSecretHash: client.SecretHash,
+		Name:             client.Name,
+		GrantTypes:       core.FormatGrantTypes(client.GrantTypes),
+		AllowedScope:     string(client.Scope),
+		Public:           client.Public,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = q.DeleteClientRedirectURIs(ctx, client.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return addClientRedirectURIs(ctx, q, client)
+}
+
 func (s *PostgreSQLStorage) GetClientByID(ctx context.Context, tx *sql.Tx, id string) (*core.Client, error) {
 	q := s.queries.WithTx(tx)
 	result, err := q.GetClientByID(ctx, id)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	return clientFromRow(ctx, q, result)
+}
+
+func clientFromRow(ctx context.Context, q *database.Queries, row *database.IdentityClient) (*core.Client, error) {
+	redirectURIs, err := q.GetClientRedirectURIs(ctx, row.ClientID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 	return &core.Client{
-		ID:     result.ClientID,
-		Secret: result.ClientSecret,
+		ID:                          row.ClientID,
+		SecretHash:                  row.ClientSecretHash,
+		RegistrationAccessTokenHash: row.RegistrationAccessTokenHash,
+		ClientPolicy: core.ClientPolicy{
+			RedirectURIs:         redirectURIs,
+			GrantTypes:           core.ParseGrantTypes(row.GrantTypes),
+			Scope:                core.Scope(row.AllowedScope),
+			Public:               row.Public,
+			AccessTokenLifetime:  time.Duration(row.AccessTokenLifetimeSeconds) * time.Second,
+			RefreshTokenLifetime: time.Duration(row.RefreshTokenLifetimeSeconds) * time.Second,
+		},
+		ServiceAccountUserID: row.ServiceAccountUserID.String,
+		ServiceAccountScope:  core.Scope(row.ServiceAccountScope),
+		OwnerUserID:          row.OwnerUserID.String,
+		Name:                 row.Name,
+		CreatedAt:            row.CreatedAt,
 	}, nil
 }
 
+func clientsFromRows(ctx context.Context, q *database.Queries, rows []*database.IdentityClient) ([]core.Client, error) {
+	clients := make([]core.Client, 0, len(rows))
+	for _, row := range rows {
+		client, err := clientFromRow(ctx, q, row)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, *client)
+	}
+	return clients, nil
+}
+
+func (s *PostgreSQLStorage) GetClients(ctx context.Context, tx *sql.Tx) ([]core.Client, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetClients(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return clientsFromRows(ctx, q, result)
+}
+
+func (s *PostgreSQLStorage) GetClientsByOwnerUserID(ctx context.Context, tx *sql.Tx, ownerUserID string) ([]core.Client, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetClientsByOwnerUserID(ctx, sql.NullString{
+		Valid:  true,
+		String: ownerUserID,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return clientsFromRows(ctx, q, result)
+}
+
+func (s *PostgreSQLStorage) SetClientSecretHash(ctx context.Context, tx *sql.Tx, clientID string, secretHash []byte) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetClientSecretHash(ctx, database.SetClientSecretHashParams{
+		ClientID:         clientID,
+		ClientSecretHash: secretHash,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) SetClientServiceAccount(ctx context.Context, tx *sql.Tx, clientID, userID string, scope core.Scope) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetClientServiceAccount(ctx, database.SetClientServiceAccountParams{
+		ClientID: clientID,
+		ServiceAccountUserID: sql.NullString{
+			Valid:  userID != "",
+			String: userID,
+		},
+		ServiceAccountScope: string(scope),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 func (s *PostgreSQLStorage) DeleteClientByID(ctx context.Context, tx *sql.Tx, id string) error {
 	q := s.queries.WithTx(tx)
 	err := q.DeleteClientByID(ctx, id)
@@ -90,7 +222,6 @@ func (s *PostgreSQLStorage) AddAccessToken(ctx context.Context, tx *sql.Tx, refr
 	_, err := q.AddAccessToken(ctx, database.AddAccessTokenParams{
 		TokenID:  token.ID,
 		ClientID: token.ClientID,
-		Jwt:      string(token.Token),
 		UserID:   token.UserID,
 		Revoked:  token.Revoked,
 		RefreshTokenID: sql.NullString{
@@ -101,6 +232,8 @@ func (s *PostgreSQLStorage) AddAccessToken(ctx context.Context, tx *sql.Tx, refr
 		Scope:            string(token.Scope),
 		IssuedAt:         token.IssuedAt,
 		ExpiresInSeconds: token.ExpiresInSeconds,
+		GrantType:        string(token.GrantType),
+		TokenHash:        token.TokenHash,
 	})
 	if err != nil {
 		return errors.WithStack(err)
@@ -108,15 +241,15 @@ func (s *PostgreSQLStorage) AddAccessToken(ctx context.Context, tx *sql.Tx, refr
 	return nil
 }
 
-func (s *PostgreSQLStorage) GetAccessTokenByJWT(ctx context.Context, tx *sql.Tx, jwt core.JWT) (*core.AccessToken, error) {
+func (s *PostgreSQLStorage) GetAccessTokenByID(ctx context.Context, tx *sql.Tx, id string) (*core.AccessToken, error) {
 	q := s.queries.WithTx(tx)
-	result, err := q.GetAccessTokenByJWT(ctx, string(jwt))
+	result, err := q.GetAccessTokenByID(ctx, id)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	return &core.AccessToken{
-		ID:               result.ClientID,
-		Token:            core.JWT(result.Jwt),
+		ID:               result.TokenID,
+		TokenHash:        result.TokenHash,
 		ExpiresInSeconds: result.ExpiresInSeconds,
 		UserID:           result.UserID,
 		Scope:            core.Scope(result.Scope),
@@ -124,6 +257,7 @@ func (s *PostgreSQLStorage) GetAccessTokenByJWT(ctx context.Context, tx *sql.Tx,
 		TokenType:        core.TokenType(result.Type),
 		ClientID:         result.ClientID,
 		Revoked:          result.Revoked,
+		GrantType:        core.GrantType(result.GrantType),
 	}, nil
 }
 
@@ -136,6 +270,27 @@ func (s *PostgreSQLStorage) RevokeAccessTokenByID(ctx context.Context, tx *sql.T
 	return nil
 }
 
+func (s *PostgreSQLStorage) RevokeAccessTokensByRefreshTokenID(ctx context.Context, tx *sql.Tx, refreshTokenID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.RevokeAccessTokensByRefreshTokenID(ctx, sql.NullString{
+		Valid:  true,
+		String: refreshTokenID,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) RevokeAccessTokensByRefreshTokenFamilyID(ctx context.Context, tx *sql.Tx, familyID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.RevokeAccessTokensByRefreshTokenFamilyID(ctx, familyID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 func (s *PostgreSQLStorage) DeleteAccessTokenByID(ctx context.Context, tx *sql.Tx, id string) error {
 	q := s.queries.WithTx(tx)
 	err := q.DeleteAccessTokenByID(ctx, id)
@@ -145,13 +300,31 @@ func (s *PostgreSQLStorage) DeleteAccessTokenByID(ctx context.Context, tx *sql.T
 	return nil
 }
 
+func (s *PostgreSQLStorage) GetAccessTokenFamilyID(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	q := s.queries.WithTx(tx)
+	familyID, err := q.GetAccessTokenFamilyID(ctx, id)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return familyID, nil
+}
+
 func (s *PostgreSQLStorage) AddRefreshToken(ctx context.Context, tx *sql.Tx, token core.RefreshToken) error {
 	q := s.queries.WithTx(tx)
 	_, err := q.AddRefreshToken(ctx, database.AddRefreshTokenParams{
-		TokenID:  token.ID,
-		Jwt:      string(token.Token),
-		ClientID: token.ClientID,
-		Revoked:  token.Revoked,
+		TokenID:   token.ID,
+		TokenHash: token.TokenHash,
+		ClientID:  token.ClientID,
+		Revoked:   token.Revoked,
+		UserID:    token.UserID,
+		Scope:     string(token.Scope),
+		IssuedAt:  token.IssuedAt,
+		FamilyID:  token.FamilyID,
+		Rotated:   token.Rotated,
+		ExpiresAt: sql.NullTime{
+			Valid: !token.ExpiresAt.IsZero(),
+			Time:  token.ExpiresAt,
+		},
 	})
 	if err != nil {
 		return errors.WithStack(err)
@@ -159,18 +332,28 @@ func (s *PostgreSQLStorage) AddRefreshToken(ctx context.Context, tx *sql.Tx, tok
 	return nil
 }
 
-func (s *PostgreSQLStorage) GetRefreshTokenByJWT(ctx context.Context, tx *sql.Tx, refreshToken core.JWT) (*core.RefreshToken, error) {
+func refreshTokenFromRow(row *database.IdentityRefreshToken) *core.RefreshToken {
+	return &core.RefreshToken{
+		ID:        row.TokenID,
+		TokenHash: row.TokenHash,
+		FamilyID:  row.FamilyID,
+		ClientID:  row.ClientID,
+		UserID:    row.UserID,
+		Scope:     core.Scope(row.Scope),
+		IssuedAt:  row.IssuedAt,
+		ExpiresAt: row.ExpiresAt.Time,
+		Revoked:   row.Revoked,
+		Rotated:   row.Rotated,
+	}
+}
+
+func (s *PostgreSQLStorage) GetRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) (*core.RefreshToken, error) {
 	q := s.queries.WithTx(tx)
-	result, err := q.GetRefreshTokenByJWT(ctx, string(refreshToken))
+	result, err := q.GetRefreshTokenByID(ctx, id)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return &core.RefreshToken{
-		ID:       result.ClientID,
-		Token:    core.JWT(result.Jwt),
-		ClientID: result.ClientID,
-		Revoked:  result.Revoked,
-	}, nil
+	return refreshTokenFromRow(result), nil
 }
 
 func (s *PostgreSQLStorage) RevokeRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) error {
@@ -182,6 +365,24 @@ func (s *PostgreSQLStorage) RevokeRefreshTokenByID(ctx context.Context, tx *sql.
 	return nil
 }
 
+func (s *PostgreSQLStorage) RotateRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.RotateRefreshTokenByID(ctx, id)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) RevokeRefreshTokensByFamilyID(ctx context.Context, tx *sql.Tx, familyID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.RevokeRefreshTokensByFamilyID(ctx, familyID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 func (s *PostgreSQLStorage) DeleteRefreshTokenByID(ctx context.Context, tx *sql.Tx, id string) error {
 	q := s.queries.WithTx(tx)
 	err := q.DeleteRefreshTokenByID(ctx, id)
@@ -194,10 +395,11 @@ func (s *PostgreSQLStorage) DeleteRefreshTokenByID(ctx context.Context, tx *sql.
 func (s *PostgreSQLStorage) AddUserInfo(ctx context.Context, tx *sql.Tx, user core.UserInfo) error {
 	q := s.queries.WithTx(tx)
 	_, err := q.AddIdentityUser(ctx, database.AddIdentityUserParams{
-		UserID:       user.ID,
-		Username:     user.Username,
-		Email:        user.Email,
-		PasswordHash: user.PasswordHash,
+		UserID:        user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		PasswordHash:  user.PasswordHash,
+		EmailVerified: !user.EmailUnverified,
 	})
 	if err != nil {
 		return errors.WithStack(err)
@@ -212,10 +414,41 @@ func (s *PostgreSQLStorage) GetUserInfoByUsername(ctx context.Context, tx *sql.T
 		return nil, errors.WithStack(err)
 	}
 	return &core.UserInfo{
-		ID:           result.UserID,
-		Email:        result.Email,
-		Username:     result.Username,
-		PasswordHash: result.PasswordHash,
+		ID:              result.UserID,
+		Email:           result.Email,
+		Username:        result.Username,
+		PasswordHash:    result.PasswordHash,
+		EmailUnverified: !result.EmailVerified,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) GetUserInfoByID(ctx context.Context, tx *sql.Tx, id string) (*core.UserInfo, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetIdentityUserByID(ctx, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.UserInfo{
+		ID:              result.UserID,
+		Email:           result.Email,
+		Username:        result.Username,
+		PasswordHash:    result.PasswordHash,
+		EmailUnverified: !result.EmailVerified,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) GetUserInfoByEmail(ctx context.Context, tx *sql.Tx, email string) (*core.UserInfo, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetIdentityUserByEmail(ctx, email)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.UserInfo{
+		ID:              result.UserID,
+		Email:           result.Email,
+		Username:        result.Username,
+		PasswordHash:    result.PasswordHash,
+		EmailUnverified: !result.EmailVerified,
 	}, nil
 }
 
@@ -240,3 +473,813 @@ func (s *PostgreSQLStorage) AddUser(ctx context.Context, tx *sql.Tx, user core.U
 	}
 	return nil
 }
+
+func (s *PostgreSQLStorage) SetUserAdmin(ctx context.Context, tx *sql.Tx, userID string, isAdmin bool) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetAppUserIsAdmin(ctx, database.SetAppUserIsAdminParams{
+		UserID:  userID,
+		IsAdmin: isAdmin,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) SetUserPasswordHash(ctx context.Context, tx *sql.Tx, userID string, passwordHash []byte) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetIdentityUserPasswordHash(ctx, database.SetIdentityUserPasswordHashParams{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) SetUserEmailVerified(ctx context.Context, tx *sql.Tx, userID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetIdentityUserEmailVerified(ctx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) AddAuthorizationCode(ctx context.Context, tx *sql.Tx, code core.AuthorizationCode) error {
+	q := s.queries.WithTx(tx)
+	_, err := q.AddAuthorizationCode(ctx, database.AddAuthorizationCodeParams{
+		CodeHash:            code.CodeHash,
+		ClientID:            code.ClientID,
+		UserID:              code.UserID,
+		RedirectUri:         code.RedirectURI,
+		Scope:               string(code.Scope),
+		CodeChallenge:       code.CodeChallenge,
+		CodeChallengeMethod: code.CodeChallengeMethod,
+		IssuedAt:            code.IssuedAt,
+		ExpiresAt:           code.ExpiresAt,
+		Used:                code.Used,
+		Nonce:               code.Nonce,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetAuthorizationCode(ctx context.Context, tx *sql.Tx, codeHash []byte) (*core.AuthorizationCode, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetAuthorizationCode(ctx, codeHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.AuthorizationCode{
+		CodeHash:            result.CodeHash,
+		ClientID:            result.ClientID,
+		UserID:              result.UserID,
+		RedirectURI:         result.RedirectUri,
+		Scope:               core.Scope(result.Scope),
+		CodeChallenge:       result.CodeChallenge,
+		CodeChallengeMethod: result.CodeChallengeMethod,
+		IssuedAt:            result.IssuedAt,
+		ExpiresAt:           result.ExpiresAt,
+		Used:                result.Used,
+		TokenFamilyID:       result.TokenFamilyID.String,
+		Nonce:               result.Nonce,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) MarkAuthorizationCodeUsed(ctx context.Context, tx *sql.Tx, codeHash []byte, tokenFamilyID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.MarkAuthorizationCodeUsed(ctx, database.MarkAuthorizationCodeUsedParams{
+		CodeHash: codeHash,
+		TokenFamilyID: sql.NullString{
+			Valid:  tokenFamilyID != "",
+			String: tokenFamilyID,
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetUserByID(ctx context.Context, tx *sql.Tx, id string) (*core.User, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetAppUserByID(ctx, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.User{
+		ID:       result.UserID,
+		IsAdmin:  result.IsAdmin,
+		Username: result.Username,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) AddDeviceCode(ctx context.Context, tx *sql.Tx, code core.DeviceCode) error {
+	q := s.queries.WithTx(tx)
+	_, err := q.AddDeviceCode(ctx, database.AddDeviceCodeParams{
+		DeviceCodeHash:  code.DeviceCodeHash,
+		UserCode:        code.UserCode,
+		ClientID:        code.ClientID,
+		Scope:           string(code.Scope),
+		IssuedAt:        code.IssuedAt,
+		ExpiresAt:       code.ExpiresAt,
+		IntervalSeconds: int64(code.Interval.Seconds()),
+		Status:          string(code.Status),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func deviceCodeFromRow(row *database.IdentityDeviceCode) *core.DeviceCode {
+	return &core.DeviceCode{
+		DeviceCodeHash: row.DeviceCodeHash,
+		UserCode:       row.UserCode,
+		ClientID:       row.ClientID,
+		Scope:          core.Scope(row.Scope),
+		IssuedAt:       row.IssuedAt,
+		ExpiresAt:      row.ExpiresAt,
+		Interval:       time.Duration(row.IntervalSeconds) * time.Second,
+		LastPolledAt:   row.LastPolledAt.Time,
+		Status:         core.DeviceCodeStatus(row.Status),
+		UserID:         row.UserID.String,
+	}
+}
+
+func (s *PostgreSQLStorage) GetDeviceCodeByDeviceCodeHash(ctx context.Context, tx *sql.Tx, deviceCodeHash []byte) (*core.DeviceCode, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetDeviceCodeByDeviceCodeHash(ctx, deviceCodeHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return deviceCodeFromRow(result), nil
+}
+
+func (s *PostgreSQLStorage) GetDeviceCodeByUserCode(ctx context.Context, tx *sql.Tx, userCode string) (*core.DeviceCode, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetDeviceCodeByUserCode(ctx, userCode)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return deviceCodeFromRow(result), nil
+}
+
+func (s *PostgreSQLStorage) UpdateDeviceCodePolling(ctx context.Context, tx *sql.Tx, deviceCodeHash []byte, polledAt time.Time, interval time.Duration) error {
+	q := s.queries.WithTx(tx)
+	err := q.UpdateDeviceCodePolling(ctx, database.UpdateDeviceCodePollingParams{
+		DeviceCodeHash: deviceCodeHash,
+		LastPolledAt: sql.NullTime{
+			Valid: !polledAt.IsZero(),
+			Time:  polledAt,
+		},
+		IntervalSeconds: int64(interval.Seconds()),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) SetDeviceCodeStatus(ctx context.Context, tx *sql.Tx, deviceCodeHash []byte, status core.DeviceCodeStatus, userID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetDeviceCodeStatus(ctx, database.SetDeviceCodeStatusParams{
+		DeviceCodeHash: deviceCodeHash,
+		Status:         string(status),
+		UserID: sql.NullString{
+			Valid:  userID != "",
+			String: userID,
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetLoginAttempts(ctx context.Context, tx *sql.Tx, kind core.LoginAttemptsKind, subject string) (*core.LoginAttempts, error) {
+	q := s.queries.WithTx(tx)
+	row, err := q.GetLoginAttempts(ctx, database.GetLoginAttemptsParams{
+		Kind:    string(kind),
+		Subject: subject,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.LoginAttempts{
+		Kind:           core.LoginAttemptsKind(row.Kind),
+		Subject:        row.Subject,
+		FailedAttempts: int(row.FailedAttempts),
+		LastFailedAt:   row.LastFailedAt,
+		LockedUntil:    row.LockedUntil.Time,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) SetLoginAttempts(ctx context.Context, tx *sql.Tx, attempts core.LoginAttempts) error {
+	q := s.queries.WithTx(tx)
+	//nolint:gosec //the attempts are reset long before they could overflow
+	failedAttempts := int32(attempts.FailedAttempts)
+	err := q.SetLoginAttempts(ctx, database.SetLoginAttemptsParams{
+		Kind:           string(attempts.Kind),
+		Subject:        attempts.Subject,
+		FailedAttempts: failedAttempts,
+		LastFailedAt:   attempts.LastFailedAt,
+		LockedUntil: sql.NullTime{
+			Valid: !attempts.LockedUntil.IsZero(),
+			Time:  attempts.LockedUntil,
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) DeleteLoginAttempts(ctx context.Context, tx *sql.Tx, kind core.LoginAttemptsKind, subject string) error {
+	q := s.queries.WithTx(tx)
+	err := q.DeleteLoginAttempts(ctx, database.DeleteLoginAttemptsParams{
+		Kind:    string(kind),
+		Subject: subject,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetFederatedIdentity(ctx context.Context, tx *sql.Tx, issuer, subject string) (*core.FederatedIdentity, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetFederatedIdentity(ctx, database.GetFederatedIdentityParams{
+		Issuer:  issuer,
+		Subject: subject,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.FederatedIdentity{
+		Issuer:    result.Issuer,
+		Subject:   result.Subject,
+		UserID:    result.UserID,
+		CreatedAt: result.CreatedAt,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) AddFederatedIdentity(ctx context.Context, tx *sql.Tx, identity core.FederatedIdentity) error {
+	q := s.queries.WithTx(tx)
+	err := q.AddFederatedIdentity(ctx, database.AddFederatedIdentityParams{
+		Issuer:    identity.Issuer,
+		Subject:   identity.Subject,
+		UserID:    identity.UserID,
+		CreatedAt: identity.CreatedAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetUserTOTP(ctx context.Context, tx *sql.Tx, userID string) (*core.TOTP, error) {
+	q := s.queries.WithTx(tx)
+	result, err := q.GetUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.TOTP{
+		UserID:       result.UserID,
+		Secret:       result.Secret,
+		ConfirmedAt:  result.ConfirmedAt.Time,
+		LastUsedStep: result.LastUsedStep,
+		CreatedAt:    result.CreatedAt,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) SetUserTOTP(ctx context.Context, tx *sql.Tx, totp core.TOTP) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetUserTOTP(ctx, database.SetUserTOTPParams{
+		UserID: totp.UserID,
+		Secret: totp.Secret,
+		ConfirmedAt: sql.NullTime{
+			Valid: totp.Confirmed(),
+			Time:  totp.ConfirmedAt,
+		},
+		LastUsedStep: totp.LastUsedStep,
+		CreatedAt:    totp.CreatedAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) ConfirmUserTOTP(ctx context.Context, tx *sql.Tx, userID string, confirmedAt time.Time, step int64) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.ConfirmUserTOTP(ctx, database.ConfirmUserTOTPParams{
+		UserID: userID,
+		ConfirmedAt: sql.NullTime{
+			Valid: true,
+			Time:  confirmedAt,
+		},
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) UseUserTOTPStep(ctx context.Context, tx *sql.Tx, userID string, step int64) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) DeleteUserTOTP(ctx context.Context, tx *sql.Tx, userID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.DeleteUserTOTP(ctx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) AddRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes [][]byte) error {
+	q := s.queries.WithTx(tx)
+	for _, codeHash := range codeHashes {
+		err := q.AddRecoveryCode(ctx, database.AddRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: codeHash,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) UseRecoveryCode(ctx context.Context, tx *sql.Tx, userID string, codeHash []byte, usedAt time.Time) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+		UsedAt: sql.NullTime{
+			Valid: true,
+			Time:  usedAt,
+		},
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) CountRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) (int64, error) {
+	q := s.queries.WithTx(tx)
+	count, err := q.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return count, nil
+}
+
+func (s *PostgreSQLStorage) DeleteRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func passkeyFromRow(row *database.IdentityWebauthnCredential) core.Passkey {
+	transports := []string{}
+	if row.Transports != "" {
+		transports = strings.Split(row.Transports, " ")
+	}
+	return core.Passkey{
+		CredentialID:    row.CredentialID,
+		UserID:          row.UserID,
+		PublicKey:       row.PublicKey,
+		AttestationType: row.AttestationType,
+		AAGUID:          row.Aaguid,
+		//nolint:gosec //the counter is stored from an uint32
+		SignCount:      uint32(row.SignCount),
+		Transports:     transports,
+		BackupEligible: row.BackupEligible,
+		BackupState:    row.BackupState,
+		CreatedAt:      row.CreatedAt,
+		LastUsedAt:     row.LastUsedAt.Time,
+	}
+}
+
+func (s *PostgreSQLStorage) GetPasskeys(ctx context.Context, tx *sql.Tx, userID string) ([]core.Passkey, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.GetWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	passkeys := make([]core.Passkey, len(rows))
+	for i, row := range rows {
+		passkeys[i] = passkeyFromRow(row)
+	}
+	return passkeys, nil
+}
+
+func (s *PostgreSQLStorage) GetPasskey(ctx context.Context, tx *sql.Tx, credentialID []byte) (*core.Passkey, error) {
+	q := s.queries.WithTx(tx)
+	row, err := q.GetWebAuthnCredential(ctx, credentialID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	passkey := passkeyFromRow(row)
+	return &passkey, nil
+}
+
+func (s *PostgreSQLStorage) AddPasskey(ctx context.Context, tx *sql.Tx, passkey core.Passkey) error {
+	q := s.queries.WithTx(tx)
+	err := q.AddWebAuthnCredential(ctx, database.AddWebAuthnCredentialParams{
+		CredentialID:    passkey.CredentialID,
+		UserID:          passkey.UserID,
+		PublicKey:       passkey.PublicKey,
+		AttestationType: passkey.AttestationType,
+		Aaguid:          passkey.AAGUID,
+		SignCount:       int64(passkey.SignCount),
+		Transports:      strings.Join(passkey.Transports, " "),
+		BackupEligible:  passkey.BackupEligible,
+		BackupState:     passkey.BackupState,
+		CreatedAt:       passkey.CreatedAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) UsePasskey(ctx context.Context, tx *sql.Tx, credentialID []byte, signCount uint32, backupState bool, usedAt time.Time) error {
+	q := s.queries.WithTx(tx)
+	err := q.UseWebAuthnCredential(ctx, database.UseWebAuthnCredentialParams{
+		CredentialID: credentialID,
+		SignCount:    int64(signCount),
+		BackupState:  backupState,
+		LastUsedAt: sql.NullTime{
+			Valid: true,
+			Time:  usedAt,
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) DeletePasskey(ctx context.Context, tx *sql.Tx, userID string, credentialID []byte) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.DeleteWebAuthnCredential(ctx, database.DeleteWebAuthnCredentialParams{
+		UserID:       userID,
+		CredentialID: credentialID,
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) AddWebAuthnChallenge(ctx context.Context, tx *sql.Tx, challenge core.WebAuthnChallenge) error {
+	q := s.queries.WithTx(tx)
+	err := q.AddWebAuthnChallenge(ctx, database.AddWebAuthnChallengeParams{
+		Challenge: challenge.Challenge,
+		Ceremony:  string(challenge.Ceremony),
+		UserID: sql.NullString{
+			Valid:  challenge.UserID != "",
+			String: challenge.UserID,
+		},
+		ExpiresAt: challenge.ExpiresAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) TakeWebAuthnChallenge(ctx context.Context, tx *sql.Tx, challenge string) (*core.WebAuthnChallenge, error) {
+	q := s.queries.WithTx(tx)
+	row, err := q.TakeWebAuthnChallenge(ctx, challenge)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.WebAuthnChallenge{
+		Challenge: row.Challenge,
+		Ceremony:  core.WebAuthnCeremony(row.Ceremony),
+		UserID:    row.UserID.String,
+		ExpiresAt: row.ExpiresAt,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) DeleteExpiredWebAuthnChallenges(ctx context.Context, tx *sql.Tx, now time.Time) error {
+	q := s.queries.WithTx(tx)
+	err := q.DeleteExpiredWebAuthnChallenges(ctx, now)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func personalAccessTokenFromRow(row *database.IdentityPersonalAccessToken) core.PersonalAccessToken {
+	return core.PersonalAccessToken{
+		TokenHash:  row.TokenHash,
+		ID:         row.TokenID,
+		UserID:     row.UserID,
+		Name:       row.Name,
+		Scope:      core.Scope(row.Scope),
+		CreatedAt:  row.CreatedAt,
+		ExpiresAt:  row.ExpiresAt.Time,
+		LastUsedAt: row.LastUsedAt.Time,
+	}
+}
+
+func (s *PostgreSQLStorage) GetPersonalAccessTokens(ctx context.Context, tx *sql.Tx, userID string) ([]core.PersonalAccessToken, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.GetPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tokens := make([]core.PersonalAccessToken, len(rows))
+	for i, row := range rows {
+		tokens[i] = personalAccessTokenFromRow(row)
+	}
+	return tokens, nil
+}
+
+func (s *PostgreSQLStorage) GetPersonalAccessTokenByHash(ctx context.Context, tx *sql.Tx, tokenHash []byte) (*core.PersonalAccessToken, error) {
+	q := s.queries.WithTx(tx)
+	row, err := q.GetPersonalAccessTokenByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	token := personalAccessTokenFromRow(row)
+	return &token, nil
+}
+
+func (s *PostgreSQLStorage) AddPersonalAccessToken(ctx context.Context, tx *sql.Tx, token core.PersonalAccessToken) error {
+	q := s.queries.WithTx(tx)
+	err := q.AddPersonalAccessToken(ctx, database.AddPersonalAccessTokenParams{
+		TokenID:   token.ID,
+		UserID:    token.UserID,
+		Name:      token.Name,
+		TokenHash: token.TokenHash,
+		Scope:     string(token.Scope),
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: sql.NullTime{
+			Valid: !token.ExpiresAt.IsZero(),
+			Time:  token.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) UsePersonalAccessToken(ctx context.Context, tx *sql.Tx, tokenID string, usedAt time.Time) error {
+	q := s.queries.WithTx(tx)
+	err := q.UsePersonalAccessToken(ctx, database.UsePersonalAccessTokenParams{
+		TokenID: tokenID,
+		LastUsedAt: sql.NullTime{
+			Valid: true,
+			Time:  usedAt,
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) DeletePersonalAccessToken(ctx context.Context, tx *sql.Tx, userID, tokenID string) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.DeletePersonalAccessToken(ctx, database.DeletePersonalAccessTokenParams{
+		UserID:  userID,
+		TokenID: tokenID,
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) DeletePersonalAccessTokens(ctx context.Context, tx *sql.Tx, userID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.DeletePersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) SetSession(ctx context.Context, tx *sql.Tx, session core.Session) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetSession(ctx, database.SetSessionParams{
+		FamilyID:   session.ID,
+		UserID:     session.UserID,
+		ClientID:   session.ClientID,
+		UserAgent:  session.Device.UserAgent,
+		ClientIp:   session.Device.ClientIP,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetSession(ctx context.Context, tx *sql.Tx, id string) (*core.Session, error) {
+	q := s.queries.WithTx(tx)
+	row, err := q.GetSession(ctx, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &core.Session{
+		ID:       row.FamilyID,
+		UserID:   row.UserID,
+		ClientID: row.ClientID,
+		Device: core.SessionDevice{
+			UserAgent: row.UserAgent,
+			ClientIP:  row.ClientIp,
+		},
+		CreatedAt:  row.CreatedAt,
+		LastUsedAt: row.LastUsedAt,
+	}, nil
+}
+
+func (s *PostgreSQLStorage) GetActiveSessions(ctx context.Context, tx *sql.Tx, userID string, now time.Time) ([]core.Session, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.GetActiveSessions(ctx, database.GetActiveSessionsParams{
+		UserID: userID,
+		ExpiresAt: sql.NullTime{
+			Valid: true,
+			Time:  now,
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sessions := make([]core.Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = core.Session{
+			ID:         row.FamilyID,
+			UserID:     row.UserID,
+			ClientID:   row.ClientID,
+			ClientName: row.ClientName,
+			Device: core.SessionDevice{
+				UserAgent: row.UserAgent,
+				ClientIP:  row.ClientIp,
+			},
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: row.LastUsedAt,
+		}
+	}
+	return sessions, nil
+}
+
+func (s *PostgreSQLStorage) AddPasswordReset(ctx context.Context, tx *sql.Tx, reset core.PasswordReset) error {
+	q := s.queries.WithTx(tx)
+	err := q.AddPasswordReset(ctx, database.AddPasswordResetParams{
+		ResetID:   reset.ID,
+		UserID:    reset.UserID,
+		CreatedAt: reset.CreatedAt,
+		ExpiresAt: reset.ExpiresAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) UsePasswordReset(ctx context.Context, tx *sql.Tx, id string, usedAt time.Time) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.UsePasswordReset(ctx, database.UsePasswordResetParams{
+		ResetID: id,
+		UsedAt: sql.NullTime{
+			Valid: true,
+			Time:  usedAt,
+		},
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) CountPasswordResets(ctx context.Context, tx *sql.Tx, userID string, since time.Time) (int64, error) {
+	q := s.queries.WithTx(tx)
+	count, err := q.CountPasswordResets(ctx, database.CountPasswordResetsParams{
+		UserID:    userID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return count, nil
+}
+
+func (s *PostgreSQLStorage) DeletePasswordResets(ctx context.Context, tx *sql.Tx, userID string) error {
+	q := s.queries.WithTx(tx)
+	err := q.DeletePasswordResets(ctx, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// signupModeSetting is the name under which the signup mode is stored.
+const signupModeSetting = "signup_mode"
+
+func (s *PostgreSQLStorage) GetSignupMode(ctx context.Context, tx *sql.Tx) (core.SignupMode, error) {
+	q := s.queries.WithTx(tx)
+	value, err := q.GetSetting(ctx, signupModeSetting)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return core.SignupMode(value), nil
+}
+
+func (s *PostgreSQLStorage) SetSignupMode(ctx context.Context, tx *sql.Tx, mode core.SignupMode) error {
+	q := s.queries.WithTx(tx)
+	err := q.SetSetting(ctx, database.SetSettingParams{
+		Name:  signupModeSetting,
+		Value: string(mode),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) AddInvite(ctx context.Context, tx *sql.Tx, invite core.Invite) error {
+	q := s.queries.WithTx(tx)
+	err := q.AddInvite(ctx, database.AddInviteParams{
+		InviteID:  invite.ID,
+		CodeHash:  invite.CodeHash,
+		CreatedBy: invite.CreatedBy,
+		CreatedAt: invite.CreatedAt,
+		ExpiresAt: invite.ExpiresAt,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *PostgreSQLStorage) GetInvites(ctx context.Context, tx *sql.Tx) ([]core.Invite, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.GetInvites(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	invites := make([]core.Invite, len(rows))
+	for i, row := range rows {
+		invites[i] = core.Invite{
+			ID:        row.InviteID,
+			CodeHash:  row.CodeHash,
+			CreatedBy: row.CreatedBy,
+			CreatedAt: row.CreatedAt,
+			ExpiresAt: row.ExpiresAt,
+			UsedAt:    row.UsedAt.Time,
+		}
+	}
+	return invites, nil
+}
+
+func (s *PostgreSQLStorage) UseInvite(ctx context.Context, tx *sql.Tx, codeHash []byte, usedAt time.Time) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.UseInvite(ctx, database.UseInviteParams{
+		CodeHash: codeHash,
+		UsedAt: sql.NullTime{
+			Valid: true,
+			Time:  usedAt,
+		},
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgreSQLStorage) DeleteInvite(ctx context.Context, tx *sql.Tx, id string) (bool, error) {
+	q := s.queries.WithTx(tx)
+	rows, err := q.DeleteInvite(ctx, id)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rows == 1, nil
+}
@@ -40,32 +40,26 @@ type tokenResponse struct {
 	StatusCode   int
 }
 
-func authenthicateWithCredentialsViaClientCredentialsFlow(ctx context.Context, userCreds userCredentials, clientCreds clientCredentials) (context.Context, error) {
+func requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
 	tokenEndpoint, err := makeRequestURL(ctx, "/oauth/v2/token")
 	if err != nil {
-		return ctx, err
+		return nil, err
 	}
 	client := http.Client{}
-	formBody := strings.NewReader(url.Values{
-		"username":      []string{userCreds.username},
-		"password":      []string{userCreds.password},
-		"client_id":     []string{clientCreds.id},
-		"client_secret": []string{clientCreds.secret},
-		"grant_type":    []string{"password"},
-	}.Encode())
+	formBody := strings.NewReader(form.Encode())
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, formBody)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	if err != nil {
-		return ctx, err
+		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	resp, err := client.Do(req)
 	if err != nil {
-		return ctx, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return ctx, err
+		return nil, err
 	}
 	logger.DebugContext(ctx, "Received response", "statusCode", resp.StatusCode, "body", string(body), "headers", resp.Header)
 	response := tokenResponse{
@@ -74,10 +68,102 @@ func authenthicateWithCredentialsViaClientCredentialsFlow(ctx context.Context, u
 	if resp.StatusCode == http.StatusOK {
 		err = json.Unmarshal(body, &response)
 		if err != nil {
-			return ctx, err
+			return nil, err
+		}
+	}
+	return &response, nil
+}
+
+func authenthicateWithCredentialsViaClientCredentialsFlow(ctx context.Context, userCreds userCredentials, clientCreds clientCredentials) (context.Context, error) {
+	response, err := requestToken(ctx, url.Values{
+		"username":      []string{userCreds.username},
+		"password":      []string{userCreds.password},
+		"client_id":     []string{clientCreds.id},
+		"client_secret": []string{clientCreds.secret},
+		"grant_type":    []string{"password"},
+	})
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, tokenResponseKey{}, *response), nil
+}
+
+type previousTokenResponseKey struct{}
+
+func whenIRefreshTheAccessToken(ctx context.Context, previous string) (context.Context, error) {
+	clientCreds, ok := ctx.Value(bootstrapClientKey{}).(clientCredentials)
+	if !ok {
+		return ctx, fmt.Errorf("failed to extract bootstrap client")
+	}
+	latest, ok := ctx.Value(tokenResponseKey{}).(tokenResponse)
+	if !ok {
+		return ctx, fmt.Errorf("unable to obtain token response")
+	}
+	token := latest
+	if previous != "" {
+		token, ok = ctx.Value(previousTokenResponseKey{}).(tokenResponse)
+		if !ok {
+			return ctx, fmt.Errorf("unable to obtain previous token response")
 		}
 	}
-	return context.WithValue(ctx, tokenResponseKey{}, response), nil
+
+	response, err := requestToken(ctx, url.Values{
+		"refresh_token": []string{token.RefreshToken},
+		"client_id":     []string{clientCreds.id},
+		"client_secret": []string{clientCreds.secret},
+		"grant_type":    []string{"refresh_token"},
+	})
+	if err != nil {
+		return ctx, err
+	}
+	if response.StatusCode != http.StatusOK {
+		// keep the latest successful pair around for the following steps
+		response.RefreshToken = latest.RefreshToken
+		response.AccessToken = latest.AccessToken
+	} else {
+		ctx = context.WithValue(ctx, previousTokenResponseKey{}, latest)
+	}
+	return context.WithValue(ctx, tokenResponseKey{}, *response), nil
+}
+
+func whenIRevokeTheToken(ctx context.Context, tokenType string) (context.Context, error) {
+	clientCreds, ok := ctx.Value(bootstrapClientKey{}).(clientCredentials)
+	if !ok {
+		return ctx, fmt.Errorf("failed to extract bootstrap client")
+	}
+	latest, ok := ctx.Value(tokenResponseKey{}).(tokenResponse)
+	if !ok {
+		return ctx, fmt.Errorf("unable to obtain token response")
+	}
+	token := latest.AccessToken
+	if tokenType == "refresh" {
+		token = latest.RefreshToken
+	}
+
+	revocationEndpoint, err := makeRequestURL(ctx, "/oauth/v2/revoke")
+	if err != nil {
+		return ctx, err
+	}
+	form := url.Values{
+		"token":           []string{token},
+		"token_type_hint": []string{tokenType + "_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revocationEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return ctx, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientCreds.id), url.QueryEscape(clientCreds.secret))
+	client := http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ctx, fmt.Errorf("revocation should always succeed, instead got %d status code", resp.StatusCode)
+	}
+	return ctx, nil
 }
 
 func givenIAmAuthenticatedAsAdmin(ctx context.Context) (context.Context, error) {
@@ -234,7 +320,8 @@ func thenTheClientAuthOutcomeShouldBe(ctx context.Context, outcome string) (cont
 			return ctx, fmt.Errorf("auth outcome should succeed, instead got %d status code", actualOutcome.StatusCode)
 		}
 	case "rejected":
-		if actualOutcome.StatusCode != http.StatusUnauthorized {
+		// the bad client credentials are unauthorized, the bad grants are bad requests
+		if actualOutcome.StatusCode != http.StatusUnauthorized && actualOutcome.StatusCode != http.StatusBadRequest {
 			return ctx, fmt.Errorf("auth outcome should fail, instead got %d status code", actualOutcome.StatusCode)
 		}
 	default:
@@ -257,6 +344,8 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 
 	ctx.When(`client uses credentials to authenticate`, whenClientUsesCredentialsToAuthenticate)
 	ctx.When(`I use bootstrap credentials to authenticate`, whenIUseBootstrapCredentialsToAuthenticate)
+	ctx.When(`I refresh the access token( using the previous refresh token)?`, whenIRefreshTheAccessToken)
+	ctx.When(`I revoke the (access|refresh) token`, whenIRevokeTheToken)
 	ctx.When(`I create a new (user|admin) account`, whenICreateANewAccount)
 	ctx.When(`I (?:try to )?delete (my|bootstrapped admin|that) account`, whenITryToDeleteAccount)
 